@@ -40,6 +40,8 @@ const (
 	MimetypeTypedData         = "data/typed"
 	MimetypeClique            = "application/x-clique-header"
 	MimetypeParlia            = "application/x-parlia-header"
+	MimetypeParliaDiffLayer   = "application/x-parlia-difflayer"
+	MimetypeParliaVote        = "application/x-parlia-vote"
 	MimetypeTextPlain         = "text/plain"
 )
 
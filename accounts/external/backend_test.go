@@ -0,0 +1,193 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// approvingUI is a signer UI approving every request.
+type approvingUI struct{}
+
+func (ui *approvingUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	return core.SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+}
+func (ui *approvingUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	return core.SignDataResponse{Approved: true}, nil
+}
+func (ui *approvingUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	return core.ListResponse{Accounts: request.Accounts}, nil
+}
+func (ui *approvingUI) ApproveNewAccount(request *core.NewAccountRequest) (core.NewAccountResponse, error) {
+	return core.NewAccountResponse{Approved: false}, nil
+}
+func (ui *approvingUI) OnInputRequired(info core.UserInputRequest) (core.UserInputResponse, error) {
+	return core.UserInputResponse{}, errors.New("no input")
+}
+func (ui *approvingUI) ShowError(message string)                     {}
+func (ui *approvingUI) ShowInfo(message string)                      {}
+func (ui *approvingUI) OnApprovedTx(tx ethapi.SignTransactionResult) {}
+func (ui *approvingUI) OnSignerStartup(info core.StartupInfo)        {}
+func (ui *approvingUI) RegisterUIServer(api *core.UIServerAPI)       {}
+
+// acceptingValidator is a transaction validator without any objections.
+type acceptingValidator struct{}
+
+func (v *acceptingValidator) ValidateTransaction(selector *string, tx *core.SendTxArgs) (*core.ValidationMessages, error) {
+	return new(core.ValidationMessages), nil
+}
+
+// newTestSigner starts an in-process signer holding a single account and
+// connects an external signer to it.
+func newTestSigner(t *testing.T, chainID *big.Int) (*ExternalSigner, accounts.Account) {
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("password")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	credentials := storage.NewEphemeralStorage()
+	credentials.Put(account.Address.Hex(), "password")
+
+	am := accounts.NewManager(&accounts.Config{}, ks)
+	api := core.NewSignerAPI(am, chainID.Int64(), true, new(approvingUI), new(acceptingValidator), false, credentials)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", api); err != nil {
+		t.Fatalf("failed to register signer API: %v", err)
+	}
+	t.Cleanup(server.Stop)
+
+	signer := &ExternalSigner{client: rpc.DialInProc(server), endpoint: "inproc"}
+	return signer, accounts.Account{Address: account.Address}
+}
+
+// Tests that Parlia headers are sealed through the external signer, which refuses
+// to seal a different header at a height it sealed before.
+func TestParliaHeaderSigning(t *testing.T) {
+	chainID := big.NewInt(1337)
+	signer, account := newTestSigner(t, chainID)
+
+	newHeader := func(number int64, parent common.Hash) *types.Header {
+		return &types.Header{
+			ParentHash: parent,
+			Coinbase:   account.Address,
+			Difficulty: big.NewInt(2),
+			Number:     big.NewInt(number),
+			GasLimit:   30000000,
+			Extra:      make([]byte, 32+65),
+			BaseFee:    big.NewInt(0),
+		}
+	}
+	seal := func(header *types.Header, chainID *big.Int) ([]byte, error) {
+		return signer.SignData(account, accounts.MimetypeParlia, parlia.ParliaRLP(header, chainID))
+	}
+	header := newHeader(10, common.Hash{0x01})
+	sig, err := seal(header, chainID)
+	if err != nil {
+		t.Fatalf("failed to seal header: %v", err)
+	}
+	pubkey, err := crypto.SigToPub(parlia.SealHash(header, chainID).Bytes(), sig)
+	if err != nil {
+		t.Fatalf("failed to recover sealer: %v", err)
+	}
+	if have := crypto.PubkeyToAddress(*pubkey); have != account.Address {
+		t.Fatalf("sealer mismatch: have %x, want %x", have, account.Address)
+	}
+	// Sealing the same header again is harmless, a sibling at the same height isn't
+	if _, err := seal(header, chainID); err != nil {
+		t.Fatalf("failed to seal header again: %v", err)
+	}
+	if _, err := seal(newHeader(10, common.Hash{0x02}), chainID); err == nil || !strings.Contains(err.Error(), core.ErrDoubleSign.Error()) {
+		t.Fatalf("double sign error mismatch: have %v, want %v", err, core.ErrDoubleSign)
+	}
+	if _, err := seal(newHeader(11, header.Hash()), chainID); err != nil {
+		t.Fatalf("failed to seal next header: %v", err)
+	}
+	// Headers of other chains or sealers are refused
+	if _, err := seal(newHeader(12, common.Hash{0x03}), big.NewInt(1)); err == nil {
+		t.Fatalf("sealed header of another chain")
+	}
+	other := newHeader(12, common.Hash{0x03})
+	other.Coinbase = common.Address{0xff}
+	if _, err := seal(other, chainID); err == nil {
+		t.Fatalf("sealed header of another validator")
+	}
+}
+
+// Tests that a Parlia engine authorized with an external signer signs its diff
+// layers and system transactions through it.
+func TestParliaExternalAuthorize(t *testing.T) {
+	chainID := big.NewInt(1337)
+	signer, account := newTestSigner(t, chainID)
+
+	config := &params.ChainConfig{ChainID: chainID, Parlia: &params.ParliaConfig{Period: 3, Epoch: 200}}
+	engine := parlia.New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+	engine.Authorize(account.Address, signer.SignData, signer.SignTx)
+
+	// Diff layers are signed with a plain signature over their hash
+	diffLayer := &types.DiffLayer{BlockHash: common.Hash{0x01}, Number: 10}
+	diffLayerRLP, err := rlp.EncodeToBytes(diffLayer)
+	if err != nil {
+		t.Fatalf("failed to encode diff layer: %v", err)
+	}
+	header := &types.Header{Number: big.NewInt(10), Coinbase: account.Address}
+	sig, err := engine.SignDiffLayer(header, diffLayerRLP)
+	if err != nil {
+		t.Fatalf("failed to sign diff layer: %v", err)
+	}
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(diffLayerRLP), sig)
+	if err != nil {
+		t.Fatalf("failed to recover diff layer signer: %v", err)
+	}
+	if have := crypto.PubkeyToAddress(*pubkey); have != account.Address {
+		t.Fatalf("diff layer signer mismatch: have %x, want %x", have, account.Address)
+	}
+	// Arbitrary data is not signed as a diff layer
+	if _, err := signer.SignData(account, accounts.MimetypeParliaDiffLayer, []byte("not a diff layer")); err == nil {
+		t.Fatalf("signed malformed diff layer")
+	}
+	// System transactions are signed for the chain without any gas price
+	txSigner := types.LatestSignerForChainID(chainID)
+	tx := types.NewTransaction(0, common.Address{0x10, 0x00}, big.NewInt(1), 1000000, new(big.Int), nil)
+	signed, err := signer.SignTx(account, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign system transaction: %v", err)
+	}
+	if have, want := txSigner.Hash(signed), txSigner.Hash(tx); have != want {
+		t.Fatalf("signed transaction mismatch: have %x, want %x", have, want)
+	}
+	if sender, err := types.Sender(txSigner, signed); err != nil || sender != account.Address {
+		t.Fatalf("system transaction sender mismatch: have %x (%v), want %x", sender, err, account.Address)
+	}
+}
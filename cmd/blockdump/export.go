@@ -0,0 +1,268 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	extraVanity = 32 // Fixed number of extra-data prefix bytes reserved for signer vanity
+	extraSeal   = 65 // Fixed number of extra-data suffix bytes reserved for signer seal
+)
+
+// bhvfABI is the ABI of the block header verification function (BHVF) the BAS
+// bridges use to verify validator transitions and cross chain packets.
+const bhvfABI = `[
+  {"inputs":[{"name":"rawBlockHeader","type":"bytes"},{"name":"existingValidatorSet","type":"address[]"}],"name":"verifyBlockHeader","outputs":[{"name":"newValidatorSet","type":"address[]"}],"stateMutability":"pure","type":"function"},
+  {"inputs":[{"name":"rawBlockHeader","type":"bytes"},{"name":"receiptsProof","type":"bytes"},{"name":"rawReceipt","type":"bytes"}],"name":"verifyCrossChainPacket","outputs":[{"name":"transferredAmount","type":"uint256"},{"name":"recipientAddress","type":"address"}],"stateMutability":"pure","type":"function"}
+]`
+
+var bhvf = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(bhvfABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+var errInvalidExtra = errors.New("invalid extra-data")
+
+// epochHeader is an epoch transition header along with everything a bridge
+// needs to verify it against the previous validator set.
+type epochHeader struct {
+	Number             uint64           `json:"number"`
+	Hash               common.Hash      `json:"hash"`
+	RawHeader          hexutil.Bytes    `json:"rawHeader"`
+	SigningData        hexutil.Bytes    `json:"signingData"`
+	Signer             *common.Address  `json:"signer,omitempty"` // Absent for the unsigned genesis
+	Validators         []common.Address `json:"validators"`
+	PreviousValidators []common.Address `json:"previousValidators"`
+}
+
+// receiptProof is a Merkle proof of a transaction receipt against the receipt
+// root of the header of its block.
+type receiptProof struct {
+	TxHash       common.Hash     `json:"txHash"`
+	BlockNumber  uint64          `json:"blockNumber"`
+	BlockHash    common.Hash     `json:"blockHash"`
+	ReceiptsRoot common.Hash     `json:"receiptsRoot"`
+	RawHeader    hexutil.Bytes   `json:"rawHeader"`
+	Index        uint64          `json:"index"`
+	RawReceipt   hexutil.Bytes   `json:"rawReceipt"`
+	Proof        []hexutil.Bytes `json:"proof"` // Trie nodes from the root down to the receipt
+}
+
+// headerSigner recovers the validator that sealed the header.
+func headerSigner(header *types.Header, chainID *big.Int) (common.Address, error) {
+	if len(header.Extra) < extraSeal {
+		return common.Address{}, fmt.Errorf("%w: header #%d too short", errInvalidExtra, header.Number)
+	}
+	signature := header.Extra[len(header.Extra)-extraSeal:]
+	pubkey, err := crypto.Ecrecover(parlia.SealHash(header, chainID).Bytes(), signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
+
+// exportEpochHeaders collects the epoch transition headers between the given
+// blocks. Epoch headers are recognized by the validators in their extra-data,
+// and each one carries the length of its epoch, or the chain config does, so
+// the next one is found without assuming a fixed epoch length. Every signed
+// header must be sealed by a member of the previous set.
+func exportEpochHeaders(source chainSource, config *params.ChainConfig, from, to uint64) ([]*epochHeader, error) {
+	var (
+		headers  []*epochHeader
+		previous []common.Address
+		next     uint64
+	)
+	if from > 0 {
+		parent, validators, length, err := lastEpochHeader(source, config, from-1)
+		if err != nil {
+			return nil, err
+		}
+		previous, next = validators, parent.Number.Uint64()+length
+	}
+	for next <= to {
+		number := next
+		header, err := source.HeaderByNumber(number)
+		if err != nil {
+			return nil, err
+		}
+		validators, length, err := parlia.ParseEpochHeader(config, header)
+		if err != nil {
+			return nil, err
+		}
+		if len(validators) == 0 {
+			return nil, fmt.Errorf("%w: header #%d expected to start an epoch carries no validators", errInvalidExtra, number)
+		}
+		if length == 0 {
+			return nil, fmt.Errorf("%w: header #%d starts an empty epoch", errInvalidExtra, number)
+		}
+		raw, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			return nil, err
+		}
+		exported := &epochHeader{
+			Number:             number,
+			Hash:               header.Hash(),
+			RawHeader:          raw,
+			SigningData:        parlia.ParliaRLP(header, config.ChainID),
+			Validators:         validators,
+			PreviousValidators: previous,
+		}
+		if number == 0 {
+			exported.PreviousValidators = validators
+		} else {
+			signer, err := headerSigner(header, config.ChainID)
+			if err != nil {
+				return nil, err
+			}
+			if !containsAddress(previous, signer) {
+				return nil, fmt.Errorf("header #%d sealed by %x outside of the previous validator set", number, signer)
+			}
+			exported.Signer = &signer
+		}
+		headers = append(headers, exported)
+		previous, next = validators, number+length
+	}
+	return headers, nil
+}
+
+// lastEpochHeader walks back from the given block to the closest epoch header,
+// returning it along with its validators and the length of its epoch.
+func lastEpochHeader(source chainSource, config *params.ChainConfig, number uint64) (*types.Header, []common.Address, uint64, error) {
+	for n := number + 1; n > 0; n-- {
+		header, err := source.HeaderByNumber(n - 1)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		validators, length, err := parlia.ParseEpochHeader(config, header)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if len(validators) > 0 {
+			return header, validators, length, nil
+		}
+	}
+	return nil, nil, 0, fmt.Errorf("%w: no epoch header up to #%d", errInvalidExtra, number)
+}
+
+// exportReceiptProof proves the receipt of the given transaction against the
+// receipt root of its block.
+func exportReceiptProof(source chainSource, hash common.Hash) (*receiptProof, error) {
+	header, receipts, index, err := source.TransactionReceipts(hash)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		buf      = new(bytes.Buffer)
+		encoded  [][]byte
+		indexKey []byte
+	)
+	for i := range receipts {
+		key, _ := rlp.EncodeToBytes(uint(i))
+		buf.Reset()
+		receipts.EncodeIndex(i, buf)
+		value := common.CopyBytes(buf.Bytes())
+		tr.Update(key, value)
+		encoded = append(encoded, value)
+		if uint64(i) == index {
+			indexKey = key
+		}
+	}
+	if root := tr.Hash(); root != header.ReceiptHash {
+		return nil, fmt.Errorf("receipt root mismatch for block #%d: have %x, want %x", header.Number, root, header.ReceiptHash)
+	}
+	if indexKey == nil {
+		return nil, fmt.Errorf("transaction index %d out of range in block #%d", index, header.Number)
+	}
+	var nodes light.NodeList
+	if err := tr.Prove(indexKey, 0, &nodes); err != nil {
+		return nil, err
+	}
+	raw, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	proof := &receiptProof{
+		TxHash:       hash,
+		BlockNumber:  header.Number.Uint64(),
+		BlockHash:    header.Hash(),
+		ReceiptsRoot: header.ReceiptHash,
+		RawHeader:    raw,
+		Index:        index,
+		RawReceipt:   encoded[index],
+	}
+	for _, node := range nodes {
+		proof.Proof = append(proof.Proof, hexutil.Bytes(node))
+	}
+	return proof, nil
+}
+
+// encodedProof returns the proof nodes as the RLP list the bridge contracts
+// expect as receipts proof.
+func (p *receiptProof) encodedProof() []byte {
+	nodes := make([][]byte, len(p.Proof))
+	for i, node := range p.Proof {
+		nodes[i] = node
+	}
+	enc, _ := rlp.EncodeToBytes(nodes)
+	return enc
+}
+
+// calldata returns the BHVF call verifying the epoch header.
+func (h *epochHeader) calldata() ([]byte, error) {
+	return bhvf.Pack("verifyBlockHeader", []byte(h.RawHeader), h.PreviousValidators)
+}
+
+// calldata returns the BHVF call verifying the proven receipt.
+func (p *receiptProof) calldata() ([]byte, error) {
+	return bhvf.Pack("verifyCrossChainPacket", []byte(p.RawHeader), p.encodedProof(), []byte(p.RawReceipt))
+}
+
+func containsAddress(addrs []common.Address, addr common.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
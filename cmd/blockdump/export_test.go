@@ -0,0 +1,431 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const testEpoch = 4
+
+// testValidator is a validator key of the generated test chain.
+type testValidator struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+func newTestValidators(n int) []testValidator {
+	validators := make([]testValidator, n)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		validators[i] = testValidator{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}
+	}
+	sort.Slice(validators, func(i, j int) bool {
+		return bytes.Compare(validators[i].addr[:], validators[j].addr[:]) < 0
+	})
+	return validators
+}
+
+// sealHeader fills the extra-data of a header the way Parlia does, listing the
+// validators on epoch headers and signing it with the given key.
+func sealHeader(t *testing.T, header *types.Header, chainID *big.Int, validators []testValidator, key *ecdsa.PrivateKey) {
+	header.Extra = make([]byte, extraVanity)
+	if header.Number.Uint64()%testEpoch == 0 {
+		for _, v := range validators {
+			header.Extra = append(header.Extra, v.addr.Bytes()...)
+		}
+	}
+	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+	if key == nil {
+		return
+	}
+	sig, err := crypto.Sign(parlia.SealHash(header, chainID).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to seal header: %v", err)
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+}
+
+// newTestChain writes a Parlia style chain to an in-memory database. The first
+// validator set is replaced at the second epoch, block 5 contains transactions.
+func newTestChain(t *testing.T, blocks int) (ethdb.Database, [][]testValidator, *types.Block) {
+	var (
+		db      = rawdb.NewDatabase(memorydb.New())
+		config  = &params.ChainConfig{ChainID: big.NewInt(1337), Parlia: &params.ParliaConfig{Period: 3, Epoch: testEpoch}}
+		sets    = [][]testValidator{newTestValidators(3), newTestValidators(3)}
+		txKey   = sets[0][0].key
+		signer  = types.LatestSigner(config)
+		parent  *types.Header
+		txBlock *types.Block
+	)
+	for number := 0; number < blocks; number++ {
+		header := &types.Header{
+			Number:      big.NewInt(int64(number)),
+			Difficulty:  big.NewInt(2),
+			GasLimit:    params.GenesisGasLimit,
+			Time:        uint64(number * 3),
+			UncleHash:   types.EmptyUncleHash,
+			TxHash:      types.EmptyRootHash,
+			ReceiptHash: types.EmptyRootHash,
+		}
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+		}
+		var (
+			txs      types.Transactions
+			receipts types.Receipts
+		)
+		if number == 5 {
+			for i := 0; i < 3; i++ {
+				tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{0xbb}, big.NewInt(int64(i)), params.TxGas, big.NewInt(1), nil), signer, txKey)
+				txs = append(txs, tx)
+				receipts = append(receipts, &types.Receipt{
+					Status:            types.ReceiptStatusSuccessful,
+					CumulativeGasUsed: uint64(i+1) * params.TxGas,
+					TxHash:            tx.Hash(),
+					GasUsed:           params.TxGas,
+					Logs:              []*types.Log{{Address: common.Address{0xbb}, Topics: []common.Hash{{byte(i)}}}},
+				})
+				receipts[i].Bloom = types.CreateBloom(types.Receipts{receipts[i]})
+			}
+			header.TxHash = types.DeriveSha(txs, trie.NewStackTrie(nil))
+			header.ReceiptHash = types.DeriveSha(receipts, trie.NewStackTrie(nil))
+		}
+		// Epoch headers carry the next set, but are sealed by the current one
+		set := sets[0]
+		if number >= 2*testEpoch {
+			set = sets[1]
+		}
+		var key *ecdsa.PrivateKey
+		if number > 0 {
+			sealer := sets[0]
+			if number > 2*testEpoch {
+				sealer = sets[1]
+			}
+			key = sealer[number%len(sealer)].key
+		}
+		sealHeader(t, header, config.ChainID, set, key)
+
+		block := types.NewBlockWithHeader(header).WithBody(txs, nil)
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		if len(receipts) > 0 {
+			rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts)
+			rawdb.WriteTxLookupEntriesByBlock(db, block)
+			txBlock = block
+		}
+		if number == 0 {
+			rawdb.WriteChainConfig(db, block.Hash(), config)
+		}
+		parent = header
+	}
+	return db, sets, txBlock
+}
+
+func addresses(validators []testValidator) []common.Address {
+	addrs := make([]common.Address, len(validators))
+	for i, v := range validators {
+		addrs[i] = v.addr
+	}
+	return addrs
+}
+
+func TestExportEpochHeaders(t *testing.T) {
+	db, sets, _ := newTestChain(t, 13)
+	source, err := newDBSource(db)
+	if err != nil {
+		t.Fatalf("failed to open source: %v", err)
+	}
+	chainID, _ := source.ChainID()
+
+	headers, err := exportEpochHeaders(source, source.Config(), 0, 12)
+	if err != nil {
+		t.Fatalf("failed to export headers: %v", err)
+	}
+	if len(headers) != 4 {
+		t.Fatalf("header count mismatch: have %d, want 4", len(headers))
+	}
+	wantSets := [][]common.Address{addresses(sets[0]), addresses(sets[0]), addresses(sets[1]), addresses(sets[1])}
+	for i, header := range headers {
+		if header.Number != uint64(i*testEpoch) {
+			t.Errorf("header %d: number mismatch: have %d, want %d", i, header.Number, i*testEpoch)
+		}
+		if !equalAddresses(header.Validators, wantSets[i]) {
+			t.Errorf("header %d: validator set mismatch", i)
+		}
+		prev := wantSets[0]
+		if i > 0 {
+			prev = wantSets[i-1]
+		}
+		if !equalAddresses(header.PreviousValidators, prev) {
+			t.Errorf("header %d: previous validator set mismatch", i)
+		}
+		if i == 0 {
+			if header.Signer != nil {
+				t.Errorf("genesis should have no signer")
+			}
+			continue
+		}
+		// The signer must match the signing payload and the raw header
+		var decoded types.Header
+		if err := rlp.DecodeBytes(header.RawHeader, &decoded); err != nil {
+			t.Fatalf("header %d: failed to decode raw header: %v", i, err)
+		}
+		if decoded.Hash() != header.Hash {
+			t.Errorf("header %d: raw header hash mismatch", i)
+		}
+		if !bytes.Equal(header.SigningData, parlia.ParliaRLP(&decoded, chainID)) {
+			t.Errorf("header %d: signing data mismatch", i)
+		}
+		if header.Signer == nil || !containsAddress(prev, *header.Signer) {
+			t.Errorf("header %d: signer %v not in previous set", i, header.Signer)
+		}
+	}
+	// Exporting a range starting mid-chain picks up the previous set
+	headers, err = exportEpochHeaders(source, source.Config(), 5, 9)
+	if err != nil {
+		t.Fatalf("failed to export headers: %v", err)
+	}
+	if len(headers) != 1 || headers[0].Number != 8 || !equalAddresses(headers[0].PreviousValidators, wantSets[1]) {
+		t.Fatalf("mid-chain export mismatch: %+v", headers)
+	}
+	// The hex and abi formats emit one line per header
+	for _, format := range []string{"hex", "abi"} {
+		out := new(bytes.Buffer)
+		if err := writeHeaders(out, format, headers); err != nil {
+			t.Fatalf("failed to write %s: %v", format, err)
+		}
+		if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 1 {
+			t.Errorf("%s: line count mismatch: have %d, want 1", format, len(lines))
+		}
+	}
+	out := new(bytes.Buffer)
+	if err := writeHeaders(out, "abi", headers); err != nil {
+		t.Fatalf("failed to write abi: %v", err)
+	}
+	calldata := hexutil.MustDecode(strings.TrimSpace(out.String()))
+	args, err := bhvf.Methods["verifyBlockHeader"].Inputs.Unpack(calldata[4:])
+	if err != nil {
+		t.Fatalf("failed to unpack calldata: %v", err)
+	}
+	if !bytes.Equal(args[0].([]byte), headers[0].RawHeader) || !equalAddresses(args[1].([]common.Address), wantSets[1]) {
+		t.Errorf("calldata mismatch")
+	}
+}
+
+func TestExportEpochHeadersForeignSigner(t *testing.T) {
+	db, _, _ := newTestChain(t, 9)
+
+	// Reseal the second epoch header with a key outside of the validator set
+	header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, testEpoch), testEpoch)
+	foreign, _ := crypto.GenerateKey()
+	sealHeader(t, header, big.NewInt(1337), newTestValidators(3), foreign)
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteCanonicalHash(db, header.Hash(), testEpoch)
+
+	source, _ := newDBSource(db)
+	if _, err := exportEpochHeaders(source, source.Config(), 0, testEpoch); err == nil {
+		t.Fatalf("expected error for header sealed outside of the validator set")
+	}
+}
+
+// Tests that epoch headers are found by their extra-data once their lengths are
+// governed on chain, instead of at multiples of the configured epoch length.
+func TestExportEpochHeadersGoverned(t *testing.T) {
+	var (
+		db         = rawdb.NewDatabase(memorydb.New())
+		config     = &params.ChainConfig{ChainID: big.NewInt(1337), Parlia: &params.ParliaConfig{Period: 3, Epoch: testEpoch, ParamsBlock: big.NewInt(1)}}
+		validators = newTestValidators(3)
+		lengths    = map[uint64]uint32{0: testEpoch, 4: 2, 6: 6, 12: 6} // Epoch headers and the lengths they carry
+		parent     *types.Header
+	)
+	for number := uint64(0); number < 14; number++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(number), Difficulty: big.NewInt(2), Time: number * 3}
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+		}
+		header.Extra = make([]byte, extraVanity)
+		if length, ok := lengths[number]; ok {
+			for _, v := range validators {
+				header.Extra = append(header.Extra, v.addr.Bytes()...)
+			}
+			if number > 0 {
+				params := make([]byte, 12)
+				binary.BigEndian.PutUint32(params[0:4], 3)
+				binary.BigEndian.PutUint32(params[4:8], length)
+				binary.BigEndian.PutUint32(params[8:12], 21)
+				header.Extra = append(header.Extra, params...)
+			}
+		}
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+		if number > 0 {
+			sig, err := crypto.Sign(parlia.SealHash(header, config.ChainID).Bytes(), validators[number%3].key)
+			if err != nil {
+				t.Fatalf("failed to seal header: %v", err)
+			}
+			copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), number)
+		if number == 0 {
+			rawdb.WriteChainConfig(db, header.Hash(), config)
+		}
+		parent = header
+	}
+	source, err := newDBSource(db)
+	if err != nil {
+		t.Fatalf("failed to open source: %v", err)
+	}
+	tests := []struct {
+		from, to uint64
+		want     []uint64
+	}{
+		{0, 13, []uint64{0, 4, 6, 12}},
+		{5, 12, []uint64{6, 12}},
+		{7, 11, nil},
+	}
+	for i, tt := range tests {
+		headers, err := exportEpochHeaders(source, source.Config(), tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("test %d: failed to export headers: %v", i, err)
+		}
+		var have []uint64
+		for _, header := range headers {
+			have = append(have, header.Number)
+			if !equalAddresses(header.PreviousValidators, addresses(validators)) {
+				t.Errorf("test %d: header #%d: previous validator set mismatch", i, header.Number)
+			}
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: epoch headers mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+func TestExportReceiptProof(t *testing.T) {
+	db, _, block := newTestChain(t, 8)
+	source, err := newDBSource(db)
+	if err != nil {
+		t.Fatalf("failed to open source: %v", err)
+	}
+	for i, tx := range block.Transactions() {
+		proof, err := exportReceiptProof(source, tx.Hash())
+		if err != nil {
+			t.Fatalf("tx %d: failed to export proof: %v", i, err)
+		}
+		if proof.Index != uint64(i) || proof.BlockHash != block.Hash() || proof.ReceiptsRoot != block.ReceiptHash() {
+			t.Fatalf("tx %d: proof metadata mismatch: %+v", i, proof)
+		}
+		// Verify the proof the way a bridge contract would
+		nodes := memorydb.New()
+		for _, node := range proof.Proof {
+			nodes.Put(crypto.Keccak256(node), node)
+		}
+		key, _ := rlp.EncodeToBytes(uint(i))
+		value, err := trie.VerifyProof(proof.ReceiptsRoot, key, nodes)
+		if err != nil {
+			t.Fatalf("tx %d: invalid proof: %v", i, err)
+		}
+		if !bytes.Equal(value, proof.RawReceipt) {
+			t.Errorf("tx %d: proven receipt mismatch", i)
+		}
+		var receipt types.Receipt
+		if err := rlp.DecodeBytes(proof.RawReceipt, &receipt); err != nil {
+			t.Fatalf("tx %d: failed to decode receipt: %v", i, err)
+		}
+		if receipt.Logs[0].Topics[0] != (common.Hash{byte(i)}) {
+			t.Errorf("tx %d: receipt log mismatch", i)
+		}
+		// The encoded proof decodes into the same node list
+		var decoded [][]byte
+		if err := rlp.DecodeBytes(proof.encodedProof(), &decoded); err != nil || len(decoded) != len(proof.Proof) {
+			t.Errorf("tx %d: encoded proof mismatch: %v", i, err)
+		}
+	}
+	if _, err := exportReceiptProof(source, common.Hash{0x01}); err == nil {
+		t.Fatalf("expected error for unknown transaction")
+	}
+}
+
+func equalAddresses(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Tests that a local datadir is opened with the engine its database was created
+// with, not just leveldb.
+func TestOpenDBSourcePebble(t *testing.T) {
+	datadir := t.TempDir()
+	chaindata := filepath.Join(datadir, "geth", "chaindata")
+
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Type:              "pebble",
+		Directory:         chaindata,
+		AncientsDirectory: filepath.Join(chaindata, "ancient"),
+		Cache:             16,
+		Handles:           16,
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(2)}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	rawdb.WriteHeadHeaderHash(db, genesis.Hash())
+	rawdb.WriteChainConfig(db, genesis.Hash(), &params.ChainConfig{ChainID: big.NewInt(1337), Parlia: &params.ParliaConfig{Epoch: testEpoch}})
+	db.Close()
+
+	source, err := openDBSource(datadir)
+	if err != nil {
+		t.Fatalf("failed to open source: %v", err)
+	}
+	defer source.Close()
+
+	if header, err := source.HeaderByNumber(0); err != nil || header.Hash() != genesis.Hash() {
+		t.Fatalf("genesis mismatch: have %v, %v, want %x", header, err, genesis.Hash())
+	}
+	if _, err := openDBSource(t.TempDir()); err == nil {
+		t.Fatalf("opened a datadir without a database")
+	}
+}
@@ -1,37 +1,257 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// blockdump exports Parlia epoch transition headers and receipt proofs in the
+// formats consumed by the BAS bridge contracts.
 package main
 
 import (
-	"context"
-	"math/big"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/urfave/cli.v1"
 )
 
-func main() {
-	eth, err := ethclient.Dial("https://rpc.ankr.com/bsc")
-	if err != nil {
-		panic(err)
+// Git SHA1 commit hash of the release (set via linker flags)
+var gitCommit = ""
+var gitDate = ""
+
+var app *cli.App
+
+func init() {
+	app = flags.NewApp(gitCommit, gitDate, "a Parlia header and receipt proof exporter")
+	app.Commands = []cli.Command{
+		commandHeaders,
+		commandProof,
+	}
+	cli.CommandHelpTemplate = flags.OriginCommandHelpTemplate
+}
+
+// Commonly used command line flags.
+var (
+	rpcFlag = cli.StringFlag{
+		Name:  "rpc",
+		Usage: "RPC endpoint of the node to export from",
+	}
+	datadirFlag = cli.StringFlag{
+		Name:  "datadir",
+		Usage: "Data directory of a stopped local node to export from",
+	}
+	formatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format: json, hex or abi (BHVF calldata)",
+		Value: "json",
+	}
+	fromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block of the range to export epoch headers of",
+	}
+	toFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block of the range to export epoch headers of (default = from)",
+	}
+	epochFlag = cli.Uint64Flag{
+		Name:  "epoch",
+		Usage: "Epoch length of the chain before the epoch headers carried it (default = chain config of the datadir, 200 over RPC)",
+	}
+)
+
+var commandHeaders = cli.Command{
+	Name:  "headers",
+	Usage: "export epoch transition headers",
+	Description: `
+Exports the epoch transition headers in the given block range along with their
+validator sets, signing payloads and recovered signers. Epoch headers are told
+apart by the validators in their extra-data. Over RPC the chain config is not
+known, so the headers are parsed as before the Parlia params and fast finality
+forks.
+
+The hex format prints one raw header per line, the abi format one BHVF
+verifyBlockHeader call per line.`,
+	Flags: []cli.Flag{
+		rpcFlag,
+		datadirFlag,
+		formatFlag,
+		fromFlag,
+		toFlag,
+		epochFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		source, err := openSource(ctx)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+
+		config, err := headersConfig(ctx, source)
+		if err != nil {
+			return err
+		}
+		from, to := ctx.Uint64(fromFlag.Name), ctx.Uint64(toFlag.Name)
+		if !ctx.IsSet(toFlag.Name) {
+			to = from
+		}
+		if to < from {
+			return fmt.Errorf("invalid block range %d-%d", from, to)
+		}
+		headers, err := exportEpochHeaders(source, config, from, to)
+		if err != nil {
+			return err
+		}
+		return writeHeaders(os.Stdout, ctx.String(formatFlag.Name), headers)
+	},
+}
+
+var commandProof = cli.Command{
+	Name:      "proof",
+	Usage:     "export the receipt proof of a transaction",
+	ArgsUsage: "<txhash>",
+	Description: `
+Exports the Merkle proof of the receipt of the given transaction against the
+receipt root of its block header.
+
+The hex format prints the raw header, the RLP encoded proof nodes and the raw
+receipt on separate lines, the abi format the BHVF verifyCrossChainPacket call.`,
+	Flags: []cli.Flag{
+		rpcFlag,
+		datadirFlag,
+		formatFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.NArg() != 1 {
+			return errors.New("expected a transaction hash")
+		}
+		hash, err := hexutil.Decode(ctx.Args().First())
+		if err != nil || len(hash) != common.HashLength {
+			return fmt.Errorf("invalid transaction hash %q", ctx.Args().First())
+		}
+		source, err := openSource(ctx)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+
+		proof, err := exportReceiptProof(source, common.BytesToHash(hash))
+		if err != nil {
+			return err
+		}
+		return writeProof(os.Stdout, ctx.String(formatFlag.Name), proof)
+	},
+}
+
+// openSource opens the chain source selected on the command line.
+func openSource(ctx *cli.Context) (chainSource, error) {
+	switch url, datadir := ctx.String(rpcFlag.Name), ctx.String(datadirFlag.Name); {
+	case url != "" && datadir != "":
+		return nil, fmt.Errorf("--%s and --%s are mutually exclusive", rpcFlag.Name, datadirFlag.Name)
+	case url != "":
+		return newRPCSource(url)
+	case datadir != "":
+		return openDBSource(datadir)
+	default:
+		return nil, fmt.Errorf("either --%s or --%s is required", rpcFlag.Name, datadirFlag.Name)
+	}
+}
+
+// headersConfig returns the chain config to parse the headers of the source
+// with, falling back to a plain Parlia config if the source doesn't know it.
+func headersConfig(ctx *cli.Context, source chainSource) (*params.ChainConfig, error) {
+	config := source.Config()
+	if config == nil {
+		chainID, err := source.ChainID()
+		if err != nil {
+			return nil, err
+		}
+		config = &params.ChainConfig{ChainID: chainID}
+	}
+	parlia := new(params.ParliaConfig)
+	if config.Parlia != nil {
+		*parlia = *config.Parlia
+	}
+	if ctx.IsSet(epochFlag.Name) {
+		parlia.Epoch = ctx.Uint64(epochFlag.Name)
+	}
+	if parlia.Epoch == 0 {
+		parlia.Epoch = 200
 	}
-	confirmations := 12
-	for i := 0; i < confirmations; i++ {
-		block, err := eth.BlockByNumber(context.Background(), big.NewInt(int64(15946200+i)))
+	cpy := *config
+	cpy.Parlia = parlia
+	return &cpy, nil
+}
+
+// writeHeaders prints the exported headers in the given format.
+func writeHeaders(w io.Writer, format string, headers []*epochHeader) error {
+	switch format {
+	case "json":
+		return writeJSON(w, headers)
+	case "hex":
+		for _, header := range headers {
+			fmt.Fprintln(w, header.RawHeader)
+		}
+	case "abi":
+		for _, header := range headers {
+			calldata, err := header.calldata()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, hexutil.Encode(calldata))
+		}
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return nil
+}
+
+// writeProof prints the exported receipt proof in the given format.
+func writeProof(w io.Writer, format string, proof *receiptProof) error {
+	switch format {
+	case "json":
+		return writeJSON(w, proof)
+	case "hex":
+		fmt.Fprintln(w, proof.RawHeader)
+		fmt.Fprintln(w, hexutil.Encode(proof.encodedProof()))
+		fmt.Fprintln(w, proof.RawReceipt)
+	case "abi":
+		calldata, err := proof.calldata()
 		if err != nil {
-			panic(err)
-		}
-		payload, _ := rlp.EncodeToBytes(block.Header())
-		println(hexutil.Encode(payload))
-	}
-	//println("-----------BLOCK HEADER JSON-----------------")
-	//json, _ := block.Header().MarshalJSON()
-	//println(string(json))
-	//println("------------BLOCK HEADER---------------------")
-	//payload, err := rlp.EncodeToBytes(block.Header())
-	//println(hexutil.Encode(payload)[2:])
-	//println("----------EXTRA DATA SHOULD BE---------------")
-	//println(hexutil.Encode(block.Header().Extra[:len(block.Header().Extra)-65])[2:])
-	//println("----------SIGNING DATA-----------------------")
-	//signingData := parlia.ParliaRLP(block.Header(), big.NewInt(56))
-	//println(hexutil.Encode(signingData)[2:])
+			return err
+		}
+		fmt.Fprintln(w, hexutil.Encode(calldata))
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,196 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	errUnknownHeader      = errors.New("unknown header")
+	errUnknownTransaction = errors.New("unknown transaction")
+)
+
+// chainSource is a provider of the chain data the exporters need, backed either
+// by a remote RPC endpoint or by a local node database.
+type chainSource interface {
+	// ChainID returns the chain id used to sign the headers.
+	ChainID() (*big.Int, error)
+
+	// Config returns the chain config, or nil if it isn't known.
+	Config() *params.ChainConfig
+
+	// HeaderByNumber returns the canonical header with the given number.
+	HeaderByNumber(number uint64) (*types.Header, error)
+
+	// TransactionReceipts returns the header and all receipts of the block that
+	// includes the given transaction, along with the index of the transaction.
+	TransactionReceipts(hash common.Hash) (*types.Header, types.Receipts, uint64, error)
+
+	// Close releases the resources held by the source.
+	Close()
+}
+
+// rpcSource is a chain source backed by a remote RPC endpoint.
+type rpcSource struct {
+	client *ethclient.Client
+}
+
+// newRPCSource dials the given RPC endpoint.
+func newRPCSource(url string) (*rpcSource, error) {
+	client, err := ethclient.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return &rpcSource{client: client}, nil
+}
+
+func (s *rpcSource) ChainID() (*big.Int, error) {
+	return s.client.ChainID(context.Background())
+}
+
+func (s *rpcSource) Config() *params.ChainConfig {
+	return nil // The chain config isn't exposed over RPC
+}
+
+func (s *rpcSource) HeaderByNumber(number uint64) (*types.Header, error) {
+	return s.client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(number))
+}
+
+func (s *rpcSource) TransactionReceipts(hash common.Hash) (*types.Header, types.Receipts, uint64, error) {
+	ctx := context.Background()
+
+	receipt, err := s.client.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("%w %x: %v", errUnknownTransaction, hash, err)
+	}
+	block, err := s.client.BlockByHash(ctx, receipt.BlockHash)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	receipts := make(types.Receipts, 0, len(block.Transactions()))
+	for _, tx := range block.Transactions() {
+		receipt, err := s.client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return block.Header(), receipts, uint64(receipt.TransactionIndex), nil
+}
+
+func (s *rpcSource) Close() {
+	s.client.Close()
+}
+
+// dbSource is a chain source backed by a local node database.
+type dbSource struct {
+	db     ethdb.Database
+	config *params.ChainConfig
+}
+
+// openDBSource opens the chain database of the node with the given data
+// directory in read-only mode, with whichever engine it was created with.
+func openDBSource(datadir string) (*dbSource, error) {
+	chaindata := filepath.Join(datadir, "geth", "chaindata")
+	engine := rawdb.PreexistingDatabase(chaindata)
+	if engine == "" {
+		return nil, fmt.Errorf("no chain database in %s", chaindata)
+	}
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Type:              engine,
+		Directory:         chaindata,
+		AncientsDirectory: filepath.Join(chaindata, "ancient"),
+		Cache:             16,
+		Handles:           16,
+		ReadOnly:          true,
+		DisableFreeze:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	source, err := newDBSource(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return source, nil
+}
+
+// newDBSource creates a chain source on top of the given chain database.
+func newDBSource(db ethdb.Database) (*dbSource, error) {
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil, errors.New("no genesis in database")
+	}
+	config := rawdb.ReadChainConfig(db, genesis)
+	if config == nil {
+		return nil, errors.New("no chain config in database")
+	}
+	return &dbSource{db: db, config: config}, nil
+}
+
+func (s *dbSource) ChainID() (*big.Int, error) {
+	return s.config.ChainID, nil
+}
+
+func (s *dbSource) Config() *params.ChainConfig {
+	return s.config
+}
+
+func (s *dbSource) HeaderByNumber(number uint64) (*types.Header, error) {
+	hash := rawdb.ReadCanonicalHash(s.db, number)
+	if hash == (common.Hash{}) {
+		return nil, fmt.Errorf("%w: #%d", errUnknownHeader, number)
+	}
+	header := rawdb.ReadHeader(s.db, hash, number)
+	if header == nil {
+		return nil, fmt.Errorf("%w: #%d [%x]", errUnknownHeader, number, hash)
+	}
+	return header, nil
+}
+
+func (s *dbSource) TransactionReceipts(hash common.Hash) (*types.Header, types.Receipts, uint64, error) {
+	_, blockHash, number, index := rawdb.ReadTransaction(s.db, hash)
+	if blockHash == (common.Hash{}) {
+		return nil, nil, 0, fmt.Errorf("%w: %x", errUnknownTransaction, hash)
+	}
+	header := rawdb.ReadHeader(s.db, blockHash, number)
+	if header == nil {
+		return nil, nil, 0, fmt.Errorf("%w: #%d [%x]", errUnknownHeader, number, blockHash)
+	}
+	receipts := rawdb.ReadReceipts(s.db, blockHash, number, s.config)
+	if receipts == nil {
+		return nil, nil, 0, fmt.Errorf("missing receipts of block #%d [%x]", number, blockHash)
+	}
+	return header, receipts, index, nil
+}
+
+func (s *dbSource) Close() {
+	s.db.Close()
+}
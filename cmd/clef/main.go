@@ -570,8 +570,9 @@ func signer(c *cli.Context) error {
 	log.Info("Loaded 4byte database", "embeds", embeds, "locals", locals, "local", fourByteLocal)
 
 	var (
-		api       core.ExternalAPI
-		pwStorage storage.Storage = &storage.NoStorage{}
+		api             core.ExternalAPI
+		pwStorage       storage.Storage = &storage.NoStorage{}
+		slashingStorage storage.Storage
 	)
 	configDir := c.GlobalString(configdirFlag.Name)
 	if stretchedKey, err := readMasterKey(c, ui); err != nil {
@@ -583,9 +584,11 @@ func signer(c *cli.Context) error {
 		pwkey := crypto.Keccak256([]byte("credentials"), stretchedKey)
 		jskey := crypto.Keccak256([]byte("jsstorage"), stretchedKey)
 		confkey := crypto.Keccak256([]byte("config"), stretchedKey)
+		slashkey := crypto.Keccak256([]byte("slashing"), stretchedKey)
 
 		// Initialize the encrypted storages
 		pwStorage = storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "credentials.json"), pwkey)
+		slashingStorage = storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "slashing.json"), slashkey)
 		jsStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "jsstorage.json"), jskey)
 		configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confkey)
 
@@ -625,6 +628,11 @@ func signer(c *cli.Context) error {
 		"light-kdf", lightKdf, "advanced", advanced)
 	am := core.StartClefAccountManager(ksLoc, nousb, lightKdf, scpath)
 	apiImpl := core.NewSignerAPI(am, chainId, nousb, ui, db, advanced, pwStorage)
+	if slashingStorage != nil {
+		apiImpl.SetSlashingProtection(slashingStorage)
+	} else {
+		log.Warn("Signed parlia headers only remembered until shutdown, slashing protection limited")
+	}
 
 	// Establish the bidirectional communication, by creating a new UI backend and registering
 	// it with the UI.
@@ -31,6 +31,7 @@ import (
 	"io/ioutil"
 	"math"
 	"math/big"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -41,17 +42,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
 	"github.com/ethereum/go-ethereum/core"
+	coresystemcontract "github.com/ethereum/go-ethereum/core/systemcontract"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/ethstats"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/les"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
@@ -59,6 +64,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/gorilla/websocket"
 )
 
@@ -69,12 +75,16 @@ var (
 	bootFlag    = flag.String("bootnodes", "", "Comma separated bootnode enode URLs to seed with")
 	netFlag     = flag.Uint64("network", 0, "Network ID to use for the Ethereum protocol")
 	statsFlag   = flag.String("ethstats", "", "Ethstats network monitoring auth string")
-	rpcApiFlag  = flag.String("rpcapi", "", "RPC api")
+	rpcApiFlag  = flag.String("rpcapi", "", "HTTP or WebSocket RPC endpoint of a full node to fund requests through (no light client)")
+	ipcPathFlag = flag.String("ipcpath", "", "IPC endpoint of a local full node to fund requests through (no light client)")
 
 	netnameFlag = flag.String("faucet.name", "", "Network name to assign to the faucet")
 	payoutFlag  = flag.Int("faucet.amount", 1, "Number of Ethers to pay out per user request")
 	minutesFlag = flag.Int("faucet.minutes", 1440, "Number of minutes to wait between funding rounds")
 	tiersFlag   = flag.Int("faucet.tiers", 3, "Number of funding tiers to enable (x3 time, x2.5 funds)")
+	gasFreeFlag = flag.Bool("faucet.gasfree", false, "Fund requests by adding the accounts to the gas-free list instead of sending tokens (faucet must be the gas-free admin)")
+	limitsFlag  = flag.String("faucet.limits", filepath.Join(os.Getenv("HOME"), ".faucet", "limits"), "Database persisting the funding timeouts of the users, addresses and IPs")
+	proxyFlag   = flag.Bool("faucet.proxied", false, "Rate limit by the X-Forwarded-For client IP of a trusted reverse proxy")
 
 	accJSONFlag = flag.String("account.json", "", "Key json file to fund user requests with")
 	accPassFlag = flag.String("account.pass", "", "Decryption password to access faucet funds")
@@ -96,6 +106,16 @@ var (
 	rinkebyFlag = flag.Bool("rinkeby", false, "Initializes the faucet with Rinkeby network config")
 )
 
+const (
+	// gasFreeFundingGas is the gas allowance of the transactions adding accounts
+	// to the gas-free list.
+	gasFreeFundingGas = 100000
+
+	// headPollInterval is the interval of the chain head queries on connections
+	// without notification support.
+	headPollInterval = 3 * time.Second
+)
+
 var (
 	ether        = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
 	bep2eAbiJson = `[ { "anonymous": false, "inputs": [ { "indexed": true, "internalType": "address", "name": "owner", "type": "address" }, { "indexed": true, "internalType": "address", "name": "spender", "type": "address" }, { "indexed": false, "internalType": "uint256", "name": "value", "type": "uint256" } ], "name": "Approval", "type": "event" }, { "anonymous": false, "inputs": [ { "indexed": true, "internalType": "address", "name": "from", "type": "address" }, { "indexed": true, "internalType": "address", "name": "to", "type": "address" }, { "indexed": false, "internalType": "uint256", "name": "value", "type": "uint256" } ], "name": "Transfer", "type": "event" }, { "inputs": [], "name": "totalSupply", "outputs": [ { "internalType": "uint256", "name": "", "type": "uint256" } ], "stateMutability": "view", "type": "function" }, { "inputs": [], "name": "decimals", "outputs": [ { "internalType": "uint256", "name": "", "type": "uint256" } ], "stateMutability": "view", "type": "function" }, { "inputs": [], "name": "symbol", "outputs": [ { "internalType": "string", "name": "", "type": "string" } ], "stateMutability": "view", "type": "function" }, { "inputs": [], "name": "getOwner", "outputs": [ { "internalType": "address", "name": "", "type": "address" } ], "stateMutability": "view", "type": "function" }, { "inputs": [ { "internalType": "address", "name": "account", "type": "address" } ], "name": "balanceOf", "outputs": [ { "internalType": "uint256", "name": "", "type": "uint256" } ], "stateMutability": "view", "type": "function" }, { "inputs": [ { "internalType": "address", "name": "recipient", "type": "address" }, { "internalType": "uint256", "name": "amount", "type": "uint256" } ], "name": "transfer", "outputs": [ { "internalType": "bool", "name": "", "type": "bool" } ], "stateMutability": "nonpayable", "type": "function" }, { "inputs": [ { "internalType": "address", "name": "_owner", "type": "address" }, { "internalType": "address", "name": "spender", "type": "address" } ], "name": "allowance", "outputs": [ { "internalType": "uint256", "name": "", "type": "uint256" } ], "stateMutability": "view", "type": "function" }, { "inputs": [ { "internalType": "address", "name": "spender", "type": "address" }, { "internalType": "uint256", "name": "amount", "type": "uint256" } ], "name": "approve", "outputs": [ { "internalType": "bool", "name": "", "type": "bool" } ], "stateMutability": "nonpayable", "type": "function" }, { "inputs": [ { "internalType": "address", "name": "sender", "type": "address" }, { "internalType": "address", "name": "recipient", "type": "address" }, { "internalType": "uint256", "name": "amount", "type": "uint256" } ], "name": "transferFrom", "outputs": [ { "internalType": "bool", "name": "", "type": "bool" } ], "stateMutability": "nonpayable", "type": "function" } ]`
@@ -120,6 +140,9 @@ func main() {
 		if amount == 1 {
 			amounts[i] = strings.TrimSuffix(amounts[i], "s")
 		}
+		if *gasFreeFlag {
+			amounts[i] = fmt.Sprintf("Gas-free access for %s", common.PrettyDuration(time.Duration(*minutesFlag*int(math.Pow(3, float64(i))))*time.Minute))
+		}
 	}
 	bep2eNumAmounts := make([]string, 0)
 	if bep2eAmounts != nil && len(*bep2eAmounts) > 0 {
@@ -170,23 +193,6 @@ func main() {
 	if err != nil {
 		log.Crit("Failed to render the faucet template", "err", err)
 	}
-	// Load and parse the genesis block requested by the user
-	genesis, err := getGenesis(genesisFlag, *goerliFlag, *rinkebyFlag)
-	if err != nil {
-		log.Crit("Failed to parse genesis config", "err", err)
-	}
-	// Convert the bootnodes to internal enode representations
-	var enodes []*enode.Node
-	for _, boot := range strings.Split(*bootFlag, ",") {
-		if boot == "" {
-			continue
-		}
-		if url, err := enode.Parse(enode.ValidSchemes, boot); err == nil {
-			enodes = append(enodes, url)
-		} else {
-			log.Error("Failed to parse bootnode URL", "url", boot, "err", err)
-		}
-	}
 	// Load up the account key and decrypt its password
 	blob, err := ioutil.ReadFile(*accPassFlag)
 	if err != nil {
@@ -205,12 +211,38 @@ func main() {
 	if err := ks.Unlock(acc, pass); err != nil {
 		log.Crit("Failed to unlock faucet signer account", "err", err)
 	}
-	// Assemble and start the faucet light service
+	// Open the funding timeouts persisted by previous runs
+	limits, err := newLimiter(*limitsFlag)
+	if err != nil {
+		log.Crit("Failed to open faucet limits", "path", *limitsFlag, "err", err)
+	}
+	// Assemble and start the faucet, either attached to a full node or backed by
+	// its own light service
 	var faucet *faucet
-	if *rpcApiFlag != "" {
-		faucet, err = newHttpFaucet(genesis, *rpcApiFlag, ks, website.Bytes(), bep2eInfos)
-	} else {
-		faucet, err = newFaucet(genesis, *ethPortFlag, enodes, *netFlag, *statsFlag, ks, website.Bytes(), bep2eInfos)
+	switch {
+	case *rpcApiFlag != "":
+		faucet, err = newRemoteFaucet(*rpcApiFlag, ks, limits, website.Bytes(), bep2eInfos)
+	case *ipcPathFlag != "":
+		faucet, err = newRemoteFaucet(*ipcPathFlag, ks, limits, website.Bytes(), bep2eInfos)
+	default:
+		// Load and parse the genesis block requested by the user
+		var genesis *core.Genesis
+		if genesis, err = getGenesis(genesisFlag, *goerliFlag, *rinkebyFlag); err != nil {
+			log.Crit("Failed to parse genesis config", "err", err)
+		}
+		// Convert the bootnodes to internal enode representations
+		var enodes []*enode.Node
+		for _, boot := range strings.Split(*bootFlag, ",") {
+			if boot == "" {
+				continue
+			}
+			if url, err := enode.Parse(enode.ValidSchemes, boot); err == nil {
+				enodes = append(enodes, url)
+			} else {
+				log.Error("Failed to parse bootnode URL", "url", boot, "err", err)
+			}
+		}
+		faucet, err = newFaucet(genesis, *ethPortFlag, enodes, *netFlag, *statsFlag, ks, limits, website.Bytes(), bep2eInfos)
 	}
 	if err != nil {
 		log.Crit("Failed to start faucet", "err", err)
@@ -236,12 +268,13 @@ type bep2eInfo struct {
 	AmountStr string
 }
 
-// faucet represents a crypto faucet backed by an Ethereum light client.
+// faucet represents a crypto faucet backed by an Ethereum light client or an
+// RPC connection to a full node.
 type faucet struct {
-	config *params.ChainConfig // Chain configurations for signing
-	stack  *node.Node          // Ethereum protocol stack
-	client *ethclient.Client   // Client connection to the Ethereum chain
-	index  []byte              // Index page to serve up on the web
+	chainID *big.Int          // Chain identifier for signing
+	stack   *node.Node        // Ethereum protocol stack, nil if attached to a remote node
+	client  *ethclient.Client // Client connection to the Ethereum chain
+	index   []byte            // Index page to serve up on the web
 
 	keystore *keystore.KeyStore // Keystore containing the single signer
 	account  accounts.Account   // Account funding user faucet requests
@@ -250,15 +283,16 @@ type faucet struct {
 	nonce    uint64             // Current pending nonce of the faucet
 	price    *big.Int           // Current gas price to issue funds with
 
-	conns    []*wsConn            // Currently live websocket connections
-	timeouts map[string]time.Time // History of users and their funding timeouts
-	reqs     []*request           // Currently pending funding requests
-	update   chan struct{}        // Channel to signal request updates
+	conns  []*wsConn     // Currently live websocket connections
+	limits *limiter      // History of users, addresses and IPs and their funding timeouts
+	reqs   []*request    // Currently pending funding requests
+	update chan struct{} // Channel to signal request updates
 
 	lock sync.RWMutex // Lock protecting the faucet's internals
 
-	bep2eInfos map[string]bep2eInfo
-	bep2eAbi   abi.ABI
+	bep2eInfos     map[string]bep2eInfo
+	bep2eAbi       abi.ABI
+	chainConfigAbi abi.ABI
 }
 
 // wsConn wraps a websocket connection with a write mutex as the underlying
@@ -268,7 +302,7 @@ type wsConn struct {
 	wlock sync.Mutex
 }
 
-func newFaucet(genesis *core.Genesis, port int, enodes []*enode.Node, network uint64, stats string, ks *keystore.KeyStore, index []byte, bep2eInfos map[string]bep2eInfo) (*faucet, error) {
+func newFaucet(genesis *core.Genesis, port int, enodes []*enode.Node, network uint64, stats string, ks *keystore.KeyStore, limits *limiter, index []byte, bep2eInfos map[string]bep2eInfo) (*faucet, error) {
 	// Assemble the raw devp2p protocol stack
 	stack, err := node.New(&node.Config{
 		Name:    "geth",
@@ -328,44 +362,59 @@ func newFaucet(genesis *core.Genesis, port int, enodes []*enode.Node, network ui
 	client := ethclient.NewClient(api)
 
 	return &faucet{
-		config:     genesis.Config,
-		stack:      stack,
-		client:     client,
-		index:      index,
-		keystore:   ks,
-		account:    ks.Accounts()[0],
-		timeouts:   make(map[string]time.Time),
-		update:     make(chan struct{}, 1),
-		bep2eInfos: bep2eInfos,
-		bep2eAbi:   bep2eAbi,
+		chainID:        genesis.Config.ChainID,
+		stack:          stack,
+		client:         client,
+		index:          index,
+		keystore:       ks,
+		account:        ks.Accounts()[0],
+		limits:         limits,
+		update:         make(chan struct{}, 1),
+		bep2eInfos:     bep2eInfos,
+		bep2eAbi:       bep2eAbi,
+		chainConfigAbi: coresystemcontract.ChainConfigABI(),
 	}, nil
 }
 
-func newHttpFaucet(genesis *core.Genesis, rpcApi string, ks *keystore.KeyStore, index []byte, bep2eInfos map[string]bep2eInfo) (*faucet, error) {
+// newRemoteFaucet creates a faucet attached to the HTTP, WebSocket or IPC RPC
+// endpoint of a full node, following whatever chain the node does.
+func newRemoteFaucet(endpoint string, ks *keystore.KeyStore, limits *limiter, index []byte, bep2eInfos map[string]bep2eInfo) (*faucet, error) {
 	bep2eAbi, err := abi.JSON(strings.NewReader(bep2eAbiJson))
 	if err != nil {
 		return nil, err
 	}
-	client, err := ethclient.Dial(rpcApi)
+	client, err := ethclient.Dial(endpoint)
 	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to retrieve chain id: %w", err)
+	}
 	return &faucet{
-		config:     genesis.Config,
-		client:     client,
-		index:      index,
-		keystore:   ks,
-		account:    ks.Accounts()[0],
-		timeouts:   make(map[string]time.Time),
-		update:     make(chan struct{}, 1),
-		bep2eInfos: bep2eInfos,
-		bep2eAbi:   bep2eAbi,
+		chainID:        chainID,
+		client:         client,
+		index:          index,
+		keystore:       ks,
+		account:        ks.Accounts()[0],
+		limits:         limits,
+		update:         make(chan struct{}, 1),
+		bep2eInfos:     bep2eInfos,
+		bep2eAbi:       bep2eAbi,
+		chainConfigAbi: coresystemcontract.ChainConfigABI(),
 	}, nil
 }
 
 // close terminates the Ethereum connection and tears down the faucet.
 func (f *faucet) close() error {
+	defer f.limits.close()
+
 	if f.stack == nil {
+		f.client.Close()
 		return nil
 	}
 	return f.stack.Close()
@@ -395,6 +444,7 @@ func (f *faucet) apiHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
+	ip := remoteIP(r)
 
 	// Start tracking the connection and drop at the end
 	defer conn.Close()
@@ -556,24 +606,54 @@ func (f *faucet) apiHandler(w http.ResponseWriter, r *http.Request) {
 			}
 			continue
 		}
-		log.Info("Faucet request valid", "url", msg.URL, "tier", msg.Tier, "user", username, "address", address)
+		log.Info("Faucet request valid", "url", msg.URL, "tier", msg.Tier, "user", username, "address", address, "ip", ip)
+
+		// Accounts already on the gas-free list have nothing left to be funded with
+		gasFree := msg.Symbol == "NativeToken" && *gasFreeFlag
+		if gasFree {
+			free, err := f.isGasFree(address)
+			if err == nil && free {
+				//lint:ignore ST1005 This error is to be displayed in the browser
+				err = errors.New("Account already gas-free")
+			}
+			if err != nil {
+				if err = sendError(wsconn, err); err != nil {
+					log.Warn("Failed to send gas-free error to client", "err", err)
+					return
+				}
+				continue
+			}
+		}
+		// Ensure neither the user, nor the address or IP requested funds too recently
+		keys := limitKeys(id, address, ip)
 
-		// Ensure the user didn't request funds too recently
 		f.lock.Lock()
 		var (
 			fund    bool
 			timeout time.Time
 		)
-		if timeout = f.timeouts[id]; time.Now().After(timeout) {
+		if timeout = f.limits.timeout(keys); time.Now().After(timeout) {
 			var tx *types.Transaction
-			if msg.Symbol == "NativeToken" {
+			switch {
+			case gasFree:
+				// User wasn't funded recently, add them to the gas-free list
+				input, err := f.chainConfigAbi.Pack("addFreeGasAddress", address)
+				if err != nil {
+					f.lock.Unlock()
+					log.Warn("Failed to pack gas-free transaction", "err", err)
+					continue
+				}
+				tx = types.NewTransaction(f.nonce+uint64(len(f.reqs)), systemcontract.ChainConfigContractAddress, nil, gasFreeFundingGas, f.price, input)
+
+			case msg.Symbol == "NativeToken":
 				// User wasn't funded recently, create the funding transaction
 				amount := new(big.Int).Mul(big.NewInt(int64(*payoutFlag)), ether)
 				amount = new(big.Int).Mul(amount, new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(msg.Tier)), nil))
 				amount = new(big.Int).Div(amount, new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(msg.Tier)), nil))
 
 				tx = types.NewTransaction(f.nonce+uint64(len(f.reqs)), address, amount, 21000, f.price, nil)
-			} else {
+
+			default:
 				tokenInfo, ok := f.bep2eInfos[msg.Symbol]
 				if !ok {
 					f.lock.Unlock()
@@ -588,7 +668,7 @@ func (f *faucet) apiHandler(w http.ResponseWriter, r *http.Request) {
 				}
 				tx = types.NewTransaction(f.nonce+uint64(len(f.reqs)), tokenInfo.Contract, nil, 420000, f.price, input)
 			}
-			signed, err := f.keystore.SignTx(f.account, tx, f.config.ChainID)
+			signed, err := f.keystore.SignTx(f.account, tx, f.chainID)
 			if err != nil {
 				f.lock.Unlock()
 				if err = sendError(wsconn, err); err != nil {
@@ -615,7 +695,7 @@ func (f *faucet) apiHandler(w http.ResponseWriter, r *http.Request) {
 			timeout := time.Duration(*minutesFlag*int(math.Pow(3, float64(msg.Tier)))) * time.Minute
 			grace := timeout / 288 // 24h timeout => 5m grace
 
-			f.timeouts[id] = time.Now().Add(timeout - grace)
+			f.limits.limit(keys, time.Now().Add(timeout-grace))
 			fund = true
 		}
 		f.lock.Unlock()
@@ -668,9 +748,15 @@ func (f *faucet) refresh(head *types.Header) error {
 	if fixGasPrice != nil && *fixGasPrice > 0 {
 		price = big.NewInt(*fixGasPrice)
 	} else {
-		if price, err = f.client.SuggestGasPrice(ctx); err != nil {
+		if price, err = f.chainConfigGasPrice(ctx, head.Number); err != nil {
 			return err
 		}
+		// Chains without a ChainConfig contract price their gas in the market
+		if price == nil || price.Sign() == 0 {
+			if price, err = f.client.SuggestGasPrice(ctx); err != nil {
+				return err
+			}
+		}
 	}
 	// Everything succeeded, update the cached stats and eject old requests
 	f.lock.Lock()
@@ -684,12 +770,94 @@ func (f *faucet) refresh(head *types.Header) error {
 	return nil
 }
 
+// chainConfigGasPrice retrieves the gas price set in the ChainConfig contract at
+// the given block, or nil if the chain has no such contract.
+func (f *faucet) chainConfigGasPrice(ctx context.Context, number *big.Int) (*big.Int, error) {
+	code, err := f.client.CodeAt(ctx, systemcontract.ChainConfigContractAddress, number)
+	if err != nil || len(code) == 0 {
+		return nil, err
+	}
+	var price *big.Int
+	if err := f.callChainConfig(ctx, number, &price, "getGasPrice"); err != nil {
+		return nil, err
+	}
+	return price, nil
+}
+
+// isGasFree reports whether the given account is on the gas-free list of the
+// ChainConfig contract.
+func (f *faucet) isGasFree(address common.Address) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var free bool
+	if err := f.callChainConfig(ctx, nil, &free, "isFreeGasAddress", address); err != nil {
+		return false, err
+	}
+	return free, nil
+}
+
+// callChainConfig invokes a getter of the ChainConfig contract at the given block
+// and unpacks its result into out.
+func (f *faucet) callChainConfig(ctx context.Context, number *big.Int, out interface{}, method string, args ...interface{}) error {
+	input, err := f.chainConfigAbi.Pack(method, args...)
+	if err != nil {
+		return err
+	}
+	contract := systemcontract.ChainConfigContractAddress
+	ret, err := f.client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: input}, number)
+	if err != nil {
+		return err
+	}
+	return f.chainConfigAbi.UnpackIntoInterface(out, method, ret)
+}
+
+// subscribeHeads subscribes to the chain head events of the node, falling back
+// to polling the head on connections without notification support (HTTP).
+func (f *faucet) subscribeHeads(heads chan *types.Header) (ethereum.Subscription, error) {
+	sub, err := f.client.SubscribeNewHead(context.Background(), heads)
+	if err != rpc.ErrNotificationsUnsupported {
+		return sub, err
+	}
+	log.Info("Polling for chain heads, notifications unsupported")
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ticker := time.NewTicker(headPollInterval)
+		defer ticker.Stop()
+
+		var last common.Hash
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				head, err := f.client.HeaderByNumber(ctx, nil)
+				cancel()
+				if err != nil {
+					log.Warn("Failed to poll chain head", "err", err)
+					continue
+				}
+				if head.Hash() == last {
+					continue
+				}
+				last = head.Hash()
+				select {
+				case heads <- head:
+				case <-quit:
+					return nil
+				}
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
 // loop keeps waiting for interesting events and pushes them out to connected
 // websockets.
 func (f *faucet) loop() {
 	// Wait for chain events and push them to clients
 	heads := make(chan *types.Header, 16)
-	sub, err := f.client.SubscribeNewHead(context.Background(), heads)
+	sub, err := f.subscribeHeads(heads)
 	if err != nil {
 		log.Crit("Failed to subscribe to head events", "err", err)
 	}
@@ -763,6 +931,23 @@ func (f *faucet) loop() {
 	}
 }
 
+// remoteIP returns the IP address of the client issuing the request. Behind a
+// trusted reverse proxy it's the address the proxy appended to X-Forwarded-For,
+// any earlier entries being under the control of the client.
+func remoteIP(r *http.Request) string {
+	if *proxyFlag {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			return strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // sends transmits a data packet to the remote end of the websocket, but also
 // setting a write deadline to prevent waiting forever on the node.
 func send(conn *wsConn, value interface{}, timeout time.Duration) error {
@@ -0,0 +1,121 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Prefixes of the limited request sources in the limits database.
+const (
+	limitUserPrefix    = "user-"
+	limitAddressPrefix = "addr-"
+	limitIPPrefix      = "ip-"
+)
+
+// limiter tracks the funding timeouts of the users, funded addresses and the
+// IP addresses requesting them, persisting them across faucet restarts.
+type limiter struct {
+	db ethdb.KeyValueStore
+}
+
+// newLimiter opens the limits database at the given path, dropping the expired
+// timeouts from it. An empty path keeps the timeouts in memory only.
+func newLimiter(path string) (*limiter, error) {
+	var db ethdb.KeyValueStore = memorydb.New()
+	if path != "" {
+		ldb, err := leveldb.New(path, 16, 16, "faucet/limits", false)
+		if err != nil {
+			return nil, err
+		}
+		db = ldb
+	}
+	l := &limiter{db: db}
+
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	var expired int
+	for it.Next() {
+		if until, ok := decodeTimeout(it.Value()); !ok || time.Now().After(until) {
+			db.Delete(common.CopyBytes(it.Key()))
+			expired++
+		}
+	}
+	if expired > 0 {
+		log.Info("Dropped expired faucet timeouts", "count", expired)
+	}
+	return l, nil
+}
+
+// limitKeys returns the database keys limiting a request of the given user, to
+// the given address, from the given IP. The IP is omitted if unknown.
+func limitKeys(user string, address common.Address, ip string) []string {
+	keys := []string{limitUserPrefix + user, limitAddressPrefix + address.Hex()}
+	if ip != "" {
+		keys = append(keys, limitIPPrefix+ip)
+	}
+	return keys
+}
+
+// timeout returns the latest timeout of the given keys, which is in the past if
+// none of them is limited.
+func (l *limiter) timeout(keys []string) time.Time {
+	var latest time.Time
+	for _, key := range keys {
+		blob, err := l.db.Get([]byte(key))
+		if err != nil {
+			continue
+		}
+		if until, ok := decodeTimeout(blob); ok && until.After(latest) {
+			latest = until
+		}
+	}
+	return latest
+}
+
+// limit sets the timeout of all the given keys.
+func (l *limiter) limit(keys []string, until time.Time) {
+	blob := make([]byte, 8)
+	binary.BigEndian.PutUint64(blob, uint64(until.UnixNano()))
+
+	for _, key := range keys {
+		if err := l.db.Put([]byte(key), blob); err != nil {
+			log.Error("Failed to store faucet timeout", "key", key, "err", err)
+		}
+	}
+}
+
+// close flushes and closes the limits database.
+func (l *limiter) close() error {
+	return l.db.Close()
+}
+
+// decodeTimeout parses a stored timeout.
+func decodeTimeout(blob []byte) (time.Time, bool) {
+	if len(blob) != 8 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(blob))), true
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that a funding limits any further request of the same user, to the same
+// address or from the same IP, and that the limits survive a restart.
+func TestLimiterPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits")
+
+	limits, err := newLimiter(path)
+	if err != nil {
+		t.Fatalf("failed to open limits: %v", err)
+	}
+	until := time.Now().Add(time.Hour)
+	limits.limit(limitKeys("alice", common.Address{0x01}, "10.0.0.1"), until)
+	limits.limit(limitKeys("bob", common.Address{0x02}, "10.0.0.2"), time.Now().Add(-time.Minute))
+	limits.close()
+
+	if limits, err = newLimiter(path); err != nil {
+		t.Fatalf("failed to reopen limits: %v", err)
+	}
+	defer limits.close()
+
+	tests := []struct {
+		user    string
+		address common.Address
+		ip      string
+		limited bool
+	}{
+		{"alice", common.Address{0x03}, "10.0.0.3", true},
+		{"carol", common.Address{0x01}, "10.0.0.3", true},
+		{"carol", common.Address{0x03}, "10.0.0.1", true},
+		{"carol", common.Address{0x03}, "", false},
+		{"bob", common.Address{0x02}, "10.0.0.2", false},
+	}
+	for i, tt := range tests {
+		timeout := limits.timeout(limitKeys(tt.user, tt.address, tt.ip))
+		if limited := time.Now().Before(timeout); limited != tt.limited {
+			t.Errorf("test %d: limited mismatch: have %v, want %v", i, limited, tt.limited)
+		}
+		if tt.limited && timeout.UnixNano() != until.UnixNano() {
+			t.Errorf("test %d: timeout mismatch: have %v, want %v", i, timeout, until)
+		}
+	}
+	if has, _ := limits.db.Has([]byte(limitUserPrefix + "bob")); has {
+		t.Errorf("expired timeout not dropped")
+	}
+}
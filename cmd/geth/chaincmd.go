@@ -19,6 +19,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"net"
 	"os"
 	"path"
@@ -26,6 +27,7 @@ import (
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"text/tabwriter"
 	"time"
 
 	"gopkg.in/urfave/cli.v1"
@@ -174,6 +176,21 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 The arguments are interpreted as block numbers or hashes.
 Use "ethereum dump 0" to dump the genesis block.`,
 	}
+	forksCommand = cli.Command{
+		Action:    utils.MigrateFlags(showForks),
+		Name:      "forks",
+		Usage:     "Print the fork schedule of the local chain",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The forks command prints every fork known to the client together with its
+activation block in the chain config stored in the datadir, whether it is
+already active at the current head block and whether it is part of the
+fork identifier announced to peers.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -510,3 +527,39 @@ func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
 	return err != nil
 }
+
+func showForks(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true, false)
+	defer db.Close()
+
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		utils.Fatalf("No genesis block found, initialize the datadir first")
+	}
+	config := rawdb.ReadChainConfig(db, genesis)
+	if config == nil {
+		utils.Fatalf("No chain config found for genesis %x", genesis)
+	}
+	head := new(big.Int)
+	if header := rawdb.ReadHeadHeader(db); header != nil {
+		head = header.Number
+	}
+	fmt.Printf("Chain ID: %v, genesis: %x, head block: %v\n\n", config.ChainID, genesis, head)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FORK\tBLOCK\tSTATUS\tFORKID")
+	for _, fork := range config.ForkSchedule(head) {
+		block, status := "-", "not scheduled"
+		if fork.Block != nil {
+			block, status = fork.Block.String(), "pending"
+		}
+		if fork.Active {
+			status = "active"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%v\n", fork.Name, block, status, fork.ForkID)
+	}
+	return w.Flush()
+}
@@ -66,6 +66,7 @@ var (
 		utils.BootnodesFlag,
 		utils.DataDirFlag,
 		utils.AncientFlag,
+		utils.DBEngineFlag,
 		utils.MinFreeDiskSpaceFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
@@ -91,11 +92,17 @@ var (
 		utils.TxPoolLifetimeFlag,
 		utils.TxPoolReannounceTimeFlag,
 		utils.TxPoolGasFreeContracts,
+		utils.TxPoolGasFreeWindowFlag,
+		utils.TxPoolGasFreeWindowTxsFlag,
+		utils.TxPoolGasFreeSlotsFlag,
+		utils.TxPoolGasFreeEpochGasFlag,
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.AddressLogIndexFlag,
+		utils.HistoryBlocksFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -133,6 +140,8 @@ var (
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerDelayLeftoverFlag,
 		utils.MinerNoVerfiyFlag,
+		utils.MinerGasFreeLaneShareFlag,
+		utils.MinerGasFreeContractLaneShareFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -187,6 +196,8 @@ var (
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCLogMaxRangeFlag,
+		utils.RPCLogMaxResultsFlag,
 		utils.AllowUnprotectedTxs,
 	}
 
@@ -219,6 +230,7 @@ func init() {
 		exportPreimagesCommand,
 		removedbCommand,
 		dumpCommand,
+		forksCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
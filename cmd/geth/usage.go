@@ -37,6 +37,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			configFileFlag,
 			utils.DataDirFlag,
 			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.MinFreeDiskSpaceFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
@@ -49,6 +50,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.AddressLogIndexFlag,
+			utils.HistoryBlocksFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
@@ -95,6 +98,10 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolLifetimeFlag,
 			utils.TxPoolReannounceTimeFlag,
 			utils.TxPoolGasFreeContracts,
+			utils.TxPoolGasFreeWindowFlag,
+			utils.TxPoolGasFreeWindowTxsFlag,
+			utils.TxPoolGasFreeSlotsFlag,
+			utils.TxPoolGasFreeEpochGasFlag,
 		},
 	},
 	{
@@ -142,6 +149,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.GraphQLVirtualHostsFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalTxFeeCapFlag,
+			utils.RPCLogMaxRangeFlag,
+			utils.RPCLogMaxResultsFlag,
 			utils.AllowUnprotectedTxs,
 			utils.JSpathFlag,
 			utils.ExecFlag,
@@ -179,6 +188,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerDelayLeftoverFlag,
 			utils.MinerNoVerfiyFlag,
+			utils.MinerGasFreeLaneShareFlag,
+			utils.MinerGasFreeContractLaneShareFlag,
 		},
 	},
 	{
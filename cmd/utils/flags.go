@@ -139,6 +139,11 @@ var (
 		Name:  "rangelimit",
 		Usage: "Enable 5000 blocks limit for range query",
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation to use ('leveldb' or 'pebble')",
+		Value: "leveldb",
+	}
 	AncientFlag = DirectoryFlag{
 		Name:  "datadir.ancient",
 		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
@@ -230,6 +235,14 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	AddressLogIndexFlag = cli.BoolFlag{
+		Name:  "addresslogindex",
+		Usage: "Maintain an index of the blocks containing the logs of every address, speeding up single contract log queries",
+	}
+	HistoryBlocksFlag = cli.Uint64Flag{
+		Name:  "history.blocks",
+		Usage: "Number of recent blocks to retain in the ancient store, older ones are pruned while running (0 = entire chain)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -358,6 +371,26 @@ var (
 		Name:  "txpool.gasfreecontracts",
 		Usage: "List with gas free recipients",
 	}
+	TxPoolGasFreeWindowFlag = cli.Uint64Flag{
+		Name:  "txpool.gasfreewindow",
+		Usage: "Number of blocks in a gas-free rate limiting window",
+		Value: ethconfig.Defaults.TxPool.GasFreeWindow,
+	}
+	TxPoolGasFreeWindowTxsFlag = cli.Uint64Flag{
+		Name:  "txpool.gasfreewindowtxs",
+		Usage: "Maximum gas-free transactions admitted per sender or contract in a window (0 = unlimited)",
+		Value: ethconfig.Defaults.TxPool.GasFreeWindowTxs,
+	}
+	TxPoolGasFreeSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.gasfreeslots",
+		Usage: "Maximum transaction slots per gas-free sender or contract (0 = unlimited)",
+		Value: ethconfig.Defaults.TxPool.GasFreeSlots,
+	}
+	TxPoolGasFreeEpochGasFlag = cli.Uint64Flag{
+		Name:  "txpool.gasfreeepochgas",
+		Usage: "Maximum gas admitted per gas-free sender or contract in an epoch (0 = unlimited)",
+		Value: ethconfig.Defaults.TxPool.GasFreeEpochGas,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -463,6 +496,16 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerGasFreeLaneShareFlag = cli.Uint64Flag{
+		Name:  "miner.gasfreeshare",
+		Usage: "Percentage of the block gas reserved for gas-free senders, unless set by the ChainConfig contract",
+		Value: ethconfig.Defaults.Miner.GasFreeLaneShare,
+	}
+	MinerGasFreeContractLaneShareFlag = cli.Uint64Flag{
+		Name:  "miner.gasfreecontractshare",
+		Usage: "Percentage of the block gas reserved for calls to gas-free contracts, unless set by the ChainConfig contract",
+		Value: ethconfig.Defaults.Miner.GasFreeContractLaneShare,
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 
@@ -498,6 +541,14 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	RPCLogMaxRangeFlag = cli.Uint64Flag{
+		Name:  "rpc.logs.maxrange",
+		Usage: "Sets a cap on the number of blocks a log query can span via the RPC and GraphQL APIs (0 = no cap, or 5000 with --rangelimit)",
+	}
+	RPCLogMaxResultsFlag = cli.IntFlag{
+		Name:  "rpc.logs.maxresults",
+		Usage: "Sets a cap on the number of logs a log query can return via the RPC and GraphQL APIs, larger results need eth_getLogsPage (0 = no cap)",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -1238,6 +1289,14 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(InsecureUnlockAllowedFlag.Name) {
 		cfg.InsecureUnlockAllowed = ctx.GlobalBool(InsecureUnlockAllowedFlag.Name)
 	}
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		dbEngine := ctx.GlobalString(DBEngineFlag.Name)
+		if dbEngine != "leveldb" && dbEngine != "pebble" {
+			Fatalf("Invalid choice for db.engine '%s', allowed 'leveldb' or 'pebble'", dbEngine)
+		}
+		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
+		cfg.DBEngine = dbEngine
+	}
 }
 
 func setSmartCard(ctx *cli.Context, cfg *node.Config) {
@@ -1344,6 +1403,18 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 			cfg.GasFreeContracts[address] = true
 		}
 	}
+	if ctx.GlobalIsSet(TxPoolGasFreeWindowFlag.Name) {
+		cfg.GasFreeWindow = ctx.GlobalUint64(TxPoolGasFreeWindowFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolGasFreeWindowTxsFlag.Name) {
+		cfg.GasFreeWindowTxs = ctx.GlobalUint64(TxPoolGasFreeWindowTxsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolGasFreeSlotsFlag.Name) {
+		cfg.GasFreeSlots = ctx.GlobalUint64(TxPoolGasFreeSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolGasFreeEpochGasFlag.Name) {
+		cfg.GasFreeEpochGas = ctx.GlobalUint64(TxPoolGasFreeEpochGasFlag.Name)
+	}
 }
 
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
@@ -1372,6 +1443,12 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerGasFreeLaneShareFlag.Name) {
+		cfg.GasFreeLaneShare = ctx.GlobalUint64(MinerGasFreeLaneShareFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerGasFreeContractLaneShareFlag.Name) {
+		cfg.GasFreeContractLaneShare = ctx.GlobalUint64(MinerGasFreeContractLaneShareFlag.Name)
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1534,6 +1611,17 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(AddressLogIndexFlag.Name) {
+		cfg.AddressLogIndex = ctx.GlobalBool(AddressLogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryBlocksFlag.Name) {
+		cfg.HistoryBlocks = ctx.GlobalUint64(HistoryBlocksFlag.Name)
+		// Transactions of pruned blocks can't be looked up, so don't index them
+		if cfg.HistoryBlocks != 0 && (cfg.TxLookupLimit == 0 || cfg.TxLookupLimit > cfg.HistoryBlocks) {
+			log.Warn("Limiting transaction index to the retained block history", "txlookuplimit", cfg.HistoryBlocks)
+			cfg.TxLookupLimit = cfg.HistoryBlocks
+		}
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1587,6 +1675,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCLogMaxRangeFlag.Name) {
+		cfg.LogQueryMaxRange = ctx.GlobalUint64(RPCLogMaxRangeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogMaxResultsFlag.Name) {
+		cfg.LogQueryMaxResults = ctx.GlobalInt(RPCLogMaxResultsFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
@@ -146,5 +146,51 @@ type PoSA interface {
 	IsLocalBlock(header *types.Header) bool
 	AllowLightProcess(chain ChainReader, currentHeader *types.Header) bool
 
+	// SignDiffLayer signs the rlp encoded diff layer of a locally sealed block.
+	SignDiffLayer(header *types.Header, diffLayerRLP []byte) ([]byte, error)
+
+	// VerifyDiffLayer checks that the diff layer with the given hash was signed
+	// by the sealer of the header, who must be an authorized validator.
+	VerifyDiffLayer(chain ChainHeaderReader, header *types.Header, diffHash common.Hash, signature []byte) error
+
+	// EpochBlock returns the number of the block starting the epoch the header
+	// belongs to, following the epoch lengths governed on chain.
+	EpochBlock(chain ChainHeaderReader, header *types.Header) (uint64, error)
+
+	// EpochCheckpoint returns the last epoch header at or before the given block,
+	// having retrieved all headers a light client needs to start syncing from it
+	// through getHeader.
+	EpochCheckpoint(number uint64, getHeader func(uint64) (*types.Header, error)) (*types.Header, error)
+
 	BlockRewards(blockNumber *big.Int) *big.Int
 }
+
+// FastFinality is a consensus engine whose validators vote for recent blocks,
+// finalizing them once the votes of enough validators are aggregated into the
+// chain.
+type FastFinality interface {
+	Engine
+
+	// VerifyVote checks that a vote attests a known block on top of the block
+	// justified before it, and that it is signed by one of its validators.
+	VerifyVote(chain ChainHeaderReader, vote *types.VoteEnvelope) error
+
+	// SignVote votes for the given block with the local validator key. It returns
+	// nil without error if the local node is not a validator of the block.
+	SignVote(chain ChainHeaderReader, header *types.Header) (*types.VoteEnvelope, error)
+
+	// GetJustifiedHeader returns the latest block justified by the votes in the
+	// chain up to the given header, nil if there is none.
+	GetJustifiedHeader(chain ChainHeaderReader, header *types.Header) *types.Header
+
+	// GetFinalizedHeader returns the latest block finalized by the votes in the
+	// chain up to the given header, which the fork choice never reverts. It
+	// returns nil if there is none.
+	GetFinalizedHeader(chain ChainHeaderReader, header *types.Header) *types.Header
+
+	// GetSealFinalizedHeader returns the latest block more than two thirds of the
+	// validators sealed blocks on top of in the chain up to the given header, nil
+	// if there is none. Unlike the vote finalized block, it is only final as long
+	// as the validators are honest, so it merely serves the RPC block tags.
+	GetSealFinalizedHeader(chain ChainHeaderReader, header *types.Header) *types.Header
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// londonConfig returns a chain config with the London fork at block 5.
+func londonConfig() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.LondonBlock = big.NewInt(5)
+	return &config
+}
+
+// TestBaseFeeActivation tests that headers carry a base fee exactly from the fork on.
+func TestBaseFeeActivation(t *testing.T) {
+	initial := new(big.Int).SetUint64(params.InitialBaseFee)
+	for i, tc := range []struct {
+		number  int64
+		baseFee *big.Int
+		ok      bool
+	}{
+		{4, nil, true},
+		{4, initial, false},
+		{5, nil, false},
+		{5, initial, true},
+		{5, big.NewInt(1), false},
+	} {
+		parent := &types.Header{Number: big.NewInt(tc.number - 1), GasLimit: 20000000, GasUsed: 10000000}
+		header := &types.Header{Number: big.NewInt(tc.number), GasLimit: 20000000, BaseFee: tc.baseFee}
+		if err := VerifyEip1559Header(londonConfig(), parent, header); (err == nil) != tc.ok {
+			t.Errorf("test %d: have error %v, want ok %v", i, err, tc.ok)
+		}
+	}
+}
+
+// TestCalcBaseFee assumes all blocks are 1559-blocks
+func TestCalcBaseFee(t *testing.T) {
+	tests := []struct {
+		parentBaseFee   int64
+		parentGasLimit  uint64
+		parentGasUsed   uint64
+		expectedBaseFee int64
+	}{
+		{params.InitialBaseFee, 20000000, 10000000, params.InitialBaseFee}, // usage == target
+		{params.InitialBaseFee, 20000000, 9000000, 987500000},              // usage below target
+		{params.InitialBaseFee, 20000000, 11000000, 1012500000},            // usage above target
+		{params.InitialBaseFee, 20000000, 0, 875000000},                    // empty block
+		{params.InitialBaseFee, 20000000, 20000000, 1125000000},            // full block
+		{7, 20000000, 10000001, 8},                                         // minimum increase of one wei
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   big.NewInt(5),
+			GasLimit: test.parentGasLimit,
+			GasUsed:  test.parentGasUsed,
+			BaseFee:  big.NewInt(test.parentBaseFee),
+		}
+		if have, want := CalcBaseFee(londonConfig(), parent), big.NewInt(test.expectedBaseFee); have.Cmp(want) != 0 {
+			t.Errorf("test %d: have %d  want %d, ", i, have, want)
+		}
+	}
+	// The first block of the fork starts with the initial base fee
+	parent := &types.Header{Number: big.NewInt(4), GasLimit: 20000000, GasUsed: 20000000}
+	if have := CalcBaseFee(londonConfig(), parent); have.Uint64() != params.InitialBaseFee {
+		t.Errorf("fork block: have %d, want %d", have, params.InitialBaseFee)
+	}
+}
@@ -17,8 +17,11 @@
 package parlia
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/systemcontract"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -86,3 +89,129 @@ func (api *API) GetValidatorsAtHash(hash common.Hash) ([]common.Address, error)
 	}
 	return snap.validators(), nil
 }
+
+// GetContractDeployer retrieves the deployer registered for a contract by the
+// DeployerProxy contract at the specified block.
+func (api *API) GetContractDeployer(contract common.Address, number *rpc.BlockNumber) (common.Address, error) {
+	// Retrieve the requested block number (or current if none requested)
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return common.Address{}, errUnknownBlock
+	}
+	if !api.parlia.chainConfig.HasDeployerProxy(header.Number) {
+		return common.Address{}, errDeployerProxyNotActive
+	}
+	return api.parlia.getContractDeployer(contract, header)
+}
+
+// GetChainConfigParams retrieves the parameters of the ChainConfig contract in
+// force at the specified block.
+func (api *API) GetChainConfigParams(number *rpc.BlockNumber) (*systemcontract.ChainConfigParams, error) {
+	// Retrieve the requested block number (or current if none requested)
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	if api.parlia.chainConfigReader == nil {
+		return nil, errNoChainConfigReader
+	}
+	return api.parlia.chainConfigReader.Params(header)
+}
+
+// GetEvidence retrieves the double sign evidence collected by the node, either
+// detected among the headers seen or submitted in blocks, optionally only that
+// of the given validator.
+func (api *API) GetEvidence(validator *common.Address) ([]*DoubleSignEvidence, error) {
+	evidence, err := readEvidence(api.parlia.db)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]*DoubleSignEvidence, 0, len(evidence))
+	for _, ev := range evidence {
+		if validator == nil || ev.Validator == *validator {
+			filtered = append(filtered, ev)
+		}
+	}
+	return filtered, nil
+}
+
+// GetValidatorStats retrieves the liveness of every validator over the canonical
+// blocks from..to: the blocks proposed in and out of turn, the slots missed and
+// the slashes issued for them.
+func (api *API) GetValidatorStats(from, to rpc.BlockNumber) (map[common.Address]*ValidatorStats, error) {
+	stats, err := api.rangeLiveness(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return stats.Validators, nil
+}
+
+// GetMissedBlocks retrieves the canonical blocks from..to in which the given
+// validator missed its slot.
+func (api *API) GetMissedBlocks(validator common.Address, from, to rpc.BlockNumber) ([]*BlockLiveness, error) {
+	stats, err := api.rangeLiveness(from, to)
+	if err != nil {
+		return nil, err
+	}
+	missed := make([]*BlockLiveness, 0)
+	for _, liveness := range stats.Missed {
+		if *liveness.Missed == validator {
+			missed = append(missed, liveness)
+		}
+	}
+	return missed, nil
+}
+
+// Liveness creates a subscription that is notified of the liveness of every new
+// canonical block.
+func (api *API) Liveness(ctx context.Context) (*rpc.Subscription, error) {
+	if !api.parlia.livenessTracked {
+		return nil, errLivenessNotTracked
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan *BlockLiveness, chainEventChanSize)
+		sub := api.parlia.SubscribeLivenessEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case liveness := <-events:
+				notifier.Notify(rpcSub.ID, liveness)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// rangeLiveness resolves the given block numbers against the current head and
+// aggregates the liveness of the blocks between them.
+func (api *API) rangeLiveness(from, to rpc.BlockNumber) (*livenessStats, error) {
+	head := api.chain.CurrentHeader().Number.Uint64()
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number < 0 || uint64(number) > head {
+			return head // Latest and pending both resolve to the current head
+		}
+		return uint64(number)
+	}
+	return api.parlia.rangeLiveness(api.chain, resolve(from), resolve(to))
+}
@@ -0,0 +1,377 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parlia
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	inMemorySealers         = 4096 // Number of recently sealed headers remembered to detect double signs
+	inMemoryRefusedEvidence = 128  // Number of refused double sign evidence remembered not to submit again
+	maxEvidencePerBlock     = 2    // Maximum number of double sign evidence submitted in a single block
+)
+
+// evidencePrefix is the database key prefix of the double sign evidence,
+// followed by the block number and the address of the validator.
+var evidencePrefix = []byte("parlia-evidence-")
+
+var (
+	// errInvalidEvidence is returned if double sign evidence doesn't prove that
+	// a validator sealed two different headers at the same height.
+	errInvalidEvidence = errors.New("invalid double sign evidence")
+
+	// errEvidenceDisabled is returned if double sign evidence is submitted on a
+	// chain that doesn't configure how to.
+	errEvidenceDisabled = errors.New("double sign evidence submission disabled")
+)
+
+// evidenceArgs are the arguments of the evidence submission method: the two
+// rlp encoded conflicting headers.
+var evidenceArgs = func() abi.Arguments {
+	bytesTy, _ := abi.NewType("bytes", "", nil)
+	return abi.Arguments{{Type: bytesTy}, {Type: bytesTy}}
+}()
+
+// sealerKey identifies the header a validator sealed at a given height.
+type sealerKey struct {
+	validator common.Address
+	number    uint64
+}
+
+// DoubleSignEvidence proves that a validator sealed two different headers at the
+// same height. The headers are ordered by seal hash.
+type DoubleSignEvidence struct {
+	Validator   common.Address `json:"validator"`
+	Number      uint64         `json:"number"`
+	HeaderA     *types.Header  `json:"headerA"`
+	HeaderB     *types.Header  `json:"headerB"`
+	SubmittedIn uint64         `json:"submittedIn,omitempty"` // Canonical block the evidence was submitted in, if any
+}
+
+// newDoubleSignEvidence creates the evidence of two conflicting headers sealed by
+// the given validator.
+func newDoubleSignEvidence(validator common.Address, a, b *types.Header, chainId *big.Int) *DoubleSignEvidence {
+	hashA, hashB := SealHash(a, chainId), SealHash(b, chainId)
+	if bytes.Compare(hashA[:], hashB[:]) > 0 {
+		a, b = b, a
+	}
+	return &DoubleSignEvidence{
+		Validator: validator,
+		Number:    a.Number.Uint64(),
+		HeaderA:   types.CopyHeader(a),
+		HeaderB:   types.CopyHeader(b),
+	}
+}
+
+// verify checks that the evidence consists of two different headers at the same
+// height, both sealed by the validator.
+func (e *DoubleSignEvidence) verify(chainId *big.Int) error {
+	a, b := e.HeaderA, e.HeaderB
+	if a == nil || b == nil || a.Number == nil || b.Number == nil {
+		return fmt.Errorf("%w: missing header", errInvalidEvidence)
+	}
+	if !a.Number.IsUint64() || a.Number.Uint64() != e.Number || b.Number.Cmp(a.Number) != 0 {
+		return fmt.Errorf("%w: height mismatch", errInvalidEvidence)
+	}
+	if SealHash(a, chainId) == SealHash(b, chainId) {
+		return fmt.Errorf("%w: identical headers", errInvalidEvidence)
+	}
+	for _, header := range []*types.Header{a, b} {
+		if len(header.Extra) < extraSeal {
+			return fmt.Errorf("%w: %v", errInvalidEvidence, errMissingSignature)
+		}
+		pubkey, err := crypto.Ecrecover(SealHash(header, chainId).Bytes(), header.Extra[len(header.Extra)-extraSeal:])
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidEvidence, err)
+		}
+		var signer common.Address
+		copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+		if signer != e.Validator || header.Coinbase != e.Validator {
+			return fmt.Errorf("%w: not sealed by %x", errInvalidEvidence, e.Validator)
+		}
+	}
+	return nil
+}
+
+// evidenceKey = evidencePrefix + number (uint64 big endian) + validator
+func evidenceKey(number uint64, validator common.Address) []byte {
+	key := make([]byte, len(evidencePrefix)+8+common.AddressLength)
+	copy(key, evidencePrefix)
+	binary.BigEndian.PutUint64(key[len(evidencePrefix):], number)
+	copy(key[len(evidencePrefix)+8:], validator[:])
+	return key
+}
+
+// storeEvidence inserts double sign evidence into the database, replacing any
+// evidence of the same validator at the same height.
+func storeEvidence(db ethdb.KeyValueWriter, evidence *DoubleSignEvidence) error {
+	blob, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+	return db.Put(evidenceKey(evidence.Number, evidence.Validator), blob)
+}
+
+// readEvidence retrieves all double sign evidence from the database, ordered by
+// height.
+func readEvidence(db ethdb.Iteratee) ([]*DoubleSignEvidence, error) {
+	it := db.NewIterator(evidencePrefix, nil)
+	defer it.Release()
+
+	var evidence []*DoubleSignEvidence
+	for it.Next() {
+		ev := new(DoubleSignEvidence)
+		if err := json.Unmarshal(it.Value(), ev); err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, ev)
+	}
+	return evidence, it.Error()
+}
+
+// observeSeal remembers the header sealed by a validator, storing double sign
+// evidence if the validator sealed a different header at the same height before.
+func (p *Parlia) observeSeal(header *types.Header, validator common.Address) {
+	key := sealerKey{validator: validator, number: header.Number.Uint64()}
+
+	p.sealersLock.Lock()
+	defer p.sealersLock.Unlock()
+
+	seen, ok := p.sealers.Get(key)
+	if !ok {
+		p.sealers.Add(key, types.CopyHeader(header))
+		return
+	}
+	prev := seen.(*types.Header)
+	if SealHash(prev, p.chainConfig.ChainID) == SealHash(header, p.chainConfig.ChainID) {
+		return
+	}
+	if has, _ := p.db.Has(evidenceKey(key.number, validator)); has {
+		return
+	}
+	evidence := newDoubleSignEvidence(validator, prev, header, p.chainConfig.ChainID)
+	if err := storeEvidence(p.db, evidence); err != nil {
+		log.Error("Failed to store double sign evidence", "number", key.number, "validator", validator, "err", err)
+		return
+	}
+	log.Warn("Detected double sign", "number", key.number, "validator", validator, "hashA", evidence.HeaderA.Hash(), "hashB", evidence.HeaderB.Hash())
+}
+
+// evidenceContract returns the system contract double sign evidence is
+// submitted to at the given block, or nil if evidence submission is disabled.
+// Contracts that are no system contracts are ignored, as calls to them are no
+// system transactions.
+func (p *Parlia) evidenceContract(number *big.Int) *common.Address {
+	if !p.chainConfig.IsDoubleSignEvidence(number) || p.config.EvidenceMethod == "" {
+		return nil
+	}
+	contract := common.HexToAddress(systemcontract.SlashContract)
+	if p.config.EvidenceContract != nil {
+		contract = *p.config.EvidenceContract
+	}
+	if !isToSystemContract(contract) {
+		return nil
+	}
+	return &contract
+}
+
+// packEvidence encodes the call submitting double sign evidence.
+func (p *Parlia) packEvidence(evidence *DoubleSignEvidence) ([]byte, error) {
+	if p.config.EvidenceMethod == "" {
+		return nil, errEvidenceDisabled
+	}
+	a, err := rlp.EncodeToBytes(evidence.HeaderA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := rlp.EncodeToBytes(evidence.HeaderB)
+	if err != nil {
+		return nil, err
+	}
+	args, err := evidenceArgs.Pack(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return append(crypto.Keccak256([]byte(p.config.EvidenceMethod))[:4], args...), nil
+}
+
+// unpackEvidence decodes the double sign evidence submitted by a transaction in
+// the given block, returning nil if the transaction doesn't submit evidence.
+func (p *Parlia) unpackEvidence(tx *types.Transaction, number *big.Int) (*DoubleSignEvidence, error) {
+	contract := p.evidenceContract(number)
+	if contract == nil || tx.To() == nil || *tx.To() != *contract {
+		return nil, nil
+	}
+	data := tx.Data()
+	if len(data) < 4 || !bytes.Equal(data[:4], crypto.Keccak256([]byte(p.config.EvidenceMethod))[:4]) {
+		return nil, nil
+	}
+	args, err := evidenceArgs.Unpack(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidEvidence, err)
+	}
+	var a, b types.Header
+	if err := rlp.DecodeBytes(args[0].([]byte), &a); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidEvidence, err)
+	}
+	if err := rlp.DecodeBytes(args[1].([]byte), &b); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidEvidence, err)
+	}
+	evidence := &DoubleSignEvidence{Validator: a.Coinbase, HeaderA: &a, HeaderB: &b}
+	if a.Number != nil && a.Number.IsUint64() {
+		evidence.Number = a.Number.Uint64()
+	}
+	return evidence, nil
+}
+
+// submitEvidence applies the system transactions submitting double sign
+// evidence in a block. When mining, the pending evidence of the local database
+// is submitted, otherwise the evidence submitted by the received system
+// transactions is verified and applied.
+//
+// The database isn't updated here, as the block may never become canonical, or
+// be assembled again at the same height. Evidence is only marked submitted once
+// a block containing it is imported into the canonical chain, see recordEvidence.
+func (p *Parlia) submitEvidence(state *state.StateDB, header *types.Header, chain core.ChainContext,
+	txs *[]*types.Transaction, receipts *[]*types.Receipt, receivedTxs *[]*types.Transaction, usedGas *uint64, mining bool) error {
+	contract := p.evidenceContract(header.Number)
+	if contract == nil {
+		return nil
+	}
+	number := header.Number.Uint64()
+	if !mining {
+		for receivedTxs != nil && len(*receivedTxs) > 0 {
+			evidence, err := p.unpackEvidence((*receivedTxs)[0], header.Number)
+			if err != nil {
+				return err
+			}
+			if evidence == nil {
+				return nil
+			}
+			if evidence.Number >= number {
+				return fmt.Errorf("%w: future height %d", errInvalidEvidence, evidence.Number)
+			}
+			if err := evidence.verify(p.chainConfig.ChainID); err != nil {
+				return err
+			}
+			msg := p.getSystemMessage(header.Coinbase, *contract, (*receivedTxs)[0].Data(), common.Big0)
+			if err := p.applyTransaction(msg, state, header, chain, txs, receipts, receivedTxs, usedGas, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	evidence, err := readEvidence(p.db)
+	if err != nil {
+		return err
+	}
+	submitted := 0
+	for _, ev := range evidence {
+		if submitted == maxEvidencePerBlock {
+			break
+		}
+		key := sealerKey{validator: ev.Validator, number: ev.Number}
+		if ev.SubmittedIn != 0 || ev.Number >= number || p.refused.Contains(key) {
+			continue
+		}
+		data, err := p.packEvidence(ev)
+		if err != nil {
+			return err
+		}
+		msg := p.getSystemMessage(header.Coinbase, *contract, data, common.Big0)
+		if err := p.applyTransaction(msg, state, header, chain, txs, receipts, nil, usedGas, true); err != nil {
+			log.Warn("Double sign evidence refused", "number", ev.Number, "validator", ev.Validator, "err", err)
+			p.refused.Add(key, struct{}{})
+			continue
+		}
+		log.Info("Submitted double sign evidence", "number", ev.Number, "validator", ev.Validator)
+		submitted++
+	}
+	return nil
+}
+
+// evidenceChain is the chain whose canonical blocks are scanned for submitted
+// double sign evidence.
+type evidenceChain interface {
+	// SubscribeChainEvent registers a subscription of canonical block events.
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+}
+
+// TrackEvidence starts recording the double sign evidence submitted in every
+// new canonical block, so that the local node doesn't submit it again.
+func (p *Parlia) TrackEvidence(chain evidenceChain) {
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	sub := chain.SubscribeChainEvent(events)
+
+	go p.evidenceLoop(events, sub)
+}
+
+// evidenceLoop processes the chain events of the evidence tracker until the
+// engine is closed.
+func (p *Parlia) evidenceLoop(events chan core.ChainEvent, sub event.Subscription) {
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-events:
+			p.recordEvidence(ev.Block)
+		case <-sub.Err():
+			return
+		case <-p.closeCh:
+			return
+		}
+	}
+}
+
+// recordEvidence marks the double sign evidence submitted by the system
+// transactions of a canonical block as submitted in it.
+func (p *Parlia) recordEvidence(block *types.Block) {
+	if p.evidenceContract(block.Number()) == nil {
+		return
+	}
+	for _, tx := range block.Transactions() {
+		if system, err := p.IsSystemTransaction(tx, block.Header()); err != nil || !system {
+			continue
+		}
+		evidence, err := p.unpackEvidence(tx, block.Number())
+		if err != nil || evidence == nil || evidence.verify(p.chainConfig.ChainID) != nil {
+			continue
+		}
+		evidence.SubmittedIn = block.NumberU64()
+		if err := storeEvidence(p.db, evidence); err != nil {
+			log.Warn("Failed to store submitted double sign evidence", "number", evidence.Number, "validator", evidence.Validator, "err", err)
+		}
+	}
+}
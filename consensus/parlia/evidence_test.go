@@ -0,0 +1,211 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parlia
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// forgeHeader creates a header at the given height sealed by the given key.
+func forgeHeader(config *params.ChainConfig, parent common.Hash, number, time uint64, key *ecdsa.PrivateKey) *types.Header {
+	header := &types.Header{
+		ParentHash: parent,
+		Coinbase:   crypto.PubkeyToAddress(key.PublicKey),
+		Number:     new(big.Int).SetUint64(number),
+		Time:       time,
+		Difficulty: diffInTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	sig, err := crypto.Sign(SealHash(header, config.ChainID).Bytes(), key)
+	if err != nil {
+		panic(err)
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+	return header
+}
+
+// Tests that a validator sealing two different headers at the same height is
+// detected while verifying them, and the evidence persisted.
+func TestDoubleSignDetection(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	addrs := make([]common.Address, 2)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Parlia: &params.ParliaConfig{Period: 3, Epoch: 100}}
+	chain := &headerChain{config: config, headers: make(map[uint64]*types.Header)}
+
+	genesis := &types.Header{Number: big.NewInt(0), Extra: make([]byte, extraVanity, extraVanity+2*common.AddressLength+extraSeal)}
+	for _, addr := range addrs {
+		genesis.Extra = append(genesis.Extra, addr.Bytes()...)
+	}
+	genesis.Extra = append(genesis.Extra, make([]byte, extraSeal)...)
+	chain.headers[0] = genesis
+
+	engine := New(config, rawdb.NewMemoryDatabase(), nil, genesis.Hash())
+	engine.fakeDiff = true
+	api := &API{chain: chain, parlia: engine}
+
+	// Verifying the same header twice and headers of different validators is fine
+	first := forgeHeader(config, genesis.Hash(), 1, 10, keys[0])
+	for _, header := range []*types.Header{first, first, forgeHeader(config, genesis.Hash(), 1, 10, keys[1])} {
+		if err := engine.verifySeal(chain, header, nil); err != nil {
+			t.Fatalf("failed to verify seal: %v", err)
+		}
+	}
+	if evidence, _ := api.GetEvidence(nil); len(evidence) != 0 {
+		t.Fatalf("unexpected evidence: %v", evidence)
+	}
+	// A sibling sealed by the same validator is a double sign
+	second := forgeHeader(config, genesis.Hash(), 1, 11, keys[0])
+	if err := engine.verifySeal(chain, second, nil); err != nil {
+		t.Fatalf("failed to verify seal: %v", err)
+	}
+	evidence, err := api.GetEvidence(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve evidence: %v", err)
+	}
+	if len(evidence) != 1 {
+		t.Fatalf("evidence count mismatch: have %d, want 1", len(evidence))
+	}
+	ev := evidence[0]
+	if ev.Validator != addrs[0] || ev.Number != 1 {
+		t.Fatalf("evidence mismatch: have %x #%d, want %x #1", ev.Validator, ev.Number, addrs[0])
+	}
+	if err := ev.verify(config.ChainID); err != nil {
+		t.Fatalf("collected evidence invalid: %v", err)
+	}
+	if filtered, _ := api.GetEvidence(&addrs[1]); len(filtered) != 0 {
+		t.Fatalf("evidence of other validator returned: %v", filtered)
+	}
+}
+
+// Tests that double sign evidence is packed into and unpacked from submission
+// transactions and forged evidence is rejected.
+func TestDoubleSignEvidenceSubmission(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Parlia: &params.ParliaConfig{Period: 3, Epoch: 100}}
+	engine := New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+
+	a := forgeHeader(config, common.Hash{0x01}, 10, 100, key)
+	b := forgeHeader(config, common.Hash{0x02}, 10, 100, key)
+	evidence := newDoubleSignEvidence(validator, a, b, config.ChainID)
+	if err := evidence.verify(config.ChainID); err != nil {
+		t.Fatalf("valid evidence rejected: %v", err)
+	}
+	// Submission is disabled until the method is configured and the fork activated
+	if _, err := engine.packEvidence(evidence); err != errEvidenceDisabled {
+		t.Fatalf("disabled submission error mismatch: have %v, want %v", err, errEvidenceDisabled)
+	}
+	config.Parlia.EvidenceMethod = "submitDoubleSignEvidence(bytes,bytes)"
+	config.Parlia.EvidenceBlock = big.NewInt(20)
+	if contract := engine.evidenceContract(big.NewInt(19)); contract != nil {
+		t.Fatalf("evidence submitted before the fork to %x", *contract)
+	}
+	number := big.NewInt(20)
+	data, err := engine.packEvidence(evidence)
+	if err != nil {
+		t.Fatalf("failed to pack evidence: %v", err)
+	}
+	tx := types.NewTransaction(0, *engine.evidenceContract(number), common.Big0, 0, common.Big0, data)
+	unpacked, err := engine.unpackEvidence(tx, number)
+	if err != nil {
+		t.Fatalf("failed to unpack evidence: %v", err)
+	}
+	if err := unpacked.verify(config.ChainID); err != nil {
+		t.Fatalf("unpacked evidence invalid: %v", err)
+	}
+	if unpacked.HeaderA.Hash() != evidence.HeaderA.Hash() || unpacked.HeaderB.Hash() != evidence.HeaderB.Hash() {
+		t.Fatalf("unpacked evidence mismatch")
+	}
+	// Other calls to the contract are no evidence
+	if ev, err := engine.unpackEvidence(types.NewTransaction(0, *engine.evidenceContract(number), common.Big0, 0, common.Big0, []byte{0x01, 0x02, 0x03, 0x04}), number); ev != nil || err != nil {
+		t.Fatalf("unrelated call unpacked as evidence: %v, %v", ev, err)
+	}
+	// Forged evidence is refused
+	forged := []*DoubleSignEvidence{
+		newDoubleSignEvidence(validator, a, a, config.ChainID),
+		newDoubleSignEvidence(validator, a, forgeHeader(config, common.Hash{0x02}, 11, 100, key), config.ChainID),
+		newDoubleSignEvidence(validator, a, forgeHeader(config, common.Hash{0x02}, 10, 100, other), config.ChainID),
+	}
+	// A header sealed by another key, claiming the validator as coinbase
+	c := forgeHeader(config, common.Hash{0x03}, 10, 100, other)
+	c.Coinbase = validator
+	forged = append(forged, newDoubleSignEvidence(validator, a, c, config.ChainID))
+
+	for i, ev := range forged {
+		if err := ev.verify(config.ChainID); !errors.Is(err, errInvalidEvidence) {
+			t.Errorf("forged evidence %d: error mismatch: have %v, want %v", i, err, errInvalidEvidence)
+		}
+	}
+	// Non system contracts can't receive evidence
+	config.Parlia.EvidenceContract = &common.Address{0xff}
+	if contract := engine.evidenceContract(number); contract != nil {
+		t.Fatalf("evidence submitted to non system contract %x", *contract)
+	}
+}
+
+// Tests that double sign evidence is only marked submitted once a canonical block
+// carries it in a system transaction.
+func TestDoubleSignEvidenceRecording(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sealer, _ := crypto.GenerateKey()
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Parlia: &params.ParliaConfig{Period: 3, Epoch: 100, EvidenceBlock: big.NewInt(1), EvidenceMethod: "submitDoubleSignEvidence(bytes,bytes)"}}
+	engine := New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+	api := &API{parlia: engine}
+
+	evidence := newDoubleSignEvidence(validator, forgeHeader(config, common.Hash{0x01}, 10, 100, key), forgeHeader(config, common.Hash{0x02}, 10, 100, key), config.ChainID)
+	if err := storeEvidence(engine.db, evidence); err != nil {
+		t.Fatalf("failed to store evidence: %v", err)
+	}
+	data, err := engine.packEvidence(evidence)
+	if err != nil {
+		t.Fatalf("failed to pack evidence: %v", err)
+	}
+	header := &types.Header{Number: big.NewInt(20), Coinbase: crypto.PubkeyToAddress(sealer.PublicKey)}
+	submit := func(key *ecdsa.PrivateKey) *types.Block {
+		tx, err := types.SignTx(types.NewTransaction(0, *engine.evidenceContract(header.Number), common.Big0, 0, common.Big0, data), engine.signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		return types.NewBlockWithHeader(header).WithBody([]*types.Transaction{tx}, nil)
+	}
+	// Evidence submitted by someone else than the sealer is no system transaction
+	engine.recordEvidence(submit(key))
+	if stored, _ := api.GetEvidence(nil); len(stored) != 1 || stored[0].SubmittedIn != 0 {
+		t.Fatalf("evidence marked submitted by non system transaction: %v", stored)
+	}
+	engine.recordEvidence(submit(sealer))
+	if stored, _ := api.GetEvidence(nil); len(stored) != 1 || stored[0].SubmittedIn != 20 {
+		t.Fatalf("evidence not marked submitted: %v", stored)
+	}
+}
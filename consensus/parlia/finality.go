@@ -0,0 +1,350 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parlia
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// attestationLengthSize is the number of bytes after the FastFinality fork in
+// front of the seal holding the length of the attestation preceding them, as a
+// big endian uint32.
+const attestationLengthSize = 4
+
+var (
+	// errMissingAttestationLength is returned if a header after the FastFinality
+	// fork doesn't leave room for the length of its attestation.
+	errMissingAttestationLength = errors.New("extra-data attestation length missing")
+
+	// errInvalidAttestation is returned if the attestation of a header can't be
+	// decoded.
+	errInvalidAttestation = errors.New("invalid vote attestation")
+
+	// errInvalidAttestationTarget is returned if the attestation of a header
+	// doesn't vote for its parent.
+	errInvalidAttestationTarget = errors.New("attestation target is not the parent block")
+
+	// errInvalidVoteSource is returned if a vote or an attestation doesn't build
+	// on top of the latest justified block of its target.
+	errInvalidVoteSource = errors.New("vote source is not the latest justified block")
+
+	// errInvalidVote is returned if a vote is malformed.
+	errInvalidVote = errors.New("invalid vote")
+
+	// errUnauthorizedVoter is returned if a vote is signed by someone else than
+	// a validator of the block voted for.
+	errUnauthorizedVoter = errors.New("unauthorized voter")
+
+	// errDuplicateVote is returned if an attestation contains the vote of the
+	// same validator twice.
+	errDuplicateVote = errors.New("duplicate vote in attestation")
+
+	// errInsufficientVotes is returned if an attestation is signed by less than
+	// two thirds of the validators.
+	errInsufficientVotes = errors.New("insufficient votes in attestation")
+
+	// errFastFinalityInactive is returned if a vote is cast for a block whose
+	// descendants can't carry attestations.
+	errFastFinalityInactive = errors.New("fast finality not active")
+)
+
+// VotePool is the source of the votes the engine aggregates into the headers it
+// prepares.
+type VotePool interface {
+	// FetchVotes retrieves the verified votes for the given block.
+	FetchVotes(targetHash common.Hash) []*types.VoteEnvelope
+}
+
+// SetVotePool sets the pool of the votes to aggregate into sealed headers. It is
+// injected once the blockchain is available, which is created after the engine.
+func (p *Parlia) SetVotePool(pool VotePool) {
+	p.votePool = pool
+}
+
+// splitExtra splits the extra-data of a header between the vanity and the seal
+// into the epoch data, i.e. the validators and consensus parameters elected on
+// epoch blocks, and the RLP encoded vote attestation of the parent block after
+// the FastFinality fork.
+func splitExtra(config *params.ChainConfig, header *types.Header) ([]byte, []byte, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, nil, errMissingSignature
+	}
+	data := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	if header.Number.Sign() == 0 || !config.IsFastFinality(header.Number) {
+		return data, nil, nil
+	}
+	if len(data) < attestationLengthSize {
+		return nil, nil, errMissingAttestationLength
+	}
+	size := uint64(binary.BigEndian.Uint32(data[len(data)-attestationLengthSize:]))
+	data = data[:len(data)-attestationLengthSize]
+	if size > uint64(len(data)) {
+		return nil, nil, errInvalidAttestation
+	}
+	split := len(data) - int(size)
+	return data[:split], data[split:], nil
+}
+
+// parseAttestation retrieves the vote attestation a header carries, nil if it
+// doesn't carry any.
+func parseAttestation(config *params.ChainConfig, header *types.Header) (*types.VoteAttestation, error) {
+	_, enc, err := splitExtra(config, header)
+	if err != nil || len(enc) == 0 {
+		return nil, err
+	}
+	attestation := new(types.VoteAttestation)
+	if err := rlp.DecodeBytes(enc, attestation); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidAttestation, err)
+	}
+	if attestation.Data == nil {
+		return nil, errInvalidAttestation
+	}
+	return attestation, nil
+}
+
+// encodeAttestation is the inverse of splitExtra for the attestation, returning
+// the extra-data section following the epoch data. The attestation may be nil.
+func encodeAttestation(attestation *types.VoteAttestation) ([]byte, error) {
+	var enc []byte
+	if attestation != nil {
+		var err error
+		if enc, err = rlp.EncodeToBytes(attestation); err != nil {
+			return nil, err
+		}
+	}
+	var size [attestationLengthSize]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(enc)))
+	return append(enc, size[:]...), nil
+}
+
+// justified returns the latest block justified as of the snapshot, the genesis
+// block if no block was justified yet.
+func (p *Parlia) justified(snap *Snapshot) (uint64, common.Hash) {
+	if snap.Attestation == nil {
+		return 0, p.genesisHash
+	}
+	return snap.Attestation.TargetNumber, snap.Attestation.TargetHash
+}
+
+// verifyAttestation checks that the attestation a header carries, if any, is
+// signed by at least two thirds of the validators voting for its parent on top
+// of the latest justified block. The snapshot has to be the one of the parent.
+func (p *Parlia) verifyAttestation(snap *Snapshot, header *types.Header) error {
+	attestation, err := parseAttestation(p.chainConfig, header)
+	if err != nil || attestation == nil {
+		return err
+	}
+	data := attestation.Data
+	if data.TargetNumber+1 != header.Number.Uint64() || data.TargetHash != header.ParentHash {
+		return errInvalidAttestationTarget
+	}
+	if number, hash := p.justified(snap); data.SourceNumber != number || data.SourceHash != hash {
+		return errInvalidVoteSource
+	}
+	voters, err := attestation.Validators()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidAttestation, err)
+	}
+	seen := make(map[common.Address]struct{}, len(voters))
+	for _, voter := range voters {
+		if _, ok := snap.Validators[voter]; !ok {
+			return errUnauthorizedVoter
+		}
+		if _, ok := seen[voter]; ok {
+			return errDuplicateVote
+		}
+		seen[voter] = struct{}{}
+	}
+	if len(seen) < snap.voteQuorum() {
+		return errInsufficientVotes
+	}
+	return nil
+}
+
+// assembleAttestation aggregates the pooled votes for the parent of a header,
+// returning nil if they don't reach the quorum of the validators. The snapshot
+// has to be the one of the parent.
+func (p *Parlia) assembleAttestation(snap *Snapshot, header *types.Header) *types.VoteAttestation {
+	if p.votePool == nil {
+		return nil
+	}
+	data := &types.VoteData{
+		TargetNumber: header.Number.Uint64() - 1,
+		TargetHash:   header.ParentHash,
+	}
+	data.SourceNumber, data.SourceHash = p.justified(snap)
+
+	var (
+		signatures [][]byte
+		seen       = make(map[common.Address]struct{})
+	)
+	for _, vote := range p.votePool.FetchVotes(header.ParentHash) {
+		if vote.Data == nil || *vote.Data != *data {
+			continue
+		}
+		voter, err := vote.Validator()
+		if err != nil {
+			continue
+		}
+		if _, ok := snap.Validators[voter]; !ok {
+			continue
+		}
+		if _, ok := seen[voter]; ok {
+			continue
+		}
+		seen[voter] = struct{}{}
+		signatures = append(signatures, vote.Signature)
+	}
+	if len(signatures) < snap.voteQuorum() {
+		return nil
+	}
+	return &types.VoteAttestation{Data: data, Signatures: signatures}
+}
+
+// VerifyVote implements consensus.FastFinality, checking that a vote attests a
+// known block on top of the block justified before it, and that it is signed
+// by one of its validators.
+func (p *Parlia) VerifyVote(chain consensus.ChainHeaderReader, vote *types.VoteEnvelope) error {
+	data := vote.Data
+	if data == nil || data.TargetNumber == 0 {
+		return errInvalidVote
+	}
+	if !p.chainConfig.IsFastFinality(new(big.Int).SetUint64(data.TargetNumber + 1)) {
+		return errFastFinalityInactive
+	}
+	if chain.GetHeader(data.TargetHash, data.TargetNumber) == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	snap, err := p.snapshot(chain, data.TargetNumber, data.TargetHash, nil)
+	if err != nil {
+		return err
+	}
+	if number, hash := p.justified(snap); data.SourceNumber != number || data.SourceHash != hash {
+		return errInvalidVoteSource
+	}
+	voter, err := vote.Validator()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidVote, err)
+	}
+	if _, ok := snap.Validators[voter]; !ok {
+		return errUnauthorizedVoter
+	}
+	return nil
+}
+
+// SignVote implements consensus.FastFinality, voting for the given block with
+// the local validator key on top of the latest block justified before it.
+func (p *Parlia) SignVote(chain consensus.ChainHeaderReader, header *types.Header) (*types.VoteEnvelope, error) {
+	if !p.chainConfig.IsFastFinality(new(big.Int).Add(header.Number, common.Big1)) {
+		return nil, nil
+	}
+	p.lock.RLock()
+	val, signFn := p.val, p.signFn
+	p.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, nil
+	}
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := snap.Validators[val]; !ok {
+		return nil, nil
+	}
+	data := &types.VoteData{
+		TargetNumber: header.Number.Uint64(),
+		TargetHash:   header.Hash(),
+	}
+	data.SourceNumber, data.SourceHash = p.justified(snap)
+
+	enc, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signFn(accounts.Account{Address: val}, accounts.MimetypeParliaVote, enc)
+	if err != nil {
+		return nil, err
+	}
+	return &types.VoteEnvelope{Data: data, Signature: sig}, nil
+}
+
+// GetJustifiedHeader implements consensus.FastFinality, returning the latest
+// block justified by the attestations in the chain up to the given header.
+func (p *Parlia) GetJustifiedHeader(chain consensus.ChainHeaderReader, header *types.Header) *types.Header {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil || snap.Attestation == nil {
+		return nil
+	}
+	return chain.GetHeader(snap.Attestation.TargetHash, snap.Attestation.TargetNumber)
+}
+
+// GetFinalizedHeader implements consensus.FastFinality, returning the latest
+// block finalized by the attestations in the chain up to the given header. A
+// justified block is final once its direct child is justified on top of it.
+func (p *Parlia) GetFinalizedHeader(chain consensus.ChainHeaderReader, header *types.Header) *types.Header {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil || snap.FinalizedHash == (common.Hash{}) {
+		return nil
+	}
+	return chain.GetHeader(snap.FinalizedHash, snap.FinalizedNumber)
+}
+
+// GetSealFinalizedHeader implements consensus.FastFinality, walking the chain
+// back from the given header until more than two thirds of the validators sealed
+// the blocks walked, and returning the parent of the last one. Reverting it would
+// take some of them to seal a conflicting chain.
+//
+// The walk is bounded by twice the number of validators, which seal in turn, nil
+// is returned if not enough of them sealed blocks in that range.
+func (p *Parlia) GetSealFinalizedHeader(chain consensus.ChainHeaderReader, header *types.Header) *types.Header {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil
+	}
+	var (
+		quorum  = snap.sealQuorum()
+		sealers = make(map[common.Address]struct{}, quorum)
+		head    = header.Number.Uint64()
+		depth   = 2 * uint64(len(snap.Validators))
+	)
+	for header != nil && header.Number.Sign() > 0 && head-header.Number.Uint64() < depth {
+		number := header.Number.Uint64()
+		sealer, ok := snap.Recents[number]
+		if !ok {
+			sealer = header.Coinbase // Verified to be the signer of the seal
+		}
+		if _, ok := snap.Validators[sealer]; ok {
+			sealers[sealer] = struct{}{}
+		}
+		if len(sealers) >= quorum {
+			return chain.GetHeader(header.ParentHash, number-1)
+		}
+		header = chain.GetHeader(header.ParentHash, number-1)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parlia
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// finalityTester is a chain of four validators with fast finality active from
+// the first block on.
+type finalityTester struct {
+	t      *testing.T
+	keys   []*ecdsa.PrivateKey
+	config *params.ChainConfig
+	chain  *headerChain
+	engine *Parlia
+}
+
+func newFinalityTester(t *testing.T) *finalityTester {
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Parlia: &params.ParliaConfig{Period: 3, Epoch: 100, FastFinalityBlock: big.NewInt(1)}}
+	tester := &finalityTester{
+		t:      t,
+		keys:   make([]*ecdsa.PrivateKey, 4),
+		config: config,
+		chain:  &headerChain{config: config, headers: make(map[uint64]*types.Header)},
+	}
+	genesis := &types.Header{Number: big.NewInt(0), Extra: make([]byte, extraVanity)}
+	for i := range tester.keys {
+		tester.keys[i], _ = crypto.GenerateKey()
+		genesis.Extra = append(genesis.Extra, crypto.PubkeyToAddress(tester.keys[i].PublicKey).Bytes()...)
+	}
+	genesis.Extra = append(genesis.Extra, make([]byte, extraSeal)...)
+	tester.chain.headers[0] = genesis
+
+	tester.engine = New(config, rawdb.NewMemoryDatabase(), nil, genesis.Hash())
+	tester.engine.fakeDiff = true
+	return tester
+}
+
+// vote signs the given vote data with the keys of the given validators.
+func (ft *finalityTester) vote(data *types.VoteData, validators ...int) *types.VoteAttestation {
+	attestation := &types.VoteAttestation{Data: data}
+	for _, i := range validators {
+		sig, err := crypto.Sign(data.Hash().Bytes(), ft.keys[i])
+		if err != nil {
+			ft.t.Fatalf("failed to sign vote: %v", err)
+		}
+		attestation.Signatures = append(attestation.Signatures, sig)
+	}
+	return attestation
+}
+
+// header creates the next header on top of the given parent, carrying the given
+// attestation, sealed by the in-turn validator.
+func (ft *finalityTester) header(parent *types.Header, attestation *types.VoteAttestation) *types.Header {
+	enc, err := encodeAttestation(attestation)
+	if err != nil {
+		ft.t.Fatalf("failed to encode attestation: %v", err)
+	}
+	number := parent.Number.Uint64() + 1
+	extra := append(make([]byte, extraVanity), enc...)
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).SetUint64(number),
+		Time:       parent.Time + 3,
+		Difficulty: diffInTurn,
+		Extra:      append(extra, make([]byte, extraSeal)...),
+	}
+	snap, err := ft.engine.snapshot(ft.chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		ft.t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	key := ft.keys[0]
+	for _, k := range ft.keys {
+		if crypto.PubkeyToAddress(k.PublicKey) == snap.validators()[number%uint64(len(ft.keys))] {
+			key = k
+		}
+	}
+	header.Coinbase = crypto.PubkeyToAddress(key.PublicKey)
+	sig, err := crypto.Sign(SealHash(header, ft.config.ChainID).Bytes(), key)
+	if err != nil {
+		ft.t.Fatalf("failed to seal header: %v", err)
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+	return header
+}
+
+// verify checks the attestation of a header against the snapshot of its parent.
+func (ft *finalityTester) verify(header *types.Header) error {
+	snap, err := ft.engine.snapshot(ft.chain, header.Number.Uint64()-1, header.ParentHash, nil)
+	if err != nil {
+		ft.t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	return ft.engine.verifyAttestation(snap, header)
+}
+
+// Tests that attestations are verified against the validator set and the latest
+// justified block, and that consecutive justified blocks finalize the chain.
+func TestAttestationFinality(t *testing.T) {
+	ft := newFinalityTester(t)
+	genesis := ft.chain.headers[0]
+
+	// The first block carries no attestation
+	first := ft.header(genesis, nil)
+	if err := ft.verify(first); err != nil {
+		t.Fatalf("failed to verify header without attestation: %v", err)
+	}
+	ft.chain.headers[1] = first
+
+	// The second block attests the first one on top of the genesis
+	data := &types.VoteData{SourceHash: genesis.Hash(), TargetNumber: 1, TargetHash: first.Hash()}
+	outsider, _ := crypto.GenerateKey()
+	ft.keys = append(ft.keys, outsider)
+
+	tests := []struct {
+		attestation *types.VoteAttestation
+		err         error
+	}{
+		{ft.vote(data, 0, 1), errInsufficientVotes},
+		{ft.vote(data, 0, 1, 1), errDuplicateVote},
+		{ft.vote(data, 0, 1, 4), errUnauthorizedVoter},
+		{ft.vote(&types.VoteData{SourceNumber: 1, SourceHash: first.Hash(), TargetNumber: 1, TargetHash: first.Hash()}, 0, 1, 2), errInvalidVoteSource},
+		{ft.vote(&types.VoteData{SourceHash: genesis.Hash(), TargetHash: genesis.Hash()}, 0, 1, 2), errInvalidAttestationTarget},
+		{ft.vote(data, 0, 1, 2), nil},
+	}
+	for i, tt := range tests {
+		if err := ft.verify(ft.header(first, tt.attestation)); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	ft.keys = ft.keys[:4]
+
+	second := ft.header(first, ft.vote(data, 1, 2, 3))
+	ft.chain.headers[2] = second
+	if header := ft.engine.GetJustifiedHeader(ft.chain, second); header == nil || header.Hash() != first.Hash() {
+		t.Fatalf("justified header mismatch: have %v, want %v", header, first.Hash())
+	}
+	// The third block justifies the direct child of the first one, finalizing it
+	third := ft.header(second, ft.vote(&types.VoteData{SourceNumber: 1, SourceHash: first.Hash(), TargetNumber: 2, TargetHash: second.Hash()}, 0, 2, 3))
+	if err := ft.verify(third); err != nil {
+		t.Fatalf("failed to verify attestation: %v", err)
+	}
+	ft.chain.headers[3] = third
+	if header := ft.engine.GetJustifiedHeader(ft.chain, third); header == nil || header.Hash() != second.Hash() {
+		t.Fatalf("justified header mismatch: have %v, want %v", header, second.Hash())
+	}
+	if header := ft.engine.GetFinalizedHeader(ft.chain, third); header == nil || header.Hash() != first.Hash() {
+		t.Fatalf("finalized header mismatch: have %v, want %v", header, first.Hash())
+	}
+	// Without an attestation the justified and finalized blocks are kept
+	fourth := ft.header(third, nil)
+	ft.chain.headers[4] = fourth
+	if header := ft.engine.GetFinalizedHeader(ft.chain, fourth); header == nil || header.Hash() != first.Hash() {
+		t.Fatalf("finalized header mismatch: have %v, want %v", header, first.Hash())
+	}
+}
+
+// Tests that the votes signed by the local validator are accepted by the other
+// nodes, while votes of unknown validators or sources are rejected.
+func TestSignAndVerifyVote(t *testing.T) {
+	ft := newFinalityTester(t)
+	genesis := ft.chain.headers[0]
+	first := ft.header(genesis, nil)
+	ft.chain.headers[1] = first
+
+	// Non-validators don't vote
+	outsider, _ := crypto.GenerateKey()
+	signFn := func(key *ecdsa.PrivateKey) SignerFn {
+		return func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+			if mimeType != accounts.MimetypeParliaVote {
+				t.Fatalf("mimetype mismatch: have %s, want %s", mimeType, accounts.MimetypeParliaVote)
+			}
+			return crypto.Sign(crypto.Keccak256(data), key)
+		}
+	}
+	ft.engine.Authorize(crypto.PubkeyToAddress(outsider.PublicKey), signFn(outsider), nil)
+	if vote, err := ft.engine.SignVote(ft.chain, first); err != nil || vote != nil {
+		t.Fatalf("non-validator voted: %v, %v", vote, err)
+	}
+	ft.engine.Authorize(crypto.PubkeyToAddress(ft.keys[2].PublicKey), signFn(ft.keys[2]), nil)
+	vote, err := ft.engine.SignVote(ft.chain, first)
+	if err != nil || vote == nil {
+		t.Fatalf("failed to sign vote: %v", err)
+	}
+	if vote.Data.TargetHash != first.Hash() || vote.Data.SourceHash != genesis.Hash() {
+		t.Fatalf("vote data mismatch: %+v", vote.Data)
+	}
+	if err := ft.engine.VerifyVote(ft.chain, vote); err != nil {
+		t.Fatalf("failed to verify vote: %v", err)
+	}
+	// Forged votes are rejected
+	forged, _ := crypto.Sign(vote.Data.Hash().Bytes(), outsider)
+	if err := ft.engine.VerifyVote(ft.chain, &types.VoteEnvelope{Data: vote.Data, Signature: forged}); err != errUnauthorizedVoter {
+		t.Fatalf("forged vote error mismatch: have %v, want %v", err, errUnauthorizedVoter)
+	}
+	source := *vote.Data
+	source.SourceNumber, source.SourceHash = 1, first.Hash()
+	if err := ft.engine.VerifyVote(ft.chain, &types.VoteEnvelope{Data: &source, Signature: ft.vote(&source, 2).Signatures[0]}); err != errInvalidVoteSource {
+		t.Fatalf("wrong source error mismatch: have %v, want %v", err, errInvalidVoteSource)
+	}
+	unknown := *vote.Data
+	unknown.TargetHash = common.Hash{0x01}
+	if err := ft.engine.VerifyVote(ft.chain, &types.VoteEnvelope{Data: &unknown, Signature: ft.vote(&unknown, 2).Signatures[0]}); err == nil {
+		t.Fatalf("vote for unknown block accepted")
+	}
+}
+
+// Tests that without attestations, blocks are finalized once more than two thirds
+// of the validators sealed blocks on top of them.
+func TestSealFinality(t *testing.T) {
+	ft := newFinalityTester(t)
+
+	headers := []*types.Header{ft.chain.headers[0]}
+	for number := uint64(1); number <= 4; number++ {
+		header := ft.header(headers[number-1], nil)
+		ft.chain.headers[number] = header
+		headers = append(headers, header)
+	}
+	// Three of the four validators have to seal on top of a block
+	if header := ft.engine.GetSealFinalizedHeader(ft.chain, headers[2]); header != nil {
+		t.Fatalf("block finalized by two validators: %d", header.Number)
+	}
+	for number, want := range map[int]int{3: 0, 4: 1} {
+		if header := ft.engine.GetSealFinalizedHeader(ft.chain, headers[number]); header == nil || header.Hash() != headers[want].Hash() {
+			t.Errorf("finalized header mismatch at %d: have %v, want %d", number, header, want)
+		}
+		// Seal depth never finalizes blocks for the fork choice
+		if header := ft.engine.GetFinalizedHeader(ft.chain, headers[number]); header != nil {
+			t.Errorf("block %d finalized without votes", header.Number)
+		}
+	}
+}
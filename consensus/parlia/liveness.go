@@ -0,0 +1,307 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parlia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	maxLivenessRange   = 100000 // Maximum number of blocks a single liveness query may span
+	chainEventChanSize = 10     // Size of the channel listening to chain events
+)
+
+// livenessPrefix is the database key prefix of the per-epoch liveness records,
+// followed by the hash of the last block of the epoch.
+var livenessPrefix = []byte("parlia-liveness-")
+
+var (
+	// errInvalidRange is returned if a liveness query spans no blocks or more
+	// than maxLivenessRange.
+	errInvalidRange = errors.New("invalid block range")
+
+	// errLivenessNotTracked is returned if liveness is subscribed to on a node
+	// that doesn't track it.
+	errLivenessNotTracked = errors.New("liveness not tracked")
+)
+
+// livenessChain is the subset of the blockchain the liveness tracker needs.
+type livenessChain interface {
+	consensus.ChainHeaderReader
+
+	// SubscribeChainEvent registers a subscription of canonical block events.
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+}
+
+// BlockLiveness is the liveness outcome of a single block: who proposed it and,
+// if it was proposed out of turn, which validator missed its slot.
+type BlockLiveness struct {
+	Number   uint64          `json:"number"`
+	Hash     common.Hash     `json:"hash"`
+	Proposer common.Address  `json:"proposer"`
+	InTurn   bool            `json:"inTurn"`
+	Missed   *common.Address `json:"missed,omitempty"` // In-turn validator that missed its slot
+	Slashed  bool            `json:"slashed"`          // Whether the missing validator was slashed
+}
+
+// newBlockLiveness derives the liveness of a block from the snapshot of its parent,
+// following the same rules Finalize applies to slash validators.
+func newBlockLiveness(snap *Snapshot, header *types.Header) *BlockLiveness {
+	liveness := &BlockLiveness{
+		Number:   header.Number.Uint64(),
+		Hash:     header.Hash(),
+		Proposer: header.Coinbase,
+		InTurn:   header.Difficulty.Cmp(diffInTurn) == 0,
+	}
+	if !liveness.InTurn {
+		missed := snap.supposeValidator()
+		liveness.Missed = &missed
+		liveness.Slashed = !snap.signedRecently(missed)
+	}
+	return liveness
+}
+
+// ValidatorStats is the liveness record of a validator over a range of blocks.
+type ValidatorStats struct {
+	InTurn    uint64 `json:"inTurn"`    // Blocks proposed in turn
+	OutOfTurn uint64 `json:"outOfTurn"` // Blocks proposed out of turn
+	Missed    uint64 `json:"missed"`    // In-turn slots proposed by another validator
+	Slashed   uint64 `json:"slashed"`   // Missed slots the validator was slashed for
+}
+
+// livenessStats accumulates the liveness of a range of blocks.
+type livenessStats struct {
+	Validators map[common.Address]*ValidatorStats `json:"validators"`
+	Missed     []*BlockLiveness                   `json:"missed"` // Blocks with a missed slot
+}
+
+func newLivenessStats() *livenessStats {
+	return &livenessStats{Validators: make(map[common.Address]*ValidatorStats)}
+}
+
+// validator returns the stats of the given validator, creating them if needed.
+func (s *livenessStats) validator(addr common.Address) *ValidatorStats {
+	stats := s.Validators[addr]
+	if stats == nil {
+		stats = new(ValidatorStats)
+		s.Validators[addr] = stats
+	}
+	return stats
+}
+
+// add accounts the liveness of a block.
+func (s *livenessStats) add(liveness *BlockLiveness) {
+	if liveness.InTurn {
+		s.validator(liveness.Proposer).InTurn++
+		return
+	}
+	s.validator(liveness.Proposer).OutOfTurn++
+
+	missed := s.validator(*liveness.Missed)
+	missed.Missed++
+	if liveness.Slashed {
+		missed.Slashed++
+	}
+	s.Missed = append(s.Missed, liveness)
+}
+
+// merge accounts the liveness of another range of blocks.
+func (s *livenessStats) merge(other *livenessStats) {
+	for addr, stats := range other.Validators {
+		own := s.validator(addr)
+		own.InTurn += stats.InTurn
+		own.OutOfTurn += stats.OutOfTurn
+		own.Missed += stats.Missed
+		own.Slashed += stats.Slashed
+	}
+	s.Missed = append(s.Missed, other.Missed...)
+}
+
+// loadEpochLiveness retrieves the liveness record of the epoch ending with the
+// block with the given hash from the database.
+func loadEpochLiveness(db ethdb.KeyValueReader, hash common.Hash) (*livenessStats, error) {
+	blob, err := db.Get(append(livenessPrefix, hash[:]...))
+	if err != nil {
+		return nil, err
+	}
+	stats := new(livenessStats)
+	if err := json.Unmarshal(blob, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// storeEpochLiveness inserts the liveness record of the epoch ending with the
+// block with the given hash into the database.
+func storeEpochLiveness(db ethdb.KeyValueWriter, hash common.Hash, stats *livenessStats) error {
+	blob, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(livenessPrefix, hash[:]...), blob)
+}
+
+// blockLiveness derives the liveness of the given block.
+func (p *Parlia) blockLiveness(chain consensus.ChainHeaderReader, header *types.Header) (*BlockLiveness, error) {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return &BlockLiveness{Hash: header.Hash(), Proposer: header.Coinbase, InTurn: true}, nil
+	}
+	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	return newBlockLiveness(snap, header), nil
+}
+
+// epochLiveness returns the liveness record of the epoch ending with the given
+// block, aggregating and persisting it if it isn't stored yet. Records are keyed
+// by block hash, so the ones of reorged epochs are never served.
+func (p *Parlia) epochLiveness(chain consensus.ChainHeaderReader, last *types.Header) (*livenessStats, error) {
+	if stats, err := loadEpochLiveness(p.db, last.Hash()); err == nil {
+		return stats, nil
+	}
+	snap, err := p.snapshot(chain, last.Number.Uint64(), last.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Gather the headers of the epoch and process them in ascending order, so
+	// the snapshots are built incrementally
+	headers := []*types.Header{last}
+	for number := last.Number.Uint64(); number > snap.EpochBlock; number-- {
+		parent := chain.GetHeader(headers[len(headers)-1].ParentHash, number-1)
+		if parent == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		headers = append(headers, parent)
+	}
+	stats := newLivenessStats()
+	for i := len(headers) - 1; i >= 0; i-- {
+		liveness, err := p.blockLiveness(chain, headers[i])
+		if err != nil {
+			return nil, err
+		}
+		stats.add(liveness)
+	}
+	if err := storeEpochLiveness(p.db, last.Hash(), stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// rangeLiveness aggregates the liveness of the canonical blocks from..to, using
+// the persisted records of the complete epochs within the range.
+func (p *Parlia) rangeLiveness(chain consensus.ChainHeaderReader, from, to uint64) (*livenessStats, error) {
+	if from > to || to-from >= maxLivenessRange {
+		return nil, fmt.Errorf("%w: %d-%d", errInvalidRange, from, to)
+	}
+	stats := newLivenessStats()
+	for number := from; number <= to; {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("%w: #%d", errUnknownBlock, number)
+		}
+		snap, err := p.snapshot(chain, number, header.Hash(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if end := number + snap.Epoch - 1; snap.EpochBlock == number && end <= to {
+			last := chain.GetHeaderByNumber(end)
+			if last == nil {
+				return nil, fmt.Errorf("%w: #%d", errUnknownBlock, end)
+			}
+			epoch, err := p.epochLiveness(chain, last)
+			if err != nil {
+				return nil, err
+			}
+			stats.merge(epoch)
+			number = end + 1
+			continue
+		}
+		liveness, err := p.blockLiveness(chain, header)
+		if err != nil {
+			return nil, err
+		}
+		stats.add(liveness)
+		number++
+	}
+	return stats, nil
+}
+
+// TrackLiveness starts deriving the liveness of every new canonical block of the
+// chain, notifying the liveness subscribers and persisting the record of every
+// epoch as soon as it completes.
+func (p *Parlia) TrackLiveness(chain livenessChain) {
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	sub := chain.SubscribeChainEvent(events)
+
+	p.livenessTracked = true
+	go p.livenessLoop(chain, events, sub)
+}
+
+// livenessLoop processes the chain events of the liveness tracker until the
+// engine is closed.
+func (p *Parlia) livenessLoop(chain livenessChain, events chan core.ChainEvent, sub event.Subscription) {
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-events:
+			header := ev.Block.Header()
+			liveness, err := p.blockLiveness(chain, header)
+			if err != nil {
+				log.Debug("Failed to derive block liveness", "number", header.Number, "hash", header.Hash(), "err", err)
+				continue
+			}
+			if liveness.Missed != nil {
+				log.Debug("Validator missed its slot", "number", liveness.Number, "validator", *liveness.Missed, "proposer", liveness.Proposer, "slashed", liveness.Slashed)
+			}
+			p.livenessFeed.Send(liveness)
+
+			snap, err := p.snapshot(chain, liveness.Number, liveness.Hash, nil)
+			if err != nil {
+				log.Debug("Failed to retrieve snapshot", "number", header.Number, "hash", header.Hash(), "err", err)
+				continue
+			}
+			if snap.isEpoch(liveness.Number + 1) {
+				if _, err := p.epochLiveness(chain, header); err != nil {
+					log.Warn("Failed to store epoch liveness", "number", header.Number, "hash", header.Hash(), "err", err)
+				}
+			}
+		case <-sub.Err():
+			return
+		case <-p.closeCh:
+			return
+		}
+	}
+}
+
+// SubscribeLivenessEvent registers a subscription for the liveness of every new
+// canonical block.
+func (p *Parlia) SubscribeLivenessEvent(ch chan<- *BlockLiveness) event.Subscription {
+	return p.livenessScope.Track(p.livenessFeed.Subscribe(ch))
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parlia
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockLiveness(t *testing.T) {
+	validators := []common.Address{randomAddress(), randomAddress(), randomAddress()}
+	sort.Sort(validatorsAscending(validators))
+
+	// At block 3 the validator at index 1 is in turn, the one at index 0 signed recently
+	snap := newSnapshot(&params.ChainConfig{Parlia: &params.ParliaConfig{Epoch: 200}}, nil, 3, common.Hash{}, validators, nil)
+	snap.Recents[3] = validators[0]
+
+	stats := newLivenessStats()
+	header := &types.Header{Number: big.NewInt(4), Coinbase: validators[1], Difficulty: diffInTurn}
+	liveness := newBlockLiveness(snap, header)
+	assert.True(t, liveness.InTurn)
+	assert.Nil(t, liveness.Missed)
+	stats.add(liveness)
+
+	header = &types.Header{Number: big.NewInt(4), Coinbase: validators[2], Difficulty: diffNoTurn}
+	liveness = newBlockLiveness(snap, header)
+	assert.False(t, liveness.InTurn)
+	assert.Equal(t, validators[1], *liveness.Missed)
+	assert.True(t, liveness.Slashed)
+	stats.add(liveness)
+
+	// A missing validator that signed recently isn't slashed
+	snap.Recents[3] = validators[1]
+	liveness = newBlockLiveness(snap, header)
+	assert.False(t, liveness.Slashed)
+	stats.add(liveness)
+
+	assert.Equal(t, ValidatorStats{InTurn: 1, Missed: 2, Slashed: 1}, *stats.Validators[validators[1]])
+	assert.Equal(t, ValidatorStats{OutOfTurn: 2}, *stats.Validators[validators[2]])
+	assert.Len(t, stats.Missed, 2)
+
+	// Epoch records survive a database round trip and merge into range stats
+	db := rawdb.NewMemoryDatabase()
+	assert.NoError(t, storeEpochLiveness(db, header.Hash(), stats))
+	stored, err := loadEpochLiveness(db, header.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, stats, stored)
+
+	_, err = loadEpochLiveness(db, common.Hash{})
+	assert.Error(t, err)
+
+	merged := newLivenessStats()
+	merged.merge(stats)
+	merged.merge(stored)
+	assert.Equal(t, ValidatorStats{InTurn: 2, Missed: 4, Slashed: 2}, *merged.Validators[validators[1]])
+	assert.Len(t, merged.Missed, 4)
+}
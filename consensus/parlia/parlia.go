@@ -31,10 +31,12 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/core/state"
+	coresystemcontract "github.com/ethereum/go-ethereum/core/systemcontract"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -55,6 +57,7 @@ const (
 	nextForkHashSize = 4  // Fixed number of extra-data suffix bytes reserved for nextForkHash.
 
 	validatorBytesLength = common.AddressLength
+	epochParamsLength    = 12        // Period, epoch length and validator limit as big endian uint32s, after the ParamsBlock fork
 	wiggleTime           = uint64(1) // second, Random delay (per signer) to allow concurrent signers
 	initialBackOffTime   = uint64(1) // second
 	processBackOffTime   = uint64(1) // second
@@ -96,6 +99,14 @@ var (
 	// invalid list of validators (i.e. non divisible by 20 bytes).
 	errInvalidSpanValidators = errors.New("invalid validator list on sprint end block")
 
+	// errInvalidEpochParams is returned if an epoch block after the ParamsBlock
+	// fork carries missing or unusable consensus parameters.
+	errInvalidEpochParams = errors.New("invalid consensus parameters on epoch block")
+
+	// errTooManyValidators is returned if an epoch block elects more validators
+	// than the limit in force.
+	errTooManyValidators = errors.New("too many validators on epoch block")
+
 	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
 	errInvalidMixDigest = errors.New("non-zero mix digest")
 
@@ -130,6 +141,34 @@ var (
 	// errRecentlySigned is returned if a header is signed by an authorized entity
 	// that already signed a header recently, thus is temporarily not allowed to.
 	errRecentlySigned = errors.New("recently signed")
+
+	// errDeployerProxyNotActive is returned if the deployer of a contract is
+	// requested at a block where the DeployerProxy fork is not active yet.
+	errDeployerProxyNotActive = errors.New("deployer proxy is not active")
+
+	// errHeaderOnly is returned if the chain state is requested from an engine
+	// running in header-only mode, e.g. on a light client.
+	errHeaderOnly = errors.New("system contracts not available in header-only mode")
+
+	// errNoChainConfigReader is returned if the ChainConfig contract parameters
+	// are requested from an engine that has no access to the chain state.
+	errNoChainConfigReader = errors.New("chain config reader not available")
+
+	// errSignedTxMismatch is returned if the signer of the validator returns a
+	// different system transaction than the one it was requested to sign.
+	errSignedTxMismatch = errors.New("signed system transaction mismatch")
+
+	// errNotLocalBlock is returned if a diff layer is requested to be signed for
+	// a block that wasn't sealed by the local validator.
+	errNotLocalBlock = errors.New("block not sealed by local validator")
+
+	// errMissingDiffSignature is returned if a diff layer carries no well formed
+	// signature of its block sealer.
+	errMissingDiffSignature = errors.New("diff layer signature missing")
+
+	// errDiffSignerMismatch is returned if a diff layer is signed by someone else
+	// than the sealer of its block.
+	errDiffSignerMismatch = errors.New("diff layer not signed by block sealer")
 )
 
 // SignerFn is a signer callback function to request a header to be signed by a
@@ -179,6 +218,60 @@ func ParliaRLP(header *types.Header, chainId *big.Int) []byte {
 	return b.Bytes()
 }
 
+// sigHeader is the layout of the header fields covered by the seal, as encoded
+// by ParliaRLP.
+type sigHeader struct {
+	ChainID     *big.Int
+	ParentHash  common.Hash
+	UncleHash   common.Hash
+	Coinbase    common.Address
+	Root        common.Hash
+	TxHash      common.Hash
+	ReceiptHash common.Hash
+	Bloom       types.Bloom
+	Difficulty  *big.Int
+	Number      *big.Int
+	GasLimit    uint64
+	GasUsed     uint64
+	Time        uint64
+	Extra       []byte
+	MixDigest   common.Hash
+	Nonce       types.BlockNonce
+	BaseFee     *big.Int `rlp:"optional"`
+}
+
+// DecodeParliaRLP is the inverse of ParliaRLP, returning the header with a zero
+// seal and the chain id it is sealed for. It allows remote signers to show what
+// they are about to sign.
+func DecodeParliaRLP(data []byte) (*types.Header, *big.Int, error) {
+	var dec sigHeader
+	if err := rlp.DecodeBytes(data, &dec); err != nil {
+		return nil, nil, err
+	}
+	if dec.ChainID == nil || dec.Difficulty == nil || dec.Number == nil {
+		return nil, nil, errors.New("incomplete parlia header")
+	}
+	header := &types.Header{
+		ParentHash:  dec.ParentHash,
+		UncleHash:   dec.UncleHash,
+		Coinbase:    dec.Coinbase,
+		Root:        dec.Root,
+		TxHash:      dec.TxHash,
+		ReceiptHash: dec.ReceiptHash,
+		Bloom:       dec.Bloom,
+		Difficulty:  dec.Difficulty,
+		Number:      dec.Number,
+		GasLimit:    dec.GasLimit,
+		GasUsed:     dec.GasUsed,
+		Time:        dec.Time,
+		Extra:       append(dec.Extra, make([]byte, extraSeal)...),
+		MixDigest:   dec.MixDigest,
+		Nonce:       dec.Nonce,
+		BaseFee:     dec.BaseFee,
+	}
+	return header, dec.ChainID, nil
+}
+
 // Parlia is the consensus engine of BSC
 type Parlia struct {
 	chainConfig *params.ChainConfig  // Chain config
@@ -189,6 +282,10 @@ type Parlia struct {
 	recentSnaps *lru.ARCCache // Snapshots for recent block to speed up
 	signatures  *lru.ARCCache // Signatures of recent blocks to speed up mining
 
+	sealers     *lru.ARCCache // Recently sealed headers by validator and height, to detect double signs
+	sealersLock sync.Mutex    // Makes double sign detection atomic
+	refused     *lru.ARCCache // Double sign evidence the evidence contract refused when mining
+
 	signer types.Signer
 
 	val      common.Address // Ethereum address of the signing key
@@ -197,15 +294,27 @@ type Parlia struct {
 
 	lock sync.RWMutex // Protects the signer fields
 
-	ethAPI          *ethapi.PublicBlockChainAPI
-	validatorSetABI abi.ABI
-	slashABI        abi.ABI
+	ethAPI            *ethapi.PublicBlockChainAPI
+	headerOnly        bool // Whether the engine verifies headers only, without chain state
+	chainConfigReader *coresystemcontract.ChainConfigReader
+	votePool          VotePool // Votes to aggregate into prepared headers, nil if not voting
+	validatorSetABI   abi.ABI
+	slashABI          abi.ABI
+
+	livenessFeed    event.Feed
+	livenessScope   event.SubscriptionScope
+	livenessTracked bool // Whether the liveness of new blocks is tracked
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
 
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
 }
 
-// New creates a Parlia consensus engine.
+// New creates a Parlia consensus engine. Without an ethapi backend, e.g. on
+// light clients, the engine runs in header-only mode, deriving the validator
+// sets solely from the epoch headers.
 func New(
 	chainConfig *params.ChainConfig,
 	db ethdb.Database,
@@ -229,6 +338,14 @@ func New(
 	if err != nil {
 		panic(err)
 	}
+	sealers, err := lru.NewARC(inMemorySealers)
+	if err != nil {
+		panic(err)
+	}
+	refused, err := lru.NewARC(inMemoryRefusedEvidence)
+	if err != nil {
+		panic(err)
+	}
 	vABI, err := abi.JSON(strings.NewReader(validatorSetABI))
 	if err != nil {
 		panic(err)
@@ -243,11 +360,15 @@ func New(
 		genesisHash:     genesisHash,
 		db:              db,
 		ethAPI:          ethAPI,
+		headerOnly:      ethAPI == nil,
 		recentSnaps:     recentSnaps,
 		signatures:      signatures,
+		sealers:         sealers,
+		refused:         refused,
 		validatorSetABI: vABI,
 		slashABI:        sABI,
 		signer:          types.NewEIP155Signer(chainConfig.ChainID),
+		closeCh:         make(chan struct{}),
 	}
 
 	return c
@@ -327,19 +448,11 @@ func (p *Parlia) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 	if len(header.Extra) < extraVanity+extraSeal {
 		return errMissingSignature
 	}
-	// check extra data
-	isEpoch := number%p.config.Epoch == 0
-
-	// Ensure that the extra-data contains a signer list on checkpoint, but none otherwise
-	signersBytes := len(header.Extra) - extraVanity - extraSeal
-	if !isEpoch && signersBytes != 0 {
-		return errExtraValidators
-	}
-
-	if isEpoch && signersBytes%validatorBytesLength != 0 {
+	// The validator list only fits on the genesis block here, epoch blocks depend
+	// on the parameters in force and are checked with the cascading fields
+	if number == 0 && (len(header.Extra)-extraVanity-extraSeal)%validatorBytesLength != 0 {
 		return errInvalidSpanValidators
 	}
-
 	// Ensure that the mix digest is zero as we don't have fork protection currently
 	if header.MixDigest != (common.Hash{}) {
 		return errInvalidMixDigest
@@ -388,6 +501,12 @@ func (p *Parlia) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 	if err != nil {
 		return err
 	}
+	if err := p.verifyEpochExtra(snap, header); err != nil {
+		return err
+	}
+	if err := p.verifyAttestation(snap, header); err != nil {
+		return err
+	}
 
 	err = p.blockTimeVerifyForRamanujanFork(snap, header, parent)
 	if err != nil {
@@ -414,11 +533,58 @@ func (p *Parlia) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 	if uint64(diff) >= limit || header.GasLimit < params.MinGasLimit {
 		return fmt.Errorf("invalid gas limit: have %d, want %d += %d", header.GasLimit, parent.GasLimit, limit)
 	}
+	// Verify the header's EIP-1559 attributes.
+	if err := misc.VerifyEip1559Header(chain.Config(), parent, header); err != nil {
+		return err
+	}
 
 	// All basic checks passed, verify the seal and return
 	return p.verifySeal(chain, header, parents)
 }
 
+// verifyEpochExtra checks that the extra-data of a header contains a validator
+// list and the consensus parameters of the next epoch on epoch blocks, but none
+// otherwise. The snapshot has to be the one of the parent block.
+func (p *Parlia) verifyEpochExtra(snap *Snapshot, header *types.Header) error {
+	if !snap.isEpoch(header.Number.Uint64()) {
+		data, _, err := splitExtra(p.chainConfig, header)
+		if err != nil {
+			return err
+		}
+		if len(data) != 0 {
+			return errExtraValidators
+		}
+		return nil
+	}
+	validators, params, err := parseEpochExtra(p.chainConfig, header)
+	if err != nil {
+		if err == errInvalidEpochParams {
+			return err
+		}
+		return errInvalidSpanValidators
+	}
+	if params == nil {
+		return nil
+	}
+	if !validEpochParams(params, len(snap.Validators), len(validators)) {
+		return errInvalidEpochParams
+	}
+	if params.MaxValidators > 0 && uint64(len(validators)) > params.MaxValidators {
+		return errTooManyValidators
+	}
+	return nil
+}
+
+// validEpochParams reports whether the consensus parameters can take over at an
+// epoch block replacing a validator set of the given size. The epoch has to be
+// long enough for the validator sets to be switched within it.
+func validEpochParams(params *epochParams, current, next int) bool {
+	if params.Epoch == 0 || params.Epoch > math.MaxUint32 || params.Period > math.MaxUint32 {
+		return false
+	}
+	return params.Epoch > uint64(current/2) && params.Epoch > uint64(next/2)
+}
+
 // snapshot retrieves the authorization snapshot at a given point in time.
 func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
@@ -436,7 +602,7 @@ func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 
 		// If an on-disk checkpoint snapshot can be found, use that
 		if number%checkpointInterval == 0 {
-			if s, err := loadSnapshot(p.config, p.signatures, p.db, hash, p.ethAPI); err == nil {
+			if s, err := loadSnapshot(p.chainConfig, p.signatures, p.db, hash, p.ethAPI); err == nil {
 				log.Trace("Loaded snapshot from disk", "number", number, "hash", hash)
 				snap = s
 				break
@@ -458,7 +624,7 @@ func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 				}
 
 				// new snap shot
-				snap = newSnapshot(p.config, p.signatures, number, hash, validators, p.ethAPI)
+				snap = newSnapshot(p.chainConfig, p.signatures, number, hash, validators, p.ethAPI)
 				if err := snap.store(p.db); err != nil {
 					return nil, err
 				}
@@ -467,6 +633,17 @@ func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 			}
 		}
 
+		// If we're at an epoch block without a parent (light client CHT), consider
+		// the checkpoint trusted and snapshot it
+		if p.headerOnly && number > 0 {
+			if s, err := p.epochSnapshot(chain, number, hash); err != nil {
+				return nil, err
+			} else if s != nil {
+				snap = s
+				break
+			}
+		}
+
 		// No snapshot for this header, gather the header and move backward
 		var header *types.Header
 		if len(parents) > 0 {
@@ -520,6 +697,157 @@ func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 	return snap, err
 }
 
+// epochSnapshot creates a snapshot at the given block if it is an epoch block
+// whose parent is not available locally, returning nil otherwise. Epoch blocks
+// are recognized by the validators in their extra-data, as the epoch length may
+// have been changed by governance after the ParamsBlock fork.
+//
+// The validator set of an epoch header only takes over after the first half of
+// the epoch, so the snapshot starts off with the validators of the previous epoch
+// header, which has to be available too. The recent signers are unknown, so the
+// spam protection kicks in gradually.
+func (p *Parlia) epochSnapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash) (*Snapshot, error) {
+	checkpoint := chain.GetHeader(hash, number)
+	if checkpoint == nil || chain.GetHeader(checkpoint.ParentHash, number-1) != nil {
+		return nil, nil
+	}
+	if data, _, err := splitExtra(p.chainConfig, checkpoint); err != nil || len(data) == 0 {
+		return nil, err
+	}
+	elected, params, err := parseEpochExtra(p.chainConfig, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(elected) == 0 {
+		return nil, errInvalidSpanValidators
+	}
+	previous, err := p.previousEpochHeader(checkpoint, params, func(n uint64) (*types.Header, error) {
+		return chain.GetHeaderByNumber(n), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	validators, _, err := parseEpochExtra(p.chainConfig, previous)
+	if err != nil {
+		return nil, err
+	}
+	if len(validators) == 0 {
+		return nil, errInvalidSpanValidators
+	}
+	snap := newSnapshot(p.chainConfig, p.signatures, number, hash, validators, p.ethAPI)
+	snap.EpochBlock = number
+	if params != nil {
+		snap.setParams(params)
+	}
+	if err := snap.store(p.db); err != nil {
+		return nil, err
+	}
+	log.Info("Stored epoch snapshot to disk", "number", number, "hash", hash)
+	return snap, nil
+}
+
+// previousEpochHeader retrieves the epoch header preceding the given epoch
+// header, which carries the given parameters, if any. The length of the previous
+// epoch is unknown without its snapshot, so the header is looked up at the
+// distance of the parameters of the epoch header and of the genesis first, and
+// otherwise found by walking back to the closest header carrying validators in
+// its extra-data, skipping the headers getHeader doesn't know. Either way it is
+// only accepted if the epoch it starts ends right before the given header.
+func (p *Parlia) previousEpochHeader(checkpoint *types.Header, params *epochParams, getHeader func(uint64) (*types.Header, error)) (*types.Header, error) {
+	number := checkpoint.Number.Uint64()
+	lengths := []uint64{p.config.Epoch}
+	if params != nil && params.Epoch != p.config.Epoch {
+		lengths = append(lengths, params.Epoch)
+	}
+	for _, length := range lengths {
+		if length == 0 || length > number {
+			continue
+		}
+		previous, err := getHeader(number - length)
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil && p.epochLength(previous) == length {
+			return previous, nil
+		}
+	}
+	for n := number; n > 0; n-- {
+		previous, err := getHeader(n - 1)
+		if err != nil {
+			return nil, err
+		}
+		if previous == nil {
+			continue
+		}
+		if length := p.epochLength(previous); length != 0 {
+			if length != number-(n-1) {
+				return nil, fmt.Errorf("%w: epoch block %d ends at %d, not at %d", errInvalidEpochParams, n-1, n-1+length, number)
+			}
+			return previous, nil
+		}
+	}
+	return nil, consensus.ErrUnknownAncestor
+}
+
+// epochLength returns the length of the epoch started by the given header, or
+// zero if it is not an epoch header.
+func (p *Parlia) epochLength(header *types.Header) uint64 {
+	_, length, err := ParseEpochHeader(p.chainConfig, header)
+	if err != nil {
+		return 0
+	}
+	return length
+}
+
+// EpochCheckpoint implements consensus.PoSA, walking back from the given block
+// to the last epoch header, recognized by the validators in its extra-data, and
+// retrieving the epoch header preceding it, which is needed to create a snapshot
+// at the former without its ancestors.
+func (p *Parlia) EpochCheckpoint(number uint64, getHeader func(uint64) (*types.Header, error)) (*types.Header, error) {
+	for n := number + 1; n > 0; n-- {
+		checkpoint, err := getHeader(n - 1)
+		if err != nil {
+			return nil, err
+		}
+		if checkpoint == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		if p.epochLength(checkpoint) == 0 {
+			continue
+		}
+		if n == 1 {
+			return checkpoint, nil
+		}
+		_, params, err := parseEpochExtra(p.chainConfig, checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.previousEpochHeader(checkpoint, params, getHeader); err != nil {
+			return nil, err
+		}
+		return checkpoint, nil
+	}
+	return nil, consensus.ErrUnknownAncestor
+}
+
+// EpochBlock implements consensus.PoSA, returning the number of the epoch block
+// starting the epoch the given header belongs to. Epoch lengths follow the
+// parameters governed on chain.
+func (p *Parlia) EpochBlock(chain consensus.ChainHeaderReader, header *types.Header) (uint64, error) {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return 0, nil
+	}
+	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return 0, err
+	}
+	if snap.isEpoch(number) {
+		return number, nil
+	}
+	return snap.EpochBlock, nil
+}
+
 // VerifyUncles implements consensus.Engine, always returning an error for any
 // uncles as this consensus mechanism doesn't permit uncles.
 func (p *Parlia) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
@@ -564,6 +892,7 @@ func (p *Parlia) verifySeal(chain consensus.ChainHeaderReader, header *types.Hea
 	if _, ok := snap.Validators[signer]; !ok {
 		return errUnauthorizedValidator
 	}
+	p.observeSeal(header, signer)
 
 	for seen, recent := range snap.Recents {
 		if recent == signer {
@@ -614,21 +943,19 @@ func (p *Parlia) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 	nextForkHash := forkid.NextForkHash(p.chainConfig, p.genesisHash, number)
 	header.Extra = append(header.Extra, nextForkHash[:]...)
 
-	if number%p.config.Epoch == 0 {
-		newValidators, err := p.getCurrentValidators(header.ParentHash)
+	if snap.isEpoch(number) {
+		epochExtra, err := p.epochExtra(chain, snap, header)
 		if err != nil {
 			return err
 		}
-		// sort validator by address
-		sort.Sort(validatorsAscending(newValidators))
-		var newValidatorsString []string
-		for _, validator := range newValidators {
-			newValidatorsString = append(newValidatorsString, validator.Hex())
-		}
-		log.Info("Updating validator set", "validator", strings.Join(newValidatorsString, ","))
-		for _, validator := range newValidators {
-			header.Extra = append(header.Extra, validator.Bytes()...)
+		header.Extra = append(header.Extra, epochExtra...)
+	}
+	if p.chainConfig.IsFastFinality(header.Number) {
+		attestation, err := encodeAttestation(p.assembleAttestation(snap, header))
+		if err != nil {
+			return err
 		}
+		header.Extra = append(header.Extra, attestation...)
 	}
 
 	// add extra seal space
@@ -649,6 +976,73 @@ func (p *Parlia) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 	return nil
 }
 
+// epochExtra assembles the extra-data section of an epoch block from the state
+// of its parent: the validators elected by the validator set contract, limited
+// to the maximum in force and sorted by address, followed after the ParamsBlock
+// fork by the consensus parameters of the ChainConfig contract. Parameters the
+// contract leaves unset or that don't fit the validator sets carry over from
+// the snapshot of the parent.
+func (p *Parlia) epochExtra(chain consensus.ChainHeaderReader, snap *Snapshot, header *types.Header) ([]byte, error) {
+	newValidators, err := p.getCurrentValidators(header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	var params *epochParams
+	if p.chainConfig.IsParliaParams(header.Number) {
+		if params, err = p.nextEpochParams(chain, snap, header); err != nil {
+			return nil, err
+		}
+		if params.MaxValidators > 0 && uint64(len(newValidators)) > params.MaxValidators {
+			newValidators = newValidators[:params.MaxValidators]
+		}
+		if !validEpochParams(params, len(snap.Validators), len(newValidators)) {
+			log.Warn("Ignoring unusable epoch length", "number", header.Number, "epoch", params.Epoch, "validators", len(newValidators))
+			params.Epoch = snap.Epoch
+		}
+	}
+	// sort validator by address
+	sort.Sort(validatorsAscending(newValidators))
+	var newValidatorsString []string
+	for _, validator := range newValidators {
+		newValidatorsString = append(newValidatorsString, validator.Hex())
+	}
+	log.Info("Updating validator set", "validator", strings.Join(newValidatorsString, ","))
+
+	return encodeEpochExtra(newValidators, params), nil
+}
+
+// nextEpochParams reads the consensus parameters of the epoch starting at the
+// given header from the ChainConfig contract in the state of its parent. The
+// state is always executed, as the cache of the reader is a mere heuristic.
+func (p *Parlia) nextEpochParams(chain consensus.ChainHeaderReader, snap *Snapshot, header *types.Header) (*epochParams, error) {
+	if p.chainConfigReader == nil {
+		return nil, errNoChainConfigReader
+	}
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	cfg, err := p.chainConfigReader.ParamsAt(parent)
+	if err != nil {
+		return nil, err
+	}
+	params := &epochParams{
+		Period:        snap.Period,
+		Epoch:         snap.Epoch,
+		MaxValidators: snap.MaxValidators,
+	}
+	if cfg.BlockPeriod > 0 {
+		params.Period = uint64(cfg.BlockPeriod)
+	}
+	if cfg.EpochBlockInterval > 0 {
+		params.Epoch = uint64(cfg.EpochBlockInterval)
+	}
+	if cfg.ActiveValidatorsLength > 0 {
+		params.MaxValidators = uint64(cfg.ActiveValidatorsLength)
+	}
+	return params, nil
+}
+
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
 // rewards given.
 func (p *Parlia) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction,
@@ -665,25 +1059,16 @@ func (p *Parlia) Finalize(chain consensus.ChainHeaderReader, header *types.Heade
 	}
 	// If the block is an epoch end block, verify the validator list
 	// The verification can only be done when the state is ready, it can't be done in VerifyHeader.
-	if header.Number.Uint64()%p.config.Epoch == 0 {
-		newValidators, err := p.getCurrentValidators(header.ParentHash)
+	if snap.isEpoch(number) {
+		epochExtra, err := p.epochExtra(chain, snap, header)
 		if err != nil {
 			return err
 		}
-		// sort validator by address
-		sort.Sort(validatorsAscending(newValidators))
-		validatorsBytes := make([]byte, len(newValidators)*validatorBytesLength)
-		for i, validator := range newValidators {
-			copy(validatorsBytes[i*validatorBytesLength:], validator.Bytes())
-		}
-		var newValidatorsString []string
-		for _, validator := range newValidators {
-			newValidatorsString = append(newValidatorsString, validator.Hex())
+		data, _, err := splitExtra(p.chainConfig, header)
+		if err != nil {
+			return err
 		}
-		log.Info("Updating validator set", "validator", strings.Join(newValidatorsString, ","))
-
-		extraSuffix := len(header.Extra) - extraSeal
-		if !bytes.Equal(header.Extra[extraVanity:extraSuffix], validatorsBytes) {
+		if !bytes.Equal(data, epochExtra) {
 			return errMismatchingEpochValidators
 		}
 	}
@@ -696,16 +1081,12 @@ func (p *Parlia) Finalize(chain consensus.ChainHeaderReader, header *types.Heade
 			return err
 		}
 	}
+	if err := p.submitEvidence(state, header, cx, txs, receipts, systemTxs, usedGas, false); err != nil {
+		return err
+	}
 	if header.Difficulty.Cmp(diffInTurn) != 0 {
 		spoiledVal := snap.supposeValidator()
-		signedRecently := false
-		for _, recent := range snap.Recents {
-			if recent == spoiledVal {
-				signedRecently = true
-				break
-			}
-		}
-		if !signedRecently {
+		if !snap.signedRecently(spoiledVal) {
 			log.Trace("slash validator", "block hash", header.Hash(), "address", spoiledVal)
 			err = p.slash(spoiledVal, state, header, cx, txs, receipts, systemTxs, usedGas, false)
 			if err != nil {
@@ -744,6 +1125,9 @@ func (p *Parlia) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *
 			return nil, nil, err
 		}
 	}
+	if err := p.submitEvidence(state, header, cx, &txs, &receipts, nil, &header.GasUsed, true); err != nil {
+		return nil, nil, err
+	}
 	if header.Difficulty.Cmp(diffInTurn) != 0 {
 		number := header.Number.Uint64()
 		snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
@@ -751,14 +1135,7 @@ func (p *Parlia) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *
 			return nil, nil, err
 		}
 		spoiledVal := snap.supposeValidator()
-		signedRecently := false
-		for _, recent := range snap.Recents {
-			if recent == spoiledVal {
-				signedRecently = true
-				break
-			}
-		}
-		if !signedRecently {
+		if !snap.signedRecently(spoiledVal) {
 			err = p.slash(spoiledVal, state, header, cx, &txs, &receipts, nil, &header.GasUsed, true)
 			if err != nil {
 				// it is possible that slash validator failed because of the slash channel is disabled.
@@ -804,6 +1181,13 @@ func (p *Parlia) Authorize(val common.Address, signFn SignerFn, signTxFn SignerT
 	p.signTxFn = signTxFn
 }
 
+// SetChainConfigReader sets the reader used to access the parameters of the
+// ChainConfig contract. It is injected once the blockchain is available, which
+// is created after the consensus engine.
+func (p *Parlia) SetChainConfigReader(reader *coresystemcontract.ChainConfigReader) {
+	p.chainConfigReader = reader
+}
+
 func (p *Parlia) Delay(chain consensus.ChainReader, header *types.Header) *time.Duration {
 	number := header.Number.Uint64()
 	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
@@ -812,7 +1196,7 @@ func (p *Parlia) Delay(chain consensus.ChainReader, header *types.Header) *time.
 	}
 	delay := p.delayForRamanujanFork(snap, header)
 	// The blocking time should be no more than half of period
-	half := time.Duration(p.config.Period) * time.Second / 2
+	half := time.Duration(snap.Period) * time.Second / 2
 	if delay > half {
 		delay = half
 	}
@@ -829,11 +1213,6 @@ func (p *Parlia) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 	if number == 0 {
 		return errUnknownBlock
 	}
-	// For 0-period chains, refuse to seal empty blocks (no reward but would spin sealing)
-	if p.config.Period == 0 && len(block.Transactions()) == 0 {
-		log.Info("Sealing paused, waiting for transactions")
-		return nil
-	}
 	// Don't hold the val fields for the entire sealing procedure
 	p.lock.RLock()
 	val, signFn := p.val, p.signFn
@@ -843,6 +1222,11 @@ func (p *Parlia) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 	if err != nil {
 		return err
 	}
+	// For 0-period chains, refuse to seal empty blocks (no reward but would spin sealing)
+	if snap.Period == 0 && len(block.Transactions()) == 0 {
+		log.Info("Sealing paused, waiting for transactions")
+		return nil
+	}
 
 	// Bail out if we're unauthorized to sign a block
 	if _, authorized := snap.Validators[val]; !authorized {
@@ -937,6 +1321,95 @@ func (p *Parlia) AllowLightProcess(chain consensus.ChainReader, currentHeader *t
 	return idx < 0
 }
 
+// SealingStatus is the state of the validator set after a block, as seen by the
+// local validator.
+type SealingStatus struct {
+	InTurn         bool           `json:"inTurn"`         // Whether the block was sealed by the in-turn validator
+	Validators     int            `json:"validators"`     // Size of the validator set after the block
+	Epoch          uint64         `json:"epoch"`          // Number of the epoch the block belongs to
+	EpochLength    uint64         `json:"epochLength"`    // Number of blocks per epoch
+	Validator      common.Address `json:"validator"`      // Local validator, zero if not sealing
+	Authorized     bool           `json:"authorized"`     // Whether the local validator is in the validator set
+	NextInTurn     bool           `json:"nextInTurn"`     // Whether the local validator is in-turn for the next block
+	SignedRecently bool           `json:"signedRecently"` // Whether the local validator is barred from sealing the next block
+	Backoff        uint64         `json:"backoff"`        // Seconds the local validator waits to seal the next block out of turn
+}
+
+// SealingStatus retrieves the state of the validator set after the given block,
+// and the position of the local validator in it.
+func (p *Parlia) SealingStatus(chain consensus.ChainHeaderReader, header *types.Header) (*SealingStatus, error) {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	p.lock.RLock()
+	val := p.val
+	p.lock.RUnlock()
+
+	status := &SealingStatus{
+		InTurn:      header.Difficulty != nil && header.Difficulty.Cmp(diffInTurn) == 0,
+		Validators:  len(snap.Validators),
+		EpochLength: snap.Epoch,
+		Validator:   val,
+	}
+	if snap.Epoch > 0 {
+		status.Epoch = header.Number.Uint64() / snap.Epoch
+	}
+	if _, ok := snap.Validators[val]; ok {
+		status.Authorized = true
+		status.NextInTurn = snap.inturn(val)
+		status.Backoff = backOffTime(snap, val)
+
+		number := header.Number.Uint64() + 1
+		for seen, recent := range snap.Recents {
+			// Signer is among recents, only barred if the next block doesn't shift it out
+			if limit := uint64(len(snap.Validators)/2 + 1); recent == val && (number < limit || seen > number-limit) {
+				status.SignedRecently = true
+			}
+		}
+	}
+	return status, nil
+}
+
+// SignDiffLayer signs the rlp encoded diff layer of a block sealed by the local
+// validator, so that light processing peers can verify where it comes from.
+func (p *Parlia) SignDiffLayer(header *types.Header, diffLayerRLP []byte) ([]byte, error) {
+	p.lock.RLock()
+	val, signFn := p.val, p.signFn
+	p.lock.RUnlock()
+
+	if signFn == nil || header.Coinbase != val {
+		return nil, errNotLocalBlock
+	}
+	return signFn(accounts.Account{Address: val}, accounts.MimetypeParliaDiffLayer, diffLayerRLP)
+}
+
+// VerifyDiffLayer checks that the diff layer with the given hash was signed by
+// the sealer of the header, and that the sealer is in the validator set.
+func (p *Parlia) VerifyDiffLayer(chain consensus.ChainHeaderReader, header *types.Header, diffHash common.Hash, signature []byte) error {
+	if len(signature) != crypto.SignatureLength {
+		return errMissingDiffSignature
+	}
+	pubkey, err := crypto.Ecrecover(diffHash.Bytes(), signature)
+	if err != nil {
+		return err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+
+	if signer != header.Coinbase {
+		return errDiffSignerMismatch
+	}
+	snap, err := p.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if _, ok := snap.Validators[signer]; !ok {
+		return errUnauthorizedValidator
+	}
+	return nil
+}
+
 func (p *Parlia) IsLocalBlock(header *types.Header) bool {
 	return p.val == header.Coinbase
 }
@@ -1001,8 +1474,12 @@ func (p *Parlia) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 	}}
 }
 
-// Close implements consensus.Engine. It's a noop for parlia as there are no background threads.
+// Close implements consensus.Engine, terminating the liveness tracker.
 func (p *Parlia) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closeCh)
+		p.livenessScope.Close()
+	})
 	return nil
 }
 
@@ -1010,6 +1487,9 @@ func (p *Parlia) Close() error {
 
 // getCurrentValidators get current validators
 func (p *Parlia) getCurrentValidators(blockHash common.Hash) ([]common.Address, error) {
+	if p.headerOnly {
+		return nil, errHeaderOnly
+	}
 	// block
 	blockNr := rpc.BlockNumberOrHashWithHash(blockHash, false)
 
@@ -1052,6 +1532,16 @@ func (p *Parlia) getCurrentValidators(blockHash common.Hash) ([]common.Address,
 	}
 	return valz, nil
 }
+
+// getContractDeployer returns the deployer registered for the given contract in
+// the DeployerProxy contract at the given block.
+func (p *Parlia) getContractDeployer(contract common.Address, header *types.Header) (common.Address, error) {
+	if p.chainConfigReader == nil {
+		return common.Address{}, errHeaderOnly
+	}
+	return p.chainConfigReader.ContractDeployer(header, contract)
+}
+
 func (p *Parlia) BlockRewards(blockNumber *big.Int) *big.Int {
 	if rules := p.chainConfig.Rules(blockNumber); rules.HasBlockRewards {
 		if p.chainConfig.Parlia.StopMintBlock != nil && p.chainConfig.Parlia.StopMintBlock.Cmp(blockNumber) <= 0 {
@@ -1240,6 +1730,10 @@ func (p *Parlia) applyTransaction(
 		if err != nil {
 			return err
 		}
+		// A remote signer may hand back something else than it was asked for
+		if signedHash := p.signer.Hash(expectedTx); signedHash != expectedHash {
+			return fmt.Errorf("%w: have %v, want %v", errSignedTxMismatch, signedHash, expectedHash)
+		}
 	} else {
 		if receivedTxs == nil || len(*receivedTxs) == 0 || (*receivedTxs)[0] == nil {
 			return errors.New("supposed to get a actual transaction, but get none")
@@ -1297,7 +1791,7 @@ func SealHash(header *types.Header, chainId *big.Int) (hash common.Hash) {
 }
 
 func encodeSigHeader(w io.Writer, header *types.Header, chainId *big.Int) {
-	err := rlp.Encode(w, []interface{}{
+	enc := []interface{}{
 		chainId,
 		header.ParentHash,
 		header.UncleHash,
@@ -1314,8 +1808,12 @@ func encodeSigHeader(w io.Writer, header *types.Header, chainId *big.Int) {
 		header.Extra[:len(header.Extra)-65], // this will panic if extra is too short, should check before calling encodeSigHeader
 		header.MixDigest,
 		header.Nonce,
-	})
-	if err != nil {
+	}
+	// The base fee is only part of the sealed fields from the London fork on.
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	if err := rlp.Encode(w, enc); err != nil {
 		panic("can't encode: " + err.Error())
 	}
 }
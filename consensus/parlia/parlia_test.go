@@ -1,11 +1,21 @@
 package parlia
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
 	"math/rand"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestImpactOfValidatorOutOfService(t *testing.T) {
@@ -144,3 +154,57 @@ func randomAddress() common.Address {
 	rand.Read(addrBytes)
 	return common.BytesToAddress(addrBytes)
 }
+
+func TestRuntimeUpgradeSystemTransaction(t *testing.T) {
+	var (
+		target   = common.HexToAddress(systemcontract.StakingPoolContract)
+		oldCode  = hexutil.MustDecode("0x00")
+		newCode  = hexutil.MustDecode("0x600160005500") // SSTORE(0, 1)
+		coinbase = randomAddress()
+	)
+	// LOG2(0, CALLDATASIZE, hook, target) after copying the calldata to memory
+	upgradeCode := hexutil.MustDecode("0x366000600037")
+	upgradeCode = append(upgradeCode, byte(vm.PUSH20))
+	upgradeCode = append(upgradeCode, target.Bytes()...)
+	upgradeCode = append(upgradeCode, byte(vm.PUSH20))
+	upgradeCode = append(upgradeCode, systemcontract.EvmHookRuntimeUpgradeAddress.Bytes()...)
+	upgradeCode = append(upgradeCode, hexutil.MustDecode("0x366000a200")...)
+
+	bytesTy, _ := abi.NewType("bytes", "", nil)
+	data, err := abi.Arguments{{Type: bytesTy}, {Type: bytesTy}}.Pack(newCode, []byte{0x01})
+	if err != nil {
+		t.Fatalf("failed to pack upgrade request: %v", err)
+	}
+	for _, tc := range []struct {
+		fork      *big.Int
+		wantCode  []byte
+		wantState common.Hash
+	}{
+		{big.NewInt(10), newCode, common.BigToHash(common.Big1)},
+		{big.NewInt(11), oldCode, common.Hash{}},
+		{nil, oldCode, common.Hash{}},
+	} {
+		config := *params.TestChainConfig
+		config.RuntimeUpgradeBlock = tc.fork
+		config.Parlia = &params.ParliaConfig{Period: 3, Epoch: 200}
+
+		db := rawdb.NewMemoryDatabase()
+		engine := New(&config, db, nil, common.Hash{})
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db), nil)
+		statedb.SetCode(systemcontract.RuntimeUpgradeContractAddress, upgradeCode)
+		statedb.SetCode(target, oldCode)
+		statedb.Finalise(true)
+
+		header := &types.Header{Number: big.NewInt(10), Coinbase: coinbase, Difficulty: diffInTurn, GasLimit: 8000000}
+		msg := engine.getSystemMessage(coinbase, systemcontract.RuntimeUpgradeContractAddress, data, common.Big0)
+		if _, err := applyMessage(msg, statedb, header, &config, chainContext{parlia: engine}); err != nil {
+			t.Fatalf("fork %v: failed to apply system transaction: %v", tc.fork, err)
+		}
+		if code := statedb.GetCode(target); !bytes.Equal(code, tc.wantCode) {
+			t.Errorf("fork %v: code mismatch: have %x, want %x", tc.fork, code, tc.wantCode)
+		}
+		if val := statedb.GetState(target, common.Hash{}); val != tc.wantState {
+			t.Errorf("fork %v: storage mismatch: have %x, want %x", tc.fork, val, tc.wantState)
+		}
+	}
+}
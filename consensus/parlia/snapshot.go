@@ -18,6 +18,7 @@ package parlia
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -36,7 +37,7 @@ import (
 
 // Snapshot is the state of the validatorSet at a given point.
 type Snapshot struct {
-	config   *params.ParliaConfig // Consensus engine parameters to fine tune behavior
+	config   *params.ChainConfig // Chain configuration, including the consensus engine parameters
 	ethAPI   *ethapi.PublicBlockChainAPI
 	sigCache *lru.ARCCache // Cache of recent block signatures to speed up ecrecover
 
@@ -45,13 +46,30 @@ type Snapshot struct {
 	Validators       map[common.Address]struct{} `json:"validators"`         // Set of authorized validators at this moment
 	Recents          map[uint64]common.Address   `json:"recents"`            // Set of recent validators for spam protections
 	RecentForkHashes map[uint64]string           `json:"recent_fork_hashes"` // Set of recent forkHash
+
+	Period        uint64 `json:"period"`         // Number of seconds between blocks in force
+	Epoch         uint64 `json:"epoch"`          // Epoch length in force
+	MaxValidators uint64 `json:"max_validators"` // Maximum size of the validator set elected on epoch blocks (0 = unlimited)
+	EpochBlock    uint64 `json:"epoch_block"`    // Number of the latest epoch block
+
+	Attestation     *types.VoteData `json:"attestation,omitempty"` // Latest attestation, justifying its target block
+	FinalizedNumber uint64          `json:"finalized_number"`      // Number of the latest finalized block
+	FinalizedHash   common.Hash     `json:"finalized_hash"`        // Hash of the latest finalized block (empty = none)
+}
+
+// epochParams are the consensus parameters carried by epoch headers after the
+// ParamsBlock fork. They take effect from the epoch block onwards.
+type epochParams struct {
+	Period        uint64
+	Epoch         uint64
+	MaxValidators uint64
 }
 
 // newSnapshot creates a new snapshot with the specified startup parameters. This
 // method does not initialize the set of recent validators, so only ever use it for
 // the genesis block.
 func newSnapshot(
-	config *params.ParliaConfig,
+	config *params.ChainConfig,
 	sigCache *lru.ARCCache,
 	number uint64,
 	hash common.Hash,
@@ -67,6 +85,9 @@ func newSnapshot(
 		Recents:          make(map[uint64]common.Address),
 		RecentForkHashes: make(map[uint64]string),
 		Validators:       make(map[common.Address]struct{}),
+		Period:           config.Parlia.Period,
+		Epoch:            config.Parlia.Epoch,
+		EpochBlock:       number - number%config.Parlia.Epoch,
 	}
 	for _, v := range validators {
 		snap.Validators[v] = struct{}{}
@@ -82,7 +103,7 @@ func (s validatorsAscending) Less(i, j int) bool { return bytes.Compare(s[i][:],
 func (s validatorsAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
 // loadSnapshot loads an existing snapshot from the database.
-func loadSnapshot(config *params.ParliaConfig, sigCache *lru.ARCCache, db ethdb.Database, hash common.Hash, ethAPI *ethapi.PublicBlockChainAPI) (*Snapshot, error) {
+func loadSnapshot(config *params.ChainConfig, sigCache *lru.ARCCache, db ethdb.Database, hash common.Hash, ethAPI *ethapi.PublicBlockChainAPI) (*Snapshot, error) {
 	blob, err := db.Get(append([]byte("parlia-"), hash[:]...))
 	if err != nil {
 		return nil, err
@@ -95,6 +116,13 @@ func loadSnapshot(config *params.ParliaConfig, sigCache *lru.ARCCache, db ethdb.
 	snap.sigCache = sigCache
 	snap.ethAPI = ethAPI
 
+	// Snapshots stored before the consensus parameters were tracked are from
+	// before the ParamsBlock fork, so the genesis parameters are in force
+	if snap.Epoch == 0 {
+		snap.Period = config.Parlia.Period
+		snap.Epoch = config.Parlia.Epoch
+		snap.EpochBlock = snap.Number - snap.Number%config.Parlia.Epoch
+	}
 	return snap, nil
 }
 
@@ -118,6 +146,16 @@ func (s *Snapshot) copy() *Snapshot {
 		Validators:       make(map[common.Address]struct{}),
 		Recents:          make(map[uint64]common.Address),
 		RecentForkHashes: make(map[uint64]string),
+		Period:           s.Period,
+		Epoch:            s.Epoch,
+		MaxValidators:    s.MaxValidators,
+		EpochBlock:       s.EpochBlock,
+		FinalizedNumber:  s.FinalizedNumber,
+		FinalizedHash:    s.FinalizedHash,
+	}
+	if s.Attestation != nil {
+		attestation := *s.Attestation
+		cpy.Attestation = &attestation
 	}
 
 	for v := range s.Validators {
@@ -132,6 +170,31 @@ func (s *Snapshot) copy() *Snapshot {
 	return cpy
 }
 
+// isEpoch reports whether the given block, following the snapshot, is an epoch
+// block electing a new validator set.
+func (s *Snapshot) isEpoch(number uint64) bool {
+	return number == s.EpochBlock+s.Epoch
+}
+
+// voteQuorum returns the number of validators whose votes justify a block, at
+// least two thirds of them.
+func (s *Snapshot) voteQuorum() int {
+	return (2*len(s.Validators) + 2) / 3
+}
+
+// sealQuorum returns the number of distinct validators sealing on top of a block
+// which make it irreversible, more than two thirds of them.
+func (s *Snapshot) sealQuorum() int {
+	return 2*len(s.Validators)/3 + 1
+}
+
+// setParams switches the snapshot to the consensus parameters of an epoch.
+func (s *Snapshot) setParams(params *epochParams) {
+	s.Period = params.Period
+	s.Epoch = params.Epoch
+	s.MaxValidators = params.MaxValidators
+}
+
 func (s *Snapshot) isMajorityFork(forkHash string) bool {
 	ally := 0
 	for _, h := range s.RecentForkHashes {
@@ -188,16 +251,37 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 			}
 		}
 		snap.Recents[number] = validator
+		// track the epoch and the consensus parameters it brings in
+		if snap.isEpoch(number) {
+			snap.EpochBlock = number
+			_, params, err := parseEpochExtra(s.config, header)
+			if err != nil {
+				return nil, err
+			}
+			if params != nil {
+				snap.setParams(params)
+			}
+		}
+		// track the blocks justified and finalized by the attestations
+		attestation, err := parseAttestation(s.config, header)
+		if err != nil {
+			return nil, err
+		}
+		if attestation != nil {
+			snap.Attestation = attestation.Data
+			if attestation.Data.TargetNumber == attestation.Data.SourceNumber+1 {
+				snap.FinalizedNumber = attestation.Data.SourceNumber
+				snap.FinalizedHash = attestation.Data.SourceHash
+			}
+		}
 		// change validator set
-		if number > 0 && number%s.config.Epoch == uint64(len(snap.Validators)/2) {
+		if number > 0 && number == snap.EpochBlock+uint64(len(snap.Validators)/2) {
 			checkpointHeader := FindAncientHeader(header, uint64(len(snap.Validators)/2), chain, parents)
 			if checkpointHeader == nil {
 				return nil, consensus.ErrUnknownAncestor
 			}
-
-			validatorBytes := checkpointHeader.Extra[extraVanity : len(checkpointHeader.Extra)-extraSeal]
 			// get validators from headers and use that for new validator set
-			newValArr, err := ParseValidators(validatorBytes)
+			newValArr, _, err := parseEpochExtra(s.config, checkpointHeader)
 			if err != nil {
 				return nil, err
 			}
@@ -287,6 +371,17 @@ func (s *Snapshot) supposeValidator() common.Address {
 	return validators[index]
 }
 
+// signedRecently reports whether the validator is among the recent signers, which
+// exempts it from being slashed for missing its slot.
+func (s *Snapshot) signedRecently(validator common.Address) bool {
+	for _, recent := range s.Recents {
+		if recent == validator {
+			return true
+		}
+	}
+	return false
+}
+
 func ParseValidators(validatorsBytes []byte) ([]common.Address, error) {
 	if len(validatorsBytes)%validatorBytesLength != 0 {
 		return nil, errors.New("invalid validators bytes")
@@ -301,6 +396,67 @@ func ParseValidators(validatorsBytes []byte) ([]common.Address, error) {
 	return result, nil
 }
 
+// parseEpochExtra splits the extra-data of an epoch header into the validators
+// and, after the ParamsBlock fork, the consensus parameters of the epoch.
+func parseEpochExtra(config *params.ChainConfig, header *types.Header) ([]common.Address, *epochParams, error) {
+	data, _, err := splitExtra(config, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.Number.Sign() == 0 || !config.IsParliaParams(header.Number) {
+		validators, err := ParseValidators(data)
+		return validators, nil, err
+	}
+	if len(data) < epochParamsLength {
+		return nil, nil, errInvalidEpochParams
+	}
+	validators, err := ParseValidators(data[:len(data)-epochParamsLength])
+	if err != nil {
+		return nil, nil, err
+	}
+	data = data[len(data)-epochParamsLength:]
+	return validators, &epochParams{
+		Period:        uint64(binary.BigEndian.Uint32(data[0:4])),
+		Epoch:         uint64(binary.BigEndian.Uint32(data[4:8])),
+		MaxValidators: uint64(binary.BigEndian.Uint32(data[8:12])),
+	}, nil
+}
+
+// ParseEpochHeader parses the validators and the length of the epoch an epoch
+// header starts from its extra-data. Other headers carry no validators and are
+// reported with an epoch length of zero.
+func ParseEpochHeader(config *params.ChainConfig, header *types.Header) ([]common.Address, uint64, error) {
+	data, _, err := splitExtra(config, header)
+	if err != nil || len(data) == 0 {
+		return nil, 0, err
+	}
+	validators, params, err := parseEpochExtra(config, header)
+	if err != nil || len(validators) == 0 {
+		return nil, 0, err
+	}
+	if params != nil {
+		return validators, params.Epoch, nil
+	}
+	return validators, config.Parlia.Epoch, nil
+}
+
+// encodeEpochExtra is the inverse of parseEpochExtra, returning the extra-data
+// section of an epoch header between the vanity and the attestation.
+func encodeEpochExtra(validators []common.Address, params *epochParams) []byte {
+	data := make([]byte, 0, len(validators)*validatorBytesLength+epochParamsLength)
+	for _, validator := range validators {
+		data = append(data, validator.Bytes()...)
+	}
+	if params != nil {
+		var enc [epochParamsLength]byte
+		binary.BigEndian.PutUint32(enc[0:4], uint32(params.Period))
+		binary.BigEndian.PutUint32(enc[4:8], uint32(params.Epoch))
+		binary.BigEndian.PutUint32(enc[8:12], uint32(params.MaxValidators))
+		data = append(data, enc[:]...)
+	}
+	return data
+}
+
 func FindAncientHeader(header *types.Header, ite uint64, chain consensus.ChainHeaderReader, candidateParents []*types.Header) *types.Header {
 	ancient := header
 	for i := uint64(1); i <= ite; i++ {
@@ -2,12 +2,20 @@ package parlia
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"math/big"
 	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestValidatorSetSort(t *testing.T) {
@@ -21,3 +29,293 @@ func TestValidatorSetSort(t *testing.T) {
 		assert.True(t, bytes.Compare(validators[i][:], validators[i+1][:]) < 0)
 	}
 }
+
+// headerChain is a minimal header store which only knows about a few headers,
+// like a light client after a checkpoint sync.
+type headerChain struct {
+	config  *params.ChainConfig
+	headers map[uint64]*types.Header
+}
+
+func (c *headerChain) Config() *params.ChainConfig             { return c.config }
+func (c *headerChain) CurrentHeader() *types.Header            { return nil }
+func (c *headerChain) GetHighestVerifiedHeader() *types.Header { return nil }
+
+func (c *headerChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[number]; header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c *headerChain) GetHeaderByNumber(number uint64) *types.Header {
+	return c.headers[number]
+}
+
+func (c *headerChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+// Tests that in header-only mode the snapshot of an epoch block without parent
+// is derived from the epoch headers, switching the validator set halfway.
+func TestEpochSnapshotHeaderOnly(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	addrs := make([]common.Address, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Parlia: &params.ParliaConfig{Period: 3, Epoch: 10}}
+	chain := &headerChain{config: config, headers: make(map[uint64]*types.Header)}
+
+	sign := func(header *types.Header, key *ecdsa.PrivateKey) {
+		sig, err := crypto.Sign(SealHash(header, config.ChainID).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign header: %v", err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		chain.headers[header.Number.Uint64()] = header
+	}
+	newHeader := func(number uint64, parent common.Hash, validators []common.Address) *types.Header {
+		extra := make([]byte, extraVanity, extraVanity+len(validators)*common.AddressLength+extraSeal)
+		for _, val := range validators {
+			extra = append(extra, val.Bytes()...)
+		}
+		return &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			ParentHash: parent,
+			Difficulty: diffNoTurn,
+			Extra:      append(extra, make([]byte, extraSeal)...),
+		}
+	}
+	// The previous epoch elected the first two validators, the checkpoint adds the third
+	sign(newHeader(10, common.Hash{0x01}, addrs[:2]), keys[0])
+	checkpoint := newHeader(20, common.Hash{0x02}, addrs)
+	sign(checkpoint, keys[1])
+
+	parent := checkpoint.Hash()
+	for i, number := range []uint64{21, 22, 23} {
+		header := newHeader(number, parent, nil)
+		sign(header, keys[i])
+		parent = header.Hash()
+	}
+	// A full node engine can't verify without the history
+	full := New(config, rawdb.NewMemoryDatabase(), new(ethapi.PublicBlockChainAPI), common.Hash{})
+	if _, err := full.snapshot(chain, 20, checkpoint.Hash(), nil); err != consensus.ErrUnknownAncestor {
+		t.Fatalf("full node snapshot error mismatch: have %v, want %v", err, consensus.ErrUnknownAncestor)
+	}
+	light := New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+	snap, err := light.snapshot(chain, 20, checkpoint.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create epoch snapshot: %v", err)
+	}
+	assert.ElementsMatch(t, addrs[:2], snap.validators())
+
+	// The third validator is only authorized after the switch at block 21
+	snap, err = light.snapshot(chain, 23, parent, nil)
+	if err != nil {
+		t.Fatalf("failed to apply headers on epoch snapshot: %v", err)
+	}
+	assert.ElementsMatch(t, addrs, snap.validators())
+	assert.Equal(t, addrs[2], snap.Recents[23])
+
+	// State dependent operations are refused
+	if _, err := light.getCurrentValidators(parent); err != errHeaderOnly {
+		t.Fatalf("validator retrieval error mismatch: have %v, want %v", err, errHeaderOnly)
+	}
+}
+
+// Tests that the consensus parameters carried by the first epoch block after the
+// ParamsBlock fork take over from that block on, moving the next epoch block,
+// the block period and the validator limit.
+func TestEpochParamsTransition(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	addrs := make([]common.Address, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	signers := make(map[common.Address]*ecdsa.PrivateKey)
+	for i, addr := range addrs {
+		signers[addr] = keys[i]
+	}
+	config := &params.ChainConfig{
+		ChainID:        big.NewInt(1),
+		RamanujanBlock: big.NewInt(0),
+		Parlia:         &params.ParliaConfig{Period: 3, Epoch: 4, ParamsBlock: big.NewInt(4)},
+	}
+	chain := &headerChain{config: config, headers: make(map[uint64]*types.Header)}
+	engine := New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+
+	newHeader := func(parent *types.Header, coinbase common.Address, extra []byte) *types.Header {
+		header := &types.Header{
+			Number:     big.NewInt(0),
+			Coinbase:   coinbase,
+			Difficulty: diffInTurn,
+			Extra:      append(append(make([]byte, extraVanity), extra...), make([]byte, extraSeal)...),
+		}
+		if parent != nil {
+			header.Number = new(big.Int).Add(parent.Number, common.Big1)
+			header.ParentHash = parent.Hash()
+			header.Time = parent.Time + 1
+		}
+		return header
+	}
+	sign := func(header *types.Header) {
+		sig, err := crypto.Sign(SealHash(header, config.ChainID).Bytes(), signers[header.Coinbase])
+		if err != nil {
+			t.Fatalf("failed to sign header: %v", err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		chain.headers[header.Number.Uint64()] = header
+	}
+	// extend seals a new block on top of the chain by the in-turn validator
+	extend := func(extra []byte) *types.Header {
+		var (
+			parent = chain.headers[uint64(len(chain.headers)-1)]
+			snap   *Snapshot
+			err    error
+		)
+		if snap, err = engine.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil); err != nil {
+			t.Fatalf("failed to retrieve snapshot #%d: %v", parent.Number, err)
+		}
+		header := newHeader(parent, snap.blockProducer(), extra)
+		header.Time = parent.Time + snap.Period
+		if err := engine.verifyEpochExtra(snap, header); err != nil {
+			t.Fatalf("invalid extra-data on block #%d: %v", header.Number, err)
+		}
+		sign(header)
+		return header
+	}
+	validators := []common.Address{addrs[0], addrs[1]}
+	sort.Sort(validatorsAscending(validators))
+	chain.headers[0] = newHeader(nil, common.Address{}, encodeEpochExtra(validators, nil))
+
+	for i := 0; i < 3; i++ {
+		extend(nil)
+	}
+	// The transition block elects the same validators with the new parameters
+	next := &epochParams{Period: 1, Epoch: 6, MaxValidators: 2}
+	extend(encodeEpochExtra(validators, next))
+
+	snap, err := engine.snapshot(chain, 4, chain.headers[4].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	assert.Equal(t, uint64(4), snap.EpochBlock)
+	assert.Equal(t, *next, epochParams{Period: snap.Period, Epoch: snap.Epoch, MaxValidators: snap.MaxValidators})
+
+	// The block after the transition is already produced with the new period
+	header := newHeader(chain.headers[4], snap.blockProducer(), nil)
+	header.Time = chain.headers[4].Time + 1
+	if err := engine.blockTimeVerifyForRamanujanFork(snap, header, chain.headers[4]); err != nil {
+		t.Fatalf("block with new period rejected: %v", err)
+	}
+	// The old epoch length doesn't produce epoch blocks anymore
+	for i := 0; i < 3; i++ {
+		extend(nil)
+	}
+	snap, _ = engine.snapshot(chain, 7, chain.headers[7].Hash(), nil)
+	if err := engine.verifyEpochExtra(snap, newHeader(chain.headers[7], addrs[0], encodeEpochExtra(validators, next))); err != errExtraValidators {
+		t.Fatalf("validators on old epoch block: have %v, want %v", err, errExtraValidators)
+	}
+	extend(nil)
+
+	// The next epoch block is checked against the parameters in force
+	snap, _ = engine.snapshot(chain, 9, extend(nil).Hash(), nil)
+	if !snap.isEpoch(10) {
+		t.Fatalf("block #10 not an epoch block")
+	}
+	tests := []struct {
+		validators []common.Address
+		params     *epochParams
+		err        error
+	}{
+		{validators, nil, errInvalidSpanValidators},
+		{validators, &epochParams{Period: 1, Epoch: 0, MaxValidators: 2}, errInvalidEpochParams},
+		{validators, &epochParams{Period: 1, Epoch: 1, MaxValidators: 3}, errInvalidEpochParams},
+		{addrs, &epochParams{Period: 1, Epoch: 6, MaxValidators: 2}, errTooManyValidators},
+		{addrs, &epochParams{Period: 2, Epoch: 8, MaxValidators: 3}, nil},
+	}
+	for i, tt := range tests {
+		header := newHeader(chain.headers[9], addrs[0], encodeEpochExtra(tt.validators, tt.params))
+		if err := engine.verifyEpochExtra(snap, header); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Snapshots persist the parameters in force
+	db := rawdb.NewMemoryDatabase()
+	if err := snap.store(db); err != nil {
+		t.Fatalf("failed to store snapshot: %v", err)
+	}
+	loaded, err := loadSnapshot(config, nil, db, snap.Hash, nil)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	assert.Equal(t, snap.EpochBlock, loaded.EpochBlock)
+	assert.Equal(t, snap.Epoch, loaded.Epoch)
+	assert.Equal(t, snap.Period, loaded.Period)
+	assert.Equal(t, snap.MaxValidators, loaded.MaxValidators)
+
+	// The epoch of every block follows the epoch length in force
+	checkpoint := extend(encodeEpochExtra(validators, next))
+	for number, want := range map[uint64]uint64{0: 0, 3: 0, 4: 4, 9: 4, 10: 10} {
+		have, err := engine.EpochBlock(chain, chain.headers[number])
+		if err != nil {
+			t.Fatalf("block #%d: failed to retrieve epoch block: %v", number, err)
+		}
+		if have != want {
+			t.Errorf("block #%d: epoch block mismatch: have %d, want %d", number, have, want)
+		}
+	}
+	// Light clients find the previous epoch block of a checkpoint at the distance
+	// governed on chain, not at the genesis epoch length
+	parent := chain.headers[9]
+	delete(chain.headers, 9)
+	light := New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+	snap, err = light.snapshot(chain, 10, checkpoint.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create epoch snapshot: %v", err)
+	}
+	assert.Equal(t, uint64(10), snap.EpochBlock)
+	assert.Equal(t, next.Epoch, snap.Epoch)
+	assert.ElementsMatch(t, validators, snap.validators())
+
+	// Another governance change can't be guessed from the parameters of either
+	// the genesis or the checkpoint, so the previous epoch block is walked back to
+	chain.headers[9] = parent
+	for i := 0; i < 5; i++ {
+		extend(nil)
+	}
+	later := extend(encodeEpochExtra(validators, &epochParams{Period: 1, Epoch: 8, MaxValidators: 2}))
+	extend(nil)
+	extend(nil)
+
+	fetched := make(map[uint64]bool)
+	have, err := light.EpochCheckpoint(18, func(number uint64) (*types.Header, error) {
+		fetched[number] = true
+		return chain.headers[number], nil
+	})
+	if err != nil {
+		t.Fatalf("failed to retrieve epoch checkpoint: %v", err)
+	}
+	if have.Hash() != later.Hash() {
+		t.Fatalf("epoch checkpoint mismatch: have #%d, want #%d", have.Number, later.Number)
+	}
+	if !fetched[10] {
+		t.Errorf("previous epoch block not retrieved")
+	}
+	// The fetched epoch headers suffice to create the snapshot of the checkpoint
+	synced := &headerChain{config: config, headers: map[uint64]*types.Header{10: checkpoint, 16: later}}
+	snap, err = New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{}).snapshot(synced, 16, later.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create epoch snapshot: %v", err)
+	}
+	assert.Equal(t, uint64(16), snap.EpochBlock)
+	assert.Equal(t, uint64(8), snap.Epoch)
+}
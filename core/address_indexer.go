@@ -0,0 +1,141 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// AddressIndexer implements a core.ChainIndexer, building up an index of the
+// blocks containing the logs of every emitting address, permitting the logs of
+// a single contract to be found without matching the blooms of all blocks.
+type AddressIndexer struct {
+	size    uint64                      // section size to generate the index for
+	db      ethdb.Database              // database instance to write index data and metadata into
+	blocks  map[common.Address][]uint64 // Offsets of the blocks with logs of every address in the section
+	section uint64                      // Section is the section number being processed currently
+	head    common.Hash                 // Head is the hash of the last header processed
+}
+
+// NewAddressIndexer returns a chain indexer that generates the address log index
+// of the canonical chain for fast logs filtering by emitter.
+func NewAddressIndexer(db ethdb.Database, size, confirms uint64) *ChainIndexer {
+	backend := &AddressIndexer{
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.AddressLogsIndexPrefix))
+
+	indexer := NewChainIndexer(db, table, backend, size, confirms, bloomThrottling, "addresslogs")
+	if tail, err := db.AncientTail(); err == nil && tail > 0 {
+		skipPrunedSections(db, indexer, size, tail)
+	}
+	return indexer
+}
+
+// skipPrunedSections moves the address log indexer past the sections reaching
+// below the given block history tail, as the receipts of the pruned blocks are
+// gone. The logs of the skipped sections are left to the bloom bits.
+func skipPrunedSections(db ethdb.Database, indexer *ChainIndexer, size, tail uint64) {
+	first := (tail + size - 1) / size
+	if sections, _, _ := indexer.Sections(); sections >= first {
+		return
+	}
+	// The last block of the preceding section may be pruned as well, but the
+	// first retained one knows its hash
+	header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, first*size), first*size)
+	if header == nil {
+		return
+	}
+	indexer.AddCheckpoint(first-1, header.ParentHash)
+	indexer.Prune(first * size)
+	log.Info("Skipped address log index of pruned history", "sections", first)
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new address log index
+// section.
+func (b *AddressIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.blocks, b.section, b.head = make(map[common.Address][]uint64), section, common.Hash{}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the emitters of a new
+// header's logs into the index.
+func (b *AddressIndexer) Process(ctx context.Context, header *types.Header) error {
+	b.head = header.Hash()
+	if header.Bloom == (types.Bloom{}) {
+		return nil
+	}
+	number := header.Number.Uint64()
+	receipts := rawdb.ReadRawReceipts(b.db, b.head, number)
+	if receipts == nil {
+		return fmt.Errorf("missing receipts of block %d [%x]", number, b.head)
+	}
+	offset := number - b.section*b.size
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			blocks := b.blocks[log.Address]
+			if len(blocks) == 0 || blocks[len(blocks)-1] != offset {
+				b.blocks[log.Address] = append(blocks, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the address log section
+// and writing it out into the database.
+func (b *AddressIndexer) Commit() error {
+	batch := b.db.NewBatch()
+	for address, blocks := range b.blocks {
+		rawdb.WriteAddressLogs(batch, address, b.section, b.head, blocks)
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Drop the sections whose block history was pruned meanwhile
+	if tail, err := b.db.AncientTail(); err == nil {
+		return b.Prune(tail)
+	}
+	return nil
+}
+
+// Prune implements core.ChainIndexerBackend, deleting the address log index of
+// the sections entirely below the given block, whose logs are left to the bloom
+// bits.
+func (b *AddressIndexer) Prune(threshold uint64) error {
+	section := threshold / b.size
+	if section <= rawdb.ReadAddressLogsTail(b.db) {
+		return nil
+	}
+	rawdb.DeleteAddressLogs(b.db, section)
+	rawdb.WriteAddressLogsTail(b.db, section)
+	return nil
+}
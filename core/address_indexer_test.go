@@ -0,0 +1,140 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the address indexer records the blocks containing the logs of every
+// emitter, once per block.
+func TestAddressIndexer(t *testing.T) {
+	const size = 8
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = GenesisBlockForTesting(db, common.Address{}, big.NewInt(1))
+		game    = common.Address{0x01}
+		token   = common.Address{0x02}
+	)
+	emitters := map[int][]common.Address{
+		1: {game, game, token},
+		4: {game},
+		9: {token},
+	}
+	blocks, receipts := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 2*size-1, func(i int, gen *BlockGen) {
+		if addresses, ok := emitters[i+1]; ok {
+			receipt := types.NewReceipt(nil, false, 0)
+			for _, address := range addresses {
+				receipt.Logs = append(receipt.Logs, &types.Log{Address: address})
+			}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil))
+		}
+	})
+	indexer := &AddressIndexer{db: db, size: size}
+	headers := []*types.Header{genesis.Header()}
+	for i, block := range blocks {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		headers = append(headers, block.Header())
+	}
+	for section := uint64(0); section < 2; section++ {
+		if err := indexer.Reset(context.Background(), section, common.Hash{}); err != nil {
+			t.Fatalf("section %d: failed to reset indexer: %v", section, err)
+		}
+		for _, header := range headers[section*size : (section+1)*size] {
+			if err := indexer.Process(context.Background(), header); err != nil {
+				t.Fatalf("section %d: failed to process header %d: %v", section, header.Number, err)
+			}
+		}
+		if err := indexer.Commit(); err != nil {
+			t.Fatalf("section %d: failed to commit: %v", section, err)
+		}
+	}
+	tests := []struct {
+		address common.Address
+		section uint64
+		offsets []uint64
+	}{
+		{game, 0, []uint64{1, 4}},
+		{token, 0, []uint64{1}},
+		{game, 1, nil},
+		{token, 1, []uint64{1}},
+	}
+	for i, tt := range tests {
+		head := headers[(tt.section+1)*size-1].Hash()
+		offsets, err := rawdb.ReadAddressLogs(db, tt.address, tt.section, head)
+		if err != nil {
+			t.Fatalf("test %d: failed to read address logs: %v", i, err)
+		}
+		if !reflect.DeepEqual(offsets, tt.offsets) {
+			t.Errorf("test %d: offsets mismatch: have %v, want %v", i, offsets, tt.offsets)
+		}
+	}
+	// Pruning drops the sections entirely below the threshold only
+	if err := indexer.Prune(size + 3); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if tail := rawdb.ReadAddressLogsTail(db); tail != 1 {
+		t.Fatalf("tail mismatch: have %d, want 1", tail)
+	}
+	if offsets, _ := rawdb.ReadAddressLogs(db, game, 0, headers[size-1].Hash()); offsets != nil {
+		t.Errorf("pruned section retained: %v", offsets)
+	}
+	if offsets, _ := rawdb.ReadAddressLogs(db, token, 1, headers[2*size-1].Hash()); !reflect.DeepEqual(offsets, []uint64{1}) {
+		t.Errorf("retained section mismatch: have %v, want %v", offsets, []uint64{1})
+	}
+}
+
+// Tests that the address log indexer skips the sections reaching below the tail
+// of the pruned block history, starting off with the first complete section.
+func TestAddressIndexerSkipPruned(t *testing.T) {
+	const size = 8
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = GenesisBlockForTesting(db, common.Address{}, big.NewInt(1))
+	)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 3*size, nil)
+	for _, block := range blocks {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	backend := &AddressIndexer{db: db, size: size}
+	indexer := NewChainIndexer(db, rawdb.NewTable(db, string(rawdb.AddressLogsIndexPrefix)), backend, size, 0, 0, "addresslogs")
+	defer indexer.Close()
+
+	// Blocks from #9 on are retained, the first section left to index is the third
+	skipPrunedSections(db, indexer, size, size+1)
+	if sections, _, head := indexer.Sections(); sections != 2 || head != blocks[2*size-2].Hash() {
+		t.Fatalf("indexer progress mismatch: have %d sections up to %x, want 2 up to %x", sections, head, blocks[2*size-2].Hash())
+	}
+	if tail := rawdb.ReadAddressLogsTail(db); tail != 2 {
+		t.Fatalf("tail mismatch: have %d, want 2", tail)
+	}
+}
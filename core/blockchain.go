@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	mrand "math/rand"
 	"sort"
@@ -39,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
@@ -193,6 +195,11 @@ type BlockChain struct {
 	//  * N:   means N block limit [HEAD-N+1, HEAD] and delete extra indexes
 	//  * nil: disable tx reindexer/deleter, but still index new blocks
 	txLookupLimit uint64
+
+	// historyBlocks is the maximum number of blocks from head whose ancient
+	// data is retained, older ones get pruned from the ancient store tail.
+	// Zero means the entire chain is kept.
+	historyBlocks uint64
 	triesInMemory uint64
 
 	hc            *HeaderChain
@@ -200,8 +207,10 @@ type BlockChain struct {
 	chainFeed     event.Feed
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
+	finalizedFeed event.Feed
 	logsFeed      event.Feed
 	blockProcFeed event.Feed
+	badDiffFeed   event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -210,6 +219,7 @@ type BlockChain struct {
 	currentBlock          atomic.Value // Current head of the block chain
 	currentFastBlock      atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 	highestVerifiedHeader atomic.Value
+	lastFinalizedHeader   atomic.Value // Latest finalized header announced
 
 	stateCache    state.Database // State database to reuse between imports (contains state cache)
 	bodyCache     *lru.Cache     // Cache for the most recent block bodies
@@ -235,6 +245,9 @@ type BlockChain struct {
 	diffNumToBlockHashes  map[uint64]map[common.Hash]struct{}              // map[number]map[blockHash]
 	diffPeersToDiffHashes map[string]map[common.Hash]struct{}              // map[pid]map[diffHash]
 
+	lightProcessed uint64 // Number of blocks imported from diff layers (atomic access)
+	fullProcessed  uint64 // Number of blocks imported by executing them (atomic access)
+
 	quit          chan struct{}  // blockchain quit channel
 	wg            sync.WaitGroup // chain processing wait group for shutting down
 	running       int32          // 0 if chain is running, 1 when stopped
@@ -448,11 +461,15 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	// Take ownership of this particular state
 	go bc.update()
 	if txLookupLimit != nil {
-		bc.txLookupLimit = *txLookupLimit
+		bc.SetTxLookupLimit(*txLookupLimit)
 
 		bc.wg.Add(1)
 		go bc.maintainTxIndex(txIndexBlock)
 	}
+	if bc.historyBlocks > 0 {
+		bc.wg.Add(1)
+		go bc.maintainHistory()
+	}
 	// If periodic cache journal is required, spin it up.
 	if bc.cacheConfig.TrieCleanRejournal > 0 {
 		if bc.cacheConfig.TrieCleanRejournal < time.Minute {
@@ -508,6 +525,38 @@ func (bc *BlockChain) cacheDiffLayer(diffLayer *types.DiffLayer) {
 	}
 }
 
+// signDiffLayer attaches the signature of the block sealer to a locally built
+// diff layer. Blocks sealed by the local validator are signed right away, for
+// others the signature of an identical diff layer received from peers is kept
+// so that it can be relayed.
+func (bc *BlockChain) signDiffLayer(header *types.Header, diffLayer *types.DiffLayer) {
+	posa, ok := bc.engine.(consensus.PoSA)
+	if !ok {
+		return
+	}
+	diffLayerRLP, err := rlp.EncodeToBytes(diffLayer)
+	if err != nil {
+		log.Error("Failed to encode diff layer", "hash", header.Hash(), "err", err)
+		return
+	}
+	diffLayer.DiffHash = crypto.Keccak256Hash(diffLayerRLP)
+
+	if posa.IsLocalBlock(header) {
+		signature, err := posa.SignDiffLayer(header, diffLayerRLP)
+		if err != nil {
+			log.Warn("Failed to sign diff layer", "hash", header.Hash(), "err", err)
+			return
+		}
+		diffLayer.Signature = signature
+		return
+	}
+	bc.diffMux.RLock()
+	defer bc.diffMux.RUnlock()
+	if untrusted, exist := bc.blockHashToDiffLayers[header.Hash()][diffLayer.DiffHash]; exist {
+		diffLayer.Signature = untrusted.Signature
+	}
+}
+
 func (bc *BlockChain) cacheBlock(hash common.Hash, block *types.Block) {
 	bc.blockCache.Add(hash, block)
 }
@@ -822,6 +871,76 @@ func (bc *BlockChain) CurrentFastBlock() *types.Block {
 	return bc.currentFastBlock.Load().(*types.Block)
 }
 
+// CurrentFinalizedHeader retrieves the latest block finalized as of the head of
+// the canonical chain, either by the votes or by the seals of the validators on
+// top of it, nil if the consensus engine doesn't finalize blocks or none is
+// finalized yet. It resolves the finalized block tag, the fork choice only
+// respects the blocks finalized by the votes.
+func (bc *BlockChain) CurrentFinalizedHeader() *types.Header {
+	if engine, ok := bc.engine.(consensus.FastFinality); ok {
+		head := bc.CurrentBlock().Header()
+		return laterHeader(engine.GetFinalizedHeader(bc, head), engine.GetSealFinalizedHeader(bc, head))
+	}
+	return nil
+}
+
+// CurrentJustifiedHeader retrieves the latest block justified as of the head of
+// the canonical chain, or the finalized one if it is more recent, nil if the
+// consensus engine doesn't justify blocks or none is justified yet.
+func (bc *BlockChain) CurrentJustifiedHeader() *types.Header {
+	if engine, ok := bc.engine.(consensus.FastFinality); ok {
+		return laterHeader(engine.GetJustifiedHeader(bc, bc.CurrentBlock().Header()), bc.CurrentFinalizedHeader())
+	}
+	return nil
+}
+
+// laterHeader returns the higher of two headers, either of which may be nil.
+func laterHeader(a, b *types.Header) *types.Header {
+	if a == nil || (b != nil && b.Number.Cmp(a.Number) > 0) {
+		return b
+	}
+	return a
+}
+
+// announceFinalized fires a ChainFinalizedEvent if the latest finalized block
+// changed since the previous announcement.
+func (bc *BlockChain) announceFinalized() {
+	header := bc.CurrentFinalizedHeader()
+	if header == nil {
+		return
+	}
+	if last, _ := bc.lastFinalizedHeader.Load().(*types.Header); last != nil && last.Hash() == header.Hash() {
+		return
+	}
+	bc.lastFinalizedHeader.Store(header)
+	bc.finalizedFeed.Send(ChainFinalizedEvent{Header: header})
+}
+
+// revertsFinalized reports whether making the given block the head of the chain
+// would revert the block finalized by the votes, which the fork choice never
+// does. Blocks are only finalized by the votes after the FastFinality fork.
+func (bc *BlockChain) revertsFinalized(block *types.Block) bool {
+	engine, ok := bc.engine.(consensus.FastFinality)
+	if !ok {
+		return false
+	}
+	head := bc.CurrentBlock().Header()
+	if !bc.chainConfig.IsFastFinality(head.Number) {
+		return false
+	}
+	finalized := engine.GetFinalizedHeader(bc, head)
+	if finalized == nil {
+		return false
+	}
+	number := finalized.Number.Uint64()
+	if block.NumberU64() < number {
+		return true
+	}
+	maxNonCanonical := uint64(math.MaxUint64)
+	hash, _ := bc.GetAncestor(block.Hash(), block.NumberU64(), block.NumberU64()-number, &maxNonCanonical)
+	return hash != finalized.Hash()
+}
+
 // Validator returns the current validator.
 func (bc *BlockChain) Validator() Validator {
 	return bc.validator
@@ -832,6 +951,12 @@ func (bc *BlockChain) Processor() Processor {
 	return bc.processor
 }
 
+// ProcessedBlocks returns the number of blocks imported since startup from the
+// diff layers of peers and by executing their transactions.
+func (bc *BlockChain) ProcessedBlocks() (light uint64, full uint64) {
+	return atomic.LoadUint64(&bc.lightProcessed), atomic.LoadUint64(&bc.fullProcessed)
+}
+
 // State returns a new mutable state based on the current HEAD block.
 func (bc *BlockChain) State() (*state.StateDB, error) {
 	return bc.StateAt(bc.CurrentBlock().Root())
@@ -997,18 +1122,25 @@ func (bc *BlockChain) GetBodyRLP(hash common.Hash) rlp.RawValue {
 
 // GetDiffLayerRLP retrieves a diff layer in RLP encoding from the cache or database by blockHash
 func (bc *BlockChain) GetDiffLayerRLP(blockHash common.Hash) rlp.RawValue {
+	diffLayerRLP, _ := bc.GetSignedDiffLayerRLP(blockHash)
+	return diffLayerRLP
+}
+
+// GetSignedDiffLayerRLP retrieves a diff layer in RLP encoding from the cache or
+// database by blockHash, along with the signature of the block sealer if known.
+func (bc *BlockChain) GetSignedDiffLayerRLP(blockHash common.Hash) (rlp.RawValue, []byte) {
 	// Short circuit if the diffLayer's already in the cache, retrieve otherwise
 	if cached, ok := bc.diffLayerRLPCache.Get(blockHash); ok {
-		return cached.(rlp.RawValue)
+		return cached.(rlp.RawValue), bc.diffLayerSignature(blockHash)
 	}
 	if cached, ok := bc.diffLayerCache.Get(blockHash); ok {
 		diff := cached.(*types.DiffLayer)
 		bz, err := rlp.EncodeToBytes(diff)
 		if err != nil {
-			return nil
+			return nil, nil
 		}
 		bc.diffLayerRLPCache.Add(blockHash, rlp.RawValue(bz))
-		return bz
+		return bz, diff.Signature
 	}
 
 	// fallback to untrusted sources.
@@ -1016,22 +1148,33 @@ func (bc *BlockChain) GetDiffLayerRLP(blockHash common.Hash) rlp.RawValue {
 	if diff != nil {
 		bz, err := rlp.EncodeToBytes(diff)
 		if err != nil {
-			return nil
+			return nil, nil
 		}
 		// No need to cache untrusted data
-		return bz
+		return bz, diff.Signature
 	}
 
 	// fallback to disk
 	diffStore := bc.db.DiffStore()
 	if diffStore == nil {
-		return nil
+		return nil, nil
 	}
 	rawData := rawdb.ReadDiffLayerRLP(diffStore, blockHash)
 	if len(rawData) != 0 {
 		bc.diffLayerRLPCache.Add(blockHash, rawData)
 	}
-	return rawData
+	return rawData, rawdb.ReadDiffLayerSignature(diffStore, blockHash)
+}
+
+// diffLayerSignature retrieves the sealer signature of a trusted diff layer.
+func (bc *BlockChain) diffLayerSignature(blockHash common.Hash) []byte {
+	if cached, ok := bc.diffLayerCache.Get(blockHash); ok {
+		return cached.(*types.DiffLayer).Signature
+	}
+	if diffStore := bc.db.DiffStore(); diffStore != nil {
+		return rawdb.ReadDiffLayerSignature(diffStore, blockHash)
+	}
+	return nil
 }
 
 func (bc *BlockChain) GetDiffAccounts(blockHash common.Hash) ([]common.Address, error) {
@@ -1249,7 +1392,10 @@ func (bc *BlockChain) Stop() {
 		for _, offset := range []uint64{0, 1, bc.triesInMemory - 1} {
 			if number := bc.CurrentBlock().NumberU64(); number > offset {
 				recent := bc.GetBlockByNumber(number - offset)
-
+				if recent == nil {
+					// The block body was already pruned from the history
+					continue
+				}
 				log.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
 				if err := triedb.Commit(recent.Root(), true, nil); err != nil {
 					log.Error("Failed to commit recent state trie", "err", err)
@@ -1623,7 +1769,14 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 
 // SetTxLookupLimit is responsible for updating the txlookup limit to the
 // original one stored in db if the new mismatches with the old one.
+//
+// The transactions of pruned blocks can't be looked up, so the limit is capped
+// to the retained block history. Indexing them would hold back the pruning.
 func (bc *BlockChain) SetTxLookupLimit(limit uint64) {
+	if bc.historyBlocks > 0 && (limit == 0 || limit > bc.historyBlocks) {
+		log.Warn("Limiting transaction index to the retained block history", "txlookuplimit", limit, "history", bc.historyBlocks)
+		limit = bc.historyBlocks
+	}
 	bc.txLookupLimit = limit
 }
 
@@ -1659,6 +1812,10 @@ func (bc *BlockChain) writeKnownBlock(block *types.Block) error {
 
 	current := bc.CurrentBlock()
 	if block.ParentHash() != current.Hash() {
+		if bc.revertsFinalized(block) {
+			log.Warn("Refusing to reorg below finalized block", "number", block.Number(), "hash", block.Hash())
+			return nil
+		}
 		if err := bc.reorg(current, block); err != nil {
 			return err
 		}
@@ -1790,6 +1947,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 		diffLayer.Receipts = receipts
 		diffLayer.BlockHash = block.Hash()
 		diffLayer.Number = block.NumberU64()
+		bc.signDiffLayer(block.Header(), diffLayer)
 		bc.cacheDiffLayer(diffLayer)
 	}
 
@@ -1815,6 +1973,11 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 			reorg = !currentPreserve && (blockPreserve || mrand.Float64() < 0.5)
 		}
 	}
+	// Never reorg the finalized block out of the canonical chain
+	if reorg && block.ParentHash() != currentBlock.Hash() && bc.revertsFinalized(block) {
+		log.Warn("Refusing to reorg below finalized block", "number", block.Number(), "hash", block.Hash())
+		reorg = false
+	}
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != currentBlock.Hash() {
@@ -1844,6 +2007,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 		// event here.
 		if emitHeadEvent {
 			bc.chainHeadFeed.Send(ChainHeadEvent{Block: block})
+			bc.announceFinalized()
 		}
 	} else {
 		bc.chainSideFeed.Send(ChainSideEvent{Block: block})
@@ -1946,6 +2110,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 	defer func() {
 		if lastCanon != nil && bc.CurrentBlock().Hash() == lastCanon.Hash() {
 			bc.chainHeadFeed.Send(ChainHeadEvent{lastCanon})
+			bc.announceFinalized()
 		}
 	}()
 	// Start the parallel header verifier
@@ -2148,6 +2313,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 				bc.reportBlock(block, receipts, err)
 				return it.index, err
 			}
+			atomic.AddUint64(&bc.fullProcessed, 1)
+		} else {
+			atomic.AddUint64(&bc.lightProcessed, 1)
 		}
 		bc.cacheReceipts(block.Hash(), receipts)
 		bc.cacheBlock(block.Hash(), block)
@@ -2677,14 +2845,26 @@ func (bc *BlockChain) GetUnTrustedDiffLayer(blockHash common.Hash, pid string) *
 	return nil
 }
 
+// removeDiffLayers drops the given bad diff layer along with every other diff
+// layer served by the peers that sent it, and notifies about the peers.
 func (bc *BlockChain) removeDiffLayers(diffHash common.Hash) {
 	bc.diffMux.Lock()
-	defer bc.diffMux.Unlock()
+	blockHash := bc.diffHashToBlockHash[diffHash]
+	pids := bc.dropDiffLayers(diffHash)
+	bc.diffMux.Unlock()
+
+	if len(pids) > 0 {
+		bc.badDiffFeed.Send(BadDiffLayerEvent{DiffHash: diffHash, BlockHash: blockHash, Peers: pids})
+	}
+}
 
+func (bc *BlockChain) dropDiffLayers(diffHash common.Hash) []string {
 	// Untrusted peers
 	pids := bc.diffHashToPeers[diffHash]
+	peers := make([]string, 0, len(pids))
 	invalidDiffHashes := make(map[common.Hash]struct{})
 	for pid := range pids {
+		peers = append(peers, pid)
 		invaliDiffHashesPeer := bc.diffPeersToDiffHashes[pid]
 		for invaliDiffHash := range invaliDiffHashesPeer {
 			invalidDiffHashes[invaliDiffHash] = struct{}{}
@@ -2702,6 +2882,7 @@ func (bc *BlockChain) removeDiffLayers(diffHash common.Hash) {
 		}
 		delete(bc.diffHashToBlockHash, invalidDiffHash)
 	}
+	return peers
 }
 
 func (bc *BlockChain) untrustedDiffLayerPruneLoop() {
@@ -2907,6 +3088,85 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	}
 }
 
+// maintainHistory is responsible for pruning the ancient chain segments which
+// fall out of the retained block history while the node is running.
+//
+// User can use flag `history.blocks` to specify a "recentness" block, below
+// which ancient blocks get deleted. Only frozen blocks are pruned, and never
+// while their transactions are still indexed.
+func (bc *BlockChain) maintainHistory() {
+	defer bc.wg.Done()
+
+	var (
+		done   chan struct{}                  // Non-nil if background pruning routine is active.
+		headCh = make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	)
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case head := <-headCh:
+			if done == nil {
+				done = make(chan struct{})
+				go bc.pruneHistory(head.Block.NumberU64(), done)
+			}
+		case <-done:
+			done = nil
+		case <-bc.quit:
+			if done != nil {
+				log.Info("Waiting background history pruner to exit")
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// pruneHistory moves the ancient store tail to the start of the retained block
+// history of the given head.
+func (bc *BlockChain) pruneHistory(head uint64, done chan struct{}) {
+	defer func() { done <- struct{}{} }()
+
+	if head < bc.historyBlocks {
+		return
+	}
+	target := head - bc.historyBlocks + 1
+	frozen, err := bc.db.Ancients()
+	if err != nil {
+		return // No ancient store to prune
+	}
+	if target > frozen {
+		target = frozen
+	}
+	if txTail := rawdb.ReadTxIndexTail(bc.db); txTail != nil && *txTail < target {
+		target = *txTail
+	}
+	tail, err := bc.db.AncientTail()
+	if err != nil || target <= tail {
+		return
+	}
+	start := time.Now()
+	if err := bc.db.TruncateTail(target); err != nil {
+		log.Error("Failed to prune ancient chain segment", "tail", target, "err", err)
+		return
+	}
+	log.Info("Pruned ancient chain segment", "blocks", target-tail, "tail", target, "elapsed", common.PrettyDuration(time.Since(start)))
+}
+
+// HistoryPruningCutoff returns the number of the oldest block whose data is
+// still retained. Older blocks, apart from the genesis, have been pruned.
+func (bc *BlockChain) HistoryPruningCutoff() uint64 {
+	tail, err := bc.db.AncientTail()
+	if err != nil {
+		return 0
+	}
+	return tail
+}
+
 func (bc *BlockChain) isCachedBadBlock(block *types.Block) bool {
 	if timeAt, exist := bc.badBlockCache.Get(block.Hash()); exist {
 		putAt := timeAt.(time.Time)
@@ -3067,6 +3327,11 @@ func (bc *BlockChain) SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Su
 	return bc.scope.Track(bc.chainHeadFeed.Subscribe(ch))
 }
 
+// SubscribeChainFinalizedEvent registers a subscription of ChainFinalizedEvent.
+func (bc *BlockChain) SubscribeChainFinalizedEvent(ch chan<- ChainFinalizedEvent) event.Subscription {
+	return bc.scope.Track(bc.finalizedFeed.Subscribe(ch))
+}
+
 // SubscribeChainSideEvent registers a subscription of ChainSideEvent.
 func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Subscription {
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
@@ -3077,6 +3342,11 @@ func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
 }
 
+// SubscribeBadDiffLayerEvent registers a subscription of BadDiffLayerEvent.
+func (bc *BlockChain) SubscribeBadDiffLayerEvent(ch chan<- BadDiffLayerEvent) event.Subscription {
+	return bc.scope.Track(bc.badDiffFeed.Subscribe(ch))
+}
+
 // SubscribeBlockProcessingEvent registers a subscription of bool where true means
 // block processing has started while false means it has stopped.
 func (bc *BlockChain) SubscribeBlockProcessingEvent(ch chan<- bool) event.Subscription {
@@ -3094,6 +3364,13 @@ func EnablePipelineCommit(bc *BlockChain) *BlockChain {
 	return bc
 }
 
+func EnableHistoryPruning(blocks uint64) BlockChainOption {
+	return func(chain *BlockChain) *BlockChain {
+		chain.historyBlocks = blocks
+		return chain
+	}
+}
+
 func EnablePersistDiff(limit uint64) BlockChainOption {
 	return func(chain *BlockChain) *BlockChain {
 		chain.diffLayerFreezerBlockLimit = limit
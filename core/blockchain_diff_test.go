@@ -22,7 +22,9 @@ package core
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"encoding/hex"
+	"errors"
 	"math/big"
 	"testing"
 	"time"
@@ -30,6 +32,7 @@ import (
 	"golang.org/x/crypto/sha3"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
@@ -203,6 +206,12 @@ func newTestBackend(blocks int, light bool) *testBackend {
 // newTestBackend creates a chain with a number of explicitly defined blocks and
 // wraps it into a mock backend.
 func newTestBackendWithGenerator(blocks int, lightProcess bool) *testBackend {
+	return newTestBackendWithEngine(blocks, lightProcess, ethash.NewFaker())
+}
+
+// newTestBackendWithEngine creates a chain with a number of explicitly defined
+// blocks sealed by the given consensus engine and wraps it into a mock backend.
+func newTestBackendWithEngine(blocks int, lightProcess bool, engine consensus.Engine) *testBackend {
 	signer := types.HomesteadSigner{}
 	// Create a database pre-initialize with a genesis block
 	db := rawdb.NewMemoryDatabase()
@@ -212,7 +221,7 @@ func newTestBackendWithGenerator(blocks int, lightProcess bool) *testBackend {
 		Alloc:  GenesisAlloc{testAddr: {Balance: big.NewInt(100000000000000000)}},
 	}).MustCommit(db)
 
-	chain, _ := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil, EnablePersistDiff(860000))
+	chain, _ := NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil, EnablePersistDiff(860000))
 	generator := func(i int, block *BlockGen) {
 		// The chain maker doesn't have access to a chain, so the difficulty will be
 		// lets unset (nil). Set it here to the correct value.
@@ -255,7 +264,7 @@ func newTestBackendWithGenerator(blocks int, lightProcess bool) *testBackend {
 		}
 
 	}
-	bs, _ := GenerateChain(params.TestChainConfig, chain.Genesis(), ethash.NewFaker(), db, blocks, generator)
+	bs, _ := GenerateChain(params.TestChainConfig, chain.Genesis(), engine, db, blocks, generator)
 	if _, err := chain.InsertChain(bs); err != nil {
 		panic(err)
 	}
@@ -360,6 +369,117 @@ func TestProcessDiffLayer(t *testing.T) {
 	}
 }
 
+// testPoSA is an ethash faker posing as a PoSA engine, which signs the diff
+// layers of the blocks it seals with the validator key.
+type testPoSA struct {
+	consensus.Engine
+	key   *ecdsa.PrivateKey
+	local bool
+}
+
+func newTestPoSA(key *ecdsa.PrivateKey, local bool) *testPoSA {
+	return &testPoSA{Engine: ethash.NewFaker(), key: key, local: local}
+}
+
+func (p *testPoSA) IsSystemTransaction(tx *types.Transaction, header *types.Header) (bool, error) {
+	return false, nil
+}
+func (p *testPoSA) IsSystemContract(to *common.Address) bool { return false }
+func (p *testPoSA) EnoughDistance(chain consensus.ChainReader, header *types.Header) bool {
+	return true
+}
+func (p *testPoSA) IsLocalBlock(header *types.Header) bool { return p.local }
+func (p *testPoSA) AllowLightProcess(chain consensus.ChainReader, currentHeader *types.Header) bool {
+	return true
+}
+func (p *testPoSA) BlockRewards(blockNumber *big.Int) *big.Int { return nil }
+func (p *testPoSA) EpochBlock(chain consensus.ChainHeaderReader, header *types.Header) (uint64, error) {
+	return 0, nil
+}
+func (p *testPoSA) EpochCheckpoint(number uint64, getHeader func(uint64) (*types.Header, error)) (*types.Header, error) {
+	return getHeader(0)
+}
+
+func (p *testPoSA) SignDiffLayer(header *types.Header, diffLayerRLP []byte) ([]byte, error) {
+	return crypto.Sign(crypto.Keccak256(diffLayerRLP), p.key)
+}
+
+func (p *testPoSA) VerifyDiffLayer(chain consensus.ChainHeaderReader, header *types.Header, diffHash common.Hash, signature []byte) error {
+	pubkey, err := crypto.SigToPub(diffHash.Bytes(), signature)
+	if err != nil {
+		return err
+	}
+	if crypto.PubkeyToAddress(*pubkey) != header.Coinbase {
+		return errors.New("diff layer not signed by sealer")
+	}
+	return nil
+}
+
+// Tests that light processing only trusts diff layers signed by the block
+// sealer, that the signatures are relayed, and that peers serving badly signed
+// diff layers are reported.
+func TestProcessSignedDiffLayer(t *testing.T) {
+	blockNum := 10
+	fullBackend := newTestBackendWithEngine(blockNum, false, newTestPoSA(testKey, true))
+	defer fullBackend.close()
+
+	lightBackend := newTestBackendWithEngine(0, true, newTestPoSA(testKey, false))
+	defer lightBackend.close()
+	// Never fall back to full processing randomly
+	lightBackend.chain.processor.(*LightStateProcessor).check = fullProcessCheck - 1
+
+	badDiffCh := make(chan BadDiffLayerEvent, 1)
+	sub := lightBackend.chain.SubscribeBadDiffLayerEvent(badDiffCh)
+	defer sub.Unsubscribe()
+
+	otherKey, _ := crypto.GenerateKey()
+	for i := 1; i <= blockNum; i++ {
+		block := fullBackend.chain.GetBlockByNumber(uint64(i))
+		rawDiff, sig := fullBackend.chain.GetSignedDiffLayerRLP(block.Hash())
+		if len(sig) != crypto.SignatureLength {
+			t.Fatalf("block %d: missing diff layer signature", i)
+		}
+		diff, err := rawDataToDiffLayer(rawDiff)
+		if err != nil {
+			t.Fatalf("block %d: failed to decode rawdata %v", i, err)
+		}
+		bad := i == blockNum
+		if bad {
+			sig, _ = crypto.Sign(diff.DiffHash.Bytes(), otherKey)
+		}
+		diff.Signature = sig
+		lightBackend.Chain().HandleDiffLayer(diff, "testpid", true)
+
+		if _, err := lightBackend.chain.insertChain([]*types.Block{block}, true); err != nil {
+			t.Fatalf("block %d: failed to insert block %v", i, err)
+		}
+		_, relayed := lightBackend.chain.GetSignedDiffLayerRLP(block.Hash())
+		if bad {
+			if len(relayed) != 0 {
+				t.Errorf("block %d: bad signature relayed", i)
+			}
+			continue
+		}
+		if !bytes.Equal(relayed, sig) {
+			t.Errorf("block %d: relayed signature mismatch: have %x, want %x", i, relayed, sig)
+		}
+	}
+	select {
+	case ev := <-badDiffCh:
+		if ev.BlockHash != fullBackend.chain.CurrentBlock().Hash() {
+			t.Errorf("bad diff layer block mismatch: have %x, want %x", ev.BlockHash, fullBackend.chain.CurrentBlock().Hash())
+		}
+		if len(ev.Peers) != 1 || ev.Peers[0] != "testpid" {
+			t.Errorf("bad diff layer peers mismatch: have %v, want [testpid]", ev.Peers)
+		}
+	default:
+		t.Fatalf("no bad diff layer event")
+	}
+	if have, want := lightBackend.chain.CurrentBlock().Hash(), fullBackend.chain.CurrentBlock().Hash(); have != want {
+		t.Errorf("head mismatch: have %x, want %x", have, want)
+	}
+}
+
 func TestFreezeDiffLayer(t *testing.T) {
 	blockNum := 1024
 	fullBackend := newTestBackend(blockNum, true)
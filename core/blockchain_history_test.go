@@ -0,0 +1,111 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the ancient chain segment falling out of the retained history is
+// pruned on a running chain, while the recent blocks stay available.
+func TestHistoryPruning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history-pruning")
+	if err != nil {
+		t.Fatalf("Failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), dir, "", false, false, false)
+	if err != nil {
+		t.Fatalf("Failed to create persistent database: %v", err)
+	}
+	defer db.Close()
+
+	genesis := (&Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}).MustCommit(db)
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil, EnableHistoryPruning(32))
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 128, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("Failed to import chain: %v", err)
+	}
+	prune := func() {
+		done := make(chan struct{}, 1)
+		chain.pruneHistory(chain.CurrentBlock().NumberU64(), done)
+	}
+	// Nothing is frozen yet, so nothing can be pruned
+	prune()
+	if cutoff := chain.HistoryPruningCutoff(); cutoff != 0 {
+		t.Fatalf("History pruned before freezing: cutoff %d", cutoff)
+	}
+	// Move all but the last 16 blocks into the ancient store and prune them
+	type freezer interface {
+		Freeze(threshold uint64) error
+	}
+	db.(freezer).Freeze(16)
+	prune()
+
+	if cutoff := chain.HistoryPruningCutoff(); cutoff != 97 {
+		t.Fatalf("History pruning cutoff mismatch: have %d, want %d", cutoff, 97)
+	}
+	for number := uint64(1); number <= 128; number++ {
+		block := chain.GetBlockByNumber(number)
+		if pruned := number < 97; pruned != (block == nil) {
+			t.Errorf("Block %d availability mismatch: pruned %v, block %v", number, pruned, block != nil)
+		}
+	}
+	if chain.GetBlockByNumber(0) == nil {
+		t.Errorf("Genesis block pruned")
+	}
+}
+
+// Tests that the transaction index doesn't reach beyond the retained history,
+// which would hold back the pruning forever.
+func TestHistoryPruningTxLookupLimit(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	(&Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}).MustCommit(db)
+
+	for _, limit := range []uint64{0, 64} {
+		chain, err := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, &limit, EnableHistoryPruning(32))
+		if err != nil {
+			t.Fatalf("Failed to create chain: %v", err)
+		}
+		if have := chain.TxLookupLimit(); have != 32 {
+			t.Errorf("limit %d: transaction lookup limit mismatch: have %d, want 32", limit, have)
+		}
+		// Limits restored after a fast sync are capped as well
+		chain.SetTxLookupLimit(limit)
+		if have := chain.TxLookupLimit(); have != 32 {
+			t.Errorf("limit %d: restored transaction lookup limit mismatch: have %d, want 32", limit, have)
+		}
+		chain.Stop()
+	}
+}
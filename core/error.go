@@ -77,4 +77,28 @@ var (
 	// ErrTxTypeNotSupported is returned if a transaction is not supported in the
 	// current network configuration.
 	ErrTxTypeNotSupported = types.ErrTxTypeNotSupported
+
+	// ErrTipAboveFeeCap is a sanity error to ensure no one is able to specify a
+	// transaction with a tip higher than the total fee cap.
+	ErrTipAboveFeeCap = errors.New("max priority fee per gas higher than max fee per gas")
+
+	// ErrTipVeryHigh is a sanity error to avoid extremely big numbers specified
+	// in the tip field.
+	ErrTipVeryHigh = errors.New("max priority fee per gas higher than 2^256-1")
+
+	// ErrFeeCapVeryHigh is a sanity error to avoid extremely big numbers specified
+	// in the fee cap field.
+	ErrFeeCapVeryHigh = errors.New("max fee per gas higher than 2^256-1")
+
+	// ErrFeeCapTooLow is returned if the transaction fee cap is less than the
+	// the base fee of the block.
+	ErrFeeCapTooLow = errors.New("max fee per gas less than block base fee")
+
+	// ErrInsufficientSponsorFunds is returned if the sponsor designated for a
+	// transaction can't pay for its gas.
+	ErrInsufficientSponsorFunds = errors.New("insufficient sponsor funds for gas * price")
+
+	// ErrSponsorQuotaExceeded is returned if the gas of a transaction exceeds the
+	// quota its sponsor has left in the current epoch.
+	ErrSponsorQuotaExceeded = errors.New("gas sponsor quota exceeded")
 )
@@ -44,3 +44,18 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ChainFinalizedEvent is posted when the latest finalized block changes along
+// with the head of the chain.
+type ChainFinalizedEvent struct{ Header *types.Header }
+
+// BadDiffLayerEvent is posted when a diff layer received from peers turns out
+// to be invalid, listing the peers that served it.
+type BadDiffLayerEvent struct {
+	DiffHash  common.Hash
+	BlockHash common.Hash
+	Peers     []string
+}
+
+// NewVoteEvent is posted when a fast finality vote enters the vote pool.
+type NewVoteEvent struct{ Vote *types.VoteEnvelope }
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -44,6 +45,11 @@ func NewEVMBlockContext(header *types.Header, chain ChainContext, author *common
 	} else {
 		beneficiary = *author
 	}
+	var baseFee *big.Int
+	if header.BaseFee != nil {
+		baseFee = new(big.Int).Set(header.BaseFee)
+	}
+	epoch, epochErr := epochBlock(chain, header)
 	return vm.BlockContext{
 		CanTransfer: CanTransfer,
 		Transfer:    Transfer,
@@ -52,8 +58,34 @@ func NewEVMBlockContext(header *types.Header, chain ChainContext, author *common
 		BlockNumber: new(big.Int).Set(header.Number),
 		Time:        new(big.Int).SetUint64(header.Time),
 		Difficulty:  new(big.Int).Set(header.Difficulty),
+		BaseFee:     baseFee,
 		GasLimit:    header.GasLimit,
+		EpochBlock:  epoch,
+		EpochErr:    epochErr,
+	}
+}
+
+// epochBlock returns the number of the block starting the consensus epoch of the
+// given header, or nil if the consensus engine has no epochs. Engines with epochs
+// failing to find it, e.g. for lack of headers, report an error instead.
+func epochBlock(chain ChainContext, header *types.Header) (*big.Int, error) {
+	// Generated chains may apply transactions without a backing chain
+	if bc, ok := chain.(*BlockChain); chain == nil || (ok && bc == nil) {
+		return nil, nil
+	}
+	posa, ok := chain.Engine().(consensus.PoSA)
+	if !ok {
+		return nil, nil
+	}
+	reader, ok := chain.(consensus.ChainHeaderReader)
+	if !ok {
+		return nil, fmt.Errorf("%w: chain can't serve headers", errUnknownEpoch)
+	}
+	number, err := posa.EpochBlock(reader, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnknownEpoch, err)
 	}
+	return new(big.Int).SetUint64(number), nil
 }
 
 // NewEVMTxContext creates a new transaction context for a single transaction.
@@ -453,6 +453,9 @@ func WriteDiffLayer(db ethdb.KeyValueWriter, hash common.Hash, layer *types.Diff
 		log.Crit("Failed to RLP encode diff layer", "err", err)
 	}
 	WriteDiffLayerRLP(db, hash, data)
+	if len(layer.Signature) != 0 {
+		WriteDiffLayerSignature(db, hash, layer.Signature)
+	}
 }
 
 func WriteDiffLayerRLP(db ethdb.KeyValueWriter, blockHash common.Hash, rlp rlp.RawValue) {
@@ -471,6 +474,7 @@ func ReadDiffLayer(db ethdb.KeyValueReader, blockHash common.Hash) *types.DiffLa
 		log.Error("Invalid diff layer RLP", "hash", blockHash, "err", err)
 		return nil
 	}
+	diff.Signature = ReadDiffLayerSignature(db, blockHash)
 	return diff
 }
 
@@ -483,6 +487,24 @@ func DeleteDiffLayer(db ethdb.KeyValueWriter, blockHash common.Hash) {
 	if err := db.Delete(diffLayerKey(blockHash)); err != nil {
 		log.Crit("Failed to delete diffLayer", "err", err)
 	}
+	if err := db.Delete(diffLayerSignatureKey(blockHash)); err != nil {
+		log.Crit("Failed to delete diffLayer signature", "err", err)
+	}
+}
+
+// WriteDiffLayerSignature stores the signature of the block sealer over the
+// diff layer of the given block.
+func WriteDiffLayerSignature(db ethdb.KeyValueWriter, blockHash common.Hash, signature []byte) {
+	if err := db.Put(diffLayerSignatureKey(blockHash), signature); err != nil {
+		log.Crit("Failed to store diff layer signature", "err", err)
+	}
+}
+
+// ReadDiffLayerSignature retrieves the signature of the block sealer over the
+// diff layer of the given block, or nil if it isn't known.
+func ReadDiffLayerSignature(db ethdb.KeyValueReader, blockHash common.Hash) []byte {
+	data, _ := db.Get(diffLayerSignatureKey(blockHash))
+	return data
 }
 
 // DeleteBody removes all block body data associated with a hash.
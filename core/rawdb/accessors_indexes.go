@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -155,6 +156,71 @@ func WriteBloomBits(db ethdb.KeyValueWriter, bit uint, section uint64, head comm
 	}
 }
 
+// ReadAddressLogs retrieves the offsets of the blocks within the given section
+// that contain logs emitted by the given address, nil if there are none.
+func ReadAddressLogs(db ethdb.KeyValueReader, address common.Address, section uint64, head common.Hash) ([]uint64, error) {
+	blob, _ := db.Get(addressLogsKey(address, section, head))
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	var offsets []uint64
+	if err := rlp.DecodeBytes(blob, &offsets); err != nil {
+		return nil, err
+	}
+	return offsets, nil
+}
+
+// WriteAddressLogs stores the offsets of the blocks within the given section
+// that contain logs emitted by the given address.
+func WriteAddressLogs(db ethdb.KeyValueWriter, address common.Address, section uint64, head common.Hash, offsets []uint64) {
+	blob, err := rlp.EncodeToBytes(offsets)
+	if err != nil {
+		log.Crit("Failed to encode address logs", "err", err)
+	}
+	if err := db.Put(addressLogsKey(address, section, head), blob); err != nil {
+		log.Crit("Failed to store address logs", "err", err)
+	}
+}
+
+// DeleteAddressLogs removes the address log index of all sections below the
+// given one.
+func DeleteAddressLogs(db ethdb.Database, section uint64) {
+	it := db.NewIterator(addressLogsPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(addressLogsPrefix)+common.AddressLength+8+common.HashLength {
+			continue
+		}
+		offset := len(addressLogsPrefix) + common.AddressLength
+		if binary.BigEndian.Uint64(key[offset:offset+8]) < section {
+			db.Delete(key)
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to delete address logs", "err", it.Error())
+	}
+}
+
+// ReadAddressLogsTail retrieves the oldest section indexed by the address log
+// indexer. Older sections are not indexed as their block history was pruned.
+func ReadAddressLogsTail(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(addressLogsTailKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteAddressLogsTail stores the oldest section indexed by the address log
+// indexer.
+func WriteAddressLogsTail(db ethdb.KeyValueWriter, section uint64) {
+	if err := db.Put(addressLogsTailKey, encodeBlockNumber(section)); err != nil {
+		log.Crit("Failed to store the address logs tail", "err", err)
+	}
+}
+
 // DeleteBloombits removes all compressed bloom bits vector belonging to the
 // given section range and bit index.
 func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"time"
 
@@ -81,6 +82,24 @@ func WriteChainConfig(db ethdb.KeyValueWriter, hash common.Hash, cfg *params.Cha
 	}
 }
 
+// ReadLastVotedNumber retrieves the number of the latest block the local validator
+// voted for, nil if it never voted.
+func ReadLastVotedNumber(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(lastVotedKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteLastVotedNumber stores the number of the latest block the local validator
+// voted for. The error is returned instead of crashing, as the caller must not
+// vote without the record.
+func WriteLastVotedNumber(db ethdb.KeyValueWriter, number uint64) error {
+	return db.Put(lastVotedKey, encodeBlockNumber(number))
+}
+
 // crashList is a list of unclean-shutdown-markers, for rlp-encoding to the
 // database
 type crashList struct {
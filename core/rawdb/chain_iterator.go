@@ -45,8 +45,13 @@ func InitDatabaseFromFreezer(db ethdb.Database) {
 		logged = start.Add(-7 * time.Second) // Unindex during import is fast, don't double log
 		hash   common.Hash
 		offset = db.AncientOffSet()
+		tail   = offset
 	)
-	for i := uint64(0) + offset; i < frozen+offset; i++ {
+	// Skip the items already pruned from the ancient tail
+	if t, err := db.AncientTail(); err == nil && t > tail {
+		tail = t
+	}
+	for i := tail; i < frozen+offset; i++ {
 		// Since the freezer has all data in sequential order on a file,
 		// it would be 'neat' to read more data in one go, and let the
 		// freezerdb return N items (e.g up to 1000 items per go)
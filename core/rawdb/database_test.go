@@ -15,3 +15,52 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 package rawdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests that the database engine is detected on reopen and that a conflicting
+// explicit choice is rejected.
+func TestOpenDatabaseEngine(t *testing.T) {
+	for _, engine := range []string{dbLeveldb, dbPebble} {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		if have := PreexistingDatabase(dir); have != "" {
+			t.Fatalf("%s: found database in empty directory: %q", engine, have)
+		}
+		db, err := Open(OpenOptions{Type: engine, Directory: dir})
+		if err != nil {
+			t.Fatalf("%s: failed to create database: %v", engine, err)
+		}
+		if err := db.Put([]byte("key"), []byte("value")); err != nil {
+			t.Fatalf("%s: failed to write: %v", engine, err)
+		}
+		db.Close()
+
+		if have := PreexistingDatabase(dir); have != engine {
+			t.Fatalf("%s: engine mismatch: have %q", engine, have)
+		}
+		other := dbPebble
+		if engine == dbPebble {
+			other = dbLeveldb
+		}
+		if _, err := Open(OpenOptions{Type: other, Directory: dir}); err == nil {
+			t.Fatalf("%s: reopened with conflicting engine %s", engine, other)
+		}
+		db, err = Open(OpenOptions{Directory: dir})
+		if err != nil {
+			t.Fatalf("%s: failed to reopen database: %v", engine, err)
+		}
+		if val, err := db.Get([]byte("key")); err != nil || string(val) != "value" {
+			t.Fatalf("%s: value mismatch: have %q, err %v", engine, val, err)
+		}
+		db.Close()
+	}
+}
@@ -43,6 +43,14 @@ var (
 
 	// errNotSupported is returned if the database doesn't support the required operation.
 	errNotSupported = errors.New("this operation is not supported")
+
+	// errTruncationAboveHead is returned if the user attempts to prune the tail
+	// of the freezer table beyond its head.
+	errTruncationAboveHead = errors.New("truncation above head")
+
+	// errTruncationBelowTail is returned if the user attempts to truncate the
+	// head of the freezer table into the already pruned tail.
+	errTruncationBelowTail = errors.New("truncation below tail")
 )
 
 // indexEntry contains the number/id of the file that the data resides in, aswell as the
@@ -77,7 +85,8 @@ type freezerTable struct {
 	// WARNING: The `items` field is accessed atomically. On 32 bit platforms, only
 	// 64-bit aligned fields can be atomic. The struct is guaranteed to be so aligned,
 	// so take advantage of that (https://golang.org/pkg/sync/atomic/#pkg-note-BUG).
-	items uint64 // Number of items stored in the table (including items removed from tail)
+	items      uint64 // Number of items stored in the table (including items removed from tail)
+	itemHidden uint64 // Number of hidden items, deleted or not yet deleted from the tail
 
 	noCompression bool   // if true, disables snappy compression. Note: does not work retroactively
 	maxFileSize   uint32 // Max file size for data-files
@@ -89,6 +98,7 @@ type freezerTable struct {
 	headId uint32              // number of the currently active head file
 	tailId uint32              // number of the earliest file
 	index  *os.File            // File descriptor for the indexEntry file of the table
+	meta   *os.File            // File descriptor for the metadata file of the table
 
 	// In the case that old items are deleted (from the tail), we use itemOffset
 	// to count how many historic items have gone missing.
@@ -171,9 +181,15 @@ func newCustomTable(path string, name string, readMeter metrics.Meter, writeMete
 	if err != nil {
 		return nil, err
 	}
+	meta, err := openFreezerFileForAppend(filepath.Join(path, fmt.Sprintf("%s.meta", name)))
+	if err != nil {
+		offsets.Close()
+		return nil, err
+	}
 	// Create the table and repair any past inconsistency
 	tab := &freezerTable{
 		index:         offsets,
+		meta:          meta,
 		files:         make(map[uint32]*os.File),
 		readMeter:     readMeter,
 		writeMeter:    writeMeter,
@@ -240,8 +256,19 @@ func (t *freezerTable) repair() error {
 	t.tailId = firstIndex.filenum
 	t.itemOffset = firstIndex.offset
 
+	// Load the virtual tail marking the items pruned but not yet deleted
+	meta, err := loadMetadata(t.meta, uint64(t.itemOffset))
+	if err != nil {
+		return err
+	}
+	t.itemHidden = meta.VirtualTail
+
 	t.index.ReadAt(buffer, offsetsSize-indexEntrySize)
 	lastIndex.unmarshalBinary(buffer)
+	if offsetsSize == indexEntrySize {
+		// Only the tail marker is left, the table is empty
+		lastIndex = indexEntry{filenum: firstIndex.filenum}
+	}
 	t.head, err = t.openFile(lastIndex.filenum, openFreezerFileForAppend)
 	if err != nil {
 		return err
@@ -273,6 +300,9 @@ func (t *freezerTable) repair() error {
 			t.index.ReadAt(buffer, offsetsSize-indexEntrySize)
 			var newLastIndex indexEntry
 			newLastIndex.unmarshalBinary(buffer)
+			if offsetsSize == indexEntrySize {
+				newLastIndex = indexEntry{filenum: firstIndex.filenum}
+			}
 			// We might have slipped back into an earlier head-file here
 			if newLastIndex.filenum != lastIndex.filenum {
 				// Release earlier opened file
@@ -302,6 +332,9 @@ func (t *freezerTable) repair() error {
 	t.items = uint64(t.itemOffset) + uint64(offsetsSize/indexEntrySize-1) // last indexEntry points to the end of the data file
 	t.headBytes = uint32(contentSize)
 	t.headId = lastIndex.filenum
+	if t.itemHidden > t.items {
+		t.itemHidden = t.items
+	}
 
 	// Close opened files and preopen all files
 	if err := t.preopen(); err != nil {
@@ -339,6 +372,9 @@ func (t *freezerTable) truncate(items uint64) error {
 	if existing <= items {
 		return nil
 	}
+	if items < atomic.LoadUint64(&t.itemHidden) {
+		return errTruncationBelowTail
+	}
 	// We need to truncate, save the old size for metrics tracking
 	oldSize, err := t.sizeNolock()
 	if err != nil {
@@ -350,16 +386,21 @@ func (t *freezerTable) truncate(items uint64) error {
 		log = t.logger.Warn // Only loud warn if we delete multiple items
 	}
 	log("Truncating freezer table", "items", existing, "limit", items)
-	if err := truncateFreezerFile(t.index, int64(items+1)*indexEntrySize); err != nil {
+	length := items - uint64(t.itemOffset)
+	if err := truncateFreezerFile(t.index, int64(length+1)*indexEntrySize); err != nil {
 		return err
 	}
 	// Calculate the new expected size of the data file and truncate it
-	buffer := make([]byte, indexEntrySize)
-	if _, err := t.index.ReadAt(buffer, int64(items*indexEntrySize)); err != nil {
-		return err
-	}
 	var expected indexEntry
-	expected.unmarshalBinary(buffer)
+	if length == 0 {
+		expected = indexEntry{filenum: t.tailId}
+	} else {
+		buffer := make([]byte, indexEntrySize)
+		if _, err := t.index.ReadAt(buffer, int64(length*indexEntrySize)); err != nil {
+			return err
+		}
+		expected.unmarshalBinary(buffer)
+	}
 
 	// We might need to truncate back to older files
 	if expected.filenum != t.headId {
@@ -393,6 +434,133 @@ func (t *freezerTable) truncate(items uint64) error {
 	return nil
 }
 
+// truncateTail discards any data below the provided threshold number. Data
+// files are only deleted once all of their items fall below the threshold,
+// the remaining items are hidden behind the persisted virtual tail.
+func (t *freezerTable) truncateTail(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Ensure the given truncate target falls in the correct range
+	if atomic.LoadUint64(&t.itemHidden) >= items {
+		return nil
+	}
+	if atomic.LoadUint64(&t.items) < items {
+		return errTruncationAboveHead
+	}
+	// Load the new tail index by the given new tail position
+	var (
+		newTailId uint32
+		deleted   = uint64(t.itemOffset)
+		buffer    = make([]byte, indexEntrySize)
+	)
+	if atomic.LoadUint64(&t.items) == items {
+		newTailId = atomic.LoadUint32(&t.headId)
+	} else {
+		if _, err := t.index.ReadAt(buffer, int64((items-deleted+1)*indexEntrySize)); err != nil {
+			return err
+		}
+		var newTail indexEntry
+		newTail.unmarshalBinary(buffer)
+		newTailId = newTail.filenum
+	}
+	// Persist the virtual tail first, hiding the items from the readers
+	if err := writeMetadata(t.meta, newMetadata(items)); err != nil {
+		return err
+	}
+	if err := t.meta.Sync(); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&t.itemHidden, items)
+
+	// Hidden items still fall in the current tail file, no data file
+	// can be dropped.
+	if t.tailId == newTailId {
+		return nil
+	}
+	if t.tailId > newTailId {
+		return fmt.Errorf("invalid index, tail-file %d, item-file %d", t.tailId, newTailId)
+	}
+	// We need to delete data files, save the old size for metrics tracking
+	oldSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	// Count how many items are stored in the dropped files, i.e. find the
+	// first item residing in the new tail file.
+	newDeleted := items
+	for current := items; current > deleted; current-- {
+		if _, err := t.index.ReadAt(buffer, int64((current-deleted)*indexEntrySize)); err != nil {
+			return err
+		}
+		var prev indexEntry
+		prev.unmarshalBinary(buffer)
+		if prev.filenum != newTailId {
+			break
+		}
+		newDeleted = current - 1
+	}
+	if err := t.truncateIndexTail(newTailId, newDeleted); err != nil {
+		return err
+	}
+	t.logger.Debug("Deleted freezer table tail", "tail", newDeleted, "files", newTailId-t.tailId)
+
+	// Release and delete the files before the current tail
+	t.tailId = newTailId
+	t.itemOffset = uint32(newDeleted)
+	t.releaseFilesBefore(t.tailId, true)
+
+	// Retrieve the new size and update the total size counter
+	newSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	t.sizeGauge.Dec(int64(oldSize - newSize))
+	return nil
+}
+
+// truncateIndexTail rewrites the index file without the entries of the items
+// below the new deleted count. The new index is assembled in a temporary file
+// and swapped in atomically, so a crash halfway leaves the old one intact.
+func (t *freezerTable) truncateIndexTail(tailId uint32, deleted uint64) error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	var (
+		name  = t.index.Name()
+		start = int64(deleted-uint64(t.itemOffset)+1) * indexEntrySize
+	)
+	tmp, err := os.OpenFile(name+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	tail := indexEntry{filenum: tailId, offset: uint32(deleted)}
+	if _, err := tmp.Write(tail.marshallBinary()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := io.Copy(tmp, io.NewSectionReader(t.index, start, stat.Size()-start)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := t.index.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return err
+	}
+	t.index, err = openFreezerFileForAppend(name)
+	return err
+}
+
 // Close closes all opened files.
 func (t *freezerTable) Close() error {
 	t.lock.Lock()
@@ -404,6 +572,13 @@ func (t *freezerTable) Close() error {
 	}
 	t.index = nil
 
+	if t.meta != nil {
+		if err := t.meta.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.meta = nil
+	}
+
 	for _, f := range t.files {
 		if err := f.Close(); err != nil {
 			errs = append(errs, err)
@@ -458,6 +633,19 @@ func (t *freezerTable) releaseFilesAfter(num uint32, remove bool) {
 	}
 }
 
+// releaseFilesBefore closes all open files with a lower number, and optionally also deletes the files
+func (t *freezerTable) releaseFilesBefore(num uint32, remove bool) {
+	for fnum, f := range t.files {
+		if fnum < num {
+			delete(t.files, fnum)
+			f.Close()
+			if remove {
+				os.Remove(f.Name())
+			}
+		}
+	}
+}
+
 // Append injects a binary blob at the end of the freezer table. The item number
 // is a precautionary parameter to ensure data correctness, but the table will
 // reject already existing data.
@@ -605,8 +793,8 @@ func (t *freezerTable) retrieve(item uint64) ([]byte, error) {
 	if atomic.LoadUint64(&t.items) <= item {
 		return nil, errOutOfBounds
 	}
-	// Ensure the item was not deleted from the tail either
-	if uint64(t.itemOffset) > item {
+	// Ensure the item was not deleted or hidden from the tail either
+	if atomic.LoadUint64(&t.itemHidden) > item {
 		return nil, errOutOfBounds
 	}
 	startOffset, endOffset, filenum, err := t.getBounds(item - uint64(t.itemOffset))
@@ -629,7 +817,7 @@ func (t *freezerTable) retrieve(item uint64) ([]byte, error) {
 // has returns an indicator whether the specified number data
 // exists in the freezer table.
 func (t *freezerTable) has(number uint64) bool {
-	return atomic.LoadUint64(&t.items) > number
+	return atomic.LoadUint64(&t.items) > number && atomic.LoadUint64(&t.itemHidden) <= number
 }
 
 // size returns the total data size in the freezer table.
@@ -628,6 +628,104 @@ func TestOffset(t *testing.T) {
 	checkPresent(1000000)
 }
 
+// TestTruncateTail tests that the tail of a freezer table can be pruned in
+// place, hiding the items first and deleting whole data files afterwards.
+func TestTruncateTail(t *testing.T) {
+	t.Parallel()
+	rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+	fname := fmt.Sprintf("truncate-tail-%d", rand.Uint64())
+
+	// Fill table, 7 x 20 bytes splitting out into four files
+	f, err := newCustomTable(os.TempDir(), fname, rm, wm, sg, 40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 7; i++ {
+		f.Append(uint64(i), getChunk(20, 0xFF-i))
+	}
+	dataFile := func(num int) string {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("%v.%04d.rdat", fname, num))
+	}
+	checkRetrieve := func(f *freezerTable, items map[uint64][]byte) {
+		t.Helper()
+		for item, exp := range items {
+			got, err := f.Retrieve(item)
+			if exp == nil {
+				if err == nil || f.has(item) {
+					t.Fatalf("item %d: expected pruned item, got %x", item, got)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("item %d: %v", item, err)
+			}
+			if !bytes.Equal(got, exp) {
+				t.Fatalf("item %d: expected %x got %x", item, exp, got)
+			}
+		}
+	}
+	checkFiles := func(deleted int) {
+		t.Helper()
+		for i := 0; i < 4; i++ {
+			if _, err := os.Stat(dataFile(i)); (err == nil) != (i >= deleted) {
+				t.Fatalf("data file %d: existence mismatch, err %v", i, err)
+			}
+		}
+	}
+	// Prune a single item, nothing can be deleted yet
+	if err := f.truncateTail(1); err != nil {
+		t.Fatal(err)
+	}
+	checkRetrieve(f, map[uint64][]byte{0: nil, 1: getChunk(20, 0xFE), 6: getChunk(20, 0xF9)})
+	checkFiles(0)
+
+	// Prune into the third file, the first two can be deleted
+	if err := f.truncateTail(5); err != nil {
+		t.Fatal(err)
+	}
+	checkRetrieve(f, map[uint64][]byte{0: nil, 3: nil, 4: nil, 5: getChunk(20, 0xFA), 6: getChunk(20, 0xF9)})
+	checkFiles(2)
+
+	// Reopen the table, the tail should be persisted
+	f.Close()
+	f, err = newCustomTable(os.TempDir(), fname, rm, wm, sg, 40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRetrieve(f, map[uint64][]byte{4: nil, 5: getChunk(20, 0xFA), 6: getChunk(20, 0xF9)})
+	if f.itemOffset != 4 || f.itemHidden != 5 {
+		t.Fatalf("tail mismatch: deleted %d, hidden %d", f.itemOffset, f.itemHidden)
+	}
+	// Truncating the head into the pruned tail is refused
+	if err := f.truncate(4); err != errTruncationBelowTail {
+		t.Fatalf("head truncation error mismatch: have %v, want %v", err, errTruncationBelowTail)
+	}
+	// Pruning beyond the head is refused as well
+	if err := f.truncateTail(8); err != errTruncationAboveHead {
+		t.Fatalf("tail truncation error mismatch: have %v, want %v", err, errTruncationAboveHead)
+	}
+	// Prune everything, appending should still work
+	if err := f.truncateTail(7); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(3)
+	if err := f.Append(7, getChunk(20, 0xF8)); err != nil {
+		t.Fatal(err)
+	}
+	checkRetrieve(f, map[uint64][]byte{6: nil, 7: getChunk(20, 0xF8)})
+	f.Close()
+
+	f, err = newCustomTable(os.TempDir(), fname, rm, wm, sg, 40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	checkRetrieve(f, map[uint64][]byte{6: nil, 7: getChunk(20, 0xF8)})
+	if f.items != 8 {
+		t.Fatalf("item count mismatch: have %d, want 8", f.items)
+	}
+}
+
 // TODO (?)
 // - test that if we remove several head-files, aswell as data last data-file,
 //   the index is truncated accordingly
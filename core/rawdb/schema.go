@@ -66,6 +66,12 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// addressLogsTailKey tracks the oldest section indexed by the address log indexer.
+	addressLogsTailKey = []byte("AddressLogIndexTail")
+
+	// lastVotedKey tracks the number of the latest block the local validator voted for.
+	lastVotedKey = []byte("LastVotedNumber")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
@@ -92,18 +98,21 @@ var (
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	addressLogsPrefix     = []byte("A") // addressLogsPrefix + address + section (uint64 big endian) + hash -> blocks with logs of the address
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
 
 	// difflayer database
-	diffLayerPrefix = []byte("d") // diffLayerPrefix + hash  -> diffLayer
+	diffLayerPrefix          = []byte("d") // diffLayerPrefix + hash  -> diffLayer
+	diffLayerSignaturePrefix = []byte("s") // diffLayerSignaturePrefix + hash -> sealer signature of diffLayer
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
-	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	BloomBitsIndexPrefix   = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	AddressLogsIndexPrefix = []byte("iA") // AddressLogsIndexPrefix is the data table of the address log indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -191,6 +200,11 @@ func diffLayerKey(hash common.Hash) []byte {
 	return append(append(diffLayerPrefix, hash.Bytes()...))
 }
 
+// diffLayerSignatureKey = diffLayerSignaturePrefix + hash
+func diffLayerSignatureKey(hash common.Hash) []byte {
+	return append(diffLayerSignaturePrefix, hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -221,6 +235,14 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// addressLogsKey = addressLogsPrefix + address + section (uint64 big endian) + hash
+func addressLogsKey(address common.Address, section uint64, hash common.Hash) []byte {
+	key := append(append(addressLogsPrefix, address.Bytes()...), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(addressLogsPrefix)+common.AddressLength:], section)
+
+	return append(key, hash.Bytes()...)
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
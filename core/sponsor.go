@@ -0,0 +1,168 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// defaultQuotaEpochLength is the length of a quota epoch if the consensus engine
+// has no epochs. It matches the Parlia default.
+const defaultQuotaEpochLength = uint64(100)
+
+// gasSponsorPrefix is the storage key prefix of the sponsorship of an account.
+//
+// Sponsorships are designated by the ChainConfig contract, which keeps them at
+// the slot
+//
+//	keccak256("gasSponsor" ++ account (20 bytes))
+//
+// of its storage, where the account is either the sender or the target contract
+// of the sponsored transactions. The slot holds the sponsor in its lowest 20
+// bytes and the quota as a uint64 in the 8 bytes above, i.e.
+// uint256(quota) << 160 | uint160(sponsor). The preimages are 30 bytes long,
+// so they can't collide with the slots Solidity derives. Reading the slots
+// directly keeps the lookup, done for every transaction, free of any EVM
+// execution that could fail or be costly.
+var gasSponsorPrefix = []byte("gasSponsor")
+
+// gasSponsorUsagePrefix is the storage key prefix of the gas a sponsor paid for
+// in an epoch.
+//
+// The usage is part of the consensus state, kept by the state transition in the
+// storage of the ChainConfig contract at the slot
+//
+//	keccak256("gasSponsorUsage" ++ sponsor (20 bytes) ++ epoch (uint64 big endian))
+//
+// where the epoch is identified by the number of its first block. The slot
+// holds the gas used as a uint256. The preimages are 43 bytes long, so they
+// can't collide with the slots Solidity derives from 32 or 64 byte preimages,
+// and the contract may read but must never write them.
+var gasSponsorUsagePrefix = []byte("gasSponsorUsage")
+
+// errUnknownEpoch is returned if the consensus epoch of a block, which scopes the
+// gas quotas, can't be determined.
+var errUnknownEpoch = errors.New("unknown consensus epoch")
+
+// GasSponsor is an account paying the gas of transactions on behalf of their
+// senders, as designated by the ChainConfig contract.
+type GasSponsor struct {
+	Address common.Address // Account charged for the gas
+	Quota   uint64         // Gas the sponsor pays for per epoch, 0 = unlimited
+}
+
+// ResolveGasSponsor looks up the sponsor paying the gas of a transaction from
+// sender to target (nil for contract creations) in the storage of the ChainConfig
+// contract. Sponsorships of the sender take precedence over the ones of the target.
+// It returns nil if the transaction isn't sponsored.
+func ResolveGasSponsor(statedb vm.StateDB, sender common.Address, target *common.Address) *GasSponsor {
+	sponsor := gasSponsorOf(statedb, sender)
+	if sponsor == nil && target != nil {
+		sponsor = gasSponsorOf(statedb, *target)
+	}
+	if sponsor == nil || sponsor.Address == sender {
+		return nil
+	}
+	return sponsor
+}
+
+// gasSponsorOf returns the sponsorship of the given account, nil if there is none.
+func gasSponsorOf(statedb vm.StateDB, account common.Address) *GasSponsor {
+	value := statedb.GetState(systemcontract.ChainConfigContractAddress, gasSponsorKey(account))
+	sponsor := &GasSponsor{
+		Address: common.BytesToAddress(value[12:]),
+		Quota:   binary.BigEndian.Uint64(value[4:12]),
+	}
+	if sponsor.Address == (common.Address{}) {
+		return nil
+	}
+	return sponsor
+}
+
+// gasSponsorKey returns the storage key of the sponsorship of the given account.
+func gasSponsorKey(account common.Address) common.Hash {
+	return crypto.Keccak256Hash(gasSponsorPrefix, account.Bytes())
+}
+
+// quotaEpoch returns the quota epoch of the block of the given context, which is
+// identified by the number of the block starting it. Gas sponsor and gas-free
+// quotas are reset at the start of every epoch, following the consensus epochs
+// if the chain has any. The quota epochs key consensus state, so a Parlia chain
+// whose epoch block is unknown fails instead of guessing it.
+func quotaEpoch(ctx vm.BlockContext, config *params.ChainConfig) (uint64, error) {
+	if ctx.EpochBlock != nil {
+		return ctx.EpochBlock.Uint64(), nil
+	}
+	if ctx.EpochErr != nil {
+		return 0, ctx.EpochErr
+	}
+	if config.Parlia != nil {
+		return 0, errUnknownEpoch
+	}
+	number := ctx.BlockNumber.Uint64()
+	return number - number%defaultQuotaEpochLength, nil
+}
+
+// gasSponsorUsageKey returns the storage key of the gas the sponsor paid for in
+// the given epoch.
+func gasSponsorUsageKey(sponsor common.Address, epoch uint64) common.Hash {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], epoch)
+	return crypto.Keccak256Hash(gasSponsorUsagePrefix, sponsor.Bytes(), enc[:])
+}
+
+// GasSponsorUsage returns the gas the sponsor paid for in the quota epoch of
+// the block of the given context.
+func GasSponsorUsage(statedb vm.StateDB, ctx vm.BlockContext, config *params.ChainConfig, sponsor common.Address) (uint64, error) {
+	epoch, err := quotaEpoch(ctx, config)
+	if err != nil {
+		return 0, err
+	}
+	return gasSponsorUsage(statedb, epoch, sponsor), nil
+}
+
+// gasSponsorUsage returns the gas the sponsor paid for in the given epoch.
+func gasSponsorUsage(statedb vm.StateDB, epoch uint64, sponsor common.Address) uint64 {
+	key := gasSponsorUsageKey(sponsor, epoch)
+	return statedb.GetState(systemcontract.ChainConfigContractAddress, key).Big().Uint64()
+}
+
+// addGasSponsorUsage charges the given amount of gas to the quota of the sponsor
+// in the given epoch.
+func addGasSponsorUsage(statedb vm.StateDB, epoch uint64, sponsor common.Address, gas uint64) {
+	key := gasSponsorUsageKey(sponsor, epoch)
+	used := statedb.GetState(systemcontract.ChainConfigContractAddress, key).Big().Uint64()
+	statedb.SetState(systemcontract.ChainConfigContractAddress, key, common.BigToHash(new(big.Int).SetUint64(used+gas)))
+}
+
+// sponsorQuotaLeft reports whether the sponsor can pay for another gas amount
+// of gas in the given epoch.
+func sponsorQuotaLeft(statedb vm.StateDB, epoch uint64, sponsor *GasSponsor, gas uint64) bool {
+	if sponsor.Quota == 0 {
+		return true
+	}
+	used := gasSponsorUsage(statedb, epoch, sponsor.Address)
+	return used <= sponsor.Quota && gas <= sponsor.Quota-used
+}
@@ -298,6 +298,12 @@ func (p *BlockPruner) backUpOldDb(name string, cache, handles int, namespace str
 	startBlockNumber := oldOffSet + itemsOfAncient - p.BlockAmountReserved
 	log.Info("new offset/new startBlockNumber is ", "new offset", startBlockNumber)
 
+	// Blocks pruned online from the ancient tail can't be reserved anymore.
+	if tail, err := chainDb.AncientTail(); err == nil && tail > startBlockNumber {
+		log.Error("the blocks to reserve were already pruned online", "ancient tail", tail, "start block number", startBlockNumber)
+		return errors.New("the blocks to reserve were already pruned online")
+	}
+
 	// Create new ancientdb backup and record the new and last version of offset in kvDB as well.
 	// For every round, newoffset actually equals to the startBlockNumber in ancient backup db.
 	frdbBack, err := rawdb.NewFreezerDb(chainDb, p.newAncientPath, namespace, readonly, startBlockNumber)
@@ -267,7 +267,6 @@ func (s *StateDB) SetSnapData(snapDestructs map[common.Address]struct{}, snapAcc
 }
 
 func (s *StateDB) AddLog(l *types.Log) {
-	//s.doRuntimeUpgrade(l)
 	s.journal.append(addLogChange{txhash: s.thash})
 
 	l.TxHash = s.thash
@@ -1627,6 +1626,25 @@ func (s *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addre
 	return s.accessList.Contains(addr, slot)
 }
 
+// TxDirties returns the accounts modified since the last call to Finalise, i.e.
+// by the transaction being executed, along with their modified storage slots.
+func (s *StateDB) TxDirties() map[common.Address][]common.Hash {
+	dirties := make(map[common.Address][]common.Hash, len(s.journal.dirties))
+	for addr := range s.journal.dirties {
+		obj, exist := s.stateObjects[addr]
+		if !exist {
+			// ripeMD might be touched without existing, see Finalise
+			continue
+		}
+		slots := make([]common.Hash, 0, len(obj.dirtyStorage))
+		for key := range obj.dirtyStorage {
+			slots = append(slots, key)
+		}
+		dirties[addr] = slots
+	}
+	return dirties
+}
+
 func (s *StateDB) GetDirtyAccounts() []common.Address {
 	accounts := make([]common.Address, 0, len(s.stateObjectsDirty))
 	for account := range s.stateObjectsDirty {
@@ -492,6 +492,36 @@ func TestTouchDelete(t *testing.T) {
 	}
 }
 
+// TestTxDirties tests that the accounts and slots modified by a transaction are
+// reported until the state is finalised, excluding the reverted ones.
+func TestTxDirties(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		sender   = common.Address{0x01}
+		contract = common.Address{0x02}
+		reverted = common.Address{0x03}
+	)
+	state.SetBalance(sender, big.NewInt(1))
+	state.SetState(contract, common.Hash{0x01}, common.Hash{0x01})
+
+	snapshot := state.Snapshot()
+	state.SetState(reverted, common.Hash{0x02}, common.Hash{0x02})
+	state.RevertToSnapshot(snapshot)
+
+	want := map[common.Address][]common.Hash{
+		sender:   {},
+		contract: {{0x01}},
+	}
+	if have := state.TxDirties(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("dirties mismatch: have %v, want %v", have, want)
+	}
+	state.Finalise(true)
+	if have := state.TxDirties(); len(have) != 0 {
+		t.Fatalf("dirties after finalise: %v", have)
+	}
+}
+
 // TestCopyOfCopy tests that modified objects are carried over to the copy, and the copy of the copy.
 // See https://github.com/ethereum/go-ethereum/pull/15225#issuecomment-380191512
 func TestCopyOfCopy(t *testing.T) {
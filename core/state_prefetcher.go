@@ -77,7 +77,7 @@ func (p *statePrefetcher) Prefetch(block *types.Block, statedb *state.StateDB, c
 					return
 				}
 				// Convert the transaction into an executable message and pre-cache its sender
-				msg, err := tx.AsMessage(signer)
+				msg, err := tx.AsMessage(signer, header.BaseFee)
 				if err != nil {
 					return // Also invalid block, bail out
 				}
@@ -103,6 +103,9 @@ func (p *LightStateProcessor) Process(block *types.Block, statedb *state.StateDB
 			}
 			time.Sleep(time.Millisecond)
 		}
+		if diffLayer != nil && !p.verifyDiffLayer(block, diffLayer) {
+			diffLayer = nil
+		}
 		if diffLayer != nil {
 			if err := diffLayer.Receipts.DeriveFields(p.bc.chainConfig, block.Hash(), block.NumberU64(), block.Transactions()); err != nil {
 				log.Error("Failed to derive block receipts fields", "hash", block.Hash(), "number", block.NumberU64(), "err", err)
@@ -136,6 +139,26 @@ func (p *LightStateProcessor) Process(block *types.Block, statedb *state.StateDB
 	return p.StateProcessor.Process(block, statedb, cfg)
 }
 
+// verifyDiffLayer checks that the diff layer was signed by the sealer of the
+// block. Peers serving layers with invalid signatures are dropped, whereas
+// unsigned layers are only skipped, as older peers do not relay signatures.
+func (p *LightStateProcessor) verifyDiffLayer(block *types.Block, diffLayer *types.DiffLayer) bool {
+	posa, ok := p.engine.(consensus.PoSA)
+	if !ok {
+		return true
+	}
+	if len(diffLayer.Signature) == 0 {
+		log.Debug("Skip unsigned diff layer", "hash", block.Hash(), "number", block.NumberU64())
+		return false
+	}
+	if err := posa.VerifyDiffLayer(p.bc, block.Header(), diffLayer.DiffHash, diffLayer.Signature); err != nil {
+		log.Warn("Invalid diff layer signature", "hash", block.Hash(), "number", block.NumberU64(), "diffHash", diffLayer.DiffHash, "err", err)
+		p.bc.removeDiffLayers(diffLayer.DiffHash)
+		return false
+	}
+	return true
+}
+
 func (p *LightStateProcessor) LightProcess(diffLayer *types.DiffLayer, block *types.Block, statedb *state.StateDB) (types.Receipts, []*types.Log, uint64, error) {
 	statedb.MarkLightProcessed()
 	fullDiffCode := make(map[common.Hash][]byte, len(diffLayer.Codes))
@@ -410,7 +433,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 			}
 		}
 
-		msg, err := tx.AsMessage(signer)
+		msg, err := tx.AsMessage(signer, header.BaseFee)
 		if err != nil {
 			return statedb, nil, nil, 0, err
 		}
@@ -467,6 +490,7 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 	}
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = result.UsedGas
+	receipt.GasPayer = result.GasPayer
 
 	// If the transaction created a contract, store the creation address in the receipt.
 	if msg.To() == nil {
@@ -489,7 +513,7 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
 func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config, receiptProcessors ...ReceiptProcessor) (*types.Receipt, error) {
-	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number))
+	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number), header.BaseFee)
 	if err != nil {
 		return nil, err
 	}
@@ -17,13 +17,22 @@
 package core
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -111,6 +120,327 @@ func TestStateProcessorErrors(t *testing.T) {
 	}
 }
 
+// TestStateProcessorRuntimeUpgrade tests that a runtime upgrade hook log emitted by
+// the RuntimeUpgrade contract replaces the code of the targeted system contract
+// once the RuntimeUpgrade fork is active, and is ignored before that.
+func TestStateProcessorRuntimeUpgrade(t *testing.T) {
+	var (
+		signer     = types.HomesteadSigner{}
+		testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		testAddr   = crypto.PubkeyToAddress(testKey.PublicKey)
+		target     = common.HexToAddress(systemcontract.StakingPoolContract)
+		oldCode    = hexutil.MustDecode("0x00")
+		newCode    = hexutil.MustDecode("0x600160005500") // SSTORE(0, 1)
+		db         = rawdb.NewMemoryDatabase()
+		config     = *params.TestChainConfig
+	)
+	config.RuntimeUpgradeBlock = big.NewInt(2)
+
+	// LOG2(0, CALLDATASIZE, hook, target) after copying the calldata to memory
+	upgradeCode := hexutil.MustDecode("0x366000600037")
+	upgradeCode = append(upgradeCode, byte(vm.PUSH20))
+	upgradeCode = append(upgradeCode, target.Bytes()...)
+	upgradeCode = append(upgradeCode, byte(vm.PUSH20))
+	upgradeCode = append(upgradeCode, systemcontract.EvmHookRuntimeUpgradeAddress.Bytes()...)
+	upgradeCode = append(upgradeCode, hexutil.MustDecode("0x366000a200")...)
+
+	bytesTy, _ := abi.NewType("bytes", "", nil)
+	input, err := abi.Arguments{{Type: bytesTy}, {Type: bytesTy}}.Pack(newCode, []byte{0x01})
+	if err != nil {
+		t.Fatalf("failed to pack upgrade request: %v", err)
+	}
+	gspec := &Genesis{
+		Config: &config,
+		Alloc: GenesisAlloc{
+			testAddr: {Balance: big.NewInt(params.Ether)},
+			systemcontract.RuntimeUpgradeContractAddress: {Code: upgradeCode, Balance: common.Big0},
+			target: {Code: oldCode, Balance: common.Big0},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, receipts := GenerateChain(&config, genesis, ethash.NewFaker(), db, 2, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testAddr), systemcontract.RuntimeUpgradeContractAddress, common.Big0, 200000, common.Big1, input), signer, testKey)
+		b.AddTx(tx)
+	})
+	for i, r := range receipts {
+		if len(r) != 1 || r[0].Status != types.ReceiptStatusSuccessful {
+			t.Fatalf("block %d: upgrade transaction failed", i+1)
+		}
+	}
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for i, want := range []struct {
+		code  []byte
+		state common.Hash
+	}{
+		{oldCode, common.Hash{}},
+		{newCode, common.BigToHash(common.Big1)},
+	} {
+		statedb, err := blockchain.StateAt(blocks[i].Root())
+		if err != nil {
+			t.Fatalf("block %d: failed to open state: %v", i+1, err)
+		}
+		if code := statedb.GetCode(target); !bytes.Equal(code, want.code) {
+			t.Errorf("block %d: code mismatch: have %x, want %x", i+1, code, want.code)
+		}
+		if val := statedb.GetState(target, common.Hash{}); val != want.state {
+			t.Errorf("block %d: storage mismatch: have %x, want %x", i+1, val, want.state)
+		}
+	}
+}
+
+// Tests that after London the fees of a transaction end up where the chain's
+// base fee policy says: only the tip is paid to the block producer unless a
+// Parlia chain distributes the base fee to its validators as well.
+func TestStateTransitionBaseFeePolicy(t *testing.T) {
+	var (
+		testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		testAddr   = crypto.PubkeyToAddress(testKey.PublicKey)
+		coinbase   = common.HexToAddress("0xc0ffee")
+		baseFee    = big.NewInt(params.InitialBaseFee)
+		tip        = big.NewInt(2 * params.GWei)
+	)
+	for i, tt := range []struct {
+		parlia    *params.ParliaConfig
+		recipient common.Address
+		fee       *big.Int // expected fee per gas credited to the recipient
+	}{
+		{nil, coinbase, tip},
+		{&params.ParliaConfig{}, consensus.SystemAddress, tip},
+		{&params.ParliaConfig{BaseFeePolicy: params.BaseFeePolicyBurn}, consensus.SystemAddress, tip},
+		{&params.ParliaConfig{BaseFeePolicy: params.BaseFeePolicyDistribute}, consensus.SystemAddress, new(big.Int).Add(tip, baseFee)},
+	} {
+		config := *params.TestChainConfig
+		config.LondonBlock = big.NewInt(0)
+		config.Parlia = tt.parlia
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.AddBalance(testAddr, big.NewInt(params.Ether))
+
+		signer := types.LatestSigner(&config)
+		tx := types.MustSignNewTx(testKey, signer, &types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			To:        &common.Address{},
+			Gas:       params.TxGas,
+			GasFeeCap: big.NewInt(10 * params.GWei),
+			GasTipCap: tip,
+		})
+		msg, err := tx.AsMessage(signer, baseFee)
+		if err != nil {
+			t.Fatalf("test %d: failed to create message: %v", i, err)
+		}
+		blockContext := vm.BlockContext{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			Coinbase:    coinbase,
+			BlockNumber: big.NewInt(1),
+			Time:        big.NewInt(0),
+			Difficulty:  big.NewInt(0),
+			GasLimit:    params.GenesisGasLimit,
+			BaseFee:     baseFee,
+		}
+		evm := vm.NewEVM(blockContext, NewEVMTxContext(msg), statedb, &config, vm.Config{})
+		result, err := ApplyMessage(evm, msg, new(GasPool).AddGas(params.GenesisGasLimit))
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		want := new(big.Int).Mul(tt.fee, new(big.Int).SetUint64(result.UsedGas))
+		if have := statedb.GetBalance(tt.recipient); have.Cmp(want) != 0 {
+			t.Errorf("test %d: fee mismatch: have %v, want %v", i, have, want)
+		}
+		paid := new(big.Int).Mul(new(big.Int).Add(tip, baseFee), new(big.Int).SetUint64(result.UsedGas))
+		if have, want := statedb.GetBalance(testAddr), new(big.Int).Sub(big.NewInt(params.Ether), paid); have.Cmp(want) != 0 {
+			t.Errorf("test %d: sender balance mismatch: have %v, want %v", i, have, want)
+		}
+	}
+}
+
+// setGasSponsor designates the given sponsor with the given quota for the
+// transactions of the account in the storage of the ChainConfig contract.
+func setGasSponsor(statedb *state.StateDB, account common.Address, sponsor common.Address, quota uint64) {
+	var value common.Hash
+	binary.BigEndian.PutUint64(value[4:12], quota)
+	copy(value[12:], sponsor.Bytes())
+	statedb.SetState(systemcontract.ChainConfigContractAddress, gasSponsorKey(account), value)
+}
+
+// Tests that after the GasSponsor fork the gas of sponsored transactions is paid
+// by the sponsor designated by the ChainConfig contract within its quota.
+func TestStateTransitionGasSponsor(t *testing.T) {
+	var (
+		testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		testAddr   = crypto.PubkeyToAddress(testKey.PublicKey)
+		sponsor    = common.HexToAddress("0x5905502")
+		gasPrice   = big.NewInt(params.GWei)
+		funds      = big.NewInt(params.Ether)
+	)
+	for i, tt := range []struct {
+		fork    *big.Int
+		account common.Address // sender or target the sponsorship is designated for
+		quota   uint64
+		err     error
+	}{
+		{nil, testAddr, 0, ErrInsufficientFunds},
+		{big.NewInt(0), testAddr, 0, nil},
+		{big.NewInt(0), common.Address{0x01}, 0, nil},
+		{big.NewInt(0), common.Address{0x02}, 0, ErrInsufficientFunds},
+		{big.NewInt(0), testAddr, 2 * params.TxGas, nil},
+		{big.NewInt(0), testAddr, params.TxGas - 1, ErrSponsorQuotaExceeded},
+	} {
+		config := *params.TestChainConfig
+		config.GasSponsorBlock = tt.fork
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		setGasSponsor(statedb, tt.account, sponsor, tt.quota)
+		statedb.AddBalance(sponsor, funds)
+
+		signer := types.LatestSigner(&config)
+		tx, _ := types.SignTx(types.NewTransaction(0, common.Address{0x01}, new(big.Int), params.TxGas, gasPrice, nil), signer, testKey)
+		msg, err := tx.AsMessage(signer, nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to create message: %v", i, err)
+		}
+		blockContext := vm.BlockContext{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			BlockNumber: big.NewInt(1),
+			Time:        big.NewInt(0),
+			Difficulty:  big.NewInt(0),
+			GasLimit:    params.GenesisGasLimit,
+		}
+		evm := vm.NewEVM(blockContext, NewEVMTxContext(msg), statedb, &config, vm.Config{})
+		result, err := ApplyMessage(evm, msg, new(GasPool).AddGas(params.GenesisGasLimit))
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if err != nil {
+			continue
+		}
+		if result.UsedGas != params.TxGas {
+			t.Errorf("test %d: used gas mismatch: have %d, want %d", i, result.UsedGas, params.TxGas)
+		}
+		if result.GasPayer == nil || *result.GasPayer != sponsor {
+			t.Errorf("test %d: gas payer mismatch: have %v, want %v", i, result.GasPayer, sponsor)
+		}
+		if have := statedb.GetBalance(testAddr); have.Sign() != 0 {
+			t.Errorf("test %d: sender balance changed: have %v", i, have)
+		}
+		paid := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(result.UsedGas))
+		if have, want := statedb.GetBalance(sponsor), new(big.Int).Sub(funds, paid); have.Cmp(want) != 0 {
+			t.Errorf("test %d: sponsor balance mismatch: have %v, want %v", i, have, want)
+		}
+		if have, err := GasSponsorUsage(statedb, blockContext, &config, sponsor); err != nil || have != result.UsedGas {
+			t.Errorf("test %d: sponsor usage mismatch: have %d (%v), want %d", i, have, err, result.UsedGas)
+		}
+	}
+}
+
+// Tests that the quota epoch follows the consensus epoch and is never guessed
+// on chains with epochs, as it keys consensus state.
+func TestQuotaEpoch(t *testing.T) {
+	parlia := *params.TestChainConfig
+	parlia.Parlia = &params.ParliaConfig{Epoch: 200}
+
+	failure := fmt.Errorf("%w: missing headers", errUnknownEpoch)
+	for i, tt := range []struct {
+		config *params.ChainConfig
+		number int64
+		epoch  *big.Int
+		err    error
+		want   uint64
+	}{
+		// chains without epochs use fixed length quota epochs
+		{params.TestChainConfig, 250, nil, nil, 200},
+		// the consensus epoch is used if known
+		{&parlia, 250, big.NewInt(240), nil, 240},
+		// failures to find the consensus epoch are reported
+		{&parlia, 250, nil, failure, 0},
+		// chains with epochs never fall back to fixed length epochs
+		{&parlia, 250, nil, nil, 0},
+	} {
+		ctx := vm.BlockContext{BlockNumber: big.NewInt(tt.number), EpochBlock: tt.epoch, EpochErr: tt.err}
+		epoch, err := quotaEpoch(ctx, tt.config)
+		if tt.want == 0 {
+			if !errors.Is(err, errUnknownEpoch) {
+				t.Errorf("test %d: error mismatch: have %v, want %v", i, err, errUnknownEpoch)
+			}
+			continue
+		}
+		if err != nil || epoch != tt.want {
+			t.Errorf("test %d: epoch mismatch: have %d (%v), want %d", i, epoch, err, tt.want)
+		}
+	}
+}
+
+// Tests that after London sponsored transactions pay the base fee like any other
+// transaction: the sponsor pays it, but the fee cap of the sender has to cover it.
+func TestStateTransitionGasSponsorBaseFee(t *testing.T) {
+	var (
+		testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sponsor    = common.HexToAddress("0x5905502")
+		baseFee    = big.NewInt(params.InitialBaseFee)
+		tip        = big.NewInt(params.GWei)
+		funds      = big.NewInt(params.Ether)
+	)
+	config := *params.TestChainConfig
+	config.LondonBlock = big.NewInt(0)
+	config.GasSponsorBlock = big.NewInt(0)
+
+	for i, tt := range []struct {
+		feeCap *big.Int
+		tipCap *big.Int
+		err    error
+	}{
+		{new(big.Int), new(big.Int), ErrFeeCapTooLow},
+		{new(big.Int).Sub(baseFee, common.Big1), new(big.Int), ErrFeeCapTooLow},
+		{baseFee, new(big.Int), nil},
+		{new(big.Int).Mul(baseFee, common.Big2), tip, nil},
+	} {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		setGasSponsor(statedb, crypto.PubkeyToAddress(testKey.PublicKey), sponsor, 0)
+		statedb.AddBalance(sponsor, funds)
+
+		signer := types.LatestSigner(&config)
+		tx := types.MustSignNewTx(testKey, signer, &types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			To:        &common.Address{0x01},
+			Gas:       params.TxGas + 1000,
+			GasFeeCap: tt.feeCap,
+			GasTipCap: tt.tipCap,
+		})
+		msg, err := tx.AsMessage(signer, baseFee)
+		if err != nil {
+			t.Fatalf("test %d: failed to create message: %v", i, err)
+		}
+		blockContext := vm.BlockContext{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			BlockNumber: big.NewInt(1),
+			Time:        big.NewInt(0),
+			Difficulty:  big.NewInt(0),
+			GasLimit:    params.GenesisGasLimit,
+			BaseFee:     baseFee,
+		}
+		evm := vm.NewEVM(blockContext, NewEVMTxContext(msg), statedb, &config, vm.Config{})
+		result, err := ApplyMessage(evm, msg, new(GasPool).AddGas(params.GenesisGasLimit))
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if err != nil {
+			continue
+		}
+		price := math.BigMin(new(big.Int).Add(tt.tipCap, baseFee), tt.feeCap)
+		paid := new(big.Int).Mul(price, new(big.Int).SetUint64(result.UsedGas))
+		if have, want := statedb.GetBalance(sponsor), new(big.Int).Sub(funds, paid); have.Cmp(want) != 0 {
+			t.Errorf("test %d: sponsor balance mismatch: have %v, want %v", i, have, want)
+		}
+	}
+}
+
 // GenerateBadBlock constructs a "block" which contains the transactions. The transactions are not expected to be
 // valid, and no proper post-state can be made. But from the perspective of the blockchain, the block is sufficiently
 // valid to be considered for import:
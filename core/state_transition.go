@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	cmath "github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -50,11 +51,15 @@ type StateTransition struct {
 	msg        Message
 	gas        uint64
 	gasPrice   *big.Int
+	gasFeeCap  *big.Int
+	gasTipCap  *big.Int
 	initialGas uint64
 	value      *big.Int
 	data       []byte
 	state      vm.StateDB
 	evm        *vm.EVM
+	sponsor    *GasSponsor // Sponsor paying for the gas, nil if paid by the sender
+	quotaEpoch uint64      // Quota epoch the gas of the sponsor is accounted in
 }
 
 // Message represents a message sent to a contract.
@@ -63,6 +68,8 @@ type Message interface {
 	To() *common.Address
 
 	GasPrice() *big.Int
+	GasFeeCap() *big.Int
+	GasTipCap() *big.Int
 	Gas() uint64
 	Value() *big.Int
 
@@ -75,9 +82,10 @@ type Message interface {
 // ExecutionResult includes all output after executing given evm
 // message no matter the execution itself is successful or not.
 type ExecutionResult struct {
-	UsedGas    uint64 // Total used gas but include the refunded gas
-	Err        error  // Any error encountered during the execution(listed in core/vm/errors.go)
-	ReturnData []byte // Returned data from evm(function result or data supplied with revert opcode)
+	UsedGas    uint64          // Total used gas but include the refunded gas
+	Err        error           // Any error encountered during the execution(listed in core/vm/errors.go)
+	ReturnData []byte          // Returned data from evm(function result or data supplied with revert opcode)
+	GasPayer   *common.Address // Sponsor who paid for the gas, nil if paid by the sender
 }
 
 // Unwrap returns the internal evm error which allows us for further
@@ -151,13 +159,15 @@ func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation b
 // NewStateTransition initialises and returns a new state transition object.
 func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition {
 	return &StateTransition{
-		gp:       gp,
-		evm:      evm,
-		msg:      msg,
-		gasPrice: msg.GasPrice(),
-		value:    msg.Value(),
-		data:     msg.Data(),
-		state:    evm.StateDB,
+		gp:        gp,
+		evm:       evm,
+		msg:       msg,
+		gasPrice:  msg.GasPrice(),
+		gasFeeCap: msg.GasFeeCap(),
+		gasTipCap: msg.GasTipCap(),
+		value:     msg.Value(),
+		data:      msg.Data(),
+		state:     evm.StateDB,
 	}
 }
 
@@ -180,9 +190,40 @@ func (st *StateTransition) to() common.Address {
 	return *st.msg.To()
 }
 
+// gasPayer returns the account paying for the gas of the message.
+func (st *StateTransition) gasPayer() common.Address {
+	if st.sponsor != nil {
+		return st.sponsor.Address
+	}
+	return st.msg.From()
+}
+
 func (st *StateTransition) buyGas() error {
+	if st.evm.ChainConfig().IsGasSponsor(st.evm.Context.BlockNumber) {
+		st.sponsor = ResolveGasSponsor(st.state, st.msg.From(), st.msg.To())
+	}
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
-	if have, want := st.state.GetBalance(st.msg.From()), mgval; have.Cmp(want) < 0 {
+	balanceCheck := mgval
+	if st.gasFeeCap != nil && st.evm.ChainConfig().IsLondon(st.evm.Context.BlockNumber) {
+		// The payer must be able to pay the fee cap, not just the effective price.
+		balanceCheck = new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasFeeCap)
+		if st.sponsor == nil {
+			balanceCheck.Add(balanceCheck, st.value)
+		}
+	}
+	if st.sponsor != nil {
+		if have, want := st.state.GetBalance(st.sponsor.Address), balanceCheck; have.Cmp(want) < 0 {
+			return fmt.Errorf("%w: sponsor %v have %v want %v", ErrInsufficientSponsorFunds, st.sponsor.Address.Hex(), have, want)
+		}
+		epoch, err := quotaEpoch(st.evm.Context, st.evm.ChainConfig())
+		if err != nil {
+			return err
+		}
+		st.quotaEpoch = epoch
+		if !sponsorQuotaLeft(st.state, st.quotaEpoch, st.sponsor, st.msg.Gas()) {
+			return fmt.Errorf("%w: sponsor %v quota %d", ErrSponsorQuotaExceeded, st.sponsor.Address.Hex(), st.sponsor.Quota)
+		}
+	} else if have, want := st.state.GetBalance(st.msg.From()), balanceCheck; have.Cmp(want) < 0 {
 		return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, st.msg.From().Hex(), have, want)
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
@@ -191,7 +232,7 @@ func (st *StateTransition) buyGas() error {
 	st.gas += st.msg.Gas()
 
 	st.initialGas = st.msg.Gas()
-	st.state.SubBalance(st.msg.From(), mgval)
+	st.state.SubBalance(st.gasPayer(), mgval)
 	return nil
 }
 
@@ -207,6 +248,32 @@ func (st *StateTransition) preCheck() error {
 				st.msg.From().Hex(), msgNonce, stNonce)
 		}
 	}
+	// Make sure that transaction gasFeeCap is greater than the baseFee (post london).
+	// There is no exemption for gas-free or sponsored transactions: the sponsor
+	// pays the base fee on behalf of the sender, but the fee cap has to cover it.
+	if st.evm.ChainConfig().IsLondon(st.evm.Context.BlockNumber) {
+		// Skip the checks if gas fields are zero and baseFee was explicitly disabled (eth_call)
+		if !st.evm.Config().NoBaseFee || st.gasFeeCap.BitLen() > 0 || st.gasTipCap.BitLen() > 0 {
+			if l := st.gasFeeCap.BitLen(); l > 256 {
+				return fmt.Errorf("%w: address %v, maxFeePerGas bit length: %d", ErrFeeCapVeryHigh,
+					st.msg.From().Hex(), l)
+			}
+			if l := st.gasTipCap.BitLen(); l > 256 {
+				return fmt.Errorf("%w: address %v, maxPriorityFeePerGas bit length: %d", ErrTipVeryHigh,
+					st.msg.From().Hex(), l)
+			}
+			if st.gasFeeCap.Cmp(st.gasTipCap) < 0 {
+				return fmt.Errorf("%w: address %v, maxPriorityFeePerGas: %s, maxFeePerGas: %s", ErrTipAboveFeeCap,
+					st.msg.From().Hex(), st.gasTipCap, st.gasFeeCap)
+			}
+			// This will panic if baseFee is nil, but basefee presence is verified
+			// as part of header validation.
+			if st.gasFeeCap.Cmp(st.evm.Context.BaseFee) < 0 {
+				return fmt.Errorf("%w: address %v, maxFeePerGas: %s baseFee: %s", ErrFeeCapTooLow,
+					st.msg.From().Hex(), st.gasFeeCap, st.evm.Context.BaseFee)
+			}
+		}
+	}
 	return st.buyGas()
 }
 
@@ -275,19 +342,37 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 		ret, st.gas, vmerr = st.evm.Call(sender, st.to(), st.data, st.gas, st.value)
 	}
 	st.refundGas()
+	if st.sponsor != nil {
+		addGasSponsorUsage(st.state, st.quotaEpoch, st.sponsor.Address, st.gasUsed())
+	}
 
-	// consensus engine is parlia
-	if st.evm.ChainConfig().Parlia != nil {
-		st.state.AddBalance(consensus.SystemAddress, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
-	} else {
-		st.state.AddBalance(st.evm.Context.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
+	// After London only the tip is paid to the block producer, the base fee is
+	// burnt unless the chain is configured to distribute it to the validators.
+	fee := st.gasPrice
+	if st.evm.ChainConfig().IsLondon(st.evm.Context.BlockNumber) && !st.evm.ChainConfig().IsBaseFeeDistributed() {
+		fee = cmath.BigMin(st.gasTipCap, new(big.Int).Sub(st.gasFeeCap, st.evm.Context.BaseFee))
+	}
+	switch {
+	case st.evm.Config().NoBaseFee && st.gasFeeCap.Sign() == 0 && st.gasTipCap.Sign() == 0:
+		// Skip fee payment when NoBaseFee is set and the fee fields are 0. This
+		// avoids a negative effective tip being applied when simulating calls.
+	case st.evm.ChainConfig().Parlia != nil:
+		// consensus engine is parlia
+		st.state.AddBalance(consensus.SystemAddress, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), fee))
+	default:
+		st.state.AddBalance(st.evm.Context.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), fee))
 	}
 
-	return &ExecutionResult{
+	result := &ExecutionResult{
 		UsedGas:    st.gasUsed(),
 		Err:        vmerr,
 		ReturnData: ret,
-	}, nil
+	}
+	if st.sponsor != nil {
+		payer := st.sponsor.Address
+		result.GasPayer = &payer
+	}
+	return result, nil
 }
 
 func (st *StateTransition) refundGas() {
@@ -300,7 +385,7 @@ func (st *StateTransition) refundGas() {
 
 	// Return ETH for remaining gas, exchanged at the original rate.
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
-	st.state.AddBalance(st.msg.From(), remaining)
+	st.state.AddBalance(st.gasPayer(), remaining)
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
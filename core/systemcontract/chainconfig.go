@@ -0,0 +1,282 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package systemcontract implements typed access to the state of the BAS system
+// contracts.
+package systemcontract
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// paramsCacheLimit is the number of blocks whose chain config parameters are
+	// kept in memory.
+	paramsCacheLimit = 256
+
+	// callGas is the gas allowance of every getter invocation. It is not charged
+	// to anyone, the calls are executed on a throwaway view of the state.
+	callGas = uint64(math.MaxUint64 / 2)
+)
+
+// errUnknownBlock is returned when the parameters of a block are requested that
+// is not part of the local chain.
+var errUnknownBlock = errors.New("unknown block")
+
+// relevantEmitters are the contracts whose logs signal that the parameters of
+// the ChainConfig contract may have changed within a block: the contract itself
+// emits an event on every setter and the RuntimeUpgrade contract may replace
+// its bytecode.
+var relevantEmitters = map[common.Address]bool{
+	systemcontract.ChainConfigContractAddress:    true,
+	systemcontract.RuntimeUpgradeContractAddress: true,
+}
+
+// ChainContext is the subset of the blockchain the ChainConfigReader needs to
+// execute the ChainConfig contract at a given block.
+type ChainContext interface {
+	core.ChainContext
+
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// GetHeaderByHash retrieves a block header from the database by its hash.
+	GetHeaderByHash(hash common.Hash) *types.Header
+
+	// GetReceiptsByHash retrieves the receipts for all transactions in a given block.
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+
+	// StateAt returns a new mutable state based on a particular point in time.
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// ChainConfigParams is the set of governance controlled parameters stored in the
+// ChainConfig system contract at a given block. Getters not implemented by the
+// deployed version of the contract leave their field at the zero value.
+type ChainConfigParams struct {
+	ActiveValidatorsLength   uint32           `json:"activeValidatorsLength"`
+	EpochBlockInterval       uint32           `json:"epochBlockInterval"`
+	MisdemeanorThreshold     uint32           `json:"misdemeanorThreshold"`
+	FelonyThreshold          uint32           `json:"felonyThreshold"`
+	ValidatorJailEpochLength uint32           `json:"validatorJailEpochLength"`
+	UndelegatePeriod         uint32           `json:"undelegatePeriod"`
+	MinValidatorStakeAmount  *big.Int         `json:"minValidatorStakeAmount"`
+	MinStakingAmount         *big.Int         `json:"minStakingAmount"`
+	GasPrice                 *big.Int         `json:"gasPrice"`
+	FreeGasAddressList       []common.Address `json:"freeGasAddressList"`
+	BlockPeriod              uint32           `json:"blockPeriod"`
+	GasFreeLaneShare         uint32           `json:"gasFreeLaneShare"`
+	GasFreeContractLaneShare uint32           `json:"gasFreeContractLaneShare"`
+}
+
+// FreeGasAddressMap returns the free gas addresses indexed by address, mapping
+// every address to its 1-based position in the list.
+func (p *ChainConfigParams) FreeGasAddressMap() map[common.Address]uint {
+	addresses := make(map[common.Address]uint, len(p.FreeGasAddressList))
+	for i, address := range p.FreeGasAddressList {
+		addresses[address] = uint(i) + 1
+	}
+	return addresses
+}
+
+// ChainConfigReader reads the parameters of the ChainConfig system contract by
+// executing its getters directly against the state of a block. Results are
+// cached per block hash and carried over from the parent as long as a block
+// emits no log that may have changed them.
+//
+// The returned parameters are shared between callers and must not be modified.
+type ChainConfigReader struct {
+	chain ChainContext
+	abi   abi.ABI
+	cache *lru.Cache // Parameters of recent blocks, block hash -> *ChainConfigParams
+}
+
+// ChainConfigABI returns the parsed ABI of the ChainConfig contract, for callers
+// interacting with it through transactions or RPC calls instead of the state.
+func ChainConfigABI() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(chainConfigABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// NewChainConfigReader creates a reader for the ChainConfig contract of the
+// given chain.
+func NewChainConfigReader(chain ChainContext) *ChainConfigReader {
+	cache, _ := lru.New(paramsCacheLimit)
+	return &ChainConfigReader{
+		chain: chain,
+		abi:   ChainConfigABI(),
+		cache: cache,
+	}
+}
+
+// Params returns the chain config parameters in force after the given block.
+func (r *ChainConfigReader) Params(header *types.Header) (*ChainConfigParams, error) {
+	hash := header.Hash()
+	if cached, ok := r.cache.Get(hash); ok {
+		return cached.(*ChainConfigParams), nil
+	}
+	if cached, ok := r.cache.Get(header.ParentHash); ok && !r.modified(header) {
+		r.cache.Add(hash, cached)
+		return cached.(*ChainConfigParams), nil
+	}
+	statedb, err := r.chain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := r.ReadParams(header, statedb)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.Add(hash, cfg)
+	return cfg, nil
+}
+
+// ParamsAt reads the chain config parameters in force after the given block
+// from its state, bypassing the cache. The cache carries the parameters over
+// blocks emitting no relevant logs, so consensus code must read them this way.
+func (r *ChainConfigReader) ParamsAt(header *types.Header) (*ChainConfigParams, error) {
+	statedb, err := r.chain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	return r.ReadParams(header, statedb)
+}
+
+// ParamsByHash returns the chain config parameters in force after the block
+// with the given hash.
+func (r *ChainConfigReader) ParamsByHash(hash common.Hash) (*ChainConfigParams, error) {
+	header := r.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, fmt.Errorf("%w: %x", errUnknownBlock, hash)
+	}
+	return r.Params(header)
+}
+
+// GasPrice returns the minimum gas price set in the ChainConfig contract after
+// the block with the given hash.
+func (r *ChainConfigReader) GasPrice(hash common.Hash) (*big.Int, error) {
+	cfg, err := r.ParamsByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.GasPrice, nil
+}
+
+// FreeGasAddressMap returns the free gas addresses set in the ChainConfig
+// contract after the block with the given hash.
+func (r *ChainConfigReader) FreeGasAddressMap(hash common.Hash) (map[common.Address]uint, error) {
+	cfg, err := r.ParamsByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.FreeGasAddressMap(), nil
+}
+
+// ReadParams executes the getters of the ChainConfig contract against the given
+// state without consulting the cache, which makes it usable on the intermediate
+// state of a block being processed. The header provides the block context of
+// the calls. Any change the calls make to the state is reverted.
+func (r *ChainConfigReader) ReadParams(header *types.Header, statedb *state.StateDB) (*ChainConfigParams, error) {
+	cfg := new(ChainConfigParams)
+	if statedb.GetCodeSize(systemcontract.ChainConfigContractAddress) == 0 {
+		return cfg, nil
+	}
+	snapshot := statedb.Snapshot()
+	defer statedb.RevertToSnapshot(snapshot)
+
+	context := core.NewEVMBlockContext(header, r.chain, &header.Coinbase)
+	evm := vm.NewEVM(context, vm.TxContext{GasPrice: new(big.Int)}, statedb, r.chain.Config(), vm.Config{NoBaseFee: true})
+
+	getters := []struct {
+		method string
+		out    interface{}
+	}{
+		{"getActiveValidatorsLength", &cfg.ActiveValidatorsLength},
+		{"getEpochBlockInterval", &cfg.EpochBlockInterval},
+		{"getMisdemeanorThreshold", &cfg.MisdemeanorThreshold},
+		{"getFelonyThreshold", &cfg.FelonyThreshold},
+		{"getValidatorJailEpochLength", &cfg.ValidatorJailEpochLength},
+		{"getUndelegatePeriod", &cfg.UndelegatePeriod},
+		{"getMinValidatorStakeAmount", &cfg.MinValidatorStakeAmount},
+		{"getMinStakingAmount", &cfg.MinStakingAmount},
+		{"getGasPrice", &cfg.GasPrice},
+		{"getFreeGasAddressList", &cfg.FreeGasAddressList},
+		{"getBlockPeriod", &cfg.BlockPeriod},
+		{"getGasFreeLaneShare", &cfg.GasFreeLaneShare},
+		{"getGasFreeContractLaneShare", &cfg.GasFreeContractLaneShare},
+	}
+	for _, getter := range getters {
+		if err := r.call(evm, getter.method, getter.out); err != nil {
+			return nil, fmt.Errorf("failed to call %s: %w", getter.method, err)
+		}
+	}
+	return cfg, nil
+}
+
+// call invokes a getter of the ChainConfig contract and unpacks its result into
+// out. Reverting getters are treated as not implemented and leave out untouched.
+func (r *ChainConfigReader) call(evm *vm.EVM, method string, out interface{}) error {
+	data, err := r.abi.Pack(method)
+	if err != nil {
+		return err
+	}
+	ret, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), systemcontract.ChainConfigContractAddress, data, callGas)
+	if errors.Is(err, vm.ErrExecutionReverted) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return r.abi.UnpackIntoInterface(out, method, ret)
+}
+
+// modified reports whether the given block may have changed the parameters of
+// the ChainConfig contract. Blocks whose receipts are not available locally are
+// conservatively reported as modifying.
+func (r *ChainConfigReader) modified(header *types.Header) bool {
+	if header.TxHash == types.EmptyRootHash {
+		return false
+	}
+	receipts := r.chain.GetReceiptsByHash(header.Hash())
+	if receipts == nil {
+		return true
+	}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if relevantEmitters[log.Address] {
+				return true
+			}
+		}
+	}
+	return false
+}
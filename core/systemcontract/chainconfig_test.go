@@ -0,0 +1,317 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package systemcontract
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testFreeGas = []common.Address{common.HexToAddress("0xf1"), common.HexToAddress("0xf2")}
+)
+
+// chainConfigCode assembles a minimal ChainConfig contract. The gas price is kept
+// in storage slot 0 and can be changed with setGasPrice, which emits the regular
+// GasPriceChanged event. The epoch length, the validator count and the free gas
+// list are constants, every other getter reverts.
+func chainConfigCode(t *testing.T, parsed abi.ABI) []byte {
+	selector := func(method string) string {
+		return fmt.Sprintf("0x%x", parsed.Methods[method].ID)
+	}
+	word := func(value common.Hash) string {
+		return fmt.Sprintf("0x%x", value)
+	}
+	source := fmt.Sprintf(`
+	PUSH 0
+	CALLDATALOAD
+	PUSH 0xe0
+	SHR
+	DUP1
+	PUSH %s
+	EQ
+	JUMPI @getGasPrice
+	DUP1
+	PUSH %s
+	EQ
+	JUMPI @setGasPrice
+	DUP1
+	PUSH %s
+	EQ
+	JUMPI @getEpochBlockInterval
+	DUP1
+	PUSH %s
+	EQ
+	JUMPI @getActiveValidatorsLength
+	DUP1
+	PUSH %s
+	EQ
+	JUMPI @getFreeGasAddressList
+	PUSH 0
+	DUP1
+	REVERT
+getGasPrice:
+	PUSH 0
+	SLOAD
+	PUSH 0
+	MSTORE
+	PUSH 32
+	PUSH 0
+	RETURN
+setGasPrice:
+	PUSH 4
+	CALLDATALOAD
+	PUSH 0
+	SSTORE
+	PUSH %s
+	PUSH 0
+	DUP1
+	LOG1
+	STOP
+getEpochBlockInterval:
+	PUSH 200
+	PUSH 0
+	MSTORE
+	PUSH 32
+	PUSH 0
+	RETURN
+getActiveValidatorsLength:
+	PUSH 21
+	PUSH 0
+	MSTORE
+	PUSH 32
+	PUSH 0
+	RETURN
+getFreeGasAddressList:
+	PUSH 32
+	PUSH 0
+	MSTORE
+	PUSH 2
+	PUSH 32
+	MSTORE
+	PUSH %s
+	PUSH 64
+	MSTORE
+	PUSH %s
+	PUSH 96
+	MSTORE
+	PUSH 128
+	PUSH 0
+	RETURN
+`,
+		selector("getGasPrice"), selector("setGasPrice"), selector("getEpochBlockInterval"),
+		selector("getActiveValidatorsLength"), selector("getFreeGasAddressList"),
+		word(parsed.Events["GasPriceChanged"].ID),
+		word(testFreeGas[0].Hash()), word(testFreeGas[1].Hash()),
+	)
+	compiler := asm.NewCompiler(false)
+	compiler.Feed(asm.Lex([]byte(source), false))
+	code, errs := compiler.Compile()
+	if len(errs) != 0 {
+		t.Fatalf("failed to assemble chain config contract: %v", errs)
+	}
+	return common.FromHex(code)
+}
+
+// newTestChain creates a chain whose genesis deploys the test ChainConfig
+// contract and imports the blocks produced by gen on top of it.
+func newTestChain(t *testing.T, n int, gen func(int, *core.BlockGen)) (*core.BlockChain, []*types.Block) {
+	parsed, err := abi.JSON(strings.NewReader(chainConfigABI))
+	if err != nil {
+		t.Fatalf("failed to parse chain config ABI: %v", err)
+	}
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testAddr: {Balance: big.NewInt(params.Ether)},
+				systemcontract.ChainConfigContractAddress: {
+					Code:    chainConfigCode(t, parsed),
+					Storage: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(params.GWei))},
+					Balance: new(big.Int),
+				},
+			},
+		}
+		gblock = genesis.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(genesis.Config, gblock, ethash.NewFaker(), db, n, gen)
+
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	return chain, blocks
+}
+
+func TestChainConfigReaderParams(t *testing.T) {
+	chain, _ := newTestChain(t, 0, nil)
+	defer chain.Stop()
+
+	reader := NewChainConfigReader(chain)
+	cfg, err := reader.Params(chain.CurrentHeader())
+	if err != nil {
+		t.Fatalf("failed to read params: %v", err)
+	}
+	if cfg.GasPrice == nil || cfg.GasPrice.Cmp(big.NewInt(params.GWei)) != 0 {
+		t.Errorf("gas price mismatch: have %v, want %v", cfg.GasPrice, params.GWei)
+	}
+	if cfg.EpochBlockInterval != 200 {
+		t.Errorf("epoch length mismatch: have %d, want %d", cfg.EpochBlockInterval, 200)
+	}
+	if cfg.ActiveValidatorsLength != 21 {
+		t.Errorf("validator count mismatch: have %d, want %d", cfg.ActiveValidatorsLength, 21)
+	}
+	if cfg.MinStakingAmount != nil || cfg.FelonyThreshold != 0 {
+		t.Errorf("unimplemented getters should be zero: have %v, %d", cfg.MinStakingAmount, cfg.FelonyThreshold)
+	}
+	free := cfg.FreeGasAddressMap()
+	if len(free) != len(testFreeGas) {
+		t.Fatalf("free gas list length mismatch: have %d, want %d", len(free), len(testFreeGas))
+	}
+	for i, address := range testFreeGas {
+		if free[address] != uint(i)+1 {
+			t.Errorf("free gas address %x index mismatch: have %d, want %d", address, free[address], i+1)
+		}
+	}
+}
+
+func TestChainConfigReaderNotDeployed(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	(&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	reader := NewChainConfigReader(chain)
+	cfg, err := reader.ParamsByHash(chain.CurrentHeader().Hash())
+	if err != nil {
+		t.Fatalf("failed to read params: %v", err)
+	}
+	if cfg.GasPrice != nil || len(cfg.FreeGasAddressList) != 0 {
+		t.Errorf("params should be empty without contract: have %+v", cfg)
+	}
+	if _, err := reader.ParamsByHash(common.Hash{0x01}); err == nil {
+		t.Errorf("expected error for unknown block")
+	}
+}
+
+func TestChainConfigReaderInvalidation(t *testing.T) {
+	parsed, _ := abi.JSON(strings.NewReader(chainConfigABI))
+	newPrice := big.NewInt(5 * params.GWei)
+
+	// Block 1 is empty, block 2 transfers ether and block 3 changes the gas price
+	chain, blocks := newTestChain(t, 3, func(i int, block *core.BlockGen) {
+		var (
+			signer = types.HomesteadSigner{}
+			tx     *types.Transaction
+		)
+		switch i {
+		case 0:
+			return
+		case 1:
+			tx = types.NewTransaction(block.TxNonce(testAddr), common.Address{0x01}, big.NewInt(1), params.TxGas, big.NewInt(1), nil)
+		case 2:
+			data, _ := parsed.Pack("setGasPrice", newPrice)
+			tx = types.NewTransaction(block.TxNonce(testAddr), systemcontract.ChainConfigContractAddress, new(big.Int), 100000, big.NewInt(1), data)
+		}
+		signed, err := types.SignTx(tx, signer, testKey)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		block.AddTx(signed)
+	})
+	defer chain.Stop()
+
+	reader := NewChainConfigReader(chain)
+	if _, err := reader.Params(chain.Genesis().Header()); err != nil {
+		t.Fatalf("failed to read genesis params: %v", err)
+	}
+	// Replace the cached genesis params with a sentinel to detect whether the
+	// descendants reuse them or execute the contract again.
+	sentinel := &ChainConfigParams{GasPrice: big.NewInt(1)}
+	reader.cache.Add(chain.Genesis().Hash(), sentinel)
+
+	for i, block := range blocks[:2] {
+		cfg, err := reader.Params(block.Header())
+		if err != nil {
+			t.Fatalf("block %d: failed to read params: %v", i+1, err)
+		}
+		if cfg != sentinel {
+			t.Errorf("block %d: params not carried over from parent", i+1)
+		}
+	}
+	price, err := reader.GasPrice(blocks[2].Hash())
+	if err != nil {
+		t.Fatalf("failed to read gas price: %v", err)
+	}
+	if price.Cmp(newPrice) != 0 {
+		t.Errorf("gas price mismatch after update: have %v, want %v", price, newPrice)
+	}
+	// The historical state stays accessible through the uncached path
+	statedb, err := chain.StateAt(blocks[1].Root())
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	old, err := reader.ReadParams(blocks[1].Header(), statedb)
+	if err != nil {
+		t.Fatalf("failed to read params: %v", err)
+	}
+	if old.GasPrice.Cmp(big.NewInt(params.GWei)) != 0 {
+		t.Errorf("historical gas price mismatch: have %v, want %v", old.GasPrice, params.GWei)
+	}
+}
+
+// Tests that reading the parameters from the state bypasses the cache.
+func TestChainConfigReaderParamsAt(t *testing.T) {
+	chain, _ := newTestChain(t, 0, nil)
+	defer chain.Stop()
+
+	reader := NewChainConfigReader(chain)
+	header := chain.CurrentHeader()
+	reader.cache.Add(header.Hash(), &ChainConfigParams{EpochBlockInterval: 1})
+
+	cfg, err := reader.ParamsAt(header)
+	if err != nil {
+		t.Fatalf("failed to read params: %v", err)
+	}
+	if cfg.EpochBlockInterval != 200 {
+		t.Errorf("epoch length mismatch: have %d, want %d", cfg.EpochBlockInterval, 200)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package systemcontract
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// ContractDeployer returns the deployer the DeployerProxy contract registered
+// for the given contract in the state after the given block. Contracts created
+// before the contract was deployed have no registered deployer.
+func (r *ChainConfigReader) ContractDeployer(header *types.Header, contract common.Address) (common.Address, error) {
+	statedb, err := r.chain.StateAt(header.Root)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if statedb.GetCodeSize(systemcontract.DeployerProxyContractAddress) == 0 {
+		return common.Address{}, nil
+	}
+	data, err := vm.DeployerProxyABI.Pack("getContractDeployer", contract)
+	if err != nil {
+		return common.Address{}, err
+	}
+	context := core.NewEVMBlockContext(header, r.chain, &header.Coinbase)
+	evm := vm.NewEVM(context, vm.TxContext{GasPrice: new(big.Int)}, statedb, r.chain.Config(), vm.Config{NoBaseFee: true})
+
+	ret, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), systemcontract.DeployerProxyContractAddress, data, callGas)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var deployer common.Address
+	if err := vm.DeployerProxyABI.UnpackIntoInterface(&deployer, "getContractDeployer", ret); err != nil {
+		return common.Address{}, err
+	}
+	return deployer, nil
+}
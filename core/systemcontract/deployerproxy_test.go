@@ -0,0 +1,73 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package systemcontract
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestContractDeployer(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0xc0")
+		deployer = common.HexToAddress("0xd0")
+		// returns the storage word keyed by the first calldata word
+		code = hexutil.MustDecode("0x6000355460005260206000f3")
+	)
+	input, err := vm.DeployerProxyABI.Pack("getContractDeployer", contract)
+	if err != nil {
+		t.Fatalf("failed to pack input: %v", err)
+	}
+	for i, deployed := range []bool{true, false} {
+		alloc := core.GenesisAlloc{}
+		if deployed {
+			alloc[systemcontract.DeployerProxyContractAddress] = core.GenesisAccount{
+				Code:    code,
+				Storage: map[common.Hash]common.Hash{common.BytesToHash(input[:32]): common.BytesToHash(deployer.Bytes())},
+				Balance: new(big.Int),
+			}
+		}
+		db := rawdb.NewMemoryDatabase()
+		(&core.Genesis{Config: params.TestChainConfig, Alloc: alloc}).MustCommit(db)
+
+		chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to create chain: %v", i, err)
+		}
+		have, err := NewChainConfigReader(chain).ContractDeployer(chain.CurrentHeader(), contract)
+		chain.Stop()
+		if err != nil {
+			t.Fatalf("test %d: failed to read deployer: %v", i, err)
+		}
+		want := common.Address{}
+		if deployed {
+			want = deployer
+		}
+		if have != want {
+			t.Errorf("test %d: deployer mismatch: have %x, want %x", i, have, want)
+		}
+	}
+}
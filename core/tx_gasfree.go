@@ -0,0 +1,155 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// GasFreeUsage is the rate limiting state of a gas-free sender or contract.
+type GasFreeUsage struct {
+	Sender   bool   // Whether the address is a gas-free sender
+	Contract bool   // Whether the address is a gas-free contract
+	Txs      uint64 // Transactions admitted in the current window
+	Gas      uint64 // Gas admitted in the current epoch
+	Slots    uint64 // Pool slots taken by the admitted transactions
+}
+
+// GasFreeStatus is the rate limiting state of all gas-free senders and contracts
+// known to the pool.
+type GasFreeStatus struct {
+	Window uint64                          // Current rate limiting window
+	Epoch  uint64                          // Block starting the current gas quota epoch
+	Usage  map[common.Address]GasFreeUsage // Usage of every gas-free address
+}
+
+// gasFreeUsage tracks the transactions admitted for a single gas-free sender or
+// contract.
+type gasFreeUsage struct {
+	txs    uint64              // Transactions admitted in the current window
+	gas    uint64              // Gas admitted in the current epoch
+	pooled map[common.Hash]int // Admitted transactions still in the pool, hash -> slots
+}
+
+// gasFreeLimiter enforces the rate limits of gas-free transactions. Limits are
+// accounted per gas-free sender and per gas-free target contract, so a single
+// transaction may count towards two addresses.
+type gasFreeLimiter struct {
+	config TxPoolConfig
+	window uint64                           // Rate limiting window of the pending block
+	epoch  uint64                           // Gas quota epoch of the pending block
+	usage  map[common.Address]*gasFreeUsage // Usage of gas-free addresses with admitted transactions
+}
+
+// newGasFreeLimiter creates a limiter enforcing the gas-free limits of the given
+// pool configuration.
+func newGasFreeLimiter(config TxPoolConfig) *gasFreeLimiter {
+	return &gasFreeLimiter{
+		config: config,
+		usage:  make(map[common.Address]*gasFreeUsage),
+	}
+}
+
+// reset moves the limiter to the window of the given pending block and to its
+// gas quota epoch. The counters restart whenever the window or epoch changes,
+// which includes reorgs to a lower block.
+func (l *gasFreeLimiter) reset(number *big.Int, epoch uint64) {
+	window := number.Uint64() / l.config.GasFreeWindow
+	for _, usage := range l.usage {
+		if window != l.window {
+			usage.txs = 0
+		}
+		if epoch != l.epoch {
+			usage.gas = 0
+		}
+	}
+	l.window, l.epoch = window, epoch
+}
+
+// retain drops the usage of all addresses that are no longer gas-free.
+func (l *gasFreeLimiter) retain(gasFree func(common.Address) bool) {
+	for addr := range l.usage {
+		if !gasFree(addr) {
+			delete(l.usage, addr)
+		}
+	}
+}
+
+// slots returns the pool slots taken by the admitted transactions of addr that
+// are still pooled, forgetting the ones that left.
+func (l *gasFreeLimiter) slots(addr common.Address, all *txLookup) uint64 {
+	usage := l.usage[addr]
+	if usage == nil {
+		return 0
+	}
+	var slots uint64
+	for hash, n := range usage.pooled {
+		if all.Get(hash) == nil {
+			delete(usage.pooled, hash)
+			continue
+		}
+		slots += uint64(n)
+	}
+	return slots
+}
+
+// check returns an error if admitting the transaction would exceed any limit of
+// the given gas-free address. Replacements don't take additional slots.
+func (l *gasFreeLimiter) check(addr common.Address, tx *types.Transaction, replacement bool, all *txLookup) error {
+	usage := l.usage[addr]
+	if usage == nil {
+		usage = new(gasFreeUsage)
+	}
+	if l.config.GasFreeWindowTxs > 0 && usage.txs >= l.config.GasFreeWindowTxs {
+		gasFreeRateLimitMeter.Mark(1)
+		return ErrGasFreeRateLimited
+	}
+	if l.config.GasFreeEpochGas > 0 && (tx.Gas() > l.config.GasFreeEpochGas || usage.gas > l.config.GasFreeEpochGas-tx.Gas()) {
+		gasFreeGasLimitMeter.Mark(1)
+		return ErrGasFreeGasLimit
+	}
+	if !replacement && l.config.GasFreeSlots > 0 && l.slots(addr, all)+uint64(numSlots(tx)) > l.config.GasFreeSlots {
+		gasFreeSlotLimitMeter.Mark(1)
+		return ErrGasFreeSlotsExceeded
+	}
+	return nil
+}
+
+// track accounts an admitted transaction to the given gas-free address.
+func (l *gasFreeLimiter) track(addr common.Address, tx *types.Transaction) {
+	usage := l.usage[addr]
+	if usage == nil {
+		usage = &gasFreeUsage{pooled: make(map[common.Hash]int)}
+		l.usage[addr] = usage
+	}
+	usage.txs++
+	usage.gas += tx.Gas()
+	usage.pooled[tx.Hash()] = numSlots(tx)
+}
+
+// status returns the usage of the given gas-free address.
+func (l *gasFreeLimiter) status(addr common.Address, all *txLookup) GasFreeUsage {
+	var status GasFreeUsage
+	if usage := l.usage[addr]; usage != nil {
+		status.Txs, status.Gas = usage.txs, usage.gas
+		status.Slots = l.slots(addr, all)
+	}
+	return status
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the gas-free counters restart with every new window and epoch,
+// including when a reorg moves the pending block back.
+func TestGasFreeLimiterReset(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := common.Address{0x01}
+
+	config := testTxPoolConfig
+	config.GasFreeWindow = 10
+	config.GasFreeWindowTxs = 1
+	config.GasFreeEpochGas = 200000
+
+	limiter := newGasFreeLimiter(config)
+	all := newTxLookup()
+
+	limiter.reset(big.NewInt(1), 0)
+	limiter.track(addr, transaction(0, 100000, key))
+	if err := limiter.check(addr, transaction(1, 100000, key), false, all); !errors.Is(err, ErrGasFreeRateLimited) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrGasFreeRateLimited)
+	}
+	// The next window allows another transaction, but the epoch gas runs out
+	limiter.reset(big.NewInt(10), 0)
+	if err := limiter.check(addr, transaction(1, 100000, key), false, all); err != nil {
+		t.Fatalf("failed to admit in new window: %v", err)
+	}
+	limiter.track(addr, transaction(1, 100000, key))
+
+	limiter.reset(big.NewInt(20), 0)
+	if err := limiter.check(addr, transaction(2, 1, key), false, all); !errors.Is(err, ErrGasFreeGasLimit) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrGasFreeGasLimit)
+	}
+	// The next epoch restores the gas, and so does a reorg back into the previous one
+	limiter.reset(big.NewInt(100), 100)
+	limiter.track(addr, transaction(2, 150000, key))
+
+	limiter.reset(big.NewInt(99), 0)
+	if err := limiter.check(addr, transaction(3, 200000, key), false, all); err != nil {
+		t.Fatalf("failed to admit after reorg: %v", err)
+	}
+	// Transactions that left the pool no longer take slots
+	if slots := limiter.slots(addr, all); slots != 0 {
+		t.Fatalf("slot mismatch: have %d, want 0", slots)
+	}
+}
@@ -26,8 +26,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/prque"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -86,6 +88,18 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrGasFreeRateLimited is returned if a gas-free sender or contract already
+	// had the maximum number of transactions admitted in the current window.
+	ErrGasFreeRateLimited = errors.New("gas-free transaction rate limit exceeded")
+
+	// ErrGasFreeGasLimit is returned if a gas-free transaction would exceed the
+	// gas its sender or contract may use in the current epoch.
+	ErrGasFreeGasLimit = errors.New("gas-free gas limit exceeded")
+
+	// ErrGasFreeSlotsExceeded is returned if a gas-free sender or contract already
+	// takes the maximum number of slots in the pool.
+	ErrGasFreeSlotsExceeded = errors.New("gas-free pool slots exceeded")
 )
 
 var (
@@ -115,6 +129,12 @@ var (
 	underpricedTxMeter = metrics.NewRegisteredMeter("txpool/underpriced", nil)
 	overflowedTxMeter  = metrics.NewRegisteredMeter("txpool/overflowed", nil)
 
+	// Metrics for gas-free transactions
+	gasFreeTxMeter        = metrics.NewRegisteredMeter("txpool/gasfree/valid", nil)
+	gasFreeRateLimitMeter = metrics.NewRegisteredMeter("txpool/gasfree/ratelimit", nil) // Rejected due to the window limit
+	gasFreeGasLimitMeter  = metrics.NewRegisteredMeter("txpool/gasfree/gaslimit", nil)  // Rejected due to the epoch gas limit
+	gasFreeSlotLimitMeter = metrics.NewRegisteredMeter("txpool/gasfree/slotlimit", nil) // Rejected or dropped due to the slot limit
+
 	pendingGauge = metrics.NewRegisteredGauge("txpool/pending", nil)
 	queuedGauge  = metrics.NewRegisteredGauge("txpool/queued", nil)
 	localGauge   = metrics.NewRegisteredGauge("txpool/local", nil)
@@ -160,6 +180,11 @@ type TxPoolConfig struct {
 	ReannounceTime time.Duration // Duration for announcing local pending transactions again
 
 	GasFreeContracts map[common.Address]bool
+
+	GasFreeWindow    uint64 // Number of blocks in a gas-free rate limiting window
+	GasFreeWindowTxs uint64 // Maximum gas-free transactions admitted per sender or contract in a window (0 = unlimited)
+	GasFreeSlots     uint64 // Maximum pool slots per gas-free sender or contract (0 = unlimited)
+	GasFreeEpochGas  uint64 // Maximum gas admitted per gas-free sender or contract in an epoch (0 = unlimited)
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -178,6 +203,10 @@ var DefaultTxPoolConfig = TxPoolConfig{
 
 	Lifetime:       3 * time.Hour,
 	ReannounceTime: 10 * 365 * 24 * time.Hour,
+
+	GasFreeWindow:    10,
+	GasFreeWindowTxs: 256,
+	GasFreeSlots:     64,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -220,6 +249,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool reannounce time", "provided", conf.ReannounceTime, "updated", time.Minute)
 		conf.ReannounceTime = time.Minute
 	}
+	if conf.GasFreeWindow < 1 {
+		log.Warn("Sanitizing invalid txpool gas-free window", "provided", conf.GasFreeWindow, "updated", DefaultTxPoolConfig.GasFreeWindow)
+		conf.GasFreeWindow = DefaultTxPoolConfig.GasFreeWindow
+	}
 	return conf
 }
 
@@ -243,10 +276,15 @@ type TxPool struct {
 
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
+	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
+	sponsor  bool // Fork indicator whether gas sponsorships are active.
 
+	currentHead   *types.Header  // Current head of the blockchain
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
+	quotaEpoch    uint64         // Gas quota epoch of the pending block
+	pendingBase   *big.Int       // Base fee of the pending block, nil before London
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
@@ -254,6 +292,8 @@ type TxPool struct {
 	gasFreeAddressMap     map[common.Address]uint                            // from address that can join tx_pool for free
 	gasFreeAddressMapFunc func(common.Hash) (map[common.Address]uint, error) // add func to get gasFreeAddressMap
 	gasPriceFunc          func(common.Hash) (*big.Int, error)                // add func to get gas price, do nothing when return 0
+	sponsored             map[common.Hash]common.Address                     // Sponsors paying the gas of pooled transactions
+	gasFree               *gasFreeLimiter                                    // Rate limits of gas-free senders and contracts
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -317,6 +357,8 @@ func NewEnhanceTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chai
 		gasFreeAddressMap:     make(map[common.Address]uint),
 		gasFreeAddressMapFunc: gasFreeAddressMapFunc,
 		gasPriceFunc:          gasPriceFunc,
+		sponsored:             make(map[common.Hash]common.Address),
+		gasFree:               newGasFreeLimiter(config),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
@@ -545,6 +587,36 @@ func (pool *TxPool) stats() (int, int) {
 	return pending, queued
 }
 
+// GasFreeStatus retrieves the rate limiting state of all gas-free senders and
+// contracts.
+func (pool *TxPool) GasFreeStatus() GasFreeStatus {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	status := GasFreeStatus{
+		Window: pool.gasFree.window,
+		Epoch:  pool.gasFree.epoch,
+		Usage:  make(map[common.Address]GasFreeUsage),
+	}
+	for addr := range pool.gasFreeAddressMap {
+		usage := pool.gasFree.status(addr, pool.all)
+		usage.Sender = true
+		status.Usage[addr] = usage
+	}
+	for addr, free := range pool.config.GasFreeContracts {
+		if !free {
+			continue
+		}
+		usage := status.Usage[addr]
+		if !usage.Sender {
+			usage = pool.gasFree.status(addr, pool.all)
+		}
+		usage.Contract = true
+		status.Usage[addr] = usage
+	}
+	return status
+}
+
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
 func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
@@ -583,6 +655,25 @@ func (pool *TxPool) Locals() []common.Address {
 	return pool.locals.flatten()
 }
 
+// GasFreeAccounts retrieves the senders on the gas-free list of the current head
+// and the contracts calls to which are admitted for free.
+func (pool *TxPool) GasFreeAccounts() (senders map[common.Address]bool, contracts map[common.Address]bool) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	senders = make(map[common.Address]bool, len(pool.gasFreeAddressMap))
+	for addr := range pool.gasFreeAddressMap {
+		senders[addr] = true
+	}
+	contracts = make(map[common.Address]bool, len(pool.config.GasFreeContracts))
+	for addr, free := range pool.config.GasFreeContracts {
+		if free {
+			contracts[addr] = true
+		}
+	}
+	return senders, contracts
+}
+
 // local retrieves all currently known local transactions, grouped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -606,6 +697,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if !pool.eip2718 && tx.Type() != types.LegacyTxType {
 		return ErrTxTypeNotSupported
 	}
+	// Reject dynamic fee transactions until EIP-1559 activates.
+	if !pool.eip1559 && tx.Type() == types.DynamicFeeTxType {
+		return ErrTxTypeNotSupported
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	if uint64(tx.Size()) > txMaxSize {
 		return ErrOversizedData
@@ -619,14 +714,25 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentMaxGas < tx.Gas() {
 		return ErrGasLimit
 	}
+	// Sanity check for extremely large numbers
+	if tx.GasFeeCap().BitLen() > 256 {
+		return ErrFeeCapVeryHigh
+	}
+	if tx.GasTipCap().BitLen() > 256 {
+		return ErrTipVeryHigh
+	}
+	// Ensure gasFeeCap is greater than or equal to gasTipCap.
+	if tx.GasFeeCapIntCmp(tx.GasTipCap()) < 0 {
+		return ErrTipAboveFeeCap
+	}
 	// Make sure the transaction is signed properly.
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
 		return ErrInvalidSender
 	}
-	// Drop non-local transactions under our own minimal accepted gas price
+	// Drop non-local transactions under our own minimal accepted gas price or tip
 	isGasFree := tx.To() != nil && pool.config.GasFreeContracts != nil && pool.config.GasFreeContracts[*tx.To()]
-	if !local && tx.GasPriceIntCmp(pool.gasPrice) < 0 && !isGasFree {
+	if !local && tx.GasTipCapIntCmp(pool.gasPrice) < 0 && !isGasFree {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
@@ -634,9 +740,15 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrNonceTooLow
 	}
 	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
+	// cost == V + GP * GL, where GP is the fee cap for dynamic fee transactions
 	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
-		return ErrInsufficientFunds
+		// Unless a sponsor pays for the gas, leaving only the value to the sender
+		if !pool.sponsor {
+			return ErrInsufficientFunds
+		}
+		if err := pool.validateSponsor(tx, from); err != nil {
+			return err
+		}
 	}
 	// Ensure the transaction has more gas than the basic tx fee.
 	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul)
@@ -649,6 +761,90 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	return nil
 }
 
+// validateSponsor checks whether the gas of a transaction the sender can't pay
+// for is covered by a sponsor. The sponsor must be able to pay for the gas of
+// all the transactions it sponsors in the pool and have enough quota left.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) validateSponsor(tx *types.Transaction, from common.Address) error {
+	sponsor := ResolveGasSponsor(pool.currentState, from, tx.To())
+	if sponsor == nil {
+		return ErrInsufficientFunds
+	}
+	if pool.currentState.GetBalance(from).Cmp(tx.Value()) < 0 {
+		return ErrInsufficientFunds
+	}
+	// Account for the other pooled transactions of the same sponsor
+	var (
+		hash = tx.Hash()
+		gas  = tx.Gas()
+		cost = new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas()))
+	)
+	for pooledHash, addr := range pool.sponsored {
+		if addr != sponsor.Address || pooledHash == hash {
+			continue
+		}
+		pooled := pool.all.Get(pooledHash)
+		if pooled == nil {
+			delete(pool.sponsored, pooledHash)
+			continue
+		}
+		gas += pooled.Gas()
+		cost.Add(cost, new(big.Int).Mul(pooled.GasFeeCap(), new(big.Int).SetUint64(pooled.Gas())))
+	}
+	if pool.currentState.GetBalance(sponsor.Address).Cmp(cost) < 0 {
+		return ErrInsufficientSponsorFunds
+	}
+	if !sponsorQuotaLeft(pool.currentState, pool.quotaEpoch, sponsor, gas) {
+		return ErrSponsorQuotaExceeded
+	}
+	pool.sponsored[hash] = sponsor.Address
+	return nil
+}
+
+// pendingQuotaEpoch returns the gas quota epoch of the pending block on top of
+// the given head.
+func (pool *TxPool) pendingQuotaEpoch(head *types.Header) (uint64, error) {
+	ctx := vm.BlockContext{BlockNumber: new(big.Int).Add(head.Number, common.Big1)}
+	if chain, ok := pool.chain.(ChainContext); ok {
+		ctx.EpochBlock, ctx.EpochErr = epochBlock(chain, &types.Header{ParentHash: head.Hash(), Number: ctx.BlockNumber})
+	}
+	return quotaEpoch(ctx, pool.chainconfig)
+}
+
+// senderCost returns the funds the sender of a pooled transaction needs to cover
+// it: only the value if its gas is paid by a sponsor, the full cost otherwise.
+func (pool *TxPool) senderCost(tx *types.Transaction) *big.Int {
+	if _, ok := pool.sponsored[tx.Hash()]; ok {
+		return tx.Value()
+	}
+	return tx.Cost()
+}
+
+// revalidateSponsors forgets the sponsorships of transactions that left the pool
+// and drops the transactions whose sponsor changed with the new head, as their
+// sender can't be assumed to pay for the gas.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) revalidateSponsors() {
+	for hash, addr := range pool.sponsored {
+		tx := pool.all.Get(hash)
+		if tx == nil {
+			delete(pool.sponsored, hash)
+			continue
+		}
+		if pool.sponsor {
+			from, _ := types.Sender(pool.signer, tx) // already validated
+			if sponsor := ResolveGasSponsor(pool.currentState, from, tx.To()); sponsor != nil && sponsor.Address == addr {
+				continue
+			}
+		}
+		log.Debug("Dropping transaction with revoked gas sponsor", "hash", hash, "sponsor", addr)
+		pool.removeTx(hash, true)
+		delete(pool.sponsored, hash)
+	}
+}
+
 // add validates a transaction and inserts it into the non-executable queue for later
 // pending promotion and execution. If the transaction is a replacement for an already
 // pending or queued one, it overwrites the previous transaction if its price is higher.
@@ -679,6 +875,24 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		invalidTxMeter.Mark(1)
 		return false, err
 	}
+	// If the gas-free sender or contract exhausted its limits, discard it
+	gasFree := pool.gasFreeAddresses(from, tx)
+	if len(gasFree) > 0 {
+		// Gas-free transactions are only exempt from the minimal tip, the base fee
+		// is paid by every transaction. Discard the ones that can't ever pay it.
+		if pool.pendingBase != nil && tx.GasFeeCapIntCmp(pool.pendingBase) < 0 {
+			log.Debug("Discarding gas-free transaction below base fee", "hash", hash, "feecap", tx.GasFeeCap(), "basefee", pool.pendingBase)
+			return false, ErrFeeCapTooLow
+		}
+		replacement := (pool.pending[from] != nil && pool.pending[from].Overlaps(tx)) ||
+			(pool.queue[from] != nil && pool.queue[from].Overlaps(tx))
+		for _, addr := range gasFree {
+			if err := pool.gasFree.check(addr, tx, replacement, pool.all); err != nil {
+				log.Debug("Discarding rate limited gas-free transaction", "hash", hash, "address", addr, "err", err)
+				return false, err
+			}
+		}
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Count()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -724,6 +938,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		pool.priced.Put(tx, isLocal)
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
+		pool.trackGasFree(gasFree, tx)
 		//log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 
 		// Successful promotion, bump the heartbeat
@@ -735,6 +950,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	if err != nil {
 		return false, err
 	}
+	pool.trackGasFree(gasFree, tx)
 	// Mark local addresses and journal local transactions
 	if (isGasFreeAccount || local) && !pool.locals.contains(from) {
 		if isGasFreeAccount {
@@ -755,6 +971,51 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	return replaced, nil
 }
 
+// gasFreeAddresses returns the gas-free sender and target contract the limits
+// of a transaction are accounted to.
+func (pool *TxPool) gasFreeAddresses(from common.Address, tx *types.Transaction) []common.Address {
+	var addrs []common.Address
+	if _, ok := pool.gasFreeAddressMap[from]; ok {
+		addrs = append(addrs, from)
+	}
+	if to := tx.To(); to != nil && pool.config.GasFreeContracts[*to] && *to != from {
+		addrs = append(addrs, *to)
+	}
+	return addrs
+}
+
+// isGasFree reports whether the given address is a gas-free sender or contract.
+func (pool *TxPool) isGasFree(addr common.Address) bool {
+	_, ok := pool.gasFreeAddressMap[addr]
+	return ok || pool.config.GasFreeContracts[addr]
+}
+
+// trackGasFree accounts an admitted transaction to its gas-free addresses.
+func (pool *TxPool) trackGasFree(addrs []common.Address, tx *types.Transaction) {
+	for _, addr := range addrs {
+		pool.gasFree.track(addr, tx)
+	}
+	if len(addrs) > 0 {
+		gasFreeTxMeter.Mark(1)
+	}
+}
+
+// gasFreePendingRoom returns the number of transactions a gas-free sender may
+// still promote into the pending pool, or false if the sender isn't limited.
+func (pool *TxPool) gasFreePendingRoom(addr common.Address) (int, bool) {
+	if _, ok := pool.gasFreeAddressMap[addr]; !ok || pool.config.GasFreeSlots == 0 {
+		return 0, false
+	}
+	var pending uint64
+	if list := pool.pending[addr]; list != nil {
+		pending = uint64(list.Len())
+	}
+	if pending >= pool.config.GasFreeSlots {
+		return 0, true
+	}
+	return int(pool.config.GasFreeSlots - pending), true
+}
+
 // enqueueTx inserts a new transaction into the non-executable transaction queue.
 //
 // Note, this method assumes the pool lock is held!
@@ -763,6 +1024,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 	from, _ := types.Sender(pool.signer, tx) // already validated
 	if pool.queue[from] == nil {
 		pool.queue[from] = newTxList(false)
+		pool.queue[from].cost = pool.senderCost
 	}
 	inserted, old := pool.queue[from].Add(tx, pool.config.PriceBump)
 	if !inserted {
@@ -815,6 +1077,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	// Try to insert the transaction into the pending queue
 	if pool.pending[addr] == nil {
 		pool.pending[addr] = newTxList(true)
+		pool.pending[addr].cost = pool.senderCost
 	}
 	list := pool.pending[addr]
 
@@ -1282,6 +1545,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 		log.Error("Failed to reset txpool state", "err", err)
 		return
 	}
+	pool.currentHead = newHead
 	pool.currentState = statedb
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
@@ -1298,6 +1562,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 				pool.locals.removeGasFree(address)
 			}
 		}
+		pool.gasFree.retain(pool.isGasFree)
 	}
 
 	// Inject any transactions discarded due to reorgs
@@ -1309,6 +1574,19 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
 	pool.eip2718 = pool.chainconfig.IsBerlin(next)
+	pool.eip1559 = pool.chainconfig.IsLondon(next)
+	pool.pendingBase = nil
+	if pool.eip1559 && (newHead.BaseFee != nil || !pool.chainconfig.IsLondon(newHead.Number)) {
+		pool.pendingBase = misc.CalcBaseFee(pool.chainconfig, newHead)
+	}
+	pool.sponsor = pool.chainconfig.IsGasSponsor(next)
+	if epoch, err := pool.pendingQuotaEpoch(newHead); err != nil {
+		log.Warn("Failed to determine gas quota epoch", "number", next, "err", err)
+	} else {
+		pool.quotaEpoch = epoch
+	}
+	pool.revalidateSponsors()
+	pool.gasFree.reset(next, pool.quotaEpoch)
 	//fncy2 update
 	if pool.chainconfig.IsFncy2(next) {
 		gasPrice, err := pool.gasPriceFunc(pool.chain.CurrentBlock().Hash())
@@ -1355,8 +1633,21 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
 
-		// Gather all executable transactions and promote them
+		// Gather all executable transactions and promote them, dropping the ones
+		// exceeding the pending slots of gas-free senders
 		readies := list.Ready(pool.pendingNonces.get(addr))
+		queuedGauge.Dec(int64(len(readies)))
+
+		var limited types.Transactions
+		if room, ok := pool.gasFreePendingRoom(addr); ok && len(readies) > room {
+			readies, limited = readies[:room], readies[room:]
+			for _, tx := range limited {
+				pool.all.Remove(tx.Hash())
+				log.Trace("Removed slot-exceeding gas-free transaction", "hash", tx.Hash())
+			}
+			pendingRateLimitMeter.Mark(int64(len(limited)))
+			gasFreeSlotLimitMeter.Mark(int64(len(limited)))
+		}
 		for _, tx := range readies {
 			hash := tx.Hash()
 			if pool.promoteTx(addr, hash, tx) {
@@ -1364,11 +1655,10 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			}
 		}
 		log.Trace("Promoted queued transactions", "count", len(promoted))
-		queuedGauge.Dec(int64(len(readies)))
 
-		// Drop all transactions over the allowed limit
+		// Drop all transactions over the allowed limit, gas-free senders included
 		var caps types.Transactions
-		if !pool.locals.contains(addr) {
+		if _, gasFree := pool.gasFreeAddressMap[addr]; gasFree || !pool.locals.contains(addr) {
 			caps = list.Cap(int(pool.config.AccountQueue))
 			for _, tx := range caps {
 				hash := tx.Hash()
@@ -1377,11 +1667,16 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
 		}
-		// Mark all the items dropped as removed
-		pool.priced.Removed(len(forwards) + len(drops) + len(caps))
+		// Mark all the items dropped as removed. Gas-free senders are locals, so
+		// their capped and slot-exceeding transactions were never priced.
+		removed := len(forwards) + len(drops)
+		if !pool.locals.contains(addr) {
+			removed += len(caps) + len(limited)
+		}
+		pool.priced.Removed(removed)
 		queuedGauge.Dec(int64(len(forwards) + len(drops) + len(caps)))
 		if pool.locals.contains(addr) {
-			localGauge.Dec(int64(len(forwards) + len(drops) + len(caps)))
+			localGauge.Dec(int64(len(forwards) + len(drops) + len(caps) + len(limited)))
 		}
 		// Delete the entire queue entry if it became empty.
 		if list.Empty() {
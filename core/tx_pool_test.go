@@ -87,12 +87,30 @@ func pricedDataTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key
 	return tx
 }
 
+func dynamicFeeTx(nonce uint64, gaslimit uint64, gasFee *big.Int, tip *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
+	tx, _ := types.SignNewTx(key, types.LatestSignerForChainID(params.TestChainConfig.ChainID), &types.DynamicFeeTx{
+		ChainID:   params.TestChainConfig.ChainID,
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: gasFee,
+		Gas:       gaslimit,
+		To:        &common.Address{},
+		Value:     big.NewInt(100),
+		Data:      nil,
+	})
+	return tx
+}
+
 func setupTxPool() (*TxPool, *ecdsa.PrivateKey) {
+	return setupTxPoolWithConfig(params.TestChainConfig)
+}
+
+func setupTxPoolWithConfig(config *params.ChainConfig) (*TxPool, *ecdsa.PrivateKey) {
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
 
 	key, _ := crypto.GenerateKey()
-	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	pool := NewTxPool(testTxPoolConfig, config, blockchain)
 
 	return pool, key
 }
@@ -270,6 +288,296 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that after the GasSponsor fork the pool admits transactions whose sender
+// can't pay for the gas as long as their sponsor can, and drops them once the
+// sponsorship is revoked.
+func TestTransactionGasSponsor(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.GasSponsorBlock = big.NewInt(0)
+
+	pool, key := setupTxPoolWithConfig(&config)
+	defer pool.Stop()
+
+	sponsor := common.HexToAddress("0x5905502")
+	tx := pricedTransaction(0, 100000, big.NewInt(1), key)
+	from, _ := deriveSender(tx)
+	setGasSponsor(pool.currentState, from, sponsor, 150000)
+
+	// Without sponsor funds, the sender is required to pay for the gas
+	pool.currentState.AddBalance(from, tx.Value())
+	if err := pool.AddRemote(tx); !errors.Is(err, ErrInsufficientSponsorFunds) {
+		t.Fatal("expected", ErrInsufficientSponsorFunds, "got", err)
+	}
+	pool.currentState.AddBalance(sponsor, big.NewInt(1000000))
+	if err := pool.AddRemote(tx); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	// The gas of all pooled transactions of a sponsor must fit in its quota
+	if err := pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(1), key)); !errors.Is(err, ErrSponsorQuotaExceeded) {
+		t.Fatal("expected", ErrSponsorQuotaExceeded, "got", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(1, 50000, big.NewInt(1), key)); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	<-pool.requestReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 2 || queued != 0 {
+		t.Fatalf("pool state mismatch: have %d pending, %d queued, want 2, 0", pending, queued)
+	}
+	// Revoking the sponsorship drops the transactions the sender can't pay for
+	setGasSponsor(pool.currentState, from, common.Address{}, 0)
+	<-pool.requestReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("pool state mismatch: have %d pending, %d queued, want 0, 0", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that gas-free senders are rate limited per window and lose their
+// exemptions, limits and usage when a new head removes them from the free list.
+func TestGasFreeSenderRateLimit(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	free := map[common.Address]uint{from: 1}
+	freeFunc := func(common.Hash) (map[common.Address]uint, error) {
+		return free, nil
+	}
+	config := testTxPoolConfig
+	config.GasFreeWindowTxs = 3
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
+	pool := NewEnhanceTxPool(config, params.TestChainConfig, blockchain, freeFunc, getGasPriceFunc())
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(from, big.NewInt(1000000))
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		if err := pool.addRemoteSync(pricedTransaction(nonce, 100000, new(big.Int), key)); err != nil {
+			t.Fatalf("gas-free tx %d: failed to add: %v", nonce, err)
+		}
+	}
+	if err := pool.addRemoteSync(pricedTransaction(3, 100000, new(big.Int), key)); !errors.Is(err, ErrGasFreeRateLimited) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrGasFreeRateLimited)
+	}
+	status := pool.GasFreeStatus()
+	if usage := status.Usage[from]; !usage.Sender || usage.Contract || usage.Txs != 3 || usage.Gas != 300000 || usage.Slots != 3 {
+		t.Fatalf("gas-free usage mismatch: have %+v", usage)
+	}
+	// Dropping the sender from the free list subjects it to the price floor
+	free = map[common.Address]uint{}
+	<-pool.requestReset(nil, nil)
+
+	if err := pool.addRemoteSync(pricedTransaction(3, 100000, new(big.Int), key)); !errors.Is(err, ErrUnderpriced) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if _, ok := pool.GasFreeStatus().Usage[from]; ok {
+		t.Fatalf("usage reported for non gas-free sender")
+	}
+	// Adding it back starts with a clean window
+	free = map[common.Address]uint{from: 1}
+	<-pool.requestReset(nil, nil)
+
+	for nonce := uint64(3); nonce < 6; nonce++ {
+		if err := pool.addRemoteSync(pricedTransaction(nonce, 100000, new(big.Int), key)); err != nil {
+			t.Fatalf("gas-free tx %d: failed to add: %v", nonce, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 6 || queued != 0 {
+		t.Fatalf("pool state mismatch: have %d pending, %d queued, want 6, 0", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that after London gas-free transactions are only exempt from the minimal
+// tip, but have to pay the base fee of the pending block like any other.
+func TestGasFreeBaseFee(t *testing.T) {
+	t.Parallel()
+
+	chainconfig := *params.TestChainConfig
+	chainconfig.LondonBlock = big.NewInt(0)
+
+	config := testTxPoolConfig
+	config.GasFreeContracts = map[common.Address]bool{{}: true}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
+	pool := NewTxPool(config, &chainconfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(0xffffffffffffff))
+
+	pool.mu.Lock()
+	pool.gasPrice = big.NewInt(10)
+	pool.pendingBase = big.NewInt(100)
+	pool.mu.Unlock()
+
+	if err := pool.addRemoteSync(dynamicFeeTx(0, 100000, new(big.Int), new(big.Int), key)); !errors.Is(err, ErrFeeCapTooLow) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrFeeCapTooLow)
+	}
+	if err := pool.addRemoteSync(dynamicFeeTx(0, 100000, big.NewInt(99), new(big.Int), key)); !errors.Is(err, ErrFeeCapTooLow) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrFeeCapTooLow)
+	}
+	if err := pool.addRemoteSync(dynamicFeeTx(0, 100000, big.NewInt(100), new(big.Int), key)); err != nil {
+		t.Fatalf("failed to add gas-free transaction paying the base fee: %v", err)
+	}
+}
+
+// Tests that the transactions to a gas-free contract can't take more than the
+// configured slots and gas of the pool.
+func TestGasFreeContractLimits(t *testing.T) {
+	t.Parallel()
+
+	contract := common.Address{}
+	config := testTxPoolConfig
+	config.GasFreeContracts = map[common.Address]bool{contract: true}
+	config.GasFreeSlots = 2
+	config.GasFreeEpochGas = 250000
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, new(big.Int), keys[0])); err != nil {
+		t.Fatalf("failed to add gas-free tx: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, new(big.Int), keys[1])); err != nil {
+		t.Fatalf("failed to add gas-free tx: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 30000, new(big.Int), keys[2])); !errors.Is(err, ErrGasFreeSlotsExceeded) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrGasFreeSlotsExceeded)
+	}
+	// Freeing up a slot still leaves the epoch gas limit in force
+	pool.mu.Lock()
+	pool.removeTx(pool.pending[crypto.PubkeyToAddress(keys[1].PublicKey)].Flatten()[0].Hash(), true)
+	pool.mu.Unlock()
+
+	if err := pool.addRemoteSync(pricedTransaction(0, 60000, new(big.Int), keys[2])); !errors.Is(err, ErrGasFreeGasLimit) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrGasFreeGasLimit)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 50000, new(big.Int), keys[2])); err != nil {
+		t.Fatalf("failed to add gas-free tx: %v", err)
+	}
+	if usage := pool.GasFreeStatus().Usage[contract]; !usage.Contract || usage.Sender || usage.Txs != 3 || usage.Gas != 250000 || usage.Slots != 2 {
+		t.Fatalf("gas-free usage mismatch: have %+v", usage)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that an account joining the free list with queued transactions can't
+// promote more of them than the gas-free pending slots allow.
+func TestGasFreePendingSlots(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	free := map[common.Address]uint{}
+	freeFunc := func(common.Hash) (map[common.Address]uint, error) {
+		return free, nil
+	}
+	config := testTxPoolConfig
+	config.GasFreeSlots = 2
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
+	pool := NewEnhanceTxPool(config, params.TestChainConfig, blockchain, freeFunc, getGasPriceFunc())
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(from, big.NewInt(10000000))
+	for nonce := uint64(1); nonce < 6; nonce++ {
+		if err := pool.addRemoteSync(transaction(nonce, 100000, key)); err != nil {
+			t.Fatalf("tx %d: failed to add: %v", nonce, err)
+		}
+	}
+	free = map[common.Address]uint{from: 1}
+	<-pool.requestReset(nil, nil)
+
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, new(big.Int), key)); err != nil {
+		t.Fatalf("failed to add gas-free tx: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 0 {
+		t.Fatalf("pool state mismatch: have %d pending, %d queued, want 2, 0", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+func TestDynamicFeeTransactions(t *testing.T) {
+	t.Parallel()
+
+	// Dynamic fee transactions are rejected before London. The pre-London
+	// signer can't even recover their sender, so validate them directly.
+	pool, key := setupTxPool()
+	tx := dynamicFeeTx(0, 100000, big.NewInt(2), big.NewInt(1), key)
+	if err := pool.validateTx(tx, false); err != ErrTxTypeNotSupported {
+		t.Error("expected", ErrTxTypeNotSupported, "got", err)
+	}
+	pool.Stop()
+
+	config := *params.TestChainConfig
+	config.LondonBlock = big.NewInt(0)
+
+	pool, key = setupTxPoolWithConfig(&config)
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(0xffffffffffffff))
+
+	tx = dynamicFeeTx(0, 100000, big.NewInt(1), big.NewInt(2), key)
+	if err := pool.AddRemote(tx); err != ErrTipAboveFeeCap {
+		t.Error("expected", ErrTipAboveFeeCap, "got", err)
+	}
+	veryBig := new(big.Int).Lsh(big.NewInt(1), 256)
+	tx = dynamicFeeTx(0, 100000, veryBig, big.NewInt(1), key)
+	if err := pool.AddRemote(tx); err != ErrFeeCapVeryHigh {
+		t.Error("expected", ErrFeeCapVeryHigh, "got", err)
+	}
+	tx = dynamicFeeTx(0, 100000, big.NewInt(2), veryBig, key)
+	if err := pool.AddRemote(tx); err != ErrTipVeryHigh {
+		t.Error("expected", ErrTipVeryHigh, "got", err)
+	}
+	// The minimal accepted gas price applies to the tip, not the fee cap
+	pool.gasPrice = big.NewInt(10)
+	tx = dynamicFeeTx(0, 100000, big.NewInt(100), big.NewInt(5), key)
+	if err := pool.AddRemote(tx); err != ErrUnderpriced {
+		t.Error("expected", ErrUnderpriced, "got", err)
+	}
+	tx = dynamicFeeTx(0, 100000, big.NewInt(100), big.NewInt(10), key)
+	if err := pool.AddRemote(tx); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+	// Replacements need to bump both the fee cap and the tip
+	tx = dynamicFeeTx(0, 100000, big.NewInt(200), big.NewInt(10), key)
+	if err := pool.AddRemote(tx); err != ErrReplaceUnderpriced {
+		t.Error("expected", ErrReplaceUnderpriced, "got", err)
+	}
+	tx = dynamicFeeTx(0, 100000, big.NewInt(200), big.NewInt(20), key)
+	if err := pool.AddRemote(tx); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -129,6 +129,47 @@ func TestEIP2718BlockEncoding(t *testing.T) {
 	}
 }
 
+func TestHeaderBaseFeeEncoding(t *testing.T) {
+	legacy := &Header{
+		Difficulty: big.NewInt(131072),
+		Number:     big.NewInt(1),
+		GasLimit:   3141592,
+		Extra:      []byte("coolest block on chain"),
+	}
+	legacyEnc, err := rlp.EncodeToBytes(legacy)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	london := CopyHeader(legacy)
+	london.BaseFee = big.NewInt(params.InitialBaseFee)
+	londonEnc, err := rlp.EncodeToBytes(london)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	if legacy.Hash() == london.Hash() {
+		t.Fatal("base fee not part of the header hash")
+	}
+	for _, enc := range [][]byte{legacyEnc, londonEnc} {
+		var header Header
+		if err := rlp.DecodeBytes(enc, &header); err != nil {
+			t.Fatal("decode error: ", err)
+		}
+		ourEnc, err := rlp.EncodeToBytes(&header)
+		if err != nil {
+			t.Fatal("encode error: ", err)
+		}
+		if !bytes.Equal(ourEnc, enc) {
+			t.Errorf("encoded header mismatch:\ngot:  %x\nwant: %x", ourEnc, enc)
+		}
+	}
+	if fee := NewBlockWithHeader(legacy).BaseFee(); fee != nil {
+		t.Errorf("legacy block base fee mismatch: have %v, want nil", fee)
+	}
+	if fee := NewBlockWithHeader(london).BaseFee(); fee.Cmp(london.BaseFee) != 0 {
+		t.Errorf("london block base fee mismatch: have %v, want %v", fee, london.BaseFee)
+	}
+}
+
 func TestUncleHash(t *testing.T) {
 	uncles := make([]*Header, 0)
 	h := CalcUncleHash(uncles)
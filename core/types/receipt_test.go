@@ -315,6 +315,36 @@ func TestTypedReceiptEncodingDecoding(t *testing.T) {
 	}
 }
 
+// Tests that receipts of dynamic fee transactions survive an encoding round trip.
+func TestDynamicFeeReceiptEncodingDecoding(t *testing.T) {
+	receipt := &Receipt{
+		Type:              DynamicFeeTxType,
+		Status:            ReceiptStatusSuccessful,
+		CumulativeGasUsed: 21000,
+		Logs:              []*Log{{Address: common.BytesToAddress([]byte{0x11})}},
+	}
+	enc, err := rlp.EncodeToBytes(receipt)
+	if err != nil {
+		t.Fatalf("failed to encode receipt: %v", err)
+	}
+	var dec Receipt
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatalf("failed to decode receipt: %v", err)
+	}
+	if dec.Type != DynamicFeeTxType {
+		t.Fatalf("receipt type mismatch: have %d, want %d", dec.Type, DynamicFeeTxType)
+	}
+	if dec.Status != receipt.Status || dec.CumulativeGasUsed != receipt.CumulativeGasUsed || len(dec.Logs) != 1 {
+		t.Fatalf("receipt mismatch: have %+v, want %+v", dec, receipt)
+	}
+	// The derivable encoding must carry the type prefix as well
+	var buf bytes.Buffer
+	Receipts{receipt}.EncodeIndex(0, &buf)
+	if buf.Len() == 0 || buf.Bytes()[0] != DynamicFeeTxType {
+		t.Fatalf("invalid derivable receipt encoding: %x", buf.Bytes())
+	}
+}
+
 func clearComputedFieldsOnReceipts(t *testing.T, receipts Receipts) {
 	t.Helper()
 
@@ -0,0 +1,91 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// VoteData is what a validator attests to with a fast finality vote: a target
+// block on top of the latest block justified from its point of view.
+type VoteData struct {
+	SourceNumber uint64      // Number of the latest justified block
+	SourceHash   common.Hash // Hash of the latest justified block
+	TargetNumber uint64      // Number of the block voted for
+	TargetHash   common.Hash // Hash of the block voted for
+}
+
+// Hash returns the keccak256 hash of the RLP encoding of the vote data, which is
+// what the validators sign.
+func (d *VoteData) Hash() common.Hash {
+	return rlpHash(d)
+}
+
+// VoteEnvelope is a vote signed by a validator, as propagated over the network.
+type VoteEnvelope struct {
+	Data      *VoteData
+	Signature []byte // Signature of the validator over the hash of the data
+}
+
+// Hash returns the keccak256 hash of the RLP encoding of the signed vote.
+func (v *VoteEnvelope) Hash() common.Hash {
+	return rlpHash(v)
+}
+
+// Validator recovers the address of the validator who signed the vote.
+func (v *VoteEnvelope) Validator() (common.Address, error) {
+	return recoverVoter(v.Data, v.Signature)
+}
+
+// VoteAttestation is the aggregation of the votes for the same data by at least
+// two thirds of the validators, which justifies the target block.
+type VoteAttestation struct {
+	Data       *VoteData
+	Signatures [][]byte // Signatures of the distinct validators voting for the data
+}
+
+// Validators recovers the addresses of the validators who signed the attestation,
+// in the order of the signatures.
+func (a *VoteAttestation) Validators() ([]common.Address, error) {
+	validators := make([]common.Address, 0, len(a.Signatures))
+	for _, sig := range a.Signatures {
+		validator, err := recoverVoter(a.Data, sig)
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, validator)
+	}
+	return validators, nil
+}
+
+// recoverVoter recovers the signer of the vote data from its signature.
+func recoverVoter(data *VoteData, sig []byte) (common.Address, error) {
+	if data == nil {
+		return common.Address{}, errors.New("missing vote data")
+	}
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, errors.New("invalid vote signature length")
+	}
+	pubkey, err := crypto.SigToPub(data.Hash().Bytes(), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
@@ -34,6 +34,11 @@ var (
 	ErrWriteProtection          = errors.New("write protection")
 	ErrReturnDataOutOfBounds    = errors.New("return data out of bounds")
 	ErrGasUintOverflow          = errors.New("gas uint64 overflow")
+
+	ErrRuntimeUpgradeMalformed     = errors.New("malformed runtime upgrade request")
+	ErrRuntimeUpgradeInvalidTarget = errors.New("runtime upgrade target is not a deployed system contract")
+	ErrDeployerNotAllowed          = errors.New("deployer is not allowed to create contracts")
+	ErrDeployerBanned              = errors.New("deployer is banned from creating contracts")
 )
 
 // ErrStackUnderflow wraps an evm error when the items on the stack less
@@ -100,6 +100,14 @@ type BlockContext struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+	BaseFee     *big.Int       // Provides information for BASEFEE
+
+	// EpochBlock is the number of the block starting the consensus epoch of the
+	// block, nil if the consensus engine has no epochs. It is not exposed to
+	// contracts, but scopes the epoch quotas of gas sponsors. EpochErr is set
+	// instead if the engine has epochs but the epoch block couldn't be found.
+	EpochBlock *big.Int
+	EpochErr   error
 }
 
 // TxContext provides the EVM with information about a transaction.
@@ -512,7 +520,20 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 
 	start := time.Now()
 
-	ret, err := run(evm, contract, nil, false)
+	// Permissioned chains only let the deployers approved by the DeployerProxy
+	// contract create new contracts. The check is done after the nonce bump so
+	// that a rejected creation transaction can't be replayed.
+	var (
+		ret             []byte
+		err             error
+		useDeployerHook = evm.isDeployerProxyActive()
+	)
+	if useDeployerHook {
+		err = evm.checkDeployer(contract, caller.Address())
+	}
+	if err == nil {
+		ret, err = run(evm, contract, nil, false)
+	}
 
 	// Check whether the max code size has been exceeded, assign err if the case.
 	maxCodeSize := params.MaxCodeSize
@@ -535,6 +556,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 			err = ErrCodeStoreOutOfGas
 		}
 	}
+	if err == nil && useDeployerHook {
+		err = evm.registerDeployedContract(contract, caller.Address())
+	}
 
 	// When an error was returned by the EVM or when setting the creation code
 	// above we revert to the snapshot and consume any gas remaining. Additionally
@@ -580,3 +604,6 @@ func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *
 
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
+
+// Config returns the EVM's configuration.
+func (evm *EVM) Config() Config { return evm.vmConfig }
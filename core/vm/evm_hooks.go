@@ -0,0 +1,198 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// RuntimeUpgradeHookTopic is the first topic of the log the RuntimeUpgrade
+// contract emits to request a bytecode replacement of a system contract. The
+// second topic holds the target contract address and the log data is the ABI
+// encoding of (bytes newByteCode, bytes applyFunction).
+var RuntimeUpgradeHookTopic = common.BytesToHash(systemcontract.EvmHookRuntimeUpgradeAddress.Bytes())
+
+var runtimeUpgradeHookArgs = func() abi.Arguments {
+	bytesTy, err := abi.NewType("bytes", "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return abi.Arguments{{Name: "newByteCode", Type: bytesTy}, {Name: "applyFunction", Type: bytesTy}}
+}()
+
+// deployerProxyHookGas caps the gas of every DeployerProxy invocation the EVM
+// makes on behalf of a contract creation. The gas is taken from the creating
+// frame, so the deployer pays for the checks like for any other call.
+const deployerProxyHookGas = uint64(1000000)
+
+const deployerProxyABI = `[
+  {"inputs":[{"internalType":"address","name":"account","type":"address"}],"name":"isDeployer","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"internalType":"address","name":"account","type":"address"}],"name":"isBanned","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"internalType":"address","name":"contractAddress","type":"address"}],"name":"getContractDeployer","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"internalType":"address","name":"deployer","type":"address"},{"internalType":"address","name":"impl","type":"address"}],"name":"registerDeployedContract","outputs":[],"stateMutability":"nonpayable","type":"function"}
+]`
+
+// DeployerProxyABI is the parsed ABI of the DeployerProxy system contract, used
+// both by the creation hooks and by the readers of its registry.
+var DeployerProxyABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(deployerProxyABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// isRuntimeUpgradeHook reports whether a log with the given topics emitted by
+// the given contract is a runtime upgrade request that must be applied.
+func (evm *EVM) isRuntimeUpgradeHook(emitter common.Address, topics []common.Hash) bool {
+	if !evm.chainRules.HasRuntimeUpgrade {
+		return false
+	}
+	return emitter == systemcontract.RuntimeUpgradeContractAddress && len(topics) > 0 && topics[0] == RuntimeUpgradeHookTopic
+}
+
+// applyRuntimeUpgradeHook replaces the bytecode of the system contract referenced
+// by the hook log and, if an apply function is provided, invokes it on behalf of
+// the RuntimeUpgrade contract with the gas left in the emitting frame. All state
+// changes are journaled, so a failure of the apply call reverts the code swap
+// together with the enclosing call frame.
+func (evm *EVM) applyRuntimeUpgradeHook(contract *Contract, topics []common.Hash, data []byte) error {
+	if len(topics) != 2 {
+		return ErrRuntimeUpgradeMalformed
+	}
+	target := common.BytesToAddress(topics[1].Bytes())
+	if !systemcontract.IsSystemContract(target) || !evm.StateDB.Exist(target) {
+		return ErrRuntimeUpgradeInvalidTarget
+	}
+	values, err := runtimeUpgradeHookArgs.Unpack(data)
+	if err != nil || len(values) != 2 {
+		return ErrRuntimeUpgradeMalformed
+	}
+	newByteCode, applyFunction := values[0].([]byte), values[1].([]byte)
+	if len(newByteCode) == 0 {
+		return ErrRuntimeUpgradeMalformed
+	}
+	maxCodeSize := params.MaxCodeSize
+	if evm.chainRules.IsContract48kBlock {
+		maxCodeSize = params.MaxCodeSize * 2
+	}
+	if len(newByteCode) > maxCodeSize {
+		return ErrMaxCodeSizeExceeded
+	}
+	evm.StateDB.SetCode(target, newByteCode)
+	if len(applyFunction) == 0 {
+		return nil
+	}
+	// The apply call is not issued by a CALL opcode, so the target must be
+	// warmed up explicitly as the EIP-2929 gas functions expect it.
+	if evm.chainRules.IsBerlin {
+		evm.StateDB.AddAddressToAccessList(target)
+	}
+	_, leftOverGas, err := evm.Call(AccountRef(contract.Address()), target, applyFunction, contract.Gas, big0)
+	contract.Gas = leftOverGas
+	return err
+}
+
+// isDeployerProxyActive reports whether contract creations have to be approved
+// by the DeployerProxy contract. Until the contract is deployed creations are
+// not restricted, even if the fork is already active.
+func (evm *EVM) isDeployerProxyActive() bool {
+	if !evm.chainRules.HasDeployerProxy {
+		return false
+	}
+	if evm.StateDB.GetCodeSize(systemcontract.DeployerProxyContractAddress) == 0 {
+		return false
+	}
+	// The hook calls are not issued by CALL opcodes, so the contract must be
+	// warmed up explicitly as the EIP-2929 gas functions expect it.
+	if evm.chainRules.IsBerlin {
+		evm.StateDB.AddAddressToAccessList(systemcontract.DeployerProxyContractAddress)
+	}
+	return true
+}
+
+// checkDeployer asks the DeployerProxy contract whether the deployer is allowed
+// to create contracts. The deployer is the immediate caller of the creation, not
+// the transaction origin: a factory contract deploys on its own behalf and has
+// to be approved itself, approving an account doesn't extend to the contracts
+// it calls. The gas of the checks is charged to the creating contract.
+func (evm *EVM) checkDeployer(contract *Contract, deployer common.Address) error {
+	banned, err := evm.callDeployerProxyBool(contract, "isBanned", deployer)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return ErrDeployerBanned
+	}
+	allowed, err := evm.callDeployerProxyBool(contract, "isDeployer", deployer)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrDeployerNotAllowed
+	}
+	return nil
+}
+
+// registerDeployedContract records the deployer of a freshly created contract in
+// the DeployerProxy contract. The call is issued from the DeployerProxy hook
+// address so that the contract can tell it apart from regular transactions. Its
+// gas is charged to the created contract's frame, like the code storage.
+func (evm *EVM) registerDeployedContract(contract *Contract, deployer common.Address) error {
+	input, err := DeployerProxyABI.Pack("registerDeployedContract", deployer, contract.Address())
+	if err != nil {
+		return err
+	}
+	gas := deployerProxyGas(contract)
+	_, leftOverGas, err := evm.Call(AccountRef(systemcontract.EvmHookDeployerProxyAddress), systemcontract.DeployerProxyContractAddress, input, gas, big0)
+	contract.UseGas(gas - leftOverGas)
+	return err
+}
+
+// callDeployerProxyBool invokes a boolean view method of the DeployerProxy
+// contract. Any failure of the contract is treated as a refusal.
+func (evm *EVM) callDeployerProxyBool(contract *Contract, method string, account common.Address) (bool, error) {
+	input, err := DeployerProxyABI.Pack(method, account)
+	if err != nil {
+		return false, err
+	}
+	gas := deployerProxyGas(contract)
+	ret, leftOverGas, err := evm.StaticCall(AccountRef(systemcontract.EvmHookDeployerProxyAddress), systemcontract.DeployerProxyContractAddress, input, gas)
+	contract.UseGas(gas - leftOverGas)
+	if err != nil {
+		return false, ErrDeployerNotAllowed
+	}
+	var result bool
+	if err := DeployerProxyABI.UnpackIntoInterface(&result, method, ret); err != nil {
+		return false, ErrDeployerNotAllowed
+	}
+	return result, nil
+}
+
+// deployerProxyGas returns the gas a DeployerProxy invocation may use out of the
+// gas left in the given frame.
+func deployerProxyGas(contract *Contract) uint64 {
+	if contract.Gas < deployerProxyHookGas {
+		return contract.Gas
+	}
+	return deployerProxyHookGas
+}
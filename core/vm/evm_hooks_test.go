@@ -0,0 +1,278 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// runtimeUpgradeCode returns bytecode that copies its calldata to memory and
+// emits it as a runtime upgrade hook log targeting the given contract.
+func runtimeUpgradeCode(target common.Address) []byte {
+	code := hexutil.MustDecode("0x366000600037") // CALLDATACOPY(0, 0, CALLDATASIZE)
+	code = append(code, byte(PUSH20))
+	code = append(code, target.Bytes()...)
+	code = append(code, byte(PUSH20))
+	code = append(code, systemcontract.EvmHookRuntimeUpgradeAddress.Bytes()...)
+	return append(code, hexutil.MustDecode("0x366000a200")...) // LOG2(0, CALLDATASIZE, hook, target)
+}
+
+func TestRuntimeUpgradeHook(t *testing.T) {
+	var (
+		target  = common.HexToAddress(systemcontract.GovernanceContract)
+		oldCode = hexutil.MustDecode("0x00")
+		// SSTORE(0, 1) on every call
+		newCode = hexutil.MustDecode("0x600160005500")
+		// REVERT(0, 0) on every call
+		revertCode = hexutil.MustDecode("0x60006000fd")
+	)
+	tests := []struct {
+		emitter   common.Address
+		target    common.Address
+		code      []byte
+		apply     []byte
+		fork      *big.Int
+		failure   error
+		wantCode  []byte
+		wantState common.Hash
+	}{
+		// plain bytecode replacement
+		{systemcontract.RuntimeUpgradeContractAddress, target, newCode, nil, common.Big0, nil, newCode, common.Hash{}},
+		// bytecode replacement followed by an apply call
+		{systemcontract.RuntimeUpgradeContractAddress, target, newCode, []byte{0x01}, common.Big0, nil, newCode, common.BigToHash(common.Big1)},
+		// failing apply call reverts the replacement
+		{systemcontract.RuntimeUpgradeContractAddress, target, revertCode, []byte{0x01}, common.Big0, ErrExecutionReverted, oldCode, common.Hash{}},
+		// only system contracts can be upgraded
+		{systemcontract.RuntimeUpgradeContractAddress, common.HexToAddress("0xdeadbeef"), newCode, nil, common.Big0, ErrRuntimeUpgradeInvalidTarget, oldCode, common.Hash{}},
+		// empty bytecode is refused
+		{systemcontract.RuntimeUpgradeContractAddress, target, nil, nil, common.Big0, ErrRuntimeUpgradeMalformed, oldCode, common.Hash{}},
+		// logs from any other contract are ignored
+		{common.HexToAddress(systemcontract.StakingPoolContract), target, newCode, nil, common.Big0, nil, oldCode, common.Hash{}},
+		// logs before the fork are ignored
+		{systemcontract.RuntimeUpgradeContractAddress, target, newCode, nil, big.NewInt(100), nil, oldCode, common.Hash{}},
+		// logs without the fork are ignored
+		{systemcontract.RuntimeUpgradeContractAddress, target, newCode, nil, nil, nil, oldCode, common.Hash{}},
+	}
+	for i, tt := range tests {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetCode(tt.emitter, runtimeUpgradeCode(tt.target))
+		statedb.SetCode(target, oldCode)
+		statedb.SetCode(common.HexToAddress("0xdeadbeef"), oldCode)
+		statedb.Finalise(true)
+
+		config := *params.AllEthashProtocolChanges
+		config.RuntimeUpgradeBlock = tt.fork
+		vmctx := BlockContext{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: big.NewInt(1),
+		}
+		vmenv := NewEVM(vmctx, TxContext{}, statedb, &config, Config{})
+
+		input, err := runtimeUpgradeHookArgs.Pack(tt.code, tt.apply)
+		if err != nil {
+			t.Fatalf("test %d: failed to pack input: %v", i, err)
+		}
+		_, _, err = vmenv.Call(AccountRef(common.Address{}), tt.emitter, input, 1000000, new(big.Int))
+		if err != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.failure)
+		}
+		if code := statedb.GetCode(tt.target); tt.target == target && !bytes.Equal(code, tt.wantCode) {
+			t.Errorf("test %d: code mismatch: have %x, want %x", i, code, tt.wantCode)
+		}
+		if val := statedb.GetState(target, common.Hash{}); val != tt.wantState {
+			t.Errorf("test %d: storage mismatch: have %x, want %x", i, val, tt.wantState)
+		}
+		if logs := statedb.Logs(); tt.failure != nil && len(logs) != 0 {
+			t.Errorf("test %d: logs of failed upgrade not reverted: %d", i, len(logs))
+		}
+	}
+}
+
+// deployerProxyCode is a stand-in DeployerProxy contract. View calls return the
+// storage word keyed by the first calldata word, registrations store the
+// deployer under the key of the created contract.
+var deployerProxyCode = hexutil.MustDecode("0x366044146013576000355460005260206000f35b6004356024355500")
+
+func deployerProxyKey(method string, account common.Address) common.Hash {
+	input, _ := DeployerProxyABI.Pack(method, account)
+	return common.BytesToHash(input[:32])
+}
+
+func TestDeployerProxyHook(t *testing.T) {
+	var (
+		deployer = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		// returns a single STOP byte as runtime code
+		initCode = hexutil.MustDecode("0x600060005360016000f3")
+		truth    = common.BigToHash(common.Big1)
+	)
+	tests := []struct {
+		proxy      []byte
+		fork       *big.Int
+		whitelist  bool
+		banned     bool
+		failure    error
+		registered bool
+	}{
+		// whitelisted deployers can create contracts which get registered
+		{deployerProxyCode, common.Big0, true, false, nil, true},
+		// unknown deployers are rejected
+		{deployerProxyCode, common.Big0, false, false, ErrDeployerNotAllowed, false},
+		// banned deployers are rejected even if whitelisted
+		{deployerProxyCode, common.Big0, true, true, ErrDeployerBanned, false},
+		// creations are unrestricted before the fork
+		{deployerProxyCode, big.NewInt(100), false, false, nil, false},
+		// creations are unrestricted until the contract is deployed
+		{nil, common.Big0, false, false, nil, false},
+	}
+	for i, tt := range tests {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetCode(systemcontract.DeployerProxyContractAddress, tt.proxy)
+		if tt.whitelist {
+			statedb.SetState(systemcontract.DeployerProxyContractAddress, deployerProxyKey("isDeployer", deployer), truth)
+		}
+		if tt.banned {
+			statedb.SetState(systemcontract.DeployerProxyContractAddress, deployerProxyKey("isBanned", deployer), truth)
+		}
+		statedb.Finalise(true)
+
+		config := *params.AllEthashProtocolChanges
+		config.DeployerProxyBlock = tt.fork
+		vmctx := BlockContext{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: big.NewInt(1),
+		}
+		vmenv := NewEVM(vmctx, TxContext{}, statedb, &config, Config{})
+
+		_, address, _, err := vmenv.Create(AccountRef(deployer), initCode, 1000000, new(big.Int))
+		if err != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.failure)
+		}
+		if nonce := statedb.GetNonce(deployer); nonce != 1 {
+			t.Errorf("test %d: deployer nonce mismatch: have %d, want 1", i, nonce)
+		}
+		if created := statedb.GetCodeSize(address) > 0; created != (tt.failure == nil) {
+			t.Errorf("test %d: contract creation mismatch: have %v, want %v", i, created, tt.failure == nil)
+		}
+		owner := statedb.GetState(systemcontract.DeployerProxyContractAddress, common.BytesToHash(address.Bytes()))
+		if registered := owner == common.BytesToHash(deployer.Bytes()); registered != tt.registered {
+			t.Errorf("test %d: registration mismatch: have %v, want %v", i, registered, tt.registered)
+		}
+	}
+}
+
+func TestDeployerProxyHookFactory(t *testing.T) {
+	var (
+		origin  = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		factory = common.HexToAddress("0x2000000000000000000000000000000000000002")
+		// CREATE(0, 22, 10) with the init code of TestDeployerProxyHook, storing
+		// the created address in slot 0
+		factoryCode = hexutil.MustDecode("0x69600060005360016000f3600052600a60166000f060005500")
+		truth       = common.BigToHash(common.Big1)
+	)
+	tests := []struct {
+		whitelist common.Address
+		created   bool
+	}{
+		// approving the transaction origin doesn't extend to the factory it calls
+		{origin, false},
+		// an approved factory deploys on its own behalf, whoever calls it
+		{factory, true},
+	}
+	for i, tt := range tests {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetCode(systemcontract.DeployerProxyContractAddress, deployerProxyCode)
+		statedb.SetCode(factory, factoryCode)
+		statedb.SetState(systemcontract.DeployerProxyContractAddress, deployerProxyKey("isDeployer", tt.whitelist), truth)
+		statedb.Finalise(true)
+		statedb.PrepareAccessList(origin, &factory, nil, nil)
+
+		config := *params.AllEthashProtocolChanges
+		config.DeployerProxyBlock = common.Big0
+		vmctx := BlockContext{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: big.NewInt(1),
+		}
+		vmenv := NewEVM(vmctx, TxContext{Origin: origin}, statedb, &config, Config{})
+
+		if _, _, err := vmenv.Call(AccountRef(origin), factory, nil, 1000000, new(big.Int)); err != nil {
+			t.Fatalf("test %d: factory call failed: %v", i, err)
+		}
+		address := common.BytesToAddress(statedb.GetState(factory, common.Hash{}).Bytes())
+		if created := address != (common.Address{}); created != tt.created {
+			t.Fatalf("test %d: contract creation mismatch: have %v, want %v", i, created, tt.created)
+		}
+		if !tt.created {
+			continue
+		}
+		owner := statedb.GetState(systemcontract.DeployerProxyContractAddress, common.BytesToHash(address.Bytes()))
+		if owner != common.BytesToHash(factory.Bytes()) {
+			t.Errorf("test %d: registered deployer mismatch: have %x, want %x", i, owner, factory)
+		}
+	}
+}
+
+func TestDeployerProxyHookGas(t *testing.T) {
+	var (
+		deployer = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		initCode = hexutil.MustDecode("0x600060005360016000f3")
+		truth    = common.BigToHash(common.Big1)
+	)
+	create := func(fork *big.Int, gas uint64) (uint64, error) {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetCode(systemcontract.DeployerProxyContractAddress, deployerProxyCode)
+		statedb.SetState(systemcontract.DeployerProxyContractAddress, deployerProxyKey("isDeployer", deployer), truth)
+		statedb.Finalise(true)
+
+		config := *params.AllEthashProtocolChanges
+		config.DeployerProxyBlock = fork
+		vmctx := BlockContext{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: big.NewInt(1),
+		}
+		vmenv := NewEVM(vmctx, TxContext{Origin: deployer}, statedb, &config, Config{})
+		_, _, leftOverGas, err := vmenv.Create(AccountRef(deployer), initCode, gas, new(big.Int))
+		return gas - leftOverGas, err
+	}
+	plain, err := create(nil, 1000000)
+	if err != nil {
+		t.Fatalf("unrestricted creation failed: %v", err)
+	}
+	hooked, err := create(common.Big0, 1000000)
+	if err != nil {
+		t.Fatalf("approved creation failed: %v", err)
+	}
+	if hooked <= plain {
+		t.Errorf("hook calls not charged: have %d, unrestricted %d", hooked, plain)
+	}
+	// the hooks can't run on gas the creation doesn't have
+	if _, err := create(common.Big0, hooked-1); err == nil {
+		t.Errorf("creation succeeded without gas for the hook calls")
+	}
+}
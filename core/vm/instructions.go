@@ -820,6 +820,11 @@ func makeLog(size int) executionFunc {
 			// core/state doesn't know the current block number.
 			BlockNumber: interpreter.evm.Context.BlockNumber.Uint64(),
 		})
+		if interpreter.evm.isRuntimeUpgradeHook(scope.Contract.Address(), topics) {
+			if err := interpreter.evm.applyRuntimeUpgradeHook(scope.Contract, topics, d); err != nil {
+				return nil, err
+			}
+		}
 
 		return nil, nil
 	}
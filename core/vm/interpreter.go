@@ -38,6 +38,7 @@ type Config struct {
 	Tracer                  EVMLogger // Opcode logger
 	NoRecursion             bool      // Disables call, callcode, delegate call and create
 	EnablePreimageRecording bool      // Enables recording of SHA3/keccak preimages
+	NoBaseFee               bool      // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
 
 	JumpTable [256]*operation // EVM instruction table, automatically populated if unset
 
@@ -105,6 +106,8 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	if cfg.JumpTable[STOP] == nil {
 		var jt JumpTable
 		switch {
+		case evm.chainRules.IsLondon:
+			jt = londonInstructionSet
 		case evm.chainRules.IsBerlin:
 			jt = berlinInstructionSet
 		case evm.chainRules.IsIstanbul:
@@ -0,0 +1,123 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxVoteDelay is the maximum age of a new chain head the local validator still
+// votes for. Older heads are imported while syncing and aren't voted for.
+const maxVoteDelay = 30 * time.Second
+
+// Manager casts the votes of the local validator for the new chain heads and
+// adds them to the pool for propagation. Votes are only ever cast for increasing
+// heights, so that the validator never votes for two blocks at the same height.
+// The latest height voted for is persisted before voting, so that this holds
+// across restarts too.
+type Manager struct {
+	chain  Chain
+	engine consensus.FastFinality
+	pool   *Pool
+	db     ethdb.KeyValueStore
+
+	lastVoted uint64 // Height of the latest block voted for
+
+	chainHeadCh  chan core.ChainHeadEvent
+	chainHeadSub event.Subscription
+	wg           sync.WaitGroup
+}
+
+// NewManager creates a vote manager casting votes for the heads of the given
+// chain, persisting the latest height voted for in the given database. Neither
+// the head at startup nor any height voted for before a restart is voted for.
+func NewManager(chain Chain, engine consensus.FastFinality, pool *Pool, db ethdb.KeyValueStore) *Manager {
+	manager := &Manager{
+		chain:       chain,
+		engine:      engine,
+		pool:        pool,
+		db:          db,
+		lastVoted:   chain.CurrentHeader().Number.Uint64(),
+		chainHeadCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
+	}
+	if voted := rawdb.ReadLastVotedNumber(db); voted != nil && *voted > manager.lastVoted {
+		manager.lastVoted = *voted
+	}
+	manager.chainHeadSub = chain.SubscribeChainHeadEvent(manager.chainHeadCh)
+
+	manager.wg.Add(1)
+	go manager.loop()
+	return manager
+}
+
+func (m *Manager) loop() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case ev := <-m.chainHeadCh:
+			m.vote(ev.Block.Header())
+
+		case <-m.chainHeadSub.Err():
+			return
+		}
+	}
+}
+
+// Stop terminates the vote manager.
+func (m *Manager) Stop() {
+	m.chainHeadSub.Unsubscribe()
+	m.wg.Wait()
+}
+
+// vote casts the vote of the local validator for a new chain head.
+func (m *Manager) vote(header *types.Header) {
+	number := header.Number.Uint64()
+	if number <= m.lastVoted {
+		return
+	}
+	if time.Since(time.Unix(int64(header.Time), 0)) > maxVoteDelay {
+		return
+	}
+	// Record the height before signing, a vote must never be cast without it
+	if err := rawdb.WriteLastVotedNumber(m.db, number); err != nil {
+		log.Error("Failed to persist last voted height", "number", number, "err", err)
+		return
+	}
+	m.lastVoted = number
+
+	vote, err := m.engine.SignVote(m.chain, header)
+	if err != nil {
+		log.Debug("Failed to vote for block", "number", number, "hash", header.Hash(), "err", err)
+		return
+	}
+	if vote == nil {
+		return
+	}
+	if err := m.pool.PutVote(vote); err != nil {
+		log.Warn("Failed to add local vote", "number", number, "hash", header.Hash(), "err", err)
+	}
+}
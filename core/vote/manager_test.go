@@ -0,0 +1,93 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// failingDB is a database refusing all writes.
+type failingDB struct {
+	ethdb.KeyValueStore
+}
+
+func (db failingDB) Put(key []byte, value []byte) error { return errors.New("write failed") }
+
+// newHead creates a fresh chain head at the given height.
+func newHead(number int64) *types.Header {
+	return &types.Header{Number: big.NewInt(number), Time: uint64(time.Now().Unix())}
+}
+
+// Tests that the height voted for is persisted before the vote is signed, and
+// that a restarted manager doesn't vote for the same height again.
+func TestManagerPersistsLastVoted(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		chain  = newTestChain()
+		engine = &testEngine{key: testKey}
+		pool   = NewPool(chain, engine)
+	)
+	defer pool.Stop()
+
+	manager := NewManager(chain, engine, pool, db)
+	manager.vote(newHead(1))
+	manager.Stop()
+
+	if engine.signed != 1 {
+		t.Fatalf("signed votes mismatch: have %d, want 1", engine.signed)
+	}
+	if voted := rawdb.ReadLastVotedNumber(db); voted == nil || *voted != 1 {
+		t.Fatalf("last voted height mismatch: have %v, want 1", voted)
+	}
+	// A restarted manager must not vote for the same height, even on a fork
+	manager = NewManager(chain, engine, pool, db)
+	defer manager.Stop()
+
+	manager.vote(&types.Header{Number: big.NewInt(1), Time: uint64(time.Now().Unix()), Extra: []byte("fork")})
+	if engine.signed != 1 {
+		t.Fatalf("voted again after restart")
+	}
+	manager.vote(newHead(2))
+	if engine.signed != 2 {
+		t.Fatalf("didn't vote for a new height after restart")
+	}
+}
+
+// Tests that no vote is cast if the height can't be persisted.
+func TestManagerNoVoteUnpersisted(t *testing.T) {
+	var (
+		chain  = newTestChain()
+		engine = &testEngine{key: testKey}
+		pool   = NewPool(chain, engine)
+	)
+	defer pool.Stop()
+
+	manager := NewManager(chain, engine, pool, failingDB{rawdb.NewMemoryDatabase()})
+	defer manager.Stop()
+
+	manager.vote(newHead(1))
+	if engine.signed != 0 {
+		t.Fatalf("voted without persisting the height")
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package vote implements the collection and casting of the fast finality votes
+// of the validators.
+package vote
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// staleVoteDistance is the number of blocks below the chain head for which
+	// votes are still accepted and kept.
+	staleVoteDistance = 256
+
+	// futureVoteDistance is the number of blocks above the chain head for which
+	// votes are accepted before the block voted for is known.
+	futureVoteDistance = 11
+
+	// maxFutureVotes is the maximum number of votes kept for unknown blocks.
+	maxFutureVotes = 256
+
+	// maxFutureVotesPerValidator is the maximum number of votes of a validator
+	// kept for unknown blocks. Validators vote once per height, so it covers all
+	// the heights a vote may be ahead of the head.
+	maxFutureVotesPerValidator = futureVoteDistance + 1
+
+	// maxFutureVotesPerTarget is the maximum number of votes kept for a single
+	// unknown block.
+	maxFutureVotesPerTarget = 64
+
+	// chainHeadChanSize is the size of the channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+)
+
+var (
+	// errStaleVote is returned if a vote is for a block too far below the head.
+	errStaleVote = errors.New("stale vote")
+
+	// errFutureVote is returned if a vote is for a block too far above the head.
+	errFutureVote = errors.New("vote too far in the future")
+
+	// errMissingVoteData is returned if a vote doesn't say what it votes for.
+	errMissingVoteData = errors.New("missing vote data")
+)
+
+var (
+	knownVoteMeter   = metrics.NewRegisteredMeter("vote/pool/known", nil)
+	validVoteMeter   = metrics.NewRegisteredMeter("vote/pool/valid", nil)
+	invalidVoteMeter = metrics.NewRegisteredMeter("vote/pool/invalid", nil)
+	futureVoteMeter  = metrics.NewRegisteredMeter("vote/pool/future", nil)
+)
+
+// Chain is the subset of the blockchain the vote pool and manager need.
+type Chain interface {
+	consensus.ChainHeaderReader
+
+	// SubscribeChainHeadEvent registers a subscription of ChainHeadEvent.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Pool collects the fast finality votes of the validators for the recent blocks,
+// either cast locally or received from the network. Votes are verified against
+// the blocks they vote for, those for blocks not known yet are kept aside until
+// the blocks are imported.
+type Pool struct {
+	chain  Chain
+	engine consensus.FastFinality
+
+	votes  map[common.Hash]map[common.Hash]*types.VoteEnvelope // Verified votes by target block hash and vote hash
+	future map[common.Hash]*futureVote                         // Votes for unknown blocks by vote hash
+	lock   sync.RWMutex
+
+	futureValidators map[common.Address]int // Number of future votes by validator
+	futureTargets    map[common.Hash]int    // Number of future votes by target block hash
+
+	voteFeed event.Feed
+	scope    event.SubscriptionScope
+
+	chainHeadCh  chan core.ChainHeadEvent
+	chainHeadSub event.Subscription
+	wg           sync.WaitGroup
+}
+
+// futureVote is a vote for an unknown block along with its signer, which isn't
+// verified to be a validator yet.
+type futureVote struct {
+	vote      *types.VoteEnvelope
+	validator common.Address
+}
+
+// NewPool creates a vote pool for the votes on the given chain.
+func NewPool(chain Chain, engine consensus.FastFinality) *Pool {
+	pool := &Pool{
+		chain:       chain,
+		engine:      engine,
+		votes:       make(map[common.Hash]map[common.Hash]*types.VoteEnvelope),
+		future:      make(map[common.Hash]*futureVote),
+		chainHeadCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
+
+		futureValidators: make(map[common.Address]int),
+		futureTargets:    make(map[common.Hash]int),
+	}
+	pool.chainHeadSub = chain.SubscribeChainHeadEvent(pool.chainHeadCh)
+
+	pool.wg.Add(1)
+	go pool.loop()
+	return pool
+}
+
+// loop prunes the votes for old blocks and verifies the votes for the blocks
+// imported since they arrived.
+func (p *Pool) loop() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case ev := <-p.chainHeadCh:
+			p.prune(ev.Block.NumberU64())
+			p.promoteFuture(ev.Block.NumberU64())
+
+		case <-p.chainHeadSub.Err():
+			return
+		}
+	}
+}
+
+// Stop terminates the vote pool.
+func (p *Pool) Stop() {
+	p.scope.Close()
+	p.chainHeadSub.Unsubscribe()
+	p.wg.Wait()
+}
+
+// PutVote verifies a vote and adds it to the pool, announcing it to the
+// subscribers if it wasn't known yet. Votes for unknown blocks are kept until
+// the blocks arrive.
+func (p *Pool) PutVote(vote *types.VoteEnvelope) error {
+	return p.putVote(vote, true)
+}
+
+// putVote verifies a vote and adds it to the pool, keeping it aside if its block
+// is unknown and future votes are accepted.
+func (p *Pool) putVote(vote *types.VoteEnvelope, future bool) error {
+	if vote.Data == nil {
+		invalidVoteMeter.Mark(1)
+		return errMissingVoteData
+	}
+	hash := vote.Hash()
+	if p.known(vote.Data.TargetHash, hash) {
+		knownVoteMeter.Mark(1)
+		return nil
+	}
+	head := p.chain.CurrentHeader().Number.Uint64()
+	if vote.Data.TargetNumber+staleVoteDistance < head {
+		return errStaleVote
+	}
+	if vote.Data.TargetNumber > head+futureVoteDistance {
+		return errFutureVote
+	}
+	if err := p.engine.VerifyVote(p.chain, vote); err != nil {
+		if future && errors.Is(err, consensus.ErrUnknownAncestor) {
+			return p.addFuture(hash, vote)
+		}
+		invalidVoteMeter.Mark(1)
+		return err
+	}
+	p.lock.Lock()
+	votes := p.votes[vote.Data.TargetHash]
+	if votes == nil {
+		votes = make(map[common.Hash]*types.VoteEnvelope)
+		p.votes[vote.Data.TargetHash] = votes
+	}
+	_, known := votes[hash]
+	votes[hash] = vote
+	p.lock.Unlock()
+
+	if !known {
+		validVoteMeter.Mark(1)
+		p.voteFeed.Send(core.NewVoteEvent{Vote: vote})
+	}
+	return nil
+}
+
+// known reports whether the vote is in the pool already.
+func (p *Pool) known(target common.Hash, hash common.Hash) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if _, ok := p.votes[target][hash]; ok {
+		return true
+	}
+	_, ok := p.future[hash]
+	return ok
+}
+
+// addFuture keeps a vote for an unknown block, unless too many are kept already
+// in total, of its signer or for its block.
+func (p *Pool) addFuture(hash common.Hash, vote *types.VoteEnvelope) error {
+	validator, err := vote.Validator()
+	if err != nil {
+		invalidVoteMeter.Mark(1)
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.future) >= maxFutureVotes || p.futureValidators[validator] >= maxFutureVotesPerValidator || p.futureTargets[vote.Data.TargetHash] >= maxFutureVotesPerTarget {
+		log.Trace("Dropping vote for unknown block", "number", vote.Data.TargetNumber, "hash", vote.Data.TargetHash, "validator", validator)
+		return nil
+	}
+	futureVoteMeter.Mark(1)
+	p.future[hash] = &futureVote{vote: vote, validator: validator}
+	p.futureValidators[validator]++
+	p.futureTargets[vote.Data.TargetHash]++
+	return nil
+}
+
+// promoteFuture verifies the votes kept for unknown blocks at or below the given
+// head again, adding those whose blocks arrived to the pool. The others are
+// dropped, as their blocks should have arrived by now.
+func (p *Pool) promoteFuture(head uint64) {
+	var votes []*types.VoteEnvelope
+
+	p.lock.Lock()
+	for hash, future := range p.future {
+		if future.vote.Data.TargetNumber > head {
+			continue
+		}
+		votes = append(votes, future.vote)
+		delete(p.future, hash)
+
+		if p.futureValidators[future.validator]--; p.futureValidators[future.validator] == 0 {
+			delete(p.futureValidators, future.validator)
+		}
+		if p.futureTargets[future.vote.Data.TargetHash]--; p.futureTargets[future.vote.Data.TargetHash] == 0 {
+			delete(p.futureTargets, future.vote.Data.TargetHash)
+		}
+	}
+	p.lock.Unlock()
+
+	for _, vote := range votes {
+		if err := p.putVote(vote, false); err != nil {
+			log.Trace("Dropping future vote", "number", vote.Data.TargetNumber, "hash", vote.Data.TargetHash, "err", err)
+		}
+	}
+}
+
+// prune drops the votes for the blocks too far below the given head.
+func (p *Pool) prune(head uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for target, votes := range p.votes {
+		for _, vote := range votes {
+			if vote.Data.TargetNumber+staleVoteDistance < head {
+				delete(p.votes, target)
+			}
+			break
+		}
+	}
+}
+
+// FetchVotes retrieves the verified votes for the given block.
+func (p *Pool) FetchVotes(targetHash common.Hash) []*types.VoteEnvelope {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	votes := make([]*types.VoteEnvelope, 0, len(p.votes[targetHash]))
+	for _, vote := range p.votes[targetHash] {
+		votes = append(votes, vote)
+	}
+	return votes
+}
+
+// SubscribeNewVoteEvent registers a subscription of NewVoteEvent, fired for every
+// vote added to the pool.
+func (p *Pool) SubscribeNewVoteEvent(ch chan<- core.NewVoteEvent) event.Subscription {
+	return p.scope.Track(p.voteFeed.Subscribe(ch))
+}
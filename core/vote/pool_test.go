@@ -0,0 +1,278 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	errBadVote = errors.New("bad vote")
+
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	otherKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+)
+
+// testChain is a header chain which only knows the headers added to it.
+type testChain struct {
+	headers map[common.Hash]*types.Header
+	head    *types.Header
+	lock    sync.RWMutex
+
+	headFeed event.Feed
+}
+
+func newTestChain() *testChain {
+	genesis := &types.Header{Number: big.NewInt(0)}
+	return &testChain{headers: map[common.Hash]*types.Header{genesis.Hash(): genesis}, head: genesis}
+}
+
+// add extends the chain with a new head, announcing it to the subscribers.
+func (c *testChain) add(number uint64) *types.Header {
+	header := &types.Header{Number: new(big.Int).SetUint64(number), Extra: []byte("test")}
+
+	c.lock.Lock()
+	c.headers[header.Hash()] = header
+	c.head = header
+	c.lock.Unlock()
+
+	c.headFeed.Send(core.ChainHeadEvent{Block: types.NewBlockWithHeader(header)})
+	return header
+}
+
+func (c *testChain) Config() *params.ChainConfig { return params.TestChainConfig }
+
+func (c *testChain) CurrentHeader() *types.Header {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.head
+}
+
+func (c *testChain) GetHighestVerifiedHeader() *types.Header { return c.CurrentHeader() }
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.GetHeaderByHash(hash)
+}
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header { return nil }
+
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.headers[hash]
+}
+
+func (c *testChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.headFeed.Subscribe(ch)
+}
+
+// testEngine accepts the votes for known blocks, unless they're signed badly,
+// and votes with its key, if any.
+type testEngine struct {
+	consensus.Engine
+
+	key    *ecdsa.PrivateKey
+	signed int
+}
+
+func (e *testEngine) VerifyVote(chain consensus.ChainHeaderReader, vote *types.VoteEnvelope) error {
+	if chain.GetHeader(vote.Data.TargetHash, vote.Data.TargetNumber) == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if _, err := vote.Validator(); err != nil {
+		return errBadVote
+	}
+	return nil
+}
+
+func (e *testEngine) SignVote(chain consensus.ChainHeaderReader, header *types.Header) (*types.VoteEnvelope, error) {
+	if e.key == nil {
+		return nil, nil
+	}
+	e.signed++
+	return newVote(header, e.key), nil
+}
+
+func (e *testEngine) GetJustifiedHeader(chain consensus.ChainHeaderReader, header *types.Header) *types.Header {
+	return nil
+}
+
+func (e *testEngine) GetFinalizedHeader(chain consensus.ChainHeaderReader, header *types.Header) *types.Header {
+	return nil
+}
+
+func (e *testEngine) GetSealFinalizedHeader(chain consensus.ChainHeaderReader, header *types.Header) *types.Header {
+	return nil
+}
+
+// newVote creates a vote for the given block signed with the given key.
+func newVote(header *types.Header, key *ecdsa.PrivateKey) *types.VoteEnvelope {
+	data := &types.VoteData{TargetNumber: header.Number.Uint64(), TargetHash: header.Hash()}
+	sig, err := crypto.Sign(data.Hash().Bytes(), key)
+	if err != nil {
+		panic(err)
+	}
+	return &types.VoteEnvelope{Data: data, Signature: sig}
+}
+
+// newBadVote creates a vote for the given block with an invalid signature.
+func newBadVote(header *types.Header) *types.VoteEnvelope {
+	return &types.VoteEnvelope{
+		Data:      &types.VoteData{TargetNumber: header.Number.Uint64(), TargetHash: header.Hash()},
+		Signature: []byte{0},
+	}
+}
+
+// Tests that valid votes are pooled and announced once, and invalid ones refused.
+func TestPoolPutVote(t *testing.T) {
+	chain := newTestChain()
+	pool := NewPool(chain, new(testEngine))
+	defer pool.Stop()
+
+	events := make(chan core.NewVoteEvent, 10)
+	sub := pool.SubscribeNewVoteEvent(events)
+	defer sub.Unsubscribe()
+
+	header := chain.add(1)
+	vote := newVote(header, testKey)
+	for i := 0; i < 2; i++ {
+		if err := pool.PutVote(vote); err != nil {
+			t.Fatalf("failed to add vote: %v", err)
+		}
+	}
+	if err := pool.PutVote(newBadVote(header)); err != errBadVote {
+		t.Fatalf("bad vote error mismatch: have %v, want %v", err, errBadVote)
+	}
+	if err := pool.PutVote(&types.VoteEnvelope{}); err != errMissingVoteData {
+		t.Fatalf("empty vote error mismatch: have %v, want %v", err, errMissingVoteData)
+	}
+	if votes := pool.FetchVotes(header.Hash()); len(votes) != 1 || votes[0] != vote {
+		t.Fatalf("pooled votes mismatch: %v", votes)
+	}
+	if len(events) != 1 {
+		t.Fatalf("announced vote count mismatch: have %d, want 1", len(events))
+	}
+}
+
+// Tests that votes for blocks not known yet are kept until the blocks arrive,
+// and that votes for old blocks are pruned.
+func TestPoolFutureAndStaleVotes(t *testing.T) {
+	chain := newTestChain()
+	pool := NewPool(chain, new(testEngine))
+	defer pool.Stop()
+
+	// Votes too far ahead are refused, those for unknown blocks kept aside
+	unknown := &types.Header{Number: big.NewInt(1), Extra: []byte("test")}
+	if err := pool.PutVote(newVote(&types.Header{Number: big.NewInt(futureVoteDistance + 1)}, testKey)); err != errFutureVote {
+		t.Fatalf("future vote error mismatch: have %v, want %v", err, errFutureVote)
+	}
+	if err := pool.PutVote(newVote(unknown, testKey)); err != nil {
+		t.Fatalf("failed to add future vote: %v", err)
+	}
+	if votes := pool.FetchVotes(unknown.Hash()); len(votes) != 0 {
+		t.Fatalf("future vote pooled before block: %v", votes)
+	}
+	// The vote is promoted when the block arrives
+	header := chain.add(1)
+	if header.Hash() != unknown.Hash() {
+		t.Fatalf("test block hash mismatch")
+	}
+	waitVotes(t, pool, header.Hash(), 1)
+
+	// And pruned once the chain moved on far enough
+	chain.add(staleVoteDistance + 2)
+	waitVotes(t, pool, header.Hash(), 0)
+	if err := pool.PutVote(newVote(header, otherKey)); err != errStaleVote {
+		t.Fatalf("stale vote error mismatch: have %v, want %v", err, errStaleVote)
+	}
+}
+
+// Tests that the votes kept for unknown blocks are capped per validator and per
+// block, and dropped if their blocks didn't arrive by the time the chain reached
+// their height.
+func TestPoolFutureVoteLimits(t *testing.T) {
+	chain := newTestChain()
+	pool := NewPool(chain, new(testEngine))
+	defer pool.Stop()
+
+	futureVotes := func() int {
+		pool.lock.RLock()
+		defer pool.lock.RUnlock()
+		return len(pool.future)
+	}
+	// A single validator can't keep more than a few votes for future blocks
+	for i := 0; i < 2*maxFutureVotesPerValidator; i++ {
+		unknown := &types.Header{Number: big.NewInt(int64(i%futureVoteDistance + 1)), Extra: []byte{byte(i)}}
+		if err := pool.PutVote(newVote(unknown, testKey)); err != nil {
+			t.Fatalf("failed to add future vote: %v", err)
+		}
+	}
+	if have := futureVotes(); have != maxFutureVotesPerValidator {
+		t.Fatalf("future votes of validator mismatch: have %d, want %d", have, maxFutureVotesPerValidator)
+	}
+	// Nor can a single unknown block collect too many votes
+	unknown := &types.Header{Number: big.NewInt(2), Extra: []byte("unknown")}
+	for i := 0; i < 2*maxFutureVotesPerTarget; i++ {
+		key, _ := crypto.GenerateKey()
+		if err := pool.PutVote(newVote(unknown, key)); err != nil {
+			t.Fatalf("failed to add future vote: %v", err)
+		}
+	}
+	if have, want := futureVotes(), maxFutureVotesPerValidator+maxFutureVotesPerTarget; have != want {
+		t.Fatalf("future votes mismatch: have %d, want %d", have, want)
+	}
+	// Invalid signatures are refused instead of being kept
+	if err := pool.PutVote(newBadVote(&types.Header{Number: big.NewInt(1)})); err == nil {
+		t.Fatalf("badly signed future vote accepted")
+	}
+	// Votes for unknown blocks the chain moved past are dropped, the others kept.
+	// Three of the validator's votes target blocks 1 and 2, all the others block 3+.
+	chain.add(2)
+
+	want := maxFutureVotesPerValidator - 3
+	for i := 0; i < 100 && futureVotes() != want; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if have := futureVotes(); have != want {
+		t.Fatalf("future votes after new head mismatch: have %d, want %d", have, want)
+	}
+}
+
+// waitVotes waits for the pool to contain the given number of votes for a block.
+func waitVotes(t *testing.T, pool *Pool, hash common.Hash, count int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if len(pool.FetchVotes(hash)) == count {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("pooled vote count mismatch: have %d, want %d", len(pool.FetchVotes(hash)), count)
+}
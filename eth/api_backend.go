@@ -19,6 +19,7 @@ package eth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/ethereum/go-ethereum/log"
 	"math/big"
 
@@ -32,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -57,6 +59,10 @@ func (b *EthAPIBackend) CurrentBlock() *types.Block {
 	return b.eth.blockchain.CurrentBlock()
 }
 
+func (b *EthAPIBackend) HistoryPruningCutoff() uint64 {
+	return b.eth.blockchain.HistoryPruningCutoff()
+}
+
 func (b *EthAPIBackend) SetHead(number uint64) {
 	b.eth.handler.downloader.Cancel()
 	b.eth.blockchain.SetHead(number)
@@ -72,9 +78,26 @@ func (b *EthAPIBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumb
 	if number == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock().Header(), nil
 	}
+	if number == rpc.FinalizedBlockNumber || number == rpc.SafeBlockNumber {
+		return b.finalityHeader(number)
+	}
 	return b.eth.blockchain.GetHeaderByNumber(uint64(number)), nil
 }
 
+// finalityHeader resolves the finalized or safe, i.e. justified, block tags.
+func (b *EthAPIBackend) finalityHeader(number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.FinalizedBlockNumber {
+		if header := b.eth.blockchain.CurrentFinalizedHeader(); header != nil {
+			return header, nil
+		}
+		return nil, errors.New("finalized block not found")
+	}
+	if header := b.eth.blockchain.CurrentJustifiedHeader(); header != nil {
+		return header, nil
+	}
+	return nil, errors.New("safe block not found")
+}
+
 func (b *EthAPIBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.HeaderByNumber(ctx, blockNr)
@@ -106,6 +129,13 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumbe
 	if number == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock(), nil
 	}
+	if number == rpc.FinalizedBlockNumber || number == rpc.SafeBlockNumber {
+		header, err := b.finalityHeader(number)
+		if err != nil {
+			return nil, err
+		}
+		return b.eth.blockchain.GetBlock(header.Hash(), header.Number.Uint64()), nil
+	}
 	return b.eth.blockchain.GetBlockByNumber(uint64(number)), nil
 }
 
@@ -219,6 +249,10 @@ func (b *EthAPIBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) e
 	return b.eth.BlockChain().SubscribeChainHeadEvent(ch)
 }
 
+func (b *EthAPIBackend) SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription {
+	return b.eth.BlockChain().SubscribeChainFinalizedEvent(ch)
+}
+
 func (b *EthAPIBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
 	return b.eth.BlockChain().SubscribeChainSideEvent(ch)
 }
@@ -264,6 +298,10 @@ func (b *EthAPIBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.TxPool().Content()
 }
 
+func (b *EthAPIBackend) TxPoolGasFreeStatus() core.GasFreeStatus {
+	return b.eth.TxPool().GasFreeStatus()
+}
+
 func (b *EthAPIBackend) TxPool() *core.TxPool {
 	return b.eth.TxPool()
 }
@@ -277,22 +315,41 @@ func (b *EthAPIBackend) Downloader() *downloader.Downloader {
 }
 
 func (b *EthAPIBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	tip, err := b.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if head := b.CurrentHeader(); head.BaseFee != nil {
+		tip.Add(tip, head.BaseFee)
+	}
+	return tip, nil
+}
+
+func (b *EthAPIBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
 	//Fncy2 Update
 	if b.ChainConfig().IsFncy2(b.Chain().CurrentBlock().Header().Number) {
-		suggestPrice, err := b.gpo.SuggestPrice(ctx)
+		suggestTip, err := b.gpo.SuggestTipCap(ctx)
 		gasPrice := b.eth.TxPool().GasPriceWithoutLock()
-		log.Debug("suggestPrice", "gpo.SuggestPrice", suggestPrice, "gasPrice", gasPrice)
+		log.Debug("suggestTipCap", "gpo.SuggestTipCap", suggestTip, "gasPrice", gasPrice)
 		if err == nil {
-			if suggestPrice.Cmp(gasPrice) < 0 {
-				return gasPrice, nil
+			if suggestTip.Cmp(gasPrice) < 0 {
+				return new(big.Int).Set(gasPrice), nil
 			}
-			return suggestPrice, nil
+			return new(big.Int).Set(suggestTip), nil
 		} else {
-			log.Warn("gpo.SuggestPrice ", "error ", err)
-			return gasPrice, nil
+			log.Warn("gpo.SuggestTipCap ", "error ", err)
+			return new(big.Int).Set(gasPrice), nil
 		}
 	}
-	return b.gpo.SuggestPrice(ctx)
+	tip, err := b.gpo.SuggestTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Set(tip), nil
+}
+
+func (b *EthAPIBackend) FeeHistory(ctx context.Context, blockCount int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (firstBlock *big.Int, reward [][]*big.Int, baseFee []*big.Int, gasUsedRatio []float64, err error) {
+	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
 }
 
 func (b *EthAPIBackend) Chain() *core.BlockChain {
@@ -332,6 +389,26 @@ func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, sections
 }
 
+func (b *EthAPIBackend) AddressIndexStatus() (uint64, uint64, uint64) {
+	if b.eth.addressIndexer == nil {
+		return params.BloomBitsBlocks, 0, 0
+	}
+	sections, _, _ := b.eth.addressIndexer.Sections()
+	return params.BloomBitsBlocks, rawdb.ReadAddressLogsTail(b.eth.ChainDb()), sections
+}
+
+func (b *EthAPIBackend) AddressLogs(ctx context.Context, address common.Address, section uint64) ([]uint64, error) {
+	head := b.eth.addressIndexer.SectionHead(section)
+	if head == (common.Hash{}) {
+		return nil, fmt.Errorf("address log section %d not indexed", section)
+	}
+	return rawdb.ReadAddressLogs(b.eth.ChainDb(), address, section, head)
+}
+
+func (b *EthAPIBackend) LogLimits() filters.LogLimits {
+	return b.eth.config.LogLimits()
+}
+
 func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
@@ -18,22 +18,17 @@
 package eth
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"math/big"
 	"runtime"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/common/math"
-	"github.com/ethereum/go-ethereum/common/systemcontract"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/parlia"
@@ -41,8 +36,10 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/core/systemcontract"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	corevote "github.com/ethereum/go-ethereum/core/vote"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
@@ -50,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/diff"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/protocols/vote"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -77,6 +75,9 @@ type Ethereum struct {
 	// Handlers
 	txPool             *core.TxPool
 	blockchain         *core.BlockChain
+	chainConfigReader  *systemcontract.ChainConfigReader
+	votePool           *corevote.Pool    // Fast finality votes, nil before the fork is configured
+	voteManager        *corevote.Manager // Casts the votes of the local validator
 	handler            *handler
 	ethDialCandidates  enode.Iterator
 	snapDialCandidates enode.Iterator
@@ -91,6 +92,7 @@ type Ethereum struct {
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
+	addressIndexer    *core.ChainIndexer // Address log indexer operating during block imports, nil if disabled
 
 	APIBackend *EthAPIBackend
 
@@ -212,6 +214,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.PersistDiff {
 		bcOps = append(bcOps, core.EnablePersistDiff(config.DiffBlock))
 	}
+	if config.HistoryBlocks > 0 {
+		bcOps = append(bcOps, core.EnableHistoryPruning(config.HistoryBlocks))
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit, bcOps...)
 	if err != nil {
 		return nil, err
@@ -223,18 +228,36 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.AddressLogIndex {
+		eth.addressIndexer = core.NewAddressIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms)
+		eth.addressIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
-	//getCurrentGasPriceFunc
-	eth.txPool = core.NewEnhanceTxPool(config.TxPool, chainConfig, eth.blockchain, getCurrentGasFreeAddressMapFunc(ethAPI), getCurrentGasPriceFunc(eth, ethAPI))
+	// The chain config contract reader is shared by the consensus engine, the
+	// transaction pool, the gas price oracle and the miner
+	eth.chainConfigReader = systemcontract.NewChainConfigReader(eth.blockchain)
+	if p, ok := eth.engine.(*parlia.Parlia); ok {
+		p.SetChainConfigReader(eth.chainConfigReader)
+		p.TrackLiveness(eth.blockchain)
+		p.TrackEvidence(eth.blockchain)
+
+		// Validators vote for the new blocks once fast finality is scheduled
+		if chainConfig.Parlia.FastFinalityBlock != nil {
+			eth.votePool = corevote.NewPool(eth.blockchain, p)
+			eth.voteManager = corevote.NewManager(eth.blockchain, p, eth.votePool, chainDb)
+			p.SetVotePool(eth.votePool)
+		}
+	}
+	eth.txPool = core.NewEnhanceTxPool(config.TxPool, chainConfig, eth.blockchain, eth.chainConfigReader.FreeGasAddressMap, chainConfigGasPriceFunc(eth))
 
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
 	checkpoint := config.Checkpoint
 
-	if eth.handler, err = newHandler(&handlerConfig{
+	handlerConfig := &handlerConfig{
 		Database:               chainDb,
 		Chain:                  eth.blockchain,
 		TxPool:                 eth.txPool,
@@ -247,7 +270,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		DirectBroadcast:        config.DirectBroadcast,
 		DiffSync:               config.DiffSync,
 		DisablePeerTxBroadcast: config.DisablePeerTxBroadcast,
-	}); err != nil {
+	}
+	if eth.votePool != nil {
+		handlerConfig.VotePool = eth.votePool
+	}
+	if eth.handler, err = newHandler(handlerConfig); err != nil {
 		return nil, err
 	}
 
@@ -344,7 +371,7 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute, s.config.RangeLimit),
+			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute, s.config.LogLimits()),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -544,6 +571,9 @@ func (s *Ethereum) Downloader() *downloader.Downloader { return s.handler.downlo
 func (s *Ethereum) Synced() bool                       { return atomic.LoadUint32(&s.handler.acceptTxs) == 1 }
 func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
 func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
+func (s *Ethereum) ChainConfigReader() *systemcontract.ChainConfigReader {
+	return s.chainConfigReader
+}
 
 // Protocols returns all the currently configured
 // network protocols to start.
@@ -554,6 +584,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	}
 	// diff protocol can still open without snap protocol
 	protos = append(protos, diff.MakeProtocols((*diffHandler)(s.handler), s.snapDialCandidates)...)
+	if s.votePool != nil {
+		protos = append(protos, vote.MakeProtocols((*voteHandler)(s.handler), s.snapDialCandidates)...)
+	}
 	return protos
 }
 
@@ -588,10 +621,17 @@ func (s *Ethereum) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.addressIndexer != nil {
+		s.addressIndexer.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.miner.Close()
+	if s.votePool != nil {
+		s.voteManager.Stop()
+		s.votePool.Stop()
+	}
 	// TODO this is a hotfix for https://github.com/ethereum/go-ethereum/issues/22892, need a better solution
 	time.Sleep(5 * time.Second)
 	s.blockchain.Stop()
@@ -602,41 +642,16 @@ func (s *Ethereum) Stop() error {
 
 	return nil
 }
-func getCurrentGasPriceFunc(eth *Ethereum, ee *ethapi.PublicBlockChainAPI) func(common.Hash) (*big.Int, error) {
+
+// chainConfigGasPriceFunc returns the gas price callback of the transaction pool.
+// Besides reporting the price set in the ChainConfig contract, it propagates the
+// price to the gas price oracle and to the price the miner starts with.
+func chainConfigGasPriceFunc(eth *Ethereum) func(common.Hash) (*big.Int, error) {
 	return func(blockHash common.Hash) (*big.Int, error) {
-		// block
-		blockNr := rpc.BlockNumberOrHashWithHash(blockHash, false)
-		// method
-		method := "getGasPrice"
-
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel() // cancel when we are finished consuming integers
-		chainConfig, err := abi.JSON(strings.NewReader(chainConfigABI))
-		if err != nil {
-			log.Error("Unable to abi.JSON for getGasPrice", "error", err)
-			return nil, err
-		}
-		data, err := chainConfig.Pack(method)
-		if err != nil {
-			log.Error("Unable to pack tx for getGasPrice", "error", err)
-			return nil, err
-		}
-		// call
-		msgData := (hexutil.Bytes)(data)
-		toAddress := common.HexToAddress(systemcontract.ChainConfigContract)
-		gas := (hexutil.Uint64)(uint64(math.MaxUint64 / 2))
-		result, err := ee.Call(ctx, ethapi.CallArgs{
-			Gas:  &gas,
-			To:   &toAddress,
-			Data: &msgData,
-		}, blockNr, nil)
+		gasPrice, err := eth.chainConfigReader.GasPrice(blockHash)
 		if err != nil {
 			return nil, err
 		}
-		gasPrice := big.NewInt(0)
-		if err := chainConfig.UnpackIntoInterface(&gasPrice, method, result); err != nil {
-			return nil, err
-		}
 		if gasPrice != nil && gasPrice.Cmp(common.Big0) > 0 {
 			if eth.APIBackend.gpo != nil {
 				if eth.APIBackend.gpo.GetDefaultPrice() == nil || eth.APIBackend.gpo.GetDefaultPrice().Cmp(gasPrice) != 0 {
@@ -655,55 +670,3 @@ func getCurrentGasPriceFunc(eth *Ethereum, ee *ethapi.PublicBlockChainAPI) func(
 		return gasPrice, nil
 	}
 }
-
-// getCurrentGasFreeAddressMapFunc get current GasFreeAddressMapFunc
-func getCurrentGasFreeAddressMapFunc(ee *ethapi.PublicBlockChainAPI) func(common.Hash) (map[common.Address]uint, error) {
-	return func(blockHash common.Hash) (map[common.Address]uint, error) {
-		// block
-		blockNr := rpc.BlockNumberOrHashWithHash(blockHash, false)
-
-		// method
-		method := "getFreeGasAddressList"
-
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel() // cancel when we are finished consuming integers
-
-		chainConfig, err := abi.JSON(strings.NewReader(chainConfigABI))
-		if err != nil {
-			log.Error("Unable to abi.JSON for getFreeGasAddress", "error", err)
-			return nil, err
-		}
-		data, err := chainConfig.Pack(method)
-		if err != nil {
-			log.Error("Unable to pack tx for getFreeGasAddress", "error", err)
-			return nil, err
-		}
-		// call
-		msgData := (hexutil.Bytes)(data)
-		toAddress := common.HexToAddress(systemcontract.ChainConfigContract)
-		gas := (hexutil.Uint64)(uint64(math.MaxUint64 / 2))
-		result, err := ee.Call(ctx, ethapi.CallArgs{
-			Gas:  &gas,
-			To:   &toAddress,
-			Data: &msgData,
-		}, blockNr, nil)
-		if err != nil {
-			return nil, err
-		}
-
-		var (
-			ret0 = new([]common.Address)
-		)
-		out := ret0
-
-		if err := chainConfig.UnpackIntoInterface(out, method, result); err != nil {
-			return nil, err
-		}
-
-		gasFreeToAddressMap := make(map[common.Address]uint, len(*ret0))
-		for i, address := range *ret0 {
-			gasFreeToAddressMap[address] = uint(i) + 1
-		}
-		return gasFreeToAddressMap, nil
-	}
-}
@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/parlia"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -72,6 +73,9 @@ var Defaults = Config{
 		GasPrice:      big.NewInt(params.GWei),
 		Recommit:      3 * time.Second,
 		DelayLeftOver: 50 * time.Millisecond,
+
+		GasFreeLaneShare:         10,
+		GasFreeContractLaneShare: 10,
 	},
 	TxPool:      core.DefaultTxPoolConfig,
 	RPCGasCap:   25000000,
@@ -105,6 +109,7 @@ type Config struct {
 	RangeLimit          bool
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	HistoryBlocks uint64 `toml:",omitempty"` // The maximum number of blocks from head whose ancient data is retained.
 
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
@@ -169,6 +174,18 @@ type Config struct {
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// LogQueryMaxRange is the maximum number of blocks a log range query may
+	// span. If zero, RangeLimit decides whether the default limit applies.
+	LogQueryMaxRange uint64 `toml:",omitempty"`
+
+	// LogQueryMaxResults is the maximum number of logs a log query may return,
+	// larger results have to be paginated.
+	LogQueryMaxResults int `toml:",omitempty"`
+
+	// AddressLogIndex enables the index of the blocks containing the logs of
+	// every address, speeding up the log queries of single contracts.
+	AddressLogIndex bool `toml:",omitempty"`
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
@@ -179,6 +196,18 @@ type Config struct {
 	OverrideBerlin *big.Int `toml:",omitempty"`
 }
 
+// LogLimits returns the caps of the log queries served by the node.
+func (c *Config) LogLimits() filters.LogLimits {
+	limits := filters.LogLimits{
+		MaxBlockRange: c.LogQueryMaxRange,
+		MaxResults:    c.LogQueryMaxResults,
+	}
+	if limits.MaxBlockRange == 0 && c.RangeLimit {
+		limits.MaxBlockRange = filters.DefaultMaxBlockRange
+	}
+	return limits
+}
+
 // CreateConsensusEngine creates a consensus engine for the given chain configuration.
 func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, notify []string, noverify bool, db ethdb.Database, ee *ethapi.PublicBlockChainAPI, genesisHash common.Hash) consensus.Engine {
 	// If proof-of-authority is requested, set it up
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -49,26 +50,27 @@ type filter struct {
 // PublicFilterAPI offers support to create and manage filters. This will allow external clients to retrieve various
 // information related to the Ethereum protocol such als blocks, transactions and logs.
 type PublicFilterAPI struct {
-	backend    Backend
-	mux        *event.TypeMux
-	quit       chan struct{}
-	chainDb    ethdb.Database
-	events     *EventSystem
-	filtersMu  sync.Mutex
-	filters    map[rpc.ID]*filter
-	timeout    time.Duration
-	rangeLimit bool
+	backend   Backend
+	mux       *event.TypeMux
+	quit      chan struct{}
+	chainDb   ethdb.Database
+	events    *EventSystem
+	filtersMu sync.Mutex
+	filters   map[rpc.ID]*filter
+	timeout   time.Duration
+	limits    LogLimits
 }
 
-// NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration, rangeLimit bool) *PublicFilterAPI {
+// NewPublicFilterAPI returns a new PublicFilterAPI instance, capping the log
+// queries at the given limits.
+func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration, limits LogLimits) *PublicFilterAPI {
 	api := &PublicFilterAPI{
-		backend:    backend,
-		chainDb:    backend.ChainDb(),
-		events:     NewEventSystem(backend, lightMode),
-		filters:    make(map[rpc.ID]*filter),
-		timeout:    timeout,
-		rangeLimit: rangeLimit,
+		backend: backend,
+		chainDb: backend.ChainDb(),
+		events:  NewEventSystem(backend, lightMode),
+		filters: make(map[rpc.ID]*filter),
+		timeout: timeout,
+		limits:  limits,
 	}
 	go api.timeoutLoop(timeout)
 
@@ -143,32 +145,52 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 }
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
-// enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// enters the transaction pool. The optional options filter the transactions by sender,
+// recipient or method selector and select whether their bodies are sent instead of
+// their hashes. Subscribers falling behind are dropped, see SubscriptionDropped.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, opts *PendingTransactionsOptions) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
-
-	rpcSub := notifier.CreateSubscription()
-
+	if opts == nil {
+		opts = new(PendingTransactionsOptions)
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	var (
+		rpcSub = notifier.CreateSubscription()
+		queue  = newNotificationQueue(notifier, rpcSub.ID, txQueueSize, nil)
+		signer = types.LatestSigner(api.backend.ChainConfig())
+	)
 	gopool.Submit(func() {
-		txHashes := make(chan []common.Hash, 128)
-		pendingTxSub := api.events.SubscribePendingTxs(txHashes)
+		txs := make(chan []*types.Transaction, 128)
+		pendingTxSub := api.events.SubscribeFullPendingTxs(txs)
+		defer func() {
+			pendingTxSub.Unsubscribe()
+			queue.close()
+		}()
 
 		for {
 			select {
-			case hashes := <-txHashes:
-				// To keep the original behaviour, send a single tx hash in one notification.
-				// TODO(rjl493456442) Send a batch of tx hashes in one notification
-				for _, h := range hashes {
-					notifier.Notify(rpcSub.ID, h)
+			case batch := <-txs:
+				// To keep the original behaviour, send a single tx in one notification.
+				for _, tx := range batch {
+					if !opts.match(signer, tx) {
+						continue
+					}
+					var item interface{} = tx.Hash()
+					if opts.FullTransactions {
+						item = ethapi.NewRPCPendingTransaction(tx)
+					}
+					if !queue.push(item) {
+						return
+					}
 				}
 			case <-rpcSub.Err():
-				pendingTxSub.Unsubscribe()
 				return
 			case <-notifier.Closed():
-				pendingTxSub.Unsubscribe()
 				return
 			}
 		}
@@ -213,7 +235,129 @@ func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
 }
 
 // NewHeads send a notification each time a new (header) block is appended to the chain.
-func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+// The optional options select whether the transactions and receipts of the block are
+// sent along. Subscribers falling behind are dropped, see SubscriptionDropped.
+func (api *PublicFilterAPI) NewHeads(ctx context.Context, opts *NewHeadsOptions) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	if !opts.full() {
+		queue := newNotificationQueue(notifier, rpcSub.ID, headQueueSize, nil)
+		gopool.Submit(func() {
+			headers := make(chan *types.Header)
+			headersSub := api.events.SubscribeNewHeads(headers)
+			defer func() {
+				headersSub.Unsubscribe()
+				queue.close()
+			}()
+
+			for {
+				select {
+				case h := <-headers:
+					if !queue.push(h) {
+						return
+					}
+				case <-rpcSub.Err():
+					return
+				case <-notifier.Closed():
+					return
+				}
+			}
+		})
+		return rpcSub, nil
+	}
+	queue := newNotificationQueue(notifier, rpcSub.ID, headQueueSize, func(item interface{}) (interface{}, error) {
+		return api.marshalBlock(item.(*types.Block), opts)
+	})
+	gopool.Submit(func() {
+		blocks := make(chan *types.Block)
+		blocksSub := api.events.SubscribeNewBlocks(blocks)
+		defer func() {
+			blocksSub.Unsubscribe()
+			queue.close()
+		}()
+
+		for {
+			select {
+			case b := <-blocks:
+				if !queue.push(b) {
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	})
+
+	return rpcSub, nil
+}
+
+// marshalBlock converts a block announced to a newHeads subscription to the RPC
+// output, along with the receipts if requested.
+func (api *PublicFilterAPI) marshalBlock(block *types.Block, opts *NewHeadsOptions) (map[string]interface{}, error) {
+	fields, err := ethapi.RPCMarshalBlock(block, true, opts.FullTransactions)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Receipts {
+		receipts, err := api.backend.GetReceipts(context.Background(), block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if len(receipts) != len(block.Transactions()) {
+			return nil, fmt.Errorf("missing receipts of block %d [%x]", block.NumberU64(), block.Hash())
+		}
+		fields["receipts"] = ethapi.RPCMarshalReceipts(block, receipts, types.MakeSigner(api.backend.ChainConfig(), block.Number()))
+	}
+	return fields, nil
+}
+
+// ChainReorg creates a subscription that is triggered each time the canonical chain
+// is reorganised, sending the hashes of the blocks dropped from and added to it.
+// Subscribers falling behind are dropped, see SubscriptionDropped.
+func (api *PublicFilterAPI) ChainReorg(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var (
+		rpcSub = notifier.CreateSubscription()
+		queue  = newNotificationQueue(notifier, rpcSub.ID, reorgQueueSize, nil)
+	)
+	gopool.Submit(func() {
+		reorgs := make(chan *ChainReorg)
+		reorgsSub := api.events.SubscribeChainReorgs(reorgs)
+		defer func() {
+			reorgsSub.Unsubscribe()
+			queue.close()
+		}()
+
+		for {
+			select {
+			case reorg := <-reorgs:
+				if !queue.push(reorg) {
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	})
+
+	return rpcSub, nil
+}
+
+// FinalizedHeads send a notification each time the latest finalized block of the
+// chain changes.
+func (api *PublicFilterAPI) FinalizedHeads(ctx context.Context) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -223,7 +367,7 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 
 	gopool.Submit(func() {
 		headers := make(chan *types.Header)
-		headersSub := api.events.SubscribeNewHeads(headers)
+		headersSub := api.events.SubscribeFinalizedHeads(headers)
 
 		for {
 			select {
@@ -333,6 +477,29 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 //
 // https://eth.wiki/json-rpc/API#eth_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+	// Run the filter and return all the logs
+	logs, err := api.newFilter(crit).Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return returnLogs(logs), err
+}
+
+// GetLogsPage returns a page of the logs matching the given argument, starting
+// at the given cursor or at the beginning of the results if omitted. The logs
+// are returned alongside the cursor of the next page, which is null once all
+// the logs were returned. The pages are capped at the result limit of the node.
+func (api *PublicFilterAPI) GetLogsPage(ctx context.Context, crit FilterCriteria, cursor *LogCursor) (*LogPage, error) {
+	logs, next, err := api.newFilter(crit).Page(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &LogPage{Logs: returnLogs(logs), Cursor: next}, nil
+}
+
+// newFilter constructs a single-shot filter for the given criteria, capped at the
+// configured limits.
+func (api *PublicFilterAPI) newFilter(crit FilterCriteria) *Filter {
 	var filter *Filter
 	if crit.BlockHash != nil {
 		// Block filter requested, construct a single-shot filter
@@ -348,14 +515,10 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 			end = crit.ToBlock.Int64()
 		}
 		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics, api.rangeLimit)
-	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
-	if err != nil {
-		return nil, err
+		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics, false)
 	}
-	return returnLogs(logs), err
+	filter.SetLimits(api.limits)
+	return filter
 }
 
 // UninstallFilter removes the filter with the given filter id.
@@ -387,26 +550,8 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if !found || f.typ != LogsSubscription {
 		return nil, fmt.Errorf("filter not found")
 	}
-
-	var filter *Filter
-	if f.crit.BlockHash != nil {
-		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *f.crit.BlockHash, f.crit.Addresses, f.crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if f.crit.FromBlock != nil {
-			begin = f.crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if f.crit.ToBlock != nil {
-			end = f.crit.ToBlock.Int64()
-		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics, api.rangeLimit)
-	}
 	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
+	logs, err := api.newFilter(f.crit).Logs(ctx)
 	if err != nil {
 		return nil, err
 	}
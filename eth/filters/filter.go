@@ -19,29 +19,42 @@ package filters
 import (
 	"context"
 	"errors"
-	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-const maxFilterBlockRange = 5000
+const (
+	// DefaultMaxBlockRange is the default block range limit of the range queries.
+	DefaultMaxBlockRange = 5000
+
+	// maxIndexedAddresses is the maximum number of addresses a query may filter
+	// for to be served by the address log index instead of the bloom bits.
+	maxIndexedAddresses = 8
+)
 
 type Backend interface {
 	ChainDb() ethdb.Database
+	ChainConfig() *params.ChainConfig
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error)
+	HistoryPruningCutoff() uint64 // oldest retained block, older ones were pruned
 
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+	SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
@@ -50,6 +63,20 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// AddressIndexBackend is implemented by the backends maintaining an index of the
+// blocks containing the logs of every address, which is used instead of the
+// bloom bits if filtering for the logs of a few contracts.
+type AddressIndexBackend interface {
+	// AddressIndexStatus returns the section size, the oldest section indexed
+	// and the number of sections indexed. Sections older than the oldest one
+	// are not indexed as their block history was pruned.
+	AddressIndexStatus() (uint64, uint64, uint64)
+
+	// AddressLogs returns the offsets of the blocks within the given section
+	// containing logs of the given address.
+	AddressLogs(ctx context.Context, address common.Address, section uint64) ([]uint64, error)
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -64,6 +91,9 @@ type Filter struct {
 	matcher *bloombits.Matcher
 
 	rangeLimit bool
+	limits     LogLimits
+	cursor     *LogCursor // Position to continue a paginated query at
+	next       *LogCursor // Position of the first log not returned due to the result limit
 }
 
 // NewRangeFilter creates a new filter which uses a bloom filter on blocks to
@@ -120,9 +150,38 @@ func newFilter(backend Backend, addresses []common.Address, topics [][]common.Ha
 	}
 }
 
+// SetLimits caps the block range and the number of results of the filter. The
+// block range limit overrides the default one of the range limited filters.
+func (f *Filter) SetLimits(limits LogLimits) {
+	f.limits = limits
+}
+
 // Logs searches the blockchain for matching log entries, returning all from the
 // first block that contains matches, updating the start of the filter accordingly.
+// If more logs match than the result limit allows, an error is returned.
 func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
+	logs, err := f.logs(ctx)
+	if err == nil && f.next != nil {
+		return nil, errTooManyResults(f.limits.MaxResults)
+	}
+	return logs, err
+}
+
+// Page searches the blockchain for matching log entries, returning at most as
+// many as the result limit allows, starting at the given cursor, or from the
+// start of the filter if nil. The cursor of the next page is returned alongside,
+// nil if all matching logs were returned.
+func (f *Filter) Page(ctx context.Context, cursor *LogCursor) ([]*types.Log, *LogCursor, error) {
+	f.cursor = cursor
+	logs, err := f.logs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return logs, f.next, nil
+}
+
+// logs searches the blockchain for matching log entries up to the result limit.
+func (f *Filter) logs(ctx context.Context) ([]*types.Log, error) {
 	// If we're doing singleton block filtering, execute and return
 	if f.block != (common.Hash{}) {
 		header, err := f.backend.HeaderByHash(ctx, f.block)
@@ -130,9 +189,22 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 			return nil, err
 		}
 		if header == nil {
+			if number := rawdb.ReadHeaderNumber(f.db, f.block); number != nil {
+				if err := f.checkPruned(*number); err != nil {
+					return nil, err
+				}
+			}
 			return nil, errors.New("unknown block")
 		}
-		return f.blockLogs(ctx, header)
+		if f.cursor != nil && f.cursor.Block != header.Number.Uint64() {
+			return nil, errInvalidCursor
+		}
+		found, err := f.blockLogs(ctx, header)
+		if err != nil {
+			return nil, err
+		}
+		logs, _ := f.collect(nil, header.Number.Uint64(), found)
+		return logs, nil
 	}
 	// Figure out the limits of the filter range
 	header, _ := f.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
@@ -148,33 +220,178 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
-	if f.rangeLimit && (int64(end)-f.begin) > maxFilterBlockRange {
-		return nil, fmt.Errorf("exceed maximum block range: %d", maxFilterBlockRange)
+	// Resolve the finalized and safe tags to the blocks they currently refer to
+	if f.begin == rpc.FinalizedBlockNumber.Int64() || f.begin == rpc.SafeBlockNumber.Int64() {
+		header, err := f.finalityHeader(ctx, rpc.BlockNumber(f.begin))
+		if err != nil {
+			return nil, err
+		}
+		f.begin = header.Number.Int64()
+	}
+	if f.end == rpc.FinalizedBlockNumber.Int64() || f.end == rpc.SafeBlockNumber.Int64() {
+		header, err := f.finalityHeader(ctx, rpc.BlockNumber(f.end))
+		if err != nil {
+			return nil, err
+		}
+		end = header.Number.Uint64()
+	}
+	maxRange := f.limits.MaxBlockRange
+	if maxRange == 0 && f.rangeLimit {
+		maxRange = DefaultMaxBlockRange
+	}
+	if maxRange > 0 && int64(end)-f.begin > int64(maxRange) {
+		return nil, errBlockRangeExceeded(maxRange)
+	}
+	// Continue a paginated query where the previous page ended
+	if f.cursor != nil {
+		if f.cursor.Block < uint64(f.begin) || f.cursor.Block > end {
+			return nil, errInvalidCursor
+		}
+		f.begin = int64(f.cursor.Block)
+	}
+	// Refuse ranges reaching into the pruned history instead of returning the
+	// logs of the retained part only. The genesis block is always retained.
+	if first := uint64(f.begin); first <= end {
+		if first == 0 && end > 0 {
+			first = 1
+		}
+		if err := f.checkPruned(first); err != nil {
+			return nil, err
+		}
 	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
 		err  error
 	)
+	if backend, ok := f.backend.(AddressIndexBackend); ok && len(f.addresses) > 0 && len(f.addresses) <= maxIndexedAddresses {
+		// Ranges reaching below the oldest indexed section are left to the bloom bits
+		size, tail, sections := backend.AddressIndexStatus()
+		if indexed := sections * size; tail*size <= uint64(f.begin) && indexed > uint64(f.begin) {
+			if indexed > end {
+				logs, err = f.addressIndexedLogs(ctx, backend, size, end)
+			} else {
+				logs, err = f.addressIndexedLogs(ctx, backend, size, indexed-1)
+			}
+			if err != nil || f.next != nil {
+				return logs, err
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
 		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
+			logs, err = f.indexedLogs(ctx, logs, end)
 		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+			logs, err = f.indexedLogs(ctx, logs, indexed-1)
 		}
-		if err != nil {
+		if err != nil || f.next != nil {
 			return logs, err
 		}
 	}
-	rest, err := f.unindexedLogs(ctx, end)
-	logs = append(logs, rest...)
-	return logs, err
+	return f.unindexedLogs(ctx, logs, end)
+}
+
+// collect appends the logs matching the filter criteria within the block with
+// the given number to the results, dropping the ones returned by the previous
+// pages. If the result limit is exceeded, the results are truncated, the cursor
+// of the next page is recorded and true is returned.
+func (f *Filter) collect(logs []*types.Log, number uint64, found []*types.Log) ([]*types.Log, bool) {
+	var skip uint64
+	if f.cursor != nil && f.cursor.Block == number {
+		skip = f.cursor.Skip
+	}
+	if skip >= uint64(len(found)) {
+		return logs, false
+	}
+	found = found[skip:]
+	logs = append(logs, found...)
+
+	if limit := f.limits.MaxResults; limit > 0 && len(logs) > limit {
+		// The results were within the limit before, so the overflow is confined
+		// to the logs of this block
+		returned := len(found) - (len(logs) - limit)
+		f.next = &LogCursor{Block: number, Skip: skip + uint64(returned)}
+		return logs[:limit], true
+	}
+	return logs, false
+}
+
+// addressIndexedLogs returns the logs matching the filter criteria based on the
+// address log index maintained by the backend.
+func (f *Filter) addressIndexedLogs(ctx context.Context, backend AddressIndexBackend, size uint64, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for section := uint64(f.begin) / size; section <= end/size; section++ {
+		// Gather the blocks with logs of any of the addresses within the section
+		var numbers []uint64
+		for _, address := range f.addresses {
+			offsets, err := backend.AddressLogs(ctx, address, section)
+			if err != nil {
+				return logs, err
+			}
+			for _, offset := range offsets {
+				numbers = append(numbers, section*size+offset)
+			}
+		}
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+		for i, number := range numbers {
+			if number < uint64(f.begin) || number > end || (i > 0 && number == numbers[i-1]) {
+				continue
+			}
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				if err == nil {
+					err = f.checkPruned(number)
+				}
+				return logs, err
+			}
+			found, err := f.checkMatches(ctx, header)
+			if err != nil {
+				return logs, err
+			}
+			var full bool
+			if logs, full = f.collect(logs, number, found); full {
+				return logs, nil
+			}
+		}
+		if (section+1)*size > end {
+			f.begin = int64(end) + 1
+		} else {
+			f.begin = int64((section + 1) * size)
+		}
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+	}
+	return logs, nil
+}
+
+// checkPruned returns the pruned history error if the block with the given
+// number was pruned, so that pruned logs are told apart from missing ones.
+func (f *Filter) checkPruned(number uint64) error {
+	return ethapi.CheckPrunedHistory(f.backend.HistoryPruningCutoff(), number)
+}
+
+// finalityHeader retrieves the block the finalized or safe tag refers to.
+func (f *Filter) finalityHeader(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	header, err := f.backend.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		if number == rpc.FinalizedBlockNumber {
+			return nil, errors.New("finalized block not found")
+		}
+		return nil, errors.New("safe block not found")
+	}
+	return header, nil
 }
 
 // indexedLogs returns the logs matching the filter criteria based on the bloom
-// bits indexed available locally or via the network.
-func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+// bits indexed available locally or via the network, appended to the given ones.
+func (f *Filter) indexedLogs(ctx context.Context, logs []*types.Log, end uint64) ([]*types.Log, error) {
 	// Create a matcher session and request servicing from the backend
 	matches := make(chan uint64, 64)
 
@@ -187,8 +404,6 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	f.backend.ServiceFilter(ctx, session)
 
 	// Iterate over the matches until exhausted or context closed
-	var logs []*types.Log
-
 	for {
 		select {
 		case number, ok := <-matches:
@@ -205,13 +420,19 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 			// Retrieve the suggested block and pull any truly matching logs
 			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
 			if header == nil || err != nil {
+				if err == nil {
+					err = f.checkPruned(number)
+				}
 				return logs, err
 			}
 			found, err := f.checkMatches(ctx, header)
 			if err != nil {
 				return logs, err
 			}
-			logs = append(logs, found...)
+			var full bool
+			if logs, full = f.collect(logs, number, found); full {
+				return logs, nil
+			}
 
 		case <-ctx.Done():
 			return logs, ctx.Err()
@@ -220,20 +441,25 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 }
 
 // unindexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
-	var logs []*types.Log
-
+// iteration and bloom matching, appended to the given ones.
+func (f *Filter) unindexedLogs(ctx context.Context, logs []*types.Log, end uint64) ([]*types.Log, error) {
 	for ; f.begin <= int64(end); f.begin++ {
 		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
 		if header == nil || err != nil {
+			if err == nil {
+				err = f.checkPruned(uint64(f.begin))
+			}
 			return logs, err
 		}
 		found, err := f.blockLogs(ctx, header)
 		if err != nil {
 			return logs, err
 		}
-		logs = append(logs, found...)
+		var full bool
+		if logs, full = f.collect(logs, uint64(f.begin), found); full {
+			f.begin++
+			return logs, nil
+		}
 	}
 	return logs, nil
 }
@@ -258,6 +484,12 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs [
 	if err != nil {
 		return nil, err
 	}
+	if logsList == nil {
+		// The receipts may have been pruned since the header was retrieved
+		if err := f.checkPruned(header.Number.Uint64()); err != nil {
+			return nil, err
+		}
+	}
 	var unfiltered []*types.Log
 	for _, logs := range logsList {
 		unfiltered = append(unfiltered, logs...)
@@ -26,6 +26,7 @@ import (
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -52,6 +53,16 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// FinalizedBlocksSubscription queries headers for blocks that are finalized
+	FinalizedBlocksSubscription
+	// FullBlocksSubscription queries full blocks that are imported
+	FullBlocksSubscription
+	// FullPendingTransactionsSubscription queries full transactions entering
+	// the pending state
+	FullPendingTransactionsSubscription
+	// ChainReorgSubscription queries the blocks dropped and added by chain
+	// reorganisations
+	ChainReorgSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -66,6 +77,8 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// finalizedEvChanSize is the size of channel listening to ChainFinalizedEvent.
+	finalizedEvChanSize = 10
 )
 
 type subscription struct {
@@ -76,10 +89,42 @@ type subscription struct {
 	logs      chan []*types.Log
 	hashes    chan []common.Hash
 	headers   chan *types.Header
+	blocks    chan *types.Block
+	txs       chan []*types.Transaction
+	reorgs    chan *ChainReorg
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
 
+// ChainReorg is the notification of a chain reorganisation, listing the blocks
+// dropped from and added to the canonical chain above their common ancestor in
+// ascending order.
+type ChainReorg struct {
+	Ancestor common.Hash    `json:"commonAncestor"`
+	Number   hexutil.Uint64 `json:"number"` // Number of the common ancestor
+	Dropped  []common.Hash  `json:"dropped"`
+	Added    []common.Hash  `json:"added"`
+}
+
+// newChainReorg creates the reorg notification from the rolled back and added
+// headers, both ordered from the heads down.
+func newChainReorg(oldHeaders, newHeaders []*types.Header) *ChainReorg {
+	first := oldHeaders[len(oldHeaders)-1]
+	reorg := &ChainReorg{
+		Ancestor: first.ParentHash,
+		Number:   hexutil.Uint64(first.Number.Uint64() - 1),
+		Dropped:  make([]common.Hash, 0, len(oldHeaders)),
+		Added:    make([]common.Hash, 0, len(newHeaders)),
+	}
+	for i := len(oldHeaders) - 1; i >= 0; i-- {
+		reorg.Dropped = append(reorg.Dropped, oldHeaders[i].Hash())
+	}
+	for i := len(newHeaders) - 1; i >= 0; i-- {
+		reorg.Added = append(reorg.Added, newHeaders[i].Hash())
+	}
+	return reorg
+}
+
 // EventSystem creates subscriptions, processes events and broadcasts them to the
 // subscription which match the subscription criteria.
 type EventSystem struct {
@@ -93,15 +138,17 @@ type EventSystem struct {
 	rmLogsSub      event.Subscription // Subscription for removed log event
 	pendingLogsSub event.Subscription // Subscription for pending log event
 	chainSub       event.Subscription // Subscription for new chain event
+	finalizedSub   event.Subscription // Subscription for finalized chain event
 
 	// Channels
-	install       chan *subscription         // install filter for event notification
-	uninstall     chan *subscription         // remove filter for event notification
-	txsCh         chan core.NewTxsEvent      // Channel to receive new transactions event
-	logsCh        chan []*types.Log          // Channel to receive new log event
-	pendingLogsCh chan []*types.Log          // Channel to receive new log event
-	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
-	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+	install       chan *subscription            // install filter for event notification
+	uninstall     chan *subscription            // remove filter for event notification
+	txsCh         chan core.NewTxsEvent         // Channel to receive new transactions event
+	logsCh        chan []*types.Log             // Channel to receive new log event
+	pendingLogsCh chan []*types.Log             // Channel to receive new log event
+	rmLogsCh      chan core.RemovedLogsEvent    // Channel to receive removed log event
+	chainCh       chan core.ChainEvent          // Channel to receive new chain event
+	finalizedCh   chan core.ChainFinalizedEvent // Channel to receive finalized chain event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -121,6 +168,7 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
 		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		finalizedCh:   make(chan core.ChainFinalizedEvent, finalizedEvChanSize),
 	}
 
 	// Subscribe events
@@ -129,9 +177,10 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
 	m.pendingLogsSub = m.backend.SubscribePendingLogsEvent(m.pendingLogsCh)
+	m.finalizedSub = m.backend.SubscribeChainFinalizedEvent(m.finalizedCh)
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil {
+	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil || m.finalizedSub == nil {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -167,6 +216,9 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.blocks:
+			case <-sub.f.txs:
+			case <-sub.f.reorgs:
 			}
 		}
 
@@ -290,6 +342,22 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 	return es.subscribe(sub)
 }
 
+// SubscribeFinalizedHeads creates a subscription that writes the header of a
+// block that is finalized in the chain.
+func (es *EventSystem) SubscribeFinalizedHeads(headers chan *types.Header) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       FinalizedBlocksSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   headers,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
 // SubscribePendingTxs creates a subscription that writes transaction hashes for
 // transactions that enter the transaction pool.
 func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscription {
@@ -306,6 +374,57 @@ func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscript
 	return es.subscribe(sub)
 }
 
+// SubscribeNewBlocks creates a subscription that writes the blocks that are
+// imported in the chain, including their transactions.
+func (es *EventSystem) SubscribeNewBlocks(blocks chan *types.Block) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       FullBlocksSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		blocks:    blocks,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeFullPendingTxs creates a subscription that writes the transactions
+// that enter the transaction pool.
+func (es *EventSystem) SubscribeFullPendingTxs(txs chan []*types.Transaction) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       FullPendingTransactionsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		txs:       txs,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeChainReorgs creates a subscription that writes the blocks dropped
+// from and added to the canonical chain by every reorganisation.
+func (es *EventSystem) SubscribeChainReorgs(reorgs chan *ChainReorg) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       ChainReorgSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		reorgs:    reorgs,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 func (es *EventSystem) handleLogs(filters filterIndex, ev []*types.Log) {
@@ -349,36 +468,73 @@ func (es *EventSystem) handleTxsEvent(filters filterIndex, ev core.NewTxsEvent)
 	for _, f := range filters[PendingTransactionsSubscription] {
 		f.hashes <- hashes
 	}
+	for _, f := range filters[FullPendingTransactionsSubscription] {
+		f.txs <- ev.Txs
+	}
 }
 
 func (es *EventSystem) handleChainEvent(filters filterIndex, ev core.ChainEvent) {
+	header := ev.Block.Header()
 	for _, f := range filters[BlocksSubscription] {
-		f.headers <- ev.Block.Header()
+		f.headers <- header
+	}
+	for _, f := range filters[FullBlocksSubscription] {
+		f.blocks <- ev.Block
+	}
+	// Track the head across events: the blocks inserted by a reorg, except the
+	// new head, are not announced one by one.
+	oldh := es.lastHead
+	es.lastHead = header
+	if oldh == nil {
+		return
+	}
+	oldHeaders, newHeaders := es.reorgHeaders(oldh, header)
+	if len(oldHeaders) > 0 && len(filters[ChainReorgSubscription]) > 0 {
+		reorg := newChainReorg(oldHeaders, newHeaders)
+		for _, f := range filters[ChainReorgSubscription] {
+			f.reorgs <- reorg
+		}
 	}
 	if es.lightMode && len(filters[LogsSubscription]) > 0 {
-		es.lightFilterNewHead(ev.Block.Header(), func(header *types.Header, remove bool) {
+		notify := func(header *types.Header, remove bool) {
 			for _, f := range filters[LogsSubscription] {
 				if matchedLogs := es.lightFilterLogs(header, f.logsCrit.Addresses, f.logsCrit.Topics, remove); len(matchedLogs) > 0 {
 					f.logs <- matchedLogs
 				}
 			}
-		})
+		}
+		// roll back old blocks
+		for _, h := range oldHeaders {
+			notify(h, true)
+		}
+		// check new blocks (array is in reverse order)
+		for i := len(newHeaders) - 1; i >= 0; i-- {
+			notify(newHeaders[i], false)
+		}
 	}
 }
 
-func (es *EventSystem) lightFilterNewHead(newHeader *types.Header, callBack func(*types.Header, bool)) {
-	oldh := es.lastHead
-	es.lastHead = newHeader
-	if oldh == nil {
-		return
+func (es *EventSystem) handleFinalizedEvent(filters filterIndex, ev core.ChainFinalizedEvent) {
+	for _, f := range filters[FinalizedBlocksSubscription] {
+		f.headers <- ev.Header
+	}
+}
+
+// reorgHeaders finds the common ancestor of the old and the new head, returning
+// the headers rolled back from the old chain and the ones added by the new one,
+// both ordered from the heads down.
+func (es *EventSystem) reorgHeaders(oldh, newh *types.Header) (oldHeaders, newHeaders []*types.Header) {
+	if newh.ParentHash == oldh.Hash() {
+		return nil, []*types.Header{newh}
 	}
-	newh := newHeader
-	// find common ancestor, create list of rolled back and new block hashes
-	var oldHeaders, newHeaders []*types.Header
 	for oldh.Hash() != newh.Hash() {
 		if oldh.Number.Uint64() >= newh.Number.Uint64() {
 			oldHeaders = append(oldHeaders, oldh)
 			oldh = rawdb.ReadHeader(es.backend.ChainDb(), oldh.ParentHash, oldh.Number.Uint64()-1)
+			if oldh == nil {
+				// the old chain is unavailable, report what was found
+				return oldHeaders, newHeaders
+			}
 		}
 		if oldh.Number.Uint64() < newh.Number.Uint64() {
 			newHeaders = append(newHeaders, newh)
@@ -389,14 +545,7 @@ func (es *EventSystem) lightFilterNewHead(newHeader *types.Header, callBack func
 			}
 		}
 	}
-	// roll back old blocks
-	for _, h := range oldHeaders {
-		callBack(h, true)
-	}
-	// check new blocks (array is in reverse order)
-	for i := len(newHeaders) - 1; i >= 0; i-- {
-		callBack(newHeaders[i], false)
-	}
+	return oldHeaders, newHeaders
 }
 
 // filter logs of a single header in light client mode
@@ -448,6 +597,7 @@ func (es *EventSystem) eventLoop() {
 		es.rmLogsSub.Unsubscribe()
 		es.pendingLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
+		es.finalizedSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
@@ -467,6 +617,8 @@ func (es *EventSystem) eventLoop() {
 			es.handlePendingLogs(index, ev)
 		case ev := <-es.chainCh:
 			es.handleChainEvent(index, ev)
+		case ev := <-es.finalizedCh:
+			es.handleFinalizedEvent(index, ev)
 
 		case f := <-es.install:
 			if f.typ == MinedAndPendingLogsSubscription {
@@ -497,6 +649,8 @@ func (es *EventSystem) eventLoop() {
 			return
 		case <-es.chainSub.Err():
 			return
+		case <-es.finalizedSub.Err():
+			return
 		}
 	}
 }
@@ -18,9 +18,11 @@ package filters
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"math/rand"
+	"net"
 	"reflect"
 	"runtime"
 	"testing"
@@ -28,11 +30,13 @@ import (
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
@@ -52,17 +56,27 @@ type testBackend struct {
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
 	chainFeed       event.Feed
+	finalizedFeed   event.Feed
+	finalized       *types.Header
+	cutoff          uint64
 }
 
 func (b *testBackend) ChainDb() ethdb.Database {
 	return b.db
 }
 
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
 func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	var (
 		hash common.Hash
 		num  uint64
 	)
+	if blockNr == rpc.FinalizedBlockNumber || blockNr == rpc.SafeBlockNumber {
+		return b.finalized, nil
+	}
 	if blockNr == rpc.LatestBlockNumber {
 		hash = rawdb.ReadHeadBlockHash(b.db)
 		number := rawdb.ReadHeaderNumber(b.db, hash)
@@ -106,6 +120,10 @@ func (b *testBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types
 	return logs, nil
 }
 
+func (b *testBackend) HistoryPruningCutoff() uint64 {
+	return b.cutoff
+}
+
 func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.txFeed.Subscribe(ch)
 }
@@ -126,6 +144,10 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription {
+	return b.finalizedFeed.Subscribe(ch)
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -168,7 +190,7 @@ func TestBlockSubscription(t *testing.T) {
 	var (
 		db          = rawdb.NewMemoryDatabase()
 		backend     = &testBackend{db: db}
-		api         = NewPublicFilterAPI(backend, false, deadline, false)
+		api         = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
@@ -213,14 +235,180 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestFinalizedBlockSubscription tests if a finalized block subscription returns
+// the headers of the posted finalized chain events.
+func TestFinalizedBlockSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		backend  = &testBackend{db: db}
+		api      = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
+		genesis  = new(core.Genesis).MustCommit(db)
+		chain, _ = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
+	)
+	headers := make(chan *types.Header)
+	sub := api.events.SubscribeFinalizedHeads(headers)
+	defer sub.Unsubscribe()
+
+	// New heads aren't delivered to finalized head subscriptions
+	go func() {
+		backend.chainFeed.Send(core.ChainEvent{Hash: chain[0].Hash(), Block: chain[0]})
+		for _, block := range chain {
+			backend.finalizedFeed.Send(core.ChainFinalizedEvent{Header: block.Header()})
+		}
+	}()
+	for i, block := range chain {
+		select {
+		case header := <-headers:
+			if header.Hash() != block.Hash() {
+				t.Fatalf("received invalid hash on index %d, want %x, got %x", i, block.Hash(), header.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("finalized header %d not received", i)
+		}
+	}
+}
+
+// TestChainReorgSubscription tests that reorg subscriptions receive the blocks
+// dropped and added by a reorg announced through its new head only, and that
+// full block subscriptions receive every posted block.
+func TestChainReorgSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		backend  = &testBackend{db: db}
+		api      = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
+		genesis  = new(core.Genesis).MustCommit(db)
+		chain, _ = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 3, func(i int, gen *core.BlockGen) {})
+		fork, _  = core.GenerateChain(params.TestChainConfig, chain[0], ethash.NewFaker(), db, 3, func(i int, gen *core.BlockGen) {
+			gen.SetCoinbase(common.Address{0x01})
+		})
+	)
+	for _, block := range append(chain, fork...) {
+		rawdb.WriteHeader(db, block.Header())
+	}
+	blocks := make(chan *types.Block)
+	blocksSub := api.events.SubscribeNewBlocks(blocks)
+	defer blocksSub.Unsubscribe()
+
+	reorgs := make(chan *ChainReorg)
+	reorgsSub := api.events.SubscribeChainReorgs(reorgs)
+	defer reorgsSub.Unsubscribe()
+
+	posted := append(chain[:len(chain):len(chain)], fork[len(fork)-1])
+	go func() {
+		for _, block := range posted {
+			backend.chainFeed.Send(core.ChainEvent{Hash: block.Hash(), Block: block})
+		}
+	}()
+	want := &ChainReorg{
+		Ancestor: chain[0].Hash(),
+		Number:   1,
+		Dropped:  []common.Hash{chain[1].Hash(), chain[2].Hash()},
+		Added:    []common.Hash{fork[0].Hash(), fork[1].Hash(), fork[2].Hash()},
+	}
+	for i, want := range posted {
+		select {
+		case block := <-blocks:
+			if block.Hash() != want.Hash() {
+				t.Fatalf("block %d: hash mismatch: have %x, want %x", i, block.Hash(), want.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("block %d not received", i)
+		}
+	}
+	select {
+	case reorg := <-reorgs:
+		if !reflect.DeepEqual(reorg, want) {
+			t.Fatalf("reorg mismatch: have %+v, want %+v", reorg, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("reorg not received")
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
+// TestSlowSubscriberDropped tests that a subscriber not reading its notifications
+// is sent a terminal notification once its queue overflows instead of going
+// silent, with the queued notifications discarded.
+func TestSlowSubscriberDropped(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
+		server  = rpc.NewServer()
+	)
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	defer server.Stop()
+
+	// The pipe is synchronous, notifications block until they are read
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.ServeCodec(rpc.NewCodec(serverConn), 0)
+
+	clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+	var (
+		enc = json.NewEncoder(clientConn)
+		dec = json.NewDecoder(clientConn)
+	)
+	if err := enc.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "eth_subscribe", "params": []interface{}{"newHeads"}}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	var subscribed struct {
+		Result rpc.ID `json:"result"`
+	}
+	if err := dec.Decode(&subscribed); err != nil || subscribed.Result == "" {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	// The events are subscribed to in the background, overflow the queue in
+	// rounds until the subscription surely is in place
+	for round := 0; round < 20; round++ {
+		for i := 0; i <= headQueueSize; i++ {
+			block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(round*(headQueueSize+1) + i))})
+			backend.chainFeed.Send(core.ChainEvent{Hash: block.Hash(), Block: block})
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for heads := 0; ; heads++ {
+		var notification struct {
+			Params struct {
+				Subscription rpc.ID          `json:"subscription"`
+				Result       json.RawMessage `json:"result"`
+			} `json:"params"`
+		}
+		if err := dec.Decode(&notification); err != nil {
+			t.Fatalf("failed to read notification %d: %v", heads, err)
+		}
+		if notification.Params.Subscription != subscribed.Result {
+			t.Fatalf("notification %d: subscription mismatch: have %s, want %s", heads, notification.Params.Subscription, subscribed.Result)
+		}
+		var dropped SubscriptionDropped
+		if err := json.Unmarshal(notification.Params.Result, &dropped); err == nil && dropped.Error != "" {
+			if dropped.Error != errSlowSubscriber.Error() {
+				t.Fatalf("terminal error mismatch: have %q, want %q", dropped.Error, errSlowSubscriber)
+			}
+			if heads >= headQueueSize {
+				t.Fatalf("queued notifications not discarded: %d heads sent", heads)
+			}
+			return
+		}
+	}
+}
+
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
 
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
 
 		transactions = []*types.Transaction{
 			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
@@ -269,13 +457,61 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestPendingTransactionsOptions tests the filtering of pending transactions by
+// sender, recipient and method selector.
+func TestPendingTransactionsOptions(t *testing.T) {
+	t.Parallel()
+
+	var (
+		signer   = types.LatestSigner(params.TestChainConfig)
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		token    = common.Address{0x01}
+		transfer = hexutil.Bytes{0xa9, 0x05, 0x9c, 0xbb}
+	)
+	sign := func(to *common.Address, data []byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{To: to, Gas: 21000, GasPrice: big.NewInt(1), Data: data}), signer, key)
+		return tx
+	}
+	var (
+		call     = sign(&token, append(common.CopyBytes(transfer), make([]byte, 64)...))
+		send     = sign(&token, nil)
+		creation = sign(nil, transfer)
+	)
+	tests := []struct {
+		opts PendingTransactionsOptions
+		tx   *types.Transaction
+		want bool
+	}{
+		{PendingTransactionsOptions{}, creation, true},
+		{PendingTransactionsOptions{From: []common.Address{sender}}, send, true},
+		{PendingTransactionsOptions{From: []common.Address{token}}, send, false},
+		{PendingTransactionsOptions{To: []common.Address{token}}, send, true},
+		{PendingTransactionsOptions{To: []common.Address{sender}}, send, false},
+		{PendingTransactionsOptions{To: []common.Address{token}}, creation, false},
+		{PendingTransactionsOptions{Selectors: []hexutil.Bytes{transfer}}, call, true},
+		{PendingTransactionsOptions{Selectors: []hexutil.Bytes{transfer}}, send, false},
+		{PendingTransactionsOptions{Selectors: []hexutil.Bytes{{0x09, 0x5e, 0xa7, 0xb3}}}, call, false},
+		{PendingTransactionsOptions{From: []common.Address{sender}, To: []common.Address{token}, Selectors: []hexutil.Bytes{transfer}}, call, true},
+	}
+	for i, tt := range tests {
+		if have := tt.opts.match(signer, tt.tx); have != tt.want {
+			t.Errorf("test %d: match mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	invalid := PendingTransactionsOptions{Selectors: []hexutil.Bytes{transfer[:3]}}
+	if err := invalid.validate(); err == nil {
+		t.Errorf("short selector accepted")
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
 
 		testCases = []struct {
 			crit    FilterCriteria
@@ -319,7 +555,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
 	)
 
 	// different situations where log filter creation should fail.
@@ -341,7 +577,7 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 	var (
 		db        = rawdb.NewMemoryDatabase()
 		backend   = &testBackend{db: db}
-		api       = NewPublicFilterAPI(backend, false, deadline, false)
+		api       = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
 		blockHash = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
 	)
 
@@ -366,7 +602,7 @@ func TestLogFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -480,7 +716,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, LogLimits{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -616,7 +852,7 @@ func TestPendingTxFilterDeadlock(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, timeout, false)
+		api     = NewPublicFilterAPI(backend, false, timeout, LogLimits{})
 		done    = make(chan struct{})
 	)
 
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -30,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 func makeReceipt(addr common.Address) *types.Receipt {
@@ -200,6 +202,24 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 2 log, got", len(logs))
 	}
 
+	// The finalized and safe tags resolve to the blocks they refer to
+	filter = NewRangeFilter(backend, rpc.FinalizedBlockNumber.Int64(), -1, nil, [][]common.Hash{{hash3, hash4}}, false)
+	if _, err := filter.Logs(context.Background()); err == nil {
+		t.Error("expected error without finalized block")
+	}
+	backend.finalized = chain[998].Header()
+
+	filter = NewRangeFilter(backend, 0, rpc.FinalizedBlockNumber.Int64(), nil, [][]common.Hash{{hash3, hash4}}, false)
+	logs, _ = filter.Logs(context.Background())
+	if len(logs) != 1 || logs[0].Topics[0] != hash3 {
+		t.Errorf("expected 1 log with topic %x up to the finalized block, got %v", hash3, logs)
+	}
+	filter = NewRangeFilter(backend, rpc.SafeBlockNumber.Int64(), -1, nil, [][]common.Hash{{hash3, hash4}}, false)
+	logs, _ = filter.Logs(context.Background())
+	if len(logs) != 2 {
+		t.Error("expected 2 log from the safe block, got", len(logs))
+	}
+
 	failHash := common.BytesToHash([]byte("fail"))
 	filter = NewRangeFilter(backend, 0, -1, nil, [][]common.Hash{{failHash}}, false)
 
@@ -223,3 +243,254 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// newLogChain creates a chain of the given length whose blocks contain a log of
+// every address returned by emitters, each with a unique topic.
+func newLogChain(blocks int, emitters func(i int) []common.Address) *testBackend {
+	db := rawdb.NewMemoryDatabase()
+	genesis := core.GenesisBlockForTesting(db, common.Address{}, big.NewInt(1))
+
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, blocks, func(i int, gen *core.BlockGen) {
+		addresses := emitters(i + 1)
+		if len(addresses) == 0 {
+			return
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		for j, address := range addresses {
+			receipt.Logs = append(receipt.Logs, &types.Log{
+				Address: address,
+				Topics:  []common.Hash{common.BigToHash(big.NewInt(int64(100*(i+1) + j)))},
+			})
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	return &testBackend{db: db}
+}
+
+// Tests that paginated log queries return every matching log exactly once, even
+// if the pages end within blocks, and that the limits are enforced.
+func TestFilterPagination(t *testing.T) {
+	addr := common.Address{0x01}
+	backend := newLogChain(10, func(i int) []common.Address {
+		if i%3 == 0 {
+			return nil
+		}
+		return []common.Address{addr, addr, addr}
+	})
+	all, err := NewRangeFilter(backend, 0, -1, []common.Address{addr}, nil, false).Logs(context.Background())
+	if err != nil || len(all) != 21 {
+		t.Fatalf("unlimited query failed: have %d logs, err %v", len(all), err)
+	}
+	for _, limit := range []int{1, 2, 3, 4, 21, 22} {
+		var (
+			logs   []*types.Log
+			cursor *LogCursor
+			pages  int
+		)
+		for {
+			filter := NewRangeFilter(backend, 0, -1, []common.Address{addr}, nil, false)
+			filter.SetLimits(LogLimits{MaxResults: limit})
+
+			page, next, err := filter.Page(context.Background(), cursor)
+			if err != nil {
+				t.Fatalf("limit %d: page %d failed: %v", limit, pages, err)
+			}
+			if len(page) > limit || (next != nil && len(page) != limit) {
+				t.Fatalf("limit %d: page %d size mismatch: have %d logs", limit, pages, len(page))
+			}
+			logs, cursor = append(logs, page...), next
+			if pages++; cursor == nil {
+				break
+			}
+		}
+		if len(logs) != len(all) {
+			t.Fatalf("limit %d: paginated logs mismatch: have %d, want %d", limit, len(logs), len(all))
+		}
+		for i := range logs {
+			if logs[i].Topics[0] != all[i].Topics[0] {
+				t.Fatalf("limit %d: log %d mismatch: have %x, want %x", limit, i, logs[i].Topics[0], all[i].Topics[0])
+			}
+		}
+		if want := (len(all) + limit - 1) / limit; pages != want {
+			t.Errorf("limit %d: page count mismatch: have %d, want %d", limit, pages, want)
+		}
+	}
+	// Unpaginated queries exceeding the limits fail with the limit exceeded code
+	tests := []struct {
+		limits LogLimits
+		code   int
+	}{
+		{LogLimits{MaxResults: 20}, limitExceededErrorCode},
+		{LogLimits{MaxResults: 21}, 0},
+		{LogLimits{MaxBlockRange: 9}, limitExceededErrorCode},
+		{LogLimits{MaxBlockRange: 10}, 0},
+	}
+	for i, tt := range tests {
+		filter := NewRangeFilter(backend, 0, 10, []common.Address{addr}, nil, false)
+		filter.SetLimits(tt.limits)
+
+		_, err := filter.Logs(context.Background())
+		if tt.code == 0 {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if qerr, ok := err.(*QueryError); !ok || qerr.ErrorCode() != tt.code {
+			t.Errorf("test %d: error mismatch: have %v, want code %d", i, err, tt.code)
+		}
+	}
+	// Cursors outside of the queried range are rejected
+	filter := NewRangeFilter(backend, 5, 10, []common.Address{addr}, nil, false)
+	if _, _, err := filter.Page(context.Background(), &LogCursor{Block: 4}); err != errInvalidCursor {
+		t.Errorf("cursor before range: have %v, want %v", err, errInvalidCursor)
+	}
+}
+
+// addressIndexBackend is a test backend serving a fixed address log index.
+type addressIndexBackend struct {
+	*testBackend
+	size    uint64
+	tail    uint64
+	offsets map[common.Address]map[uint64][]uint64 // address -> section -> block offsets
+}
+
+func (b *addressIndexBackend) AddressIndexStatus() (uint64, uint64, uint64) {
+	return b.size, b.tail, 2
+}
+
+func (b *addressIndexBackend) AddressLogs(ctx context.Context, address common.Address, section uint64) ([]uint64, error) {
+	return b.offsets[address][section], nil
+}
+
+// Tests that the blocks covered by the address log index are searched based on
+// the index, and the rest by bloom matching.
+func TestAddressIndexedFilter(t *testing.T) {
+	var (
+		game  = common.Address{0x01}
+		token = common.Address{0x02}
+	)
+	emitters := map[int][]common.Address{
+		2:  {game},
+		3:  {token},
+		5:  {game, token},
+		6:  {game},
+		9:  {game},
+		10: {token},
+	}
+	backend := &addressIndexBackend{
+		testBackend: newLogChain(10, func(i int) []common.Address { return emitters[i] }),
+		size:        4,
+		offsets: map[common.Address]map[uint64][]uint64{
+			// The index deliberately omits block 6 to tell it's relied upon
+			game:  {0: {2}, 1: {1}},
+			token: {0: {3}, 1: {1}},
+		},
+	}
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		blocks     []uint64
+	}{
+		{0, -1, []common.Address{game}, []uint64{2, 5, 9}},
+		{0, -1, []common.Address{token}, []uint64{3, 5, 10}},
+		{0, -1, []common.Address{game, token}, []uint64{2, 3, 5, 5, 9, 10}},
+		{3, 8, []common.Address{game, token}, []uint64{3, 5, 5}},
+	}
+	check := func(tests []struct {
+		begin, end int64
+		addresses  []common.Address
+		blocks     []uint64
+	}) {
+		for i, tt := range tests {
+			logs, err := NewRangeFilter(backend, tt.begin, tt.end, tt.addresses, nil, false).Logs(context.Background())
+			if err != nil {
+				t.Fatalf("test %d: query failed: %v", i, err)
+			}
+			var blocks []uint64
+			for _, log := range logs {
+				blocks = append(blocks, log.BlockNumber)
+			}
+			if !reflect.DeepEqual(blocks, tt.blocks) {
+				t.Errorf("test %d: blocks mismatch: have %v, want %v", i, blocks, tt.blocks)
+			}
+		}
+	}
+	check(tests)
+
+	// Ranges reaching below the oldest indexed section, whose history was pruned,
+	// fall back to the bloom bits
+	backend.tail = 1
+	check([]struct {
+		begin, end int64
+		addresses  []common.Address
+		blocks     []uint64
+	}{
+		{0, -1, []common.Address{game}, []uint64{2, 5, 6, 9}},
+		{4, -1, []common.Address{game}, []uint64{5, 9}},
+	})
+}
+
+// Tests that log queries reaching into the pruned history fail with the pruned
+// history error instead of returning the logs of the retained blocks only.
+func TestFilterPrunedHistory(t *testing.T) {
+	addr := common.Address{0x01}
+	backend := newLogChain(10, func(i int) []common.Address {
+		return []common.Address{addr}
+	})
+	// Prune the blocks before the cutoff, keeping their hash to number mappings
+	// like the freezer does
+	backend.cutoff = 5
+	pruned := rawdb.ReadCanonicalHash(backend.db, 3)
+	for number := uint64(1); number < backend.cutoff; number++ {
+		hash := rawdb.ReadCanonicalHash(backend.db, number)
+		rawdb.DeleteBlock(backend.db, hash, number)
+		rawdb.DeleteCanonicalHash(backend.db, number)
+		rawdb.WriteHeaderNumber(backend.db, hash, number)
+	}
+	api := NewPublicFilterAPI(backend, false, deadline, LogLimits{})
+
+	unknown := common.Hash{0xff}
+	tests := []struct {
+		crit   FilterCriteria
+		logs   int
+		pruned bool
+	}{
+		{FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(10)}, 0, true},
+		{FilterCriteria{FromBlock: big.NewInt(3), ToBlock: big.NewInt(7)}, 0, true},
+		{FilterCriteria{FromBlock: big.NewInt(4), ToBlock: big.NewInt(4)}, 0, true},
+		{FilterCriteria{FromBlock: big.NewInt(5), ToBlock: big.NewInt(10)}, 6, false},
+		{FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(0)}, 0, false},
+		{FilterCriteria{BlockHash: &pruned}, 0, true},
+		{FilterCriteria{BlockHash: &unknown}, 0, false},
+	}
+	for i, tt := range tests {
+		tt.crit.Addresses = []common.Address{addr}
+		logs, err := api.GetLogs(context.Background(), tt.crit)
+		if tt.pruned {
+			if rerr, ok := err.(rpc.Error); !ok || rerr.ErrorCode() != 4444 {
+				t.Errorf("test %d: error mismatch: have %v, want pruned history", i, err)
+			}
+			continue
+		}
+		if tt.crit.BlockHash != nil {
+			if err == nil {
+				t.Errorf("test %d: unknown block found", i)
+			} else if rerr, ok := err.(rpc.Error); ok && rerr.ErrorCode() == 4444 {
+				t.Errorf("test %d: unknown block reported as pruned", i)
+			}
+			continue
+		}
+		if err != nil || len(logs) != tt.logs {
+			t.Errorf("test %d: logs mismatch: have %d (%v), want %d", i, len(logs), err, tt.logs)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// limitExceededErrorCode is the error code of the log queries exceeding one
+	// of the configured caps, the one commonly used by the RPC providers.
+	limitExceededErrorCode = -32005
+
+	// invalidParamsErrorCode is the error code of the log queries continuing at
+	// a cursor not belonging to them.
+	invalidParamsErrorCode = -32602
+)
+
+// errInvalidCursor is returned if a paginated log query is continued at a cursor
+// outside of the range of the query.
+var errInvalidCursor = &QueryError{Message: "cursor outside of the queried range", Code: invalidParamsErrorCode}
+
+// LogLimits caps the work done by a single log query.
+type LogLimits struct {
+	MaxBlockRange uint64 // Maximum number of blocks a range query may span (0 = unlimited)
+	MaxResults    int    // Maximum number of logs a query or page may return (0 = unlimited)
+}
+
+// QueryError is returned if a log query is refused. It carries the same error
+// code through the RPC layer and, as an extension, through GraphQL.
+type QueryError struct {
+	Message string
+	Code    int
+}
+
+// Error implements error.
+func (e *QueryError) Error() string { return e.Message }
+
+// ErrorCode returns the JSON-RPC error code of the error.
+func (e *QueryError) ErrorCode() int { return e.Code }
+
+// Extensions returns the GraphQL error extensions of the error.
+func (e *QueryError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// errBlockRangeExceeded returns the error of a range query spanning more blocks
+// than allowed.
+func errBlockRangeExceeded(limit uint64) error {
+	return &QueryError{Message: fmt.Sprintf("exceed maximum block range: %d", limit), Code: limitExceededErrorCode}
+}
+
+// errTooManyResults returns the error of an unpaginated log query matching more
+// logs than allowed.
+func errTooManyResults(limit int) error {
+	return &QueryError{Message: fmt.Sprintf("query returned more than %d results, continue with a cursor", limit), Code: limitExceededErrorCode}
+}
+
+// LogCursor is the position at which a paginated log query continues: the block
+// to resume at and the number of its matching logs returned by previous pages.
+type LogCursor struct {
+	Block uint64
+	Skip  uint64
+}
+
+// MarshalText implements encoding.TextMarshaler, encoding the cursor as an
+// opaque hex string.
+func (c LogCursor) MarshalText() ([]byte, error) {
+	blob := make([]byte, 16)
+	binary.BigEndian.PutUint64(blob, c.Block)
+	binary.BigEndian.PutUint64(blob[8:], c.Skip)
+	return hexutil.Bytes(blob).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *LogCursor) UnmarshalText(input []byte) error {
+	var blob hexutil.Bytes
+	if err := blob.UnmarshalText(input); err != nil {
+		return err
+	}
+	if len(blob) != 16 {
+		return errors.New("invalid log cursor")
+	}
+	c.Block = binary.BigEndian.Uint64(blob)
+	c.Skip = binary.BigEndian.Uint64(blob[8:])
+	return nil
+}
+
+// LogPage is a page of the results of a paginated log query.
+type LogPage struct {
+	Logs   []*types.Log `json:"logs"`
+	Cursor *LogCursor   `json:"cursor"` // Position of the next page, nil if the results are exhausted
+}
@@ -0,0 +1,196 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/gopool"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// headQueueSize is the number of head notifications queued for a subscriber
+	// before it is considered too slow and dropped.
+	headQueueSize = 64
+
+	// txQueueSize is the number of pending transaction notifications queued for
+	// a subscriber before it is considered too slow and dropped.
+	txQueueSize = txChanSize
+
+	// reorgQueueSize is the number of reorg notifications queued for a subscriber
+	// before it is considered too slow and dropped.
+	reorgQueueSize = 16
+)
+
+// NewHeadsOptions selects the block contents announced by a newHeads
+// subscription. Without any of them only the headers are announced.
+type NewHeadsOptions struct {
+	FullTransactions bool `json:"fullTransactions"` // Announce the transaction bodies instead of their hashes
+	Receipts         bool `json:"receipts"`         // Announce the receipts of the transactions
+}
+
+// full reports whether the options request the blocks instead of the headers.
+func (opts *NewHeadsOptions) full() bool {
+	return opts != nil && (opts.FullTransactions || opts.Receipts)
+}
+
+// PendingTransactionsOptions selects the transactions announced by a
+// newPendingTransactions subscription and whether they are announced in full.
+type PendingTransactionsOptions struct {
+	FullTransactions bool             `json:"fullTransactions"` // Announce the transaction bodies instead of their hashes
+	From             []common.Address `json:"from"`             // Senders to announce the transactions of, all if empty
+	To               []common.Address `json:"to"`               // Recipients to announce the transactions to, all if empty
+	Selectors        []hexutil.Bytes  `json:"selectors"`        // Method selectors of the calls to announce, all if empty
+}
+
+// validate checks the options for malformed filters.
+func (opts *PendingTransactionsOptions) validate() error {
+	for _, selector := range opts.Selectors {
+		if len(selector) != 4 {
+			return fmt.Errorf("invalid method selector %v: want 4 bytes", selector)
+		}
+	}
+	return nil
+}
+
+// match reports whether the transaction passes all filters of the options. The
+// sender is only recovered if filtering for it.
+func (opts *PendingTransactionsOptions) match(signer types.Signer, tx *types.Transaction) bool {
+	if len(opts.To) > 0 && (tx.To() == nil || !includes(opts.To, *tx.To())) {
+		return false
+	}
+	if len(opts.Selectors) > 0 {
+		data := tx.Data()
+		if len(data) < 4 {
+			return false
+		}
+		var found bool
+		for _, selector := range opts.Selectors {
+			if bytes.Equal(selector, data[:4]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(opts.From) > 0 {
+		from, err := types.Sender(signer, tx)
+		if err != nil || !includes(opts.From, from) {
+			return false
+		}
+	}
+	return true
+}
+
+// SubscriptionDropped is the last notification of a subscription whose subscriber
+// fell too far behind. No further notifications are sent, the client has to
+// unsubscribe and subscribe again, refetching anything it missed.
+type SubscriptionDropped struct {
+	Error string `json:"error"`
+}
+
+// errSlowSubscriber is the error reported to a subscriber dropped for falling
+// behind.
+var errSlowSubscriber = errors.New("subscriber too slow, notifications dropped")
+
+// notificationQueue buffers the notifications of a subscription and sends them
+// in the background, so that a subscriber slow to read them holds up neither
+// the event system nor the other subscribers. Formatting is deferred to the
+// sender, keeping expensive lookups off the event path.
+type notificationQueue struct {
+	notifier *rpc.Notifier
+	id       rpc.ID
+	format   func(interface{}) (interface{}, error) // Optional conversion of the items before sending
+	items    chan interface{}
+	dropped  chan struct{} // Closed when the queue overflowed
+	quit     chan struct{}
+}
+
+// newNotificationQueue creates a queue of the given size for the subscription
+// and starts sending its notifications.
+func newNotificationQueue(notifier *rpc.Notifier, id rpc.ID, size int, format func(interface{}) (interface{}, error)) *notificationQueue {
+	q := &notificationQueue{
+		notifier: notifier,
+		id:       id,
+		format:   format,
+		items:    make(chan interface{}, size),
+		dropped:  make(chan struct{}),
+		quit:     make(chan struct{}),
+	}
+	gopool.Submit(q.loop)
+	return q
+}
+
+// push queues a notification, returning false if the queue is full, in which
+// case the subscriber should be dropped. The queued notifications are discarded
+// and the subscriber is sent a terminal SubscriptionDropped notification
+// instead, so that it can tell the subscription went silent and resubscribe.
+func (q *notificationQueue) push(item interface{}) bool {
+	select {
+	case q.items <- item:
+		return true
+	default:
+		log.Warn("Dropping slow subscriber", "id", q.id, "queued", len(q.items))
+		close(q.dropped)
+		return false
+	}
+}
+
+// close stops sending notifications, discarding the queued ones. A dropped
+// subscriber still gets its terminal notification.
+func (q *notificationQueue) close() {
+	select {
+	case <-q.dropped:
+	default:
+		close(q.quit)
+	}
+}
+
+// loop sends the queued notifications until the queue is closed or dropped.
+func (q *notificationQueue) loop() {
+	for {
+		select {
+		case <-q.dropped:
+			q.notifier.Notify(q.id, &SubscriptionDropped{Error: errSlowSubscriber.Error()})
+			return
+		default:
+		}
+		select {
+		case item := <-q.items:
+			if q.format != nil {
+				var err error
+				if item, err = q.format(item); err != nil {
+					log.Warn("Failed to assemble subscription notification", "id", q.id, "err", err)
+					continue
+				}
+			}
+			q.notifier.Notify(q.id, item)
+		case <-q.dropped:
+		case <-q.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+// maxFeeHistory is the maximum number of blocks that can be retrieved for a
+// fee history request.
+const maxFeeHistory = 1024
+
+// blockFees represents a single block for processing
+type blockFees struct {
+	baseFee, nextBaseFee *big.Int
+	gasUsedRatio         float64
+	reward               []*big.Int
+}
+
+// txGasAndReward is sorted in ascending order based on reward
+type (
+	txGasAndReward struct {
+		gasUsed uint64
+		reward  *big.Int
+	}
+	sortGasAndReward []txGasAndReward
+)
+
+func (s sortGasAndReward) Len() int { return len(s) }
+func (s sortGasAndReward) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+func (s sortGasAndReward) Less(i, j int) bool {
+	return s[i].reward.Cmp(s[j].reward) < 0
+}
+
+// processBlock retrieves the given block and calculates its base fee, the base
+// fee of the next block, the gas used ratio and, if percentiles are requested,
+// the effective tips paid at the given gas used percentiles.
+func (oracle *Oracle) processBlock(ctx context.Context, number uint64, percentiles []float64) (*blockFees, error) {
+	var (
+		header *types.Header
+		block  *types.Block
+		err    error
+	)
+	if len(percentiles) == 0 {
+		header, err = oracle.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	} else {
+		block, err = oracle.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if block != nil {
+			header = block.Header()
+		}
+	}
+	if header == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", number)
+		}
+		return nil, err
+	}
+	config := oracle.backend.ChainConfig()
+
+	fees := &blockFees{
+		baseFee:     new(big.Int),
+		nextBaseFee: new(big.Int),
+	}
+	if header.BaseFee != nil {
+		fees.baseFee.Set(header.BaseFee)
+	}
+	if config.IsLondon(new(big.Int).Add(header.Number, common.Big1)) {
+		fees.nextBaseFee = misc.CalcBaseFee(config, header)
+	}
+	if header.GasLimit > 0 {
+		fees.gasUsedRatio = float64(header.GasUsed) / float64(header.GasLimit)
+	}
+	if block == nil {
+		return fees, nil
+	}
+	fees.reward = make([]*big.Int, len(percentiles))
+
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		// return an all zero row if there are no transactions to gather data from
+		for i := range fees.reward {
+			fees.reward[i] = new(big.Int)
+		}
+		return fees, nil
+	}
+	receipts, err := oracle.backend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipt count mismatch for block #%d: have %d, want %d", number, len(receipts), len(txs))
+	}
+	sorter := make(sortGasAndReward, len(txs))
+	for i, tx := range txs {
+		reward, _ := tx.EffectiveGasTip(block.BaseFee())
+		sorter[i] = txGasAndReward{gasUsed: receipts[i].GasUsed, reward: reward}
+	}
+	sort.Sort(sorter)
+
+	var txIndex int
+	sumGasUsed := sorter[0].gasUsed
+
+	for i, p := range percentiles {
+		thresholdGasUsed := uint64(float64(block.GasUsed()) * p / 100)
+		for sumGasUsed < thresholdGasUsed && txIndex < len(txs)-1 {
+			txIndex++
+			sumGasUsed += sorter[txIndex].gasUsed
+		}
+		fees.reward[i] = sorter[txIndex].reward
+	}
+	return fees, nil
+}
+
+// FeeHistory returns data relevant for fee estimation based on the specified range of blocks.
+// The range can be specified either with absolute block numbers or ending with the latest
+// block. The pending block is treated as the latest one. Blocks are processed in ascending
+// order and the requested range is truncated to the available history. The returned values
+// are:
+// - oldest block number
+// - reward at the requested percentiles of the gas used in each block, weighted by gas used
+// - base fee of each block, including the next one after the range
+// - gas used ratio of each block
+//
+// Note: baseFee includes the next block after the newest of the returned range, because this
+// value can be derived from the newest block.
+func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
+	if blocks < 1 {
+		return common.Big0, nil, nil, nil, nil // returning with no data and no error means there are no retrievable blocks
+	}
+	if blocks > maxFeeHistory {
+		log.Warn("Sanitizing fee history length", "requested", blocks, "truncated", maxFeeHistory)
+		blocks = maxFeeHistory
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 {
+			return common.Big0, nil, nil, nil, fmt.Errorf("%w: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p < rewardPercentiles[i-1] {
+			return common.Big0, nil, nil, nil, fmt.Errorf("%w: #%d:%f > #%d:%f", errInvalidPercentile, i-1, rewardPercentiles[i-1], i, p)
+		}
+	}
+	head, err := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return common.Big0, nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if lastBlock >= 0 {
+		if uint64(lastBlock) > last {
+			return common.Big0, nil, nil, nil, errRequestBeyondHead
+		}
+		last = uint64(lastBlock)
+	}
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	var (
+		reward       [][]*big.Int
+		baseFee      = make([]*big.Int, blocks+1)
+		gasUsedRatio = make([]float64, blocks)
+	)
+	if len(rewardPercentiles) != 0 {
+		reward = make([][]*big.Int, blocks)
+	}
+	for i := 0; i < blocks; i++ {
+		fees, err := oracle.processBlock(ctx, oldest+uint64(i), rewardPercentiles)
+		if err != nil {
+			return common.Big0, nil, nil, nil, err
+		}
+		baseFee[i], baseFee[i+1] = fees.baseFee, fees.nextBaseFee
+		gasUsedRatio[i] = fees.gasUsedRatio
+		if reward != nil {
+			reward[i] = fees.reward
+		}
+	}
+	return new(big.Int).SetUint64(oldest), reward, baseFee, gasUsedRatio, nil
+}
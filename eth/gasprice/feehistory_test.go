@@ -0,0 +1,107 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestFeeHistory(t *testing.T) {
+	var cases = []struct {
+		count    int
+		last     rpc.BlockNumber
+		percent  []float64
+		expFirst uint64
+		expCount int
+		expErr   error
+	}{
+		{10, 30, nil, 21, 10, nil},
+		{10, 30, []float64{0, 10}, 21, 10, nil},
+		{20, 30, []float64{0, 10}, 11, 20, nil},
+		{40, 30, []float64{0, 10}, 0, 31, nil},
+		{10, 40, nil, 0, 0, errRequestBeyondHead},
+		{2000, rpc.LatestBlockNumber, nil, 0, 33, nil},
+		{10, rpc.LatestBlockNumber, []float64{0, 10}, 23, 10, nil},
+		{10, rpc.PendingBlockNumber, []float64{0, 10}, 23, 10, nil},
+		{10, 30, []float64{10, 0}, 0, 0, errInvalidPercentile},
+		{10, 30, []float64{0, 101}, 0, 0, errInvalidPercentile},
+		{0, 30, nil, 0, 0, nil},
+	}
+	for i, c := range cases {
+		backend := newTestBackend(t, big.NewInt(16))
+		oracle := NewOracle(backend, Config{Default: big.NewInt(params.GWei)})
+
+		first, reward, baseFee, ratio, err := oracle.FeeHistory(context.Background(), c.count, c.last, c.percent)
+
+		expReward := c.expCount
+		if len(c.percent) == 0 {
+			expReward = 0
+		}
+		expBaseFee := c.expCount
+		if expBaseFee != 0 {
+			expBaseFee++
+		}
+		if first.Uint64() != c.expFirst {
+			t.Fatalf("Test case %d: first block mismatch, want %d, got %d", i, c.expFirst, first)
+		}
+		if len(reward) != expReward {
+			t.Fatalf("Test case %d: reward array length mismatch, want %d, got %d", i, expReward, len(reward))
+		}
+		if len(baseFee) != expBaseFee {
+			t.Fatalf("Test case %d: baseFee array length mismatch, want %d, got %d", i, expBaseFee, len(baseFee))
+		}
+		if len(ratio) != c.expCount {
+			t.Fatalf("Test case %d: gasUsedRatio array length mismatch, want %d, got %d", i, c.expCount, len(ratio))
+		}
+		if err != c.expErr && !errors.Is(err, c.expErr) {
+			t.Fatalf("Test case %d: error mismatch, want %v, got %v", i, c.expErr, err)
+		}
+	}
+}
+
+func TestFeeHistoryBaseFee(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(16))
+	oracle := NewOracle(backend, Config{Default: big.NewInt(params.GWei)})
+
+	// Blocks 14..17 span the fork: no base fee before it, the initial base fee
+	// at the fork block and a derived one afterwards.
+	_, reward, baseFee, _, err := oracle.FeeHistory(context.Background(), 4, 17, []float64{50})
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if baseFee[0].Sign() != 0 || baseFee[1].Sign() != 0 {
+		t.Fatalf("pre-London base fees mismatch: have %v, %v, want 0", baseFee[0], baseFee[1])
+	}
+	if baseFee[2].Cmp(big.NewInt(params.InitialBaseFee)) != 0 {
+		t.Fatalf("fork block base fee mismatch: have %v, want %v", baseFee[2], params.InitialBaseFee)
+	}
+	if baseFee[3].Cmp(baseFee[2]) >= 0 || baseFee[4].Cmp(baseFee[3]) >= 0 {
+		t.Fatalf("base fee should decrease on blocks below target: have %v", baseFee)
+	}
+	// Block i pays a tip of i gwei, both before and after the fork
+	for i, r := range reward {
+		if want := big.NewInt(int64(14+i) * params.GWei); r[0].Cmp(want) != 0 {
+			t.Fatalf("block %d reward mismatch: have %v, want %v", 14+i, r[0], want)
+		}
+	}
+}
@@ -33,12 +33,12 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/diff"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/protocols/vote"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
-	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -46,6 +46,9 @@ const (
 	// txChanSize is the size of channel listening to NewTxsEvent.
 	// The number is referenced from the size of tx pool.
 	txChanSize = 4096
+
+	// voteChanSize is the size of channel listening to NewVoteEvent.
+	voteChanSize = 256
 )
 
 var (
@@ -79,12 +82,24 @@ type txPool interface {
 	SubscribeReannoTxsEvent(chan<- core.ReannoTxsEvent) event.Subscription
 }
 
+// votePool defines the methods needed from a vote pool implementation to
+// support propagating the fast finality votes of the validators.
+type votePool interface {
+	// PutVote should verify a vote and add it to the pool.
+	PutVote(vote *types.VoteEnvelope) error
+
+	// SubscribeNewVoteEvent should return an event subscription of
+	// NewVoteEvent and send events to the given channel.
+	SubscribeNewVoteEvent(chan<- core.NewVoteEvent) event.Subscription
+}
+
 // handlerConfig is the collection of initialization parameters to create a full
 // node network handler.
 type handlerConfig struct {
 	Database               ethdb.Database            // Database for direct sync insertions
 	Chain                  *core.BlockChain          // Blockchain to serve data from
 	TxPool                 txPool                    // Transaction pool to propagate from
+	VotePool               votePool                  // Vote pool to propagate from (nil = no fast finality)
 	Network                uint64                    // Network identifier to adfvertise
 	Sync                   downloader.SyncMode       // Whether to fast or full sync
 	DiffSync               bool                      // Whether to diff sync
@@ -112,6 +127,7 @@ type handler struct {
 
 	database ethdb.Database
 	txpool   txPool
+	votepool votePool
 	chain    *core.BlockChain
 	maxPeers int
 
@@ -127,6 +143,10 @@ type handler struct {
 	reannoTxsCh   chan core.ReannoTxsEvent
 	reannoTxsSub  event.Subscription
 	minedBlockSub *event.TypeMuxSubscription
+	badDiffCh     chan core.BadDiffLayerEvent
+	badDiffSub    event.Subscription
+	votesCh       chan core.NewVoteEvent
+	votesSub      event.Subscription
 
 	whitelist map[uint64]common.Hash
 
@@ -152,6 +172,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		eventMux:               config.EventMux,
 		database:               config.Database,
 		txpool:                 config.TxPool,
+		votepool:               config.VotePool,
 		chain:                  config.Chain,
 		peers:                  newPeerSet(),
 		whitelist:              config.Whitelist,
@@ -269,6 +290,11 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 		peer.Log().Error("Diff extension barrier failed", "err", err)
 		return err
 	}
+	vote, err := h.peers.waitVoteExtension(peer)
+	if err != nil {
+		peer.Log().Error("Vote extension barrier failed", "err", err)
+		return err
+	}
 	// TODO(karalabe): Not sure why this is needed
 	if !h.chainSync.handlePeerEvent(peer) {
 		return p2p.DiscQuitting
@@ -309,7 +335,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 	peer.Log().Debug("Ethereum peer connected", "name", peer.Name())
 
 	// Register the peer locally
-	if err := h.peers.registerPeer(peer, snap, diff); err != nil {
+	if err := h.peers.registerPeer(peer, snap, diff, vote); err != nil {
 		peer.Log().Error("Ethereum peer registration failed", "err", err)
 		return err
 	}
@@ -395,6 +421,21 @@ func (h *handler) runDiffExtension(peer *diff.Peer, handler diff.Handler) error
 	return handler(peer)
 }
 
+// runVoteExtension registers a `vote` peer into the joint eth/vote peerset and
+// starts handling inbound messages. As `vote` is only a satellite protocol to
+// `eth`, all subsystem registrations and lifecycle management will be done by
+// the main `eth` handler to prevent strange races.
+func (h *handler) runVoteExtension(peer *vote.Peer, handler vote.Handler) error {
+	h.peerWG.Add(1)
+	defer h.peerWG.Done()
+
+	if err := h.peers.registerVoteExtension(peer); err != nil {
+		peer.Log().Error("Vote extension registration failed", "err", err)
+		return err
+	}
+	return handler(peer)
+}
+
 // removePeer unregisters a peer from the downloader and fetchers, removes it from
 // the set of tracked peers and closes the network connection to it.
 func (h *handler) removePeer(id string) {
@@ -449,6 +490,20 @@ func (h *handler) Start(maxPeers int) {
 	h.minedBlockSub = h.eventMux.Subscribe(core.NewMinedBlockEvent{})
 	go h.minedBroadcastLoop()
 
+	// drop peers serving bad diff layers
+	h.wg.Add(1)
+	h.badDiffCh = make(chan core.BadDiffLayerEvent, 16)
+	h.badDiffSub = h.chain.SubscribeBadDiffLayerEvent(h.badDiffCh)
+	go h.badDiffLayerLoop()
+
+	// broadcast fast finality votes
+	if h.votepool != nil {
+		h.wg.Add(1)
+		h.votesCh = make(chan core.NewVoteEvent, voteChanSize)
+		h.votesSub = h.votepool.SubscribeNewVoteEvent(h.votesCh)
+		go h.voteBroadcastLoop()
+	}
+
 	// start sync handlers
 	h.wg.Add(2)
 	go h.chainSync.loop()
@@ -459,6 +514,10 @@ func (h *handler) Stop() {
 	h.txsSub.Unsubscribe()        // quits txBroadcastLoop
 	h.reannoTxsSub.Unsubscribe()  // quits txReannounceLoop
 	h.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
+	h.badDiffSub.Unsubscribe()    // quits badDiffLayerLoop
+	if h.votesSub != nil {
+		h.votesSub.Unsubscribe() // quits voteBroadcastLoop
+	}
 
 	// Quit chainSync and txsync64.
 	// After this is done, no new peers will be accepted.
@@ -498,11 +557,11 @@ func (h *handler) BroadcastBlock(block *types.Block, propagate bool) {
 		} else {
 			transfer = peers[:int(math.Sqrt(float64(len(peers))))]
 		}
-		diff := h.chain.GetDiffLayerRLP(block.Hash())
+		diffLayer, sig := h.chain.GetSignedDiffLayerRLP(block.Hash())
 		for _, peer := range transfer {
-			if len(diff) != 0 && peer.diffExt != nil {
+			if len(diffLayer) != 0 && peer.diffExt != nil {
 				// difflayer should send before block
-				peer.diffExt.SendDiffLayers([]rlp.RawValue{diff})
+				peer.diffExt.SendSignedDiffLayers([]diff.SignedDiffLayer{{DiffLayer: diffLayer, Signature: sig}})
 			}
 			peer.AsyncSendNewBlock(block, td)
 		}
@@ -617,3 +676,51 @@ func (h *handler) txReannounceLoop() {
 		}
 	}
 }
+
+// badDiffLayerLoop disconnects the peers which served diff layers that turned
+// out to be invalid.
+func (h *handler) badDiffLayerLoop() {
+	defer h.wg.Done()
+	for {
+		select {
+		case event := <-h.badDiffCh:
+			for _, id := range event.Peers {
+				if h.peers.peer(id) != nil {
+					log.Debug("Dropping peer serving bad diff layer", "peer", id, "diffHash", event.DiffHash)
+					h.removePeer(id)
+				}
+			}
+		case <-h.badDiffSub.Err():
+			return
+		}
+	}
+}
+
+// BroadcastVotes propagates a batch of votes to all the `vote` peers which are
+// not known to already have them.
+func (h *handler) BroadcastVotes(votes []*types.VoteEnvelope) {
+	voteset := make(map[*ethPeer][]*types.VoteEnvelope)
+	for _, vote := range votes {
+		for _, peer := range h.peers.peersWithoutVote(vote.Hash()) {
+			voteset[peer] = append(voteset[peer], vote)
+		}
+	}
+	for peer, votes := range voteset {
+		peer.voteExt.AsyncSendVotes(votes)
+	}
+	log.Trace("Vote broadcast", "votes", len(votes), "peers", len(voteset))
+}
+
+// voteBroadcastLoop propagates the new votes of the vote pool to the connected
+// peers.
+func (h *handler) voteBroadcastLoop() {
+	defer h.wg.Done()
+	for {
+		select {
+		case event := <-h.votesCh:
+			h.BroadcastVotes([]*types.VoteEnvelope{event.Vote})
+		case <-h.votesSub.Err():
+			return
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/protocols/diff"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 )
@@ -70,12 +71,23 @@ func (h *diffHandler) Handle(peer *diff.Peer, packet diff.Packet) error {
 	case *diff.FullDiffLayersPacket:
 		return h.handleDiffLayerPackage(&packet.DiffLayersPacket, peer.ID(), true)
 
+	case *diff.SignedDiffLayersPacket:
+		return h.handleDiffLayerPackage(packet, peer.ID(), false)
+
+	case *diff.FullSignedDiffLayersPacket:
+		return h.handleDiffLayerPackage(&packet.SignedDiffLayersPacket, peer.ID(), true)
+
 	default:
 		return fmt.Errorf("unexpected diff packet type: %T", packet)
 	}
 }
 
-func (h *diffHandler) handleDiffLayerPackage(packet *diff.DiffLayersPacket, pid string, fulfilled bool) error {
+// diffLayersPacket is a packet of diff layers, either signed or not.
+type diffLayersPacket interface {
+	Unpack() ([]*types.DiffLayer, error)
+}
+
+func (h *diffHandler) handleDiffLayerPackage(packet diffLayersPacket, pid string, fulfilled bool) error {
 	diffs, err := packet.Unpack()
 
 	if err != nil {
@@ -17,6 +17,7 @@
 package eth
 
 import (
+	"bytes"
 	"crypto/rand"
 	"math/big"
 	"testing"
@@ -201,3 +202,43 @@ func TestHandleDiffLayer(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleSignedDiffLayer(t *testing.T) {
+	t.Parallel()
+
+	blockNum := 1024
+	waitInterval := 100 * time.Millisecond
+	backend := newTestBackend(blockNum)
+	defer backend.close()
+
+	peer, errc := newTestPeer("peer", diff.Diff2, backend)
+	defer peer.close()
+
+	// A well formed signature is kept along with the diff layer
+	layer := &types.DiffLayer{BlockHash: common.Hash{0x1}, Number: 1025}
+	bz, _ := rlp.EncodeToBytes(layer)
+	sig := make([]byte, 65)
+	sig[0] = 0x01
+	p2p.Send(peer.app, diff.SignedDiffLayerMsg, diff.SignedDiffLayersPacket{{DiffLayer: bz, Signature: sig}})
+
+	time.Sleep(waitInterval)
+	if have := backend.chain.GetUnTrustedDiffLayer(layer.BlockHash, ""); have == nil || !bytes.Equal(have.Signature, sig) {
+		t.Fatalf("signed diff layer handle failed: %v", have)
+	}
+	// A malformed signature gets the peer dropped
+	layer = &types.DiffLayer{BlockHash: common.Hash{0x2}, Number: 1026}
+	bz, _ = rlp.EncodeToBytes(layer)
+	p2p.Send(peer.app, diff.SignedDiffLayerMsg, diff.SignedDiffLayersPacket{{DiffLayer: bz, Signature: sig[:64]}})
+
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Errorf("peer disconnected without error")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("peer not dropped on malformed signature")
+	}
+	if have := backend.chain.GetUnTrustedDiffLayer(layer.BlockHash, ""); have != nil {
+		t.Errorf("badly signed diff layer accepted")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/protocols/vote"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// voteHandler implements the vote.Backend interface to handle the various network
+// packets that are sent as broadcasts.
+type voteHandler handler
+
+func (h *voteHandler) Chain() *core.BlockChain { return h.chain }
+
+// RunPeer is invoked when a peer joins on the `vote` protocol.
+func (h *voteHandler) RunPeer(peer *vote.Peer, hand vote.Handler) error {
+	return (*handler)(h).runVoteExtension(peer, hand)
+}
+
+// PeerInfo retrieves all known `vote` information about a peer.
+func (h *voteHandler) PeerInfo(id enode.ID) interface{} {
+	if p := h.peers.peer(id.String()); p != nil && p.voteExt != nil {
+		return p.voteExt.info()
+	}
+	return nil
+}
+
+// Handle is invoked from a peer's message handler when it receives a new remote
+// message that the handler couldn't consume and serve itself.
+func (h *voteHandler) Handle(peer *vote.Peer, packet vote.Packet) error {
+	switch packet := packet.(type) {
+	case *vote.VotesPacket:
+		// Votes may turn out invalid if the peer is on another fork or lags behind,
+		// which is no reason to drop it
+		for _, v := range *packet {
+			if err := h.votepool.PutVote(v); err != nil {
+				peer.Log().Trace("Discarded propagated vote", "number", v.Data.TargetNumber, "hash", v.Data.TargetHash, "err", err)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unexpected vote packet type: %T", packet)
+	}
+}
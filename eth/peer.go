@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/diff"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/protocols/vote"
 )
 
 // ethPeerInfo represents a short summary of the `eth` sub-protocol metadata known
@@ -39,6 +40,7 @@ type ethPeer struct {
 	*eth.Peer
 	snapExt *snapPeer // Satellite `snap` connection
 	diffExt *diffPeer
+	voteExt *votePeer // Satellite `vote` connection
 
 	syncDrop *time.Timer   // Connection dropper if `eth` sync progress isn't validated in time
 	snapWait chan struct{} // Notification channel for snap connections
@@ -69,6 +71,12 @@ type diffPeerInfo struct {
 	DiffSync bool `json:"diff_sync"`
 }
 
+// votePeerInfo represents a short summary of the `vote` sub-protocol metadata known
+// about a connected peer.
+type votePeerInfo struct {
+	Version uint `json:"version"` // vote protocol version negotiated
+}
+
 // snapPeer is a wrapper around snap.Peer to maintain a few extra metadata.
 type snapPeer struct {
 	*snap.Peer
@@ -79,6 +87,18 @@ type diffPeer struct {
 	*diff.Peer
 }
 
+// votePeer is a wrapper around vote.Peer to maintain a few extra metadata.
+type votePeer struct {
+	*vote.Peer
+}
+
+// info gathers and returns some `vote` protocol metadata known about a peer.
+func (p *votePeer) info() *votePeerInfo {
+	return &votePeerInfo{
+		Version: p.Version(),
+	}
+}
+
 // info gathers and returns some `diff` protocol metadata known about a peer.
 func (p *diffPeer) info() *diffPeerInfo {
 	return &diffPeerInfo{
@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/diff"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/protocols/vote"
 	"github.com/ethereum/go-ethereum/p2p"
 )
 
@@ -53,6 +54,10 @@ var (
 	// errDiffWithoutEth is returned if a peer attempts to connect only on the
 	// diff protocol without advertising the eth main protocol.
 	errDiffWithoutEth = errors.New("peer connected on diff without compatible eth support")
+
+	// errVoteWithoutEth is returned if a peer attempts to connect only on the
+	// vote protocol without advertising the eth main protocol.
+	errVoteWithoutEth = errors.New("peer connected on vote without compatible eth support")
 )
 
 const (
@@ -73,6 +78,9 @@ type peerSet struct {
 	diffWait map[string]chan *diff.Peer // Peers connected on `eth` waiting for their diff extension
 	diffPend map[string]*diff.Peer      // Peers connected on the `diff` protocol, but not yet on `eth`
 
+	voteWait map[string]chan *vote.Peer // Peers connected on `eth` waiting for their vote extension
+	votePend map[string]*vote.Peer      // Peers connected on the `vote` protocol, but not yet on `eth`
+
 	lock   sync.RWMutex
 	closed bool
 }
@@ -85,6 +93,8 @@ func newPeerSet() *peerSet {
 		snapPend: make(map[string]*snap.Peer),
 		diffWait: make(map[string]chan *diff.Peer),
 		diffPend: make(map[string]*diff.Peer),
+		voteWait: make(map[string]chan *vote.Peer),
+		votePend: make(map[string]*vote.Peer),
 	}
 }
 
@@ -148,6 +158,36 @@ func (ps *peerSet) registerDiffExtension(peer *diff.Peer) error {
 	return nil
 }
 
+// registerVoteExtension unblocks an already connected `eth` peer waiting for its
+// `vote` extension, or if no such peer exists, tracks the extension for the time
+// being until the `eth` main protocol starts looking for it.
+func (ps *peerSet) registerVoteExtension(peer *vote.Peer) error {
+	// Reject the peer if it advertises `vote` without `eth` as `vote` is only a
+	// satellite protocol meaningful with the chain selection of `eth`
+	if !peer.RunningCap(eth.ProtocolName, eth.ProtocolVersions) {
+		return errVoteWithoutEth
+	}
+	// Ensure nobody can double connect
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	id := peer.ID()
+	if _, ok := ps.peers[id]; ok {
+		return errPeerAlreadyRegistered // avoid connections with the same id as existing ones
+	}
+	if _, ok := ps.votePend[id]; ok {
+		return errPeerAlreadyRegistered // avoid connections with the same id as pending ones
+	}
+	// Inject the peer into an `eth` counterpart is available, otherwise save for later
+	if wait, ok := ps.voteWait[id]; ok {
+		delete(ps.voteWait, id)
+		wait <- peer
+		return nil
+	}
+	ps.votePend[id] = peer
+	return nil
+}
+
 // waitExtensions blocks until all satellite protocols are connected and tracked
 // by the peerset.
 func (ps *peerSet) waitSnapExtension(peer *eth.Peer) (*snap.Peer, error) {
@@ -234,6 +274,49 @@ func (ps *peerSet) waitDiffExtension(peer *eth.Peer) (*diff.Peer, error) {
 	}
 }
 
+// waitVoteExtension blocks until all satellite protocols are connected and tracked
+// by the peerset.
+func (ps *peerSet) waitVoteExtension(peer *eth.Peer) (*vote.Peer, error) {
+	// If the peer does not support a compatible `vote`, don't wait
+	if !peer.RunningCap(vote.ProtocolName, vote.ProtocolVersions) {
+		return nil, nil
+	}
+	// Ensure nobody can double connect
+	ps.lock.Lock()
+
+	id := peer.ID()
+	if _, ok := ps.peers[id]; ok {
+		ps.lock.Unlock()
+		return nil, errPeerAlreadyRegistered // avoid connections with the same id as existing ones
+	}
+	if _, ok := ps.voteWait[id]; ok {
+		ps.lock.Unlock()
+		return nil, errPeerAlreadyRegistered // avoid connections with the same id as pending ones
+	}
+	// If `vote` already connected, retrieve the peer from the pending set
+	if vote, ok := ps.votePend[id]; ok {
+		delete(ps.votePend, id)
+
+		ps.lock.Unlock()
+		return vote, nil
+	}
+	// Otherwise wait for `vote` to connect concurrently
+	wait := make(chan *vote.Peer)
+	ps.voteWait[id] = wait
+	ps.lock.Unlock()
+
+	select {
+	case peer := <-wait:
+		return peer, nil
+
+	case <-time.After(extensionWaitTimeout):
+		ps.lock.Lock()
+		delete(ps.voteWait, id)
+		ps.lock.Unlock()
+		return nil, errPeerWaitTimeout
+	}
+}
+
 func (ps *peerSet) GetDiffPeer(pid string) downloader.IDiffPeer {
 	if p := ps.peer(pid); p != nil && p.diffExt != nil {
 		return p.diffExt
@@ -243,7 +326,7 @@ func (ps *peerSet) GetDiffPeer(pid string) downloader.IDiffPeer {
 
 // registerPeer injects a new `eth` peer into the working set, or returns an error
 // if the peer is already known.
-func (ps *peerSet) registerPeer(peer *eth.Peer, ext *snap.Peer, diffExt *diff.Peer) error {
+func (ps *peerSet) registerPeer(peer *eth.Peer, ext *snap.Peer, diffExt *diff.Peer, voteExt *vote.Peer) error {
 	// Start tracking the new peer
 	ps.lock.Lock()
 	defer ps.lock.Unlock()
@@ -265,6 +348,9 @@ func (ps *peerSet) registerPeer(peer *eth.Peer, ext *snap.Peer, diffExt *diff.Pe
 	if diffExt != nil {
 		eth.diffExt = &diffPeer{diffExt}
 	}
+	if voteExt != nil {
+		eth.voteExt = &votePeer{voteExt}
+	}
 	ps.peers[id] = eth
 	return nil
 }
@@ -340,6 +426,21 @@ func (ps *peerSet) peersWithoutTransaction(hash common.Hash) []*ethPeer {
 	return list
 }
 
+// peersWithoutVote retrieves a list of `vote` peers that do not have a given
+// vote in their set of known hashes.
+func (ps *peerSet) peersWithoutVote(hash common.Hash) []*ethPeer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*ethPeer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.voteExt != nil && !p.voteExt.KnownVote(hash) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
 // len returns if the current number of `eth` peers in the set. Since the `snap`
 // peers are tied to the existence of an `eth` connection, that will always be a
 // subset of `eth`.
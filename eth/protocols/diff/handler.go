@@ -120,6 +120,13 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if err := msg.Decode(res); err != nil {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
+		if peer.Version() >= Diff2 {
+			p2p.Send(peer.rw, FullSignedDiffLayerMsg, &FullSignedDiffLayersPacket{
+				RequestId:              res.RequestId,
+				SignedDiffLayersPacket: answerSignedDiffLayersQuery(backend, res),
+			})
+			return nil
+		}
 		diffs := answerDiffLayersQuery(backend, res)
 
 		p2p.Send(peer.rw, FullDiffLayerMsg, &FullDiffLayersPacket{
@@ -145,6 +152,25 @@ func handleMessage(backend Backend, peer *Peer) error {
 			return backend.Handle(peer, res)
 		}
 		return fmt.Errorf("%w: %v", errUnexpectedMsg, msg.Code)
+
+	case peer.Version() >= Diff2 && msg.Code == SignedDiffLayerMsg:
+		// A batch of signed diff layers was broadcast by the remote peer
+		res := new(SignedDiffLayersPacket)
+		if err := msg.Decode(res); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		return backend.Handle(peer, res)
+
+	case peer.Version() >= Diff2 && msg.Code == FullSignedDiffLayerMsg:
+		// A batch of signed diff layers arrived to one of our previous requests
+		res := new(FullSignedDiffLayersPacket)
+		if err := msg.Decode(res); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		if fulfilled := requestTracker.Fulfil(peer.id, peer.version, FullSignedDiffLayerMsg, res.RequestId); fulfilled {
+			return backend.Handle(peer, res)
+		}
+		return fmt.Errorf("%w: %v", errUnexpectedMsg, msg.Code)
 	default:
 		return fmt.Errorf("%w: %v", errInvalidMsgCode, msg.Code)
 	}
@@ -170,6 +196,26 @@ func answerDiffLayersQuery(backend Backend, query *GetDiffLayersPacket) []rlp.Ra
 	return diffLayers
 }
 
+// answerSignedDiffLayersQuery is the diff/2 counterpart of answerDiffLayersQuery,
+// attaching the known sealer signatures to the diff layers.
+func answerSignedDiffLayersQuery(backend Backend, query *GetDiffLayersPacket) []SignedDiffLayer {
+	var (
+		bytes      int
+		diffLayers []SignedDiffLayer
+	)
+	for lookups, hash := range query.BlockHashes {
+		if bytes >= softResponseLimit || len(diffLayers) >= maxDiffLayerServe ||
+			lookups >= 2*maxDiffLayerServe {
+			break
+		}
+		if data, sig := backend.Chain().GetSignedDiffLayerRLP(hash); len(data) != 0 {
+			diffLayers = append(diffLayers, SignedDiffLayer{DiffLayer: data, Signature: sig})
+			bytes += len(data) + len(sig)
+		}
+	}
+	return diffLayers
+}
+
 // NodeInfo represents a short summary of the `diff` sub-protocol metadata
 // known about the host peer.
 type NodeInfo struct{}
@@ -117,6 +117,39 @@ func (b *testBackend) Handle(*Peer, Packet) error {
 
 func TestGetDiffLayers(t *testing.T) { testGetDiffLayers(t, Diff1) }
 
+// Tests that diff/2 peers get the diff layers served along with their sealer
+// signatures, which are absent on a chain without PoSA consensus.
+func TestGetSignedDiffLayers(t *testing.T) {
+	t.Parallel()
+
+	backend := newTestBackend(16)
+	defer backend.close()
+
+	peer, _ := newTestPeer("peer", Diff2, backend)
+	defer peer.close()
+
+	var (
+		hashes []common.Hash
+		diffs  []SignedDiffLayer
+	)
+	for number := uint64(1); number <= 16; number++ {
+		hash := backend.chain.GetCanonicalHash(number)
+		raw, sig := backend.chain.GetSignedDiffLayerRLP(hash)
+		if len(raw) == 0 {
+			t.Fatalf("Failed to find rlp encoded diff layer %v", hash)
+		}
+		hashes = append(hashes, hash)
+		diffs = append(diffs, SignedDiffLayer{DiffLayer: raw, Signature: sig})
+	}
+	p2p.Send(peer.app, GetDiffLayerMsg, GetDiffLayersPacket{RequestId: 1, BlockHashes: hashes})
+	if err := p2p.ExpectMsg(peer.app, FullSignedDiffLayerMsg, FullSignedDiffLayersPacket{
+		RequestId:              1,
+		SignedDiffLayersPacket: diffs,
+	}); err != nil {
+		t.Errorf("signed diff layer mismatch: %v", err)
+	}
+}
+
 func testGetDiffLayers(t *testing.T, protocol uint) {
 	t.Parallel()
 
@@ -87,7 +87,11 @@ func (p *Peer) Close() {
 func (p *Peer) RequestDiffLayers(hashes []common.Hash) error {
 	id := rand.Uint64()
 
-	requestTracker.Track(p.id, p.version, GetDiffLayerMsg, FullDiffLayerMsg, id)
+	resCode := uint64(FullDiffLayerMsg)
+	if p.version >= Diff2 {
+		resCode = FullSignedDiffLayerMsg
+	}
+	requestTracker.Track(p.id, p.version, GetDiffLayerMsg, resCode, id)
 	return p2p.Send(p.rw, GetDiffLayerMsg, GetDiffLayersPacket{
 		RequestId:   id,
 		BlockHashes: hashes,
@@ -98,6 +102,19 @@ func (p *Peer) SendDiffLayers(diffs []rlp.RawValue) error {
 	return p2p.Send(p.rw, DiffLayerMsg, diffs)
 }
 
+// SendSignedDiffLayers propagates diff layers along with the signatures of their
+// block sealers. Peers not supporting diff/2 receive the unsigned diff layers.
+func (p *Peer) SendSignedDiffLayers(diffs []SignedDiffLayer) error {
+	if p.version < Diff2 {
+		raws := make([]rlp.RawValue, 0, len(diffs))
+		for _, diff := range diffs {
+			raws = append(raws, diff.DiffLayer)
+		}
+		return p.SendDiffLayers(raws)
+	}
+	return p2p.Send(p.rw, SignedDiffLayerMsg, diffs)
+}
+
 func (p *Peer) AsyncSendDiffLayer(diffLayers []rlp.RawValue) {
 	select {
 	case p.queuedDiffLayers <- diffLayers:
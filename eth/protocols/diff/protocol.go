@@ -20,16 +20,16 @@ import (
 	"errors"
 	"fmt"
 
-	"golang.org/x/crypto/sha3"
-
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // Constants to match up protocol versions and messages
 const (
 	Diff1 = 1
+	Diff2 = 2
 )
 
 // ProtocolName is the official short name of the `diff` protocol used during
@@ -38,11 +38,11 @@ const ProtocolName = "diff"
 
 // ProtocolVersions are the supported versions of the `diff` protocol (first
 // is primary).
-var ProtocolVersions = []uint{Diff1}
+var ProtocolVersions = []uint{Diff2, Diff1}
 
 // protocolLengths are the number of implemented message corresponding to
 // different protocol versions.
-var protocolLengths = map[uint]uint64{Diff1: 4}
+var protocolLengths = map[uint]uint64{Diff1: 4, Diff2: 6}
 
 // maxMessageSize is the maximum cap on the size of a protocol message.
 const maxMessageSize = 10 * 1024 * 1024
@@ -52,6 +52,10 @@ const (
 	GetDiffLayerMsg  = 0x01
 	DiffLayerMsg     = 0x02
 	FullDiffLayerMsg = 0x03
+
+	// Protocol messages introduced in diff/2
+	SignedDiffLayerMsg     = 0x04
+	FullSignedDiffLayerMsg = 0x05
 )
 
 var defaultExtra = []byte{0x00}
@@ -62,6 +66,7 @@ var (
 	errInvalidMsgCode = errors.New("invalid message code")
 	errUnexpectedMsg  = errors.New("unexpected message code")
 	errNoCapMsg       = errors.New("miss cap message during handshake")
+	errBadSignature   = errors.New("invalid diff layer signature")
 )
 
 // Packet represents a p2p message in the `diff` protocol.
@@ -77,26 +82,53 @@ type GetDiffLayersPacket struct {
 
 func (p *DiffLayersPacket) Unpack() ([]*types.DiffLayer, error) {
 	diffLayers := make([]*types.DiffLayer, 0, len(*p))
-	hasher := sha3.NewLegacyKeccak256()
+	hasher := crypto.NewKeccakState()
 	for _, rawData := range *p {
-		var diff types.DiffLayer
-		err := rlp.DecodeBytes(rawData, &diff)
+		diff, err := decodeDiffLayer(rawData, hasher)
 		if err != nil {
-			return nil, fmt.Errorf("%w: diff layer %v", errDecode, err)
+			return nil, err
 		}
-		diffLayers = append(diffLayers, &diff)
-		_, err = hasher.Write(rawData)
+		diffLayers = append(diffLayers, diff)
+	}
+	return diffLayers, nil
+}
+
+// Unpack decodes the signed diff layers, attaching the sealer signatures to
+// them. Layers without a signature are left unsigned.
+func (p *SignedDiffLayersPacket) Unpack() ([]*types.DiffLayer, error) {
+	diffLayers := make([]*types.DiffLayer, 0, len(*p))
+	hasher := crypto.NewKeccakState()
+	for _, signed := range *p {
+		if len(signed.Signature) != 0 && len(signed.Signature) != crypto.SignatureLength {
+			return nil, fmt.Errorf("%w: length %d", errBadSignature, len(signed.Signature))
+		}
+		diff, err := decodeDiffLayer(signed.DiffLayer, hasher)
 		if err != nil {
 			return nil, err
 		}
-		var diffHash common.Hash
-		hasher.Sum(diffHash[:0])
-		hasher.Reset()
-		diff.DiffHash = diffHash
+		if len(signed.Signature) != 0 {
+			diff.Signature = common.CopyBytes(signed.Signature)
+		}
+		diffLayers = append(diffLayers, diff)
 	}
 	return diffLayers, nil
 }
 
+// decodeDiffLayer decodes a diff layer and sets its hash to the hash of its
+// RLP encoding.
+func decodeDiffLayer(rawData rlp.RawValue, hasher crypto.KeccakState) (*types.DiffLayer, error) {
+	var diff types.DiffLayer
+	if err := rlp.DecodeBytes(rawData, &diff); err != nil {
+		return nil, fmt.Errorf("%w: diff layer %v", errDecode, err)
+	}
+	hasher.Reset()
+	if _, err := hasher.Write(rawData); err != nil {
+		return nil, err
+	}
+	hasher.Read(diff.DiffHash[:])
+	return &diff, nil
+}
+
 type DiffCapPacket struct {
 	DiffSync bool
 	Extra    rlp.RawValue // for extension
@@ -109,6 +141,20 @@ type FullDiffLayersPacket struct {
 	DiffLayersPacket
 }
 
+// SignedDiffLayer is a diff layer in RLP encoding along with the signature of
+// the block sealer over its hash.
+type SignedDiffLayer struct {
+	DiffLayer rlp.RawValue
+	Signature []byte
+}
+
+type SignedDiffLayersPacket []SignedDiffLayer
+
+type FullSignedDiffLayersPacket struct {
+	RequestId uint64
+	SignedDiffLayersPacket
+}
+
 func (*GetDiffLayersPacket) Name() string { return "GetDiffLayers" }
 func (*GetDiffLayersPacket) Kind() byte   { return GetDiffLayerMsg }
 
@@ -118,5 +164,11 @@ func (*DiffLayersPacket) Kind() byte   { return DiffLayerMsg }
 func (*FullDiffLayersPacket) Name() string { return "FullDiffLayers" }
 func (*FullDiffLayersPacket) Kind() byte   { return FullDiffLayerMsg }
 
+func (*SignedDiffLayersPacket) Name() string { return "SignedDiffLayers" }
+func (*SignedDiffLayersPacket) Kind() byte   { return SignedDiffLayerMsg }
+
+func (*FullSignedDiffLayersPacket) Name() string { return "FullSignedDiffLayers" }
+func (*FullSignedDiffLayersPacket) Kind() byte   { return FullSignedDiffLayerMsg }
+
 func (*DiffCapPacket) Name() string { return "DiffCap" }
 func (*DiffCapPacket) Kind() byte   { return DiffCapMsg }
@@ -18,12 +18,14 @@ package diff
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -129,3 +131,53 @@ func TestDiffMessages(t *testing.T) {
 		}
 	}
 }
+
+// Tests that signed diff layers carry their signature and hash through the
+// encoding, and that malformed signatures are rejected.
+func TestSignedDiffLayersPacketUnpack(t *testing.T) {
+	diffLayer := &types.DiffLayer{
+		BlockHash: common.HexToHash("0x1e9624dcd0874958723aa3dae1fe299861e93ef32b980143d798c428bdd7a20a"),
+		Number:    10479133,
+		Receipts:  []*types.Receipt{{GasUsed: 100, TransactionIndex: 1}},
+	}
+	raw, err := rlp.EncodeToBytes(diffLayer)
+	assert.NoError(t, err)
+	sig := make([]byte, crypto.SignatureLength)
+	sig[0] = 0x01
+
+	tests := []struct {
+		sig  []byte
+		fail bool
+	}{
+		{sig: sig},
+		{sig: nil},
+		{sig: sig[:crypto.SignatureLength-1], fail: true},
+	}
+	for i, tt := range tests {
+		bz, err := rlp.EncodeToBytes(SignedDiffLayersPacket{{DiffLayer: raw, Signature: tt.sig}})
+		if err != nil {
+			t.Fatalf("test %d: failed to encode packet: %v", i, err)
+		}
+		packet := new(SignedDiffLayersPacket)
+		if err := rlp.DecodeBytes(bz, packet); err != nil {
+			t.Fatalf("test %d: failed to decode packet: %v", i, err)
+		}
+		diffLayers, err := packet.Unpack()
+		if tt.fail {
+			if !errors.Is(err, errBadSignature) {
+				t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, errBadSignature)
+			}
+			continue
+		}
+		assert.NoError(t, err)
+		if len(diffLayers) != 1 {
+			t.Fatalf("test %d: diff layer count mismatch: have %d, want 1", i, len(diffLayers))
+		}
+		if have, want := diffLayers[0].DiffHash, crypto.Keccak256Hash(raw); have != want {
+			t.Errorf("test %d: diff hash mismatch: have %x, want %x", i, have, want)
+		}
+		if !bytes.Equal(diffLayers[0].Signature, tt.sig) {
+			t.Errorf("test %d: signature mismatch: have %x, want %x", i, diffLayers[0].Signature, tt.sig)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// testBackend is a mock implementation of the vote handler, collecting the
+// votes delivered by the protocol.
+type testBackend struct {
+	votes chan []*types.VoteEnvelope
+}
+
+func (b *testBackend) Chain() *core.BlockChain { return nil }
+
+func (b *testBackend) RunPeer(peer *Peer, handler Handler) error {
+	// Normally the backend would do peer mainentance and handshakes. All that
+	// is omitted and we will just give control back to the handler.
+	return handler(peer)
+}
+func (b *testBackend) PeerInfo(enode.ID) interface{} { panic("not implemented") }
+
+func (b *testBackend) Handle(peer *Peer, packet Packet) error {
+	b.votes <- *packet.(*VotesPacket)
+	return nil
+}
+
+// Tests that propagated votes are delivered to the backend and marked known for
+// the sending peer, while malformed votes disconnect it.
+func TestHandleVotes(t *testing.T) {
+	backend := &testBackend{votes: make(chan []*types.VoteEnvelope, 1)}
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	var id enode.ID
+	rand.Read(id[:])
+	peer := NewPeer(Vote1, p2p.NewPeer(id, "peer", nil), net)
+	defer peer.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- Handle(backend, peer) }()
+
+	vote := &types.VoteEnvelope{
+		Data:      &types.VoteData{TargetNumber: 1, TargetHash: common.Hash{0x01}},
+		Signature: make([]byte, 65),
+	}
+	if err := p2p.Send(app, VotesMsg, []*types.VoteEnvelope{vote}); err != nil {
+		t.Fatalf("failed to send votes: %v", err)
+	}
+	select {
+	case votes := <-backend.votes:
+		if len(votes) != 1 || votes[0].Hash() != vote.Hash() {
+			t.Fatalf("delivered votes mismatch: %v", votes)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("votes not delivered")
+	}
+	if !peer.KnownVote(vote.Hash()) {
+		t.Fatalf("delivered vote not marked known")
+	}
+	// Votes without data are malformed
+	if err := p2p.Send(app, VotesMsg, []*types.VoteEnvelope{{Signature: make([]byte, 65)}}); err != nil {
+		t.Fatalf("failed to send votes: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if !errors.Is(err, errDecode) {
+			t.Fatalf("malformed vote error mismatch: have %v, want %v", err, errDecode)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("peer not dropped on malformed vote")
+	}
+}
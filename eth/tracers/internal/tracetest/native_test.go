@@ -0,0 +1,215 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// The native tracers shadow the JavaScript ones of the same name, so the
+// reference implementations are loaded directly from their sources.
+func TestPrestateTracerNative(t *testing.T) {
+	testNativeTracer("prestateTracer", "prestate_tracer.js", t)
+}
+
+func TestFourByteTracerNative(t *testing.T) {
+	testNativeTracer("4byteTracer", "4byte_tracer.js", t)
+}
+
+func TestOpcountTracerNative(t *testing.T) {
+	testNativeTracer("opcountTracer", "opcount_tracer.js", t)
+}
+
+// testNativeTracer runs the native tracer and its JavaScript counterpart over
+// all the call tracer test cases and checks that they produce the same result.
+func testNativeTracer(tracerName string, jsFile string, t *testing.T) {
+	code, err := ioutil.ReadFile(filepath.Join("..", "..", "js", "internal", "tracers", jsFile))
+	if err != nil {
+		t.Fatalf("failed to read js tracer: %v", err)
+	}
+	files, err := ioutil.ReadDir(filepath.Join("testdata", "call_tracer"))
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(file.Name(), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			test := new(callTracerTest)
+			if blob, err := ioutil.ReadFile(filepath.Join("testdata", "call_tracer", file.Name())); err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			} else if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			have := runTracerTest(t, test, tracerName, nil)
+			want := runTracerTest(t, test, string(code), nil)
+			if !bytes.Equal(canonicalJSON(t, have), canonicalJSON(t, want)) {
+				t.Fatalf("trace mismatch: \nhave %s\nwant %s", have, want)
+			}
+		})
+	}
+}
+
+// runTracerTest executes the transaction of a call tracer test case with the
+// given tracer and returns its result.
+func runTracerTest(t *testing.T, test *callTracerTest, tracerName string, cfg json.RawMessage) json.RawMessage {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	var (
+		signer    = types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+		origin, _ = signer.Sender(tx)
+		txContext = vm.TxContext{
+			Origin:   origin,
+			GasPrice: tx.GasPrice(),
+		}
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    test.Context.Miner,
+			BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+			Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+			Difficulty:  (*big.Int)(test.Context.Difficulty),
+			GasLimit:    uint64(test.Context.GasLimit),
+		}
+		_, statedb = tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+	)
+	tracer, err := tracers.New(tracerName, new(tracers.Context), cfg)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+	msg, err := tx.AsMessage(signer, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// canonicalJSON re-encodes a json blob with sorted object keys, as the key
+// order of the JavaScript tracers follows insertion.
+func canonicalJSON(t *testing.T, blob json.RawMessage) []byte {
+	var v interface{}
+	if err := json.Unmarshal(blob, &v); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	res, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal trace result: %v", err)
+	}
+	return res
+}
+
+// TestPrestateTracerDiffMode checks that in diff mode the prestate tracer only
+// reports the modified accounts and slots, along with their new values.
+func TestPrestateTracerDiffMode(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		origin   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		signer   = types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainID)
+	)
+	// Stores 0x02 into slot 1 and reads slot 2
+	code := []byte{
+		byte(vm.PUSH1), 0x02, byte(vm.PUSH1), 0x01, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x02, byte(vm.SLOAD), byte(vm.POP),
+	}
+	alloc := core.GenesisAlloc{
+		contract: core.GenesisAccount{
+			Nonce:   1,
+			Code:    code,
+			Storage: map[common.Hash]common.Hash{common.HexToHash("0x01"): common.HexToHash("0x01"), common.HexToHash("0x02"): common.HexToHash("0x03")},
+		},
+		origin: core.GenesisAccount{
+			Balance: big.NewInt(500000000000000),
+		},
+	}
+	tx, err := types.SignNewTx(key, signer, &types.LegacyTx{
+		GasPrice: big.NewInt(1),
+		Gas:      100000,
+		To:       &contract,
+		Value:    big.NewInt(10),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	input, _ := rlp.EncodeToBytes(tx)
+	test := &callTracerTest{
+		Genesis: &core.Genesis{Config: params.AllEthashProtocolChanges, Alloc: alloc},
+		Context: &callContext{Number: 8000000, Difficulty: nil, GasLimit: 6000000},
+		Input:   common.Bytes2Hex(input),
+	}
+	res := runTracerTest(t, test, "prestateTracer", json.RawMessage(`{"diffMode": true}`))
+
+	var have struct {
+		Pre  map[common.Address]map[string]interface{} `json:"pre"`
+		Post map[common.Address]map[string]interface{} `json:"post"`
+	}
+	if err := json.Unmarshal(res, &have); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	if len(have.Pre) != 2 || len(have.Post) != 2 {
+		t.Fatalf("modified account count mismatch: %s", res)
+	}
+	// Only the written slot is reported, the read one is unmodified
+	slot := common.HexToHash("0x01").Hex()
+	if storage := have.Pre[contract]["storage"].(map[string]interface{}); len(storage) != 1 || storage[slot] != common.HexToHash("0x01").Hex() {
+		t.Errorf("contract pre storage mismatch: %v", storage)
+	}
+	if storage := have.Post[contract]["storage"].(map[string]interface{}); len(storage) != 1 || storage[slot] != common.HexToHash("0x02").Hex() {
+		t.Errorf("contract post storage mismatch: %v", storage)
+	}
+	if have.Pre[contract]["balance"] != "0x0" || have.Post[contract]["balance"] != "0xa" {
+		t.Errorf("contract balance mismatch: pre %v, post %v", have.Pre[contract]["balance"], have.Post[contract]["balance"])
+	}
+	if _, ok := have.Post[contract]["code"]; ok {
+		t.Errorf("unmodified code reported in post state")
+	}
+	if have.Pre[origin]["nonce"] != float64(0) || have.Post[origin]["nonce"] != float64(1) {
+		t.Errorf("origin nonce mismatch: pre %v, post %v", have.Pre[origin]["nonce"], have.Post[origin]["nonce"])
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	register("prestateTracer", newPrestateTracer)
+}
+
+type prestate = map[common.Address]*account
+
+type account struct {
+	Balance string                      `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    string                      `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// accountDiff is the post state of an account in diff mode, listing only the
+// fields which changed during the transaction.
+type accountDiff struct {
+	Balance string                      `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    string                      `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // If true, this tracer will return the state before and after the tx
+}
+
+type prestateTracer struct {
+	env          *vm.EVM
+	config       prestateTracerConfig
+	prestate     prestate
+	create       bool
+	from         common.Address
+	to           common.Address
+	value        *big.Int
+	gasPrice     *big.Int
+	gasUsed      uint64
+	intrinsicGas uint64
+	interrupt    uint32 // Atomic flag to signal execution interruption
+	reason       error  // Textual reason for the interruption
+}
+
+// newPrestateTracer returns a native go tracer which collects the accounts
+// and storage slots accessed by a tx, and implements vm.EVMLogger.
+func newPrestateTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config prestateTracerConfig
+	if len(cfg) > 0 {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	return &prestateTracer{config: config}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.create = create
+	t.from = from
+	t.to = to
+	t.value = value
+	t.gasPrice = env.TxContext.GasPrice
+
+	// Compute intrinsic gas
+	isHomestead := env.ChainConfig().IsHomestead(env.Context.BlockNumber)
+	isIstanbul := env.ChainConfig().IsIstanbul(env.Context.BlockNumber)
+	intrinsicGas, err := core.IntrinsicGas(input, nil, create, isHomestead, isIstanbul)
+	if err != nil {
+		return
+	}
+	t.intrinsicGas = intrinsicGas
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	t.gasUsed = gasUsed
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// Skip if tracing was interrupted
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.env.Cancel()
+		return
+	}
+	// Add the current account if we just started tracing. Balance will
+	// potentially be wrong here, since this will include the value sent
+	// along with the message. We fix that in GetResult.
+	caller := scope.Contract.Address()
+	if t.prestate == nil {
+		t.prestate = make(prestate)
+		t.lookupAccount(caller)
+	}
+	// Whenever new state is accessed, add it to the prestate
+	stack := scope.Stack
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.Address(stack.Back(0).Bytes20()))
+	case vm.CREATE:
+		t.lookupAccount(crypto.CreateAddress(caller, t.env.StateDB.GetNonce(caller)))
+	case vm.CREATE2:
+		offset, size := stack.Back(1), stack.Back(2)
+		code := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+		t.lookupAccount(crypto.CreateAddress2(caller, stack.Back(3).Bytes32(), crypto.Keccak256(code)))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.Address(stack.Back(1).Bytes20()))
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(caller, stack.Back(0).Bytes32())
+	}
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *prestateTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *prestateTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *prestateTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+}
+
+// GetResult returns the json-encoded prestate of the accessed accounts, or in
+// diff mode the state before and after the tx of the modified ones.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.env == nil {
+		return json.RawMessage(`{}`), t.reason
+	}
+	if t.prestate == nil {
+		t.prestate = make(prestate)
+	}
+	// At this point, we need to deduct the value from the outer transaction,
+	// and move it back to the origin
+	t.lookupAccount(t.from)
+	t.lookupAccount(t.to)
+
+	toBal := hexutil.MustDecodeBig(t.prestate[t.to].Balance)
+	t.prestate[t.to].Balance = hexutil.EncodeBig(new(big.Int).Sub(toBal, t.value))
+
+	fromBal := hexutil.MustDecodeBig(t.prestate[t.from].Balance)
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(t.gasUsed+t.intrinsicGas), t.gasPrice)
+	fromBal.Add(fromBal, t.value).Add(fromBal, fee)
+	t.prestate[t.from].Balance = hexutil.EncodeBig(fromBal)
+
+	// Decrement the caller's nonce, and remove empty create targets
+	t.prestate[t.from].Nonce--
+
+	var (
+		res []byte
+		err error
+	)
+	if t.config.DiffMode {
+		res, err = json.Marshal(t.diff())
+	} else {
+		if t.create {
+			// We can blindly delete the contract prestate, as any existing state would
+			// have caused the transaction to be rejected as invalid in the first place.
+			delete(t.prestate, t.to)
+		}
+		res, err = json.Marshal(t.prestate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// diff compares the prestate against the current state, dropping everything
+// the tx left unmodified. Destructed accounts are absent from the post state.
+func (t *prestateTracer) diff() interface{} {
+	var (
+		db   = t.env.StateDB
+		pre  = make(prestate)
+		post = make(map[common.Address]*accountDiff)
+	)
+	for addr, prev := range t.prestate {
+		if !db.Exist(addr) {
+			pre[addr] = prev
+			continue
+		}
+		var (
+			modified bool
+			diff     = new(accountDiff)
+			storage  = make(map[common.Hash]common.Hash)
+		)
+		if balance := hexutil.EncodeBig(db.GetBalance(addr)); balance != prev.Balance {
+			diff.Balance = balance
+			modified = true
+		}
+		if nonce := db.GetNonce(addr); nonce != prev.Nonce {
+			diff.Nonce = nonce
+			modified = true
+		}
+		if code := hexutil.Encode(db.GetCode(addr)); code != prev.Code {
+			diff.Code = code
+			modified = true
+		}
+		for key, val := range prev.Storage {
+			if cur := db.GetState(addr, key); cur != val {
+				if diff.Storage == nil {
+					diff.Storage = make(map[common.Hash]common.Hash)
+				}
+				diff.Storage[key] = cur
+				storage[key] = val
+				modified = true
+			}
+		}
+		if !modified {
+			continue
+		}
+		pre[addr] = &account{Balance: prev.Balance, Nonce: prev.Nonce, Code: prev.Code, Storage: storage}
+		post[addr] = diff
+	}
+	return struct {
+		Pre  prestate                        `json:"pre"`
+		Post map[common.Address]*accountDiff `json:"post"`
+	}{pre, post}
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// lookupAccount fetches details of an account and adds it to the prestate
+// if it doesn't exist there.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	t.prestate[addr] = &account{
+		Balance: hexutil.EncodeBig(t.env.StateDB.GetBalance(addr)),
+		Nonce:   t.env.StateDB.GetNonce(addr),
+		Code:    hexutil.Encode(t.env.StateDB.GetCode(addr)),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage fetches the requested storage slot and adds
+// it to the prestate of the given contract.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.prestate[addr].Storage[key]; ok {
+		return
+	}
+	t.prestate[addr].Storage[key] = t.env.StateDB.GetState(addr, key)
+}
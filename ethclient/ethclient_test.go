@@ -117,6 +117,22 @@ func TestToFilterArg(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"with finalized fromBlock and safe toBlock",
+			ethereum.FilterQuery{
+				Addresses: addresses,
+				FromBlock: big.NewInt(int64(rpc.FinalizedBlockNumber)),
+				ToBlock:   big.NewInt(int64(rpc.SafeBlockNumber)),
+				Topics:    [][]common.Hash{},
+			},
+			map[string]interface{}{
+				"address":   addresses,
+				"fromBlock": "finalized",
+				"toBlock":   "safe",
+				"topics":    [][]common.Hash{},
+			},
+			nil,
+		},
 		{
 			"with blockhash",
 			ethereum.FilterQuery{
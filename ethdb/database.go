@@ -87,6 +87,10 @@ type AncientReader interface {
 
 	// AncientOffSet returns the offset of current ancientDB.
 	AncientOffSet() uint64
+
+	// AncientTail returns the number of the oldest item still available in the
+	// ancient store, everything below it has been pruned.
+	AncientTail() (uint64, error)
 }
 
 // AncientWriter contains the methods required to write to immutable ancient data.
@@ -98,6 +102,10 @@ type AncientWriter interface {
 	// TruncateAncients discards all but the first n ancient data from the ancient store.
 	TruncateAncients(n uint64) error
 
+	// TruncateTail discards all ancient data below item n from the ancient store,
+	// leaving the number of ancient items untouched.
+	TruncateTail(n uint64) error
+
 	// Sync flushes all in-memory ancient store data to disk.
 	Sync() error
 }
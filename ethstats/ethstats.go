@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -58,6 +59,11 @@ const (
 	txChanSize = 4096
 	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
 	chainHeadChanSize = 10
+
+	// parliaExtension is the name of the protocol extension reporting the Parlia
+	// specific stats. They are only sent to servers accepting it upon login, so
+	// stock servers never see the extended messages.
+	parliaExtension = "parlia"
 )
 
 // backend encompasses the bare-minimum functionality needed for ethstats reporting
@@ -81,6 +87,20 @@ type fullNodeBackend interface {
 	SuggestPrice(ctx context.Context) (*big.Int, error)
 }
 
+// parliaBackend encompasses the functionality necessary for a full node of a
+// Parlia network reporting the extended stats.
+type parliaBackend interface {
+	Chain() *core.BlockChain
+	TxPoolGasFreeStatus() core.GasFreeStatus
+}
+
+// parliaEngine is implemented by the Parlia consensus engine, reporting on the
+// validator set and the system transactions.
+type parliaEngine interface {
+	SealingStatus(chain consensus.ChainHeaderReader, header *types.Header) (*parlia.SealingStatus, error)
+	IsSystemTransaction(tx *types.Transaction, header *types.Header) (bool, error)
+}
+
 // Service implements an Ethereum netstats reporting daemon that pushes local
 // chain statistics up to a monitoring server.
 type Service struct {
@@ -111,14 +131,15 @@ type Service struct {
 //   concurrently.
 //   The Close and WriteControl methods can be called concurrently with all other methods.
 type connWrapper struct {
-	conn *websocket.Conn
+	conn       *websocket.Conn
+	extensions map[string]bool // Protocol extensions accepted by the server
 
 	rlock sync.Mutex
 	wlock sync.Mutex
 }
 
 func newConnectionWrapper(conn *websocket.Conn) *connWrapper {
-	return &connWrapper{conn: conn}
+	return &connWrapper{conn: conn, extensions: make(map[string]bool)}
 }
 
 // WriteJSON wraps corresponding method on the websocket but is safe for concurrent calling
@@ -430,6 +451,8 @@ type nodeInfo struct {
 	OsVer    string `json:"os_v"`
 	Client   string `json:"client"`
 	History  bool   `json:"canUpdateHistory"`
+
+	Extensions []string `json:"extensions,omitempty"` // Protocol extensions offered by the node
 }
 
 // authMsg is the authentication infos needed to login to a monitoring server.
@@ -470,17 +493,42 @@ func (s *Service) login(conn *connWrapper) error {
 		},
 		Secret: s.pass,
 	}
+	if _, _, ok := s.parlia(); ok {
+		auth.Info.Extensions = []string{parliaExtension}
+	}
 	login := map[string][]interface{}{
 		"emit": {"hello", auth},
 	}
 	if err := conn.WriteJSON(login); err != nil {
 		return err
 	}
-	// Retrieve the remote ack or connection termination
-	var ack map[string][]string
-	if err := conn.ReadJSON(&ack); err != nil || len(ack["emit"]) != 1 || ack["emit"][0] != "ready" {
+	// Retrieve the remote ack or connection termination, servers supporting any
+	// of the offered extensions list the accepted ones after the ack
+	var (
+		ack   map[string][]json.RawMessage
+		ready string
+	)
+	if err := conn.ReadJSON(&ack); err != nil || len(ack["emit"]) == 0 || len(ack["emit"]) > 2 {
+		return errors.New("unauthorized")
+	}
+	if err := json.Unmarshal(ack["emit"][0], &ready); err != nil || ready != "ready" {
 		return errors.New("unauthorized")
 	}
+	if len(ack["emit"]) == 2 {
+		var accepted struct {
+			Extensions []string `json:"extensions"`
+		}
+		if err := json.Unmarshal(ack["emit"][1], &accepted); err != nil {
+			return fmt.Errorf("invalid extensions: %v", err)
+		}
+		for _, ext := range accepted.Extensions {
+			for _, offered := range auth.Info.Extensions {
+				if ext == offered {
+					conn.extensions[ext] = true
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -588,7 +636,13 @@ func (s *Service) reportBlock(conn *connWrapper, block *types.Block) error {
 	report := map[string][]interface{}{
 		"emit": {"block", stats},
 	}
-	return conn.WriteJSON(report)
+	if err := conn.WriteJSON(report); err != nil {
+		return err
+	}
+	if conn.extensions[parliaExtension] {
+		return s.reportParliaBlock(conn, details.Hash)
+	}
+	return nil
 }
 
 // assembleBlockStats retrieves any required metadata to report a single block
@@ -783,5 +837,136 @@ func (s *Service) reportStats(conn *connWrapper) error {
 	report := map[string][]interface{}{
 		"emit": {"stats", stats},
 	}
+	if err := conn.WriteJSON(report); err != nil {
+		return err
+	}
+	if conn.extensions[parliaExtension] {
+		return s.reportParliaStats(conn)
+	}
+	return nil
+}
+
+// parlia returns the Parlia engine and backend of the node, if it's a full node
+// of a Parlia network.
+func (s *Service) parlia() (parliaEngine, parliaBackend, bool) {
+	engine, ok := s.engine.(parliaEngine)
+	if !ok {
+		return nil, nil, false
+	}
+	backend, ok := s.backend.(parliaBackend)
+	if !ok {
+		return nil, nil, false
+	}
+	return engine, backend, true
+}
+
+// parliaBlockStats is the Parlia specific information to report about individual
+// blocks.
+type parliaBlockStats struct {
+	Number        *big.Int    `json:"number"`
+	Hash          common.Hash `json:"hash"`
+	InTurn        bool        `json:"inTurn"`
+	Validators    int         `json:"validators"`
+	Epoch         uint64      `json:"epoch"`
+	EpochLength   uint64      `json:"epochLength"`
+	SystemTxs     int         `json:"systemTransactions"`
+	SystemRewards *big.Int    `json:"systemRewards"`
+}
+
+// reportParliaBlock reports the Parlia specific details of the block with the
+// given hash to the stats server.
+func (s *Service) reportParliaBlock(conn *connWrapper, hash common.Hash) error {
+	engine, backend, _ := s.parlia()
+
+	block := backend.Chain().GetBlockByHash(hash)
+	if block == nil {
+		return nil
+	}
+	status, err := engine.SealingStatus(backend.Chain(), block.Header())
+	if err != nil {
+		log.Debug("Failed to retrieve Parlia status", "number", block.Number(), "hash", hash, "err", err)
+		return nil
+	}
+	details := &parliaBlockStats{
+		Number:        block.Number(),
+		Hash:          hash,
+		InTurn:        status.InTurn,
+		Validators:    status.Validators,
+		Epoch:         status.Epoch,
+		EpochLength:   status.EpochLength,
+		SystemRewards: new(big.Int),
+	}
+	// The system transactions distribute the block rewards to the validators
+	for _, tx := range block.Transactions() {
+		if system, _ := engine.IsSystemTransaction(tx, block.Header()); system {
+			details.SystemTxs++
+			details.SystemRewards.Add(details.SystemRewards, tx.Value())
+		}
+	}
+	log.Trace("Sending Parlia block details to ethstats", "number", details.Number, "hash", details.Hash)
+
+	stats := map[string]interface{}{
+		"id":    s.node,
+		"block": details,
+	}
+	report := map[string][]interface{}{
+		"emit": {"parlia-block", stats},
+	}
+	return conn.WriteJSON(report)
+}
+
+// parliaStats is the Parlia specific information to report about the local node.
+type parliaStats struct {
+	Validator      common.Address `json:"validator"`
+	Authorized     bool           `json:"authorized"`
+	NextInTurn     bool           `json:"nextInTurn"`
+	SignedRecently bool           `json:"signedRecently"`
+	Backoff        uint64         `json:"backoff"`
+
+	LightProcessed uint64 `json:"lightProcessed"` // Blocks imported from diff layers since startup
+	FullProcessed  uint64 `json:"fullProcessed"`  // Blocks imported by execution since startup
+
+	GasFreeSenders   int    `json:"gasFreeSenders"`
+	GasFreeContracts int    `json:"gasFreeContracts"`
+	GasFreeTxs       uint64 `json:"gasFreeTxs"`   // Admitted in the current window, summed over senders and contracts
+	GasFreeSlots     uint64 `json:"gasFreeSlots"` // Pool slots taken, summed over senders and contracts
+}
+
+// reportParliaStats reports the validator status, diff sync usage and gas-free
+// transaction counts of the local node to the stats server.
+func (s *Service) reportParliaStats(conn *connWrapper) error {
+	engine, backend, _ := s.parlia()
+
+	details := new(parliaStats)
+	if status, err := engine.SealingStatus(backend.Chain(), backend.Chain().CurrentHeader()); err != nil {
+		log.Debug("Failed to retrieve Parlia status", "err", err)
+	} else {
+		details.Validator = status.Validator
+		details.Authorized = status.Authorized
+		details.NextInTurn = status.NextInTurn
+		details.SignedRecently = status.SignedRecently
+		details.Backoff = status.Backoff
+	}
+	details.LightProcessed, details.FullProcessed = backend.Chain().ProcessedBlocks()
+
+	for _, usage := range backend.TxPoolGasFreeStatus().Usage {
+		if usage.Sender {
+			details.GasFreeSenders++
+		}
+		if usage.Contract {
+			details.GasFreeContracts++
+		}
+		details.GasFreeTxs += usage.Txs
+		details.GasFreeSlots += usage.Slots
+	}
+	log.Trace("Sending Parlia node details to ethstats")
+
+	stats := map[string]interface{}{
+		"id":    s.node,
+		"stats": details,
+	}
+	report := map[string][]interface{}{
+		"emit": {"parlia-stats", stats},
+	}
 	return conn.WriteJSON(report)
 }
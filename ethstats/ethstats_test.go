@@ -0,0 +1,291 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethstats
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	ethproto "github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	testKey, _    = crypto.GenerateKey()
+	testAddress   = crypto.PubkeyToAddress(testKey.PublicKey)
+	testSystem    = common.HexToAddress("0x0000000000000000000000000000000000001000")
+	testReward    = big.NewInt(1000)
+	testValidator = common.HexToAddress("0x01")
+)
+
+// testEngine is a PoW engine pretending to be Parlia, treating the transfers to
+// the test system address as system transactions.
+type testEngine struct {
+	consensus.Engine
+}
+
+func (e *testEngine) SealingStatus(chain consensus.ChainHeaderReader, header *types.Header) (*parlia.SealingStatus, error) {
+	return &parlia.SealingStatus{
+		InTurn:      true,
+		Validators:  3,
+		Epoch:       header.Number.Uint64() / 2,
+		EpochLength: 2,
+		Validator:   testValidator,
+		Authorized:  true,
+		Backoff:     1,
+	}, nil
+}
+
+func (e *testEngine) IsSystemTransaction(tx *types.Transaction, header *types.Header) (bool, error) {
+	return tx.To() != nil && *tx.To() == testSystem, nil
+}
+
+// testBackend is a light node backend, extended with the chain and pool access
+// of the full nodes on Parlia networks.
+type testBackend struct {
+	chain      *core.BlockChain
+	downloader *downloader.Downloader
+	txFeed     event.Feed
+}
+
+func newTestBackend(t *testing.T, blocks int) *testBackend {
+	db := rawdb.NewMemoryDatabase()
+	gspec := core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testAddress: {Balance: big.NewInt(params.Ether)}},
+	}
+	genesis := gspec.MustCommit(db)
+
+	engine := ethash.NewFaker()
+	signer := types.LatestSigner(params.TestChainConfig)
+	chain, _ := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+	t.Cleanup(chain.Stop)
+
+	bs, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, blocks, func(i int, gen *core.BlockGen) {
+		tx := types.MustSignNewTx(testKey, signer, &types.LegacyTx{
+			Nonce:    gen.TxNonce(testAddress),
+			To:       &testSystem,
+			Value:    testReward,
+			Gas:      params.TxGas,
+			GasPrice: gen.BaseFee(),
+		})
+		gen.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(bs); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	dl := downloader.New(0, db, nil, new(event.TypeMux), chain, nil, func(string) {})
+	t.Cleanup(dl.Terminate)
+
+	return &testBackend{chain: chain, downloader: dl}
+}
+
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.chain.SubscribeChainHeadEvent(ch)
+}
+func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txFeed.Subscribe(ch)
+}
+func (b *testBackend) CurrentHeader() *types.Header { return b.chain.CurrentHeader() }
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+func (b *testBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
+	return b.chain.GetTdByHash(hash)
+}
+func (b *testBackend) Stats() (pending int, queued int)   { return 0, 0 }
+func (b *testBackend) Downloader() *downloader.Downloader { return b.downloader }
+func (b *testBackend) Chain() *core.BlockChain            { return b.chain }
+func (b *testBackend) TxPoolGasFreeStatus() core.GasFreeStatus {
+	return core.GasFreeStatus{Usage: map[common.Address]core.GasFreeUsage{
+		{0x01}: {Sender: true, Txs: 2, Slots: 2},
+		{0x02}: {Contract: true, Txs: 1, Slots: 1},
+	}}
+}
+
+// testServer is a stats server recording the messages reported to it, accepting
+// the given protocol extensions upon login.
+type testServer struct {
+	*httptest.Server
+	msgs chan []json.RawMessage
+}
+
+func newTestServer(t *testing.T, extensions []string) *testServer {
+	server := &testServer{msgs: make(chan []json.RawMessage, 100)}
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var msg map[string][]json.RawMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			var command string
+			if len(msg["emit"]) == 0 || json.Unmarshal(msg["emit"][0], &command) != nil {
+				continue
+			}
+			switch command {
+			case "hello":
+				ack := []interface{}{"ready"}
+				if extensions != nil {
+					ack = append(ack, map[string][]string{"extensions": extensions})
+				}
+				conn.WriteJSON(map[string][]interface{}{"emit": ack})
+			case "node-ping":
+				conn.WriteJSON(map[string][]interface{}{"emit": {"node-pong", msg["emit"][1]}})
+			}
+			server.msgs <- msg["emit"]
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// report starts a stats service reporting to the given server and collects the
+// messages of its initial full report, concluded by the given message.
+func report(t *testing.T, server *testServer, last string) map[string]json.RawMessage {
+	p2pServer := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  testKey,
+		MaxPeers:    1,
+		NoDiscovery: true,
+		Protocols: []p2p.Protocol{{
+			Name:     "eth",
+			Version:  ethproto.ETH66,
+			NodeInfo: func() interface{} { return &ethproto.NodeInfo{Network: 1} },
+		}},
+	}}
+	if err := p2pServer.Start(); err != nil {
+		t.Fatalf("failed to start p2p server: %v", err)
+	}
+	defer p2pServer.Stop()
+
+	service := &Service{
+		server:  p2pServer,
+		backend: newTestBackend(t, 3),
+		engine:  &testEngine{ethash.NewFaker()},
+		node:    "test",
+		pass:    "secret",
+		host:    strings.TrimPrefix(server.URL, "http://"),
+		pongCh:  make(chan struct{}),
+		histCh:  make(chan []uint64, 1),
+	}
+	service.Start()
+	defer service.Stop()
+
+	msgs := make(map[string]json.RawMessage)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-server.msgs:
+			var command string
+			json.Unmarshal(msg[0], &command)
+			msgs[command] = msg[1]
+			if command == last {
+				return msgs
+			}
+		case <-timeout:
+			t.Fatalf("report not concluded, have %d messages", len(msgs))
+		}
+	}
+}
+
+// Tests that the Parlia stats are reported to the servers accepting the extension.
+func TestParliaReport(t *testing.T) {
+	msgs := report(t, newTestServer(t, []string{parliaExtension}), "parlia-stats")
+
+	var hello authMsg
+	if err := json.Unmarshal(msgs["hello"], &hello); err != nil {
+		t.Fatalf("failed to decode login: %v", err)
+	}
+	if len(hello.Info.Extensions) != 1 || hello.Info.Extensions[0] != parliaExtension {
+		t.Fatalf("offered extensions mismatch: have %v, want [%s]", hello.Info.Extensions, parliaExtension)
+	}
+
+	var block struct {
+		Block parliaBlockStats `json:"block"`
+	}
+	if err := json.Unmarshal(msgs["parlia-block"], &block); err != nil {
+		t.Fatalf("failed to decode parlia block report: %v", err)
+	}
+	if block.Block.Number.Uint64() != 3 || !block.Block.InTurn || block.Block.Validators != 3 || block.Block.Epoch != 1 {
+		t.Errorf("block status mismatch: %+v", block.Block)
+	}
+	if block.Block.SystemTxs != 1 || block.Block.SystemRewards.Cmp(testReward) != 0 {
+		t.Errorf("system transactions mismatch: have %d txs, %v rewards", block.Block.SystemTxs, block.Block.SystemRewards)
+	}
+	var stats struct {
+		Stats parliaStats `json:"stats"`
+	}
+	if err := json.Unmarshal(msgs["parlia-stats"], &stats); err != nil {
+		t.Fatalf("failed to decode parlia stats report: %v", err)
+	}
+	want := parliaStats{
+		Validator:        testValidator,
+		Authorized:       true,
+		Backoff:          1,
+		FullProcessed:    3,
+		GasFreeSenders:   1,
+		GasFreeContracts: 1,
+		GasFreeTxs:       3,
+		GasFreeSlots:     3,
+	}
+	if stats.Stats != want {
+		t.Errorf("node stats mismatch: have %+v, want %+v", stats.Stats, want)
+	}
+}
+
+// Tests that stock servers, not accepting the extension, get the standard
+// reports only. The Parlia block details would precede the node stats.
+func TestStockReport(t *testing.T) {
+	msgs := report(t, newTestServer(t, nil), "stats")
+
+	for _, command := range []string{"hello", "latency", "block", "pending", "stats"} {
+		if _, ok := msgs[command]; !ok {
+			t.Errorf("missing %s report", command)
+		}
+	}
+	for command := range msgs {
+		if strings.HasPrefix(command, "parlia") {
+			t.Errorf("extended %s report sent to stock server", command)
+		}
+	}
+}
@@ -38,8 +38,22 @@ import (
 
 var (
 	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
+	errFromBlockTag   = errors.New("cannot specify both fromBlock and fromTag")
+	errToBlockTag     = errors.New("cannot specify both toBlock and toTag")
 )
 
+// blockTagNumber converts a BlockTag into the block number standing for it.
+func blockTagNumber(tag string) rpc.BlockNumber {
+	switch tag {
+	case "SAFE":
+		return rpc.SafeBlockNumber
+	case "FINALIZED":
+		return rpc.FinalizedBlockNumber
+	default:
+		return rpc.LatestBlockNumber
+	}
+}
+
 type Long int64
 
 // ImplementsGraphQLType returns true if Long implements the provided GraphQL type.
@@ -440,6 +454,9 @@ func (b *Block) resolve(ctx context.Context) (*types.Block, error) {
 			b.hash = hash
 		}
 	}
+	if b.block == nil && err == nil {
+		err = ethapi.CheckPrunedBlock(b.backend, *b.numberOrHash)
+	}
 	return b.block, err
 }
 
@@ -454,8 +471,14 @@ func (b *Block) resolveHeader(ctx context.Context) (*types.Header, error) {
 	if b.header == nil {
 		if b.hash != (common.Hash{}) {
 			b.header, err = b.backend.HeaderByHash(ctx, b.hash)
+			if b.header == nil && err == nil {
+				err = ethapi.CheckPrunedBlock(b.backend, rpc.BlockNumberOrHashWithHash(b.hash, false))
+			}
 		} else {
 			b.header, err = b.backend.HeaderByNumberOrHash(ctx, *b.numberOrHash)
+			if b.header == nil && err == nil {
+				err = ethapi.CheckPrunedBlock(b.backend, *b.numberOrHash)
+			}
 		}
 	}
 	return b.header, err
@@ -477,6 +500,14 @@ func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
 		if err != nil {
 			return nil, err
 		}
+		if receipts == nil {
+			// The receipts may have been pruned since the header was retrieved
+			if number := rawdb.ReadHeaderNumber(b.backend.ChainDb(), hash); number != nil {
+				if err := ethapi.CheckPrunedHistory(b.backend.HistoryPruningCutoff(), *number); err != nil {
+					return nil, err
+				}
+			}
+		}
 		b.receipts = receipts
 	}
 	return b.receipts, nil
@@ -773,9 +804,18 @@ type BlockFilterCriteria struct {
 	Topics *[][]common.Hash
 }
 
+// logLimitsBackend is implemented by the backends capping the log queries, the
+// same caps applying to the RPC and GraphQL queries.
+type logLimitsBackend interface {
+	LogLimits() filters.LogLimits
+}
+
 // runFilter accepts a filter and executes it, returning all its results as
 // `Log` objects.
 func runFilter(ctx context.Context, be ethapi.Backend, filter *filters.Filter) ([]*Log, error) {
+	if limited, ok := be.(logLimitsBackend); ok {
+		filter.SetLimits(limited.LogLimits())
+	}
 	logs, err := filter.Logs(ctx)
 	if err != nil || logs == nil {
 		return nil, err
@@ -972,6 +1012,7 @@ type Resolver struct {
 func (r *Resolver) Block(ctx context.Context, args struct {
 	Number *Long
 	Hash   *common.Hash
+	Tag    *string
 }) (*Block, error) {
 	var block *Block
 	if args.Number != nil {
@@ -990,6 +1031,12 @@ func (r *Resolver) Block(ctx context.Context, args struct {
 			backend:      r.backend,
 			numberOrHash: &numberOrHash,
 		}
+	} else if args.Tag != nil {
+		numberOrHash := rpc.BlockNumberOrHashWithNumber(blockTagNumber(*args.Tag))
+		block = &Block{
+			backend:      r.backend,
+			numberOrHash: &numberOrHash,
+		}
 	} else {
 		numberOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
 		block = &Block{
@@ -1006,6 +1053,12 @@ func (r *Resolver) Block(ctx context.Context, args struct {
 	} else if h == nil {
 		return nil, nil
 	}
+	if args.Number == nil && args.Hash == nil && args.Tag != nil {
+		// Pin the tagged block, so that its fields are consistent if the tag moves
+		block.hash = h.Hash()
+		numberOrHash := rpc.BlockNumberOrHashWithHash(block.hash, false)
+		block.numberOrHash = &numberOrHash
+	}
 	return block, nil
 }
 
@@ -1066,7 +1119,9 @@ func (r *Resolver) SendRawTransaction(ctx context.Context, args struct{ Data hex
 // FilterCriteria encapsulates the arguments to `logs` on the root resolver object.
 type FilterCriteria struct {
 	FromBlock *hexutil.Uint64   // beginning of the queried range, nil means genesis block
+	FromTag   *string           // beginning of the queried range by tag, instead of number
 	ToBlock   *hexutil.Uint64   // end of the range, nil means latest block
+	ToTag     *string           // end of the range by tag, instead of number
 	Addresses *[]common.Address // restricts matches to events created by specific contracts
 
 	// The Topic list restricts matches to particular event topics. Each event has a list
@@ -1087,11 +1142,21 @@ func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria
 	// Convert the RPC block numbers into internal representations
 	begin := rpc.LatestBlockNumber.Int64()
 	if args.Filter.FromBlock != nil {
+		if args.Filter.FromTag != nil {
+			return nil, errFromBlockTag
+		}
 		begin = int64(*args.Filter.FromBlock)
+	} else if args.Filter.FromTag != nil {
+		begin = blockTagNumber(*args.Filter.FromTag).Int64()
 	}
 	end := rpc.LatestBlockNumber.Int64()
 	if args.Filter.ToBlock != nil {
+		if args.Filter.ToTag != nil {
+			return nil, errToBlockTag
+		}
 		end = int64(*args.Filter.ToBlock)
+	} else if args.Filter.ToTag != nil {
+		end = blockTagNumber(*args.Filter.ToTag).Int64()
 	}
 	var addresses []common.Address
 	if args.Filter.Addresses != nil {
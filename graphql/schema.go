@@ -31,6 +31,17 @@ const schema string = `
     # Long is a 64 bit unsigned integer.
     scalar Long
 
+    # BlockTag refers to a block by its position relative to the head of the chain.
+    enum BlockTag {
+        # LATEST is the most recent known block.
+        LATEST
+        # SAFE is the latest block justified by the validators, unlikely to be
+        # reverted.
+        SAFE
+        # FINALIZED is the latest block which can no longer be reverted.
+        FINALIZED
+    }
+
     schema {
         query: Query
         mutation: Mutation
@@ -252,9 +263,15 @@ const schema string = `
         # FromBlock is the block at which to start searching, inclusive. Defaults
         # to the latest block if not supplied.
         fromBlock: Long
+        # FromTag is the tagged block at which to start searching, in place of
+        # fromBlock.
+        fromTag: BlockTag
         # ToBlock is the block at which to stop searching, inclusive. Defaults
         # to the latest block if not supplied.
         toBlock: Long
+        # ToTag is the tagged block at which to stop searching, in place of
+        # toBlock.
+        toTag: BlockTag
         # Addresses is a list of addresses that are of interest. If this list is
         # empty, results will not be filtered by address.
         addresses: [Address!]
@@ -304,9 +321,9 @@ const schema string = `
     }
 
     type Query {
-        # Block fetches an Ethereum block by number or by hash. If neither is
-        # supplied, the most recent known block is returned.
-        block(number: Long, hash: Bytes32): Block
+        # Block fetches an Ethereum block by number, by hash or by tag. If none
+        # is supplied, the most recent known block is returned.
+        block(number: Long, hash: Bytes32, tag: BlockTag): Block
         # Blocks returns all the blocks between two numbers, inclusive. If
         # to is not supplied, it defaults to the most recent known block.
         blocks(from: Long, to: Long): [Block!]!
@@ -0,0 +1,416 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/gopool"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	callBundleMaxTxs        = 100    // Maximum number of transactions in a simulated bundle
+	callBundleMaxStructLogs = 100000 // Maximum number of struct logs traced for a whole bundle
+	callBundleMaxStateDiffs = 10000  // Maximum number of changed accounts and slots reported for a whole bundle
+)
+
+// BundleTransaction is a transaction of a simulated bundle: either a call given
+// by its arguments or a signed transaction given in its binary encoding.
+type BundleTransaction struct {
+	Call *CallArgs
+	Tx   *types.Transaction
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding a hex string as a signed
+// transaction and an object as call arguments.
+func (t *BundleTransaction) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		var raw hexutil.Bytes
+		if err := json.Unmarshal(input, &raw); err != nil {
+			return err
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return err
+		}
+		t.Tx = tx
+		return nil
+	}
+	t.Call = new(CallArgs)
+	return json.Unmarshal(input, t.Call)
+}
+
+// BlockOverrides is the set of header fields overridden in the block a bundle is
+// simulated in.
+type BlockOverrides struct {
+	Number   *hexutil.Big    `json:"number"`
+	Time     *hexutil.Uint64 `json:"time"`
+	Coinbase *common.Address `json:"coinbase"`
+}
+
+// Apply returns a copy of the given header with the fields overridden.
+func (o *BlockOverrides) Apply(header *types.Header) *types.Header {
+	header = types.CopyHeader(header)
+	if o == nil {
+		return header
+	}
+	if o.Number != nil {
+		header.Number = o.Number.ToInt()
+	}
+	if o.Time != nil {
+		header.Time = uint64(*o.Time)
+	}
+	if o.Coinbase != nil {
+		header.Coinbase = *o.Coinbase
+	}
+	return header
+}
+
+// CallBundleOptions are the optional parameters of a bundle simulation.
+type CallBundleOptions struct {
+	BlockOverrides *BlockOverrides `json:"blockOverrides"`
+	StateOverrides *StateOverride  `json:"stateOverrides"`
+	StateDiff      bool            `json:"stateDiff"` // Report the state changes of every transaction
+	Trace          *vm.LogConfig   `json:"trace"`     // Struct log every transaction with the given configuration
+}
+
+// BalanceDiff is the change of an account balance.
+type BalanceDiff struct {
+	From *hexutil.Big `json:"from"`
+	To   *hexutil.Big `json:"to"`
+}
+
+// NonceDiff is the change of an account nonce.
+type NonceDiff struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// CodeDiff is the change of an account code.
+type CodeDiff struct {
+	From hexutil.Bytes `json:"from"`
+	To   hexutil.Bytes `json:"to"`
+}
+
+// StorageDiff is the change of a storage slot.
+type StorageDiff struct {
+	From common.Hash `json:"from"`
+	To   common.Hash `json:"to"`
+}
+
+// AccountDiff is the change of an account made by a simulated transaction, the
+// unchanged fields omitted.
+type AccountDiff struct {
+	Balance *BalanceDiff                `json:"balance,omitempty"`
+	Nonce   *NonceDiff                  `json:"nonce,omitempty"`
+	Code    *CodeDiff                   `json:"code,omitempty"`
+	Storage map[common.Hash]StorageDiff `json:"storage,omitempty"`
+}
+
+// BundleTxResult is the outcome of a transaction of a simulated bundle.
+type BundleTxResult struct {
+	TxHash      *common.Hash                    `json:"txHash,omitempty"` // Hash of the signed transactions only
+	From        common.Address                  `json:"from"`
+	To          *common.Address                 `json:"to"`
+	GasUsed     hexutil.Uint64                  `json:"gasUsed"`
+	GasPayer    *common.Address                 `json:"gasPayer,omitempty"`
+	ReturnValue hexutil.Bytes                   `json:"returnValue"`
+	Error       string                          `json:"error,omitempty"`
+	Revert      hexutil.Bytes                   `json:"revert,omitempty"`
+	Logs        []*types.Log                    `json:"logs"`
+	StateDiff   map[common.Address]*AccountDiff `json:"stateDiff,omitempty"`
+	StructLogs  []StructLogRes                  `json:"structLogs,omitempty"`
+}
+
+// CallBundleResult is the outcome of a bundle simulation.
+type CallBundleResult struct {
+	StateBlockHash common.Hash      `json:"stateBlockHash"` // Block the bundle was applied on the state of
+	BlockNumber    hexutil.Uint64   `json:"blockNumber"`    // Number of the block the bundle was simulated in
+	GasUsed        hexutil.Uint64   `json:"gasUsed"`
+	Results        []BundleTxResult `json:"results"`
+}
+
+// CallBundle applies the given calls and signed transactions in order on the state
+// of the given block, returning the outcome of every one of them. The header fields
+// of the simulated block and the state can be overridden. Calls get at most the gas
+// left of the RPC gas cap, which caps the gas used by the whole bundle, just like the
+// eth_call timeout caps its run time. A transaction failing before execution, e.g.
+// due to a wrong nonce or a fee cap below the base fee, aborts the bundle. Only calls
+// are exempt from the base fee. The size of the bundle and of its traces and state
+// diffs are limited too.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to dry run sequences of dependent transactions.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, txs []BundleTransaction, blockNrOrHash rpc.BlockNumberOrHash, opts *CallBundleOptions) (*CallBundleResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM bundle finished", "runtime", time.Since(start)) }(time.Now())
+
+	if len(txs) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if len(txs) > callBundleMaxTxs {
+		return nil, fmt.Errorf("bundle of %d transactions exceeds the limit of %d", len(txs), callBundleMaxTxs)
+	}
+	if opts == nil {
+		opts = new(CallBundleOptions)
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if err := opts.StateOverrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	var (
+		stateBlock  = header.Hash()
+		config      = s.b.ChainConfig()
+		deleteEmpty = config.IsEIP158(header.Number)
+	)
+	header = opts.BlockOverrides.Apply(header)
+	signer := types.MakeSigner(config, header.Number)
+
+	// Keep the overrides out of the state diff of the first transaction
+	statedb.Finalise(deleteEmpty)
+
+	var diffs *bundleState
+	if opts.StateDiff {
+		diffs = newBundleState(statedb.Copy())
+	}
+	// Setup context so it may be cancelled the bundle has completed or timed out.
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+
+	gasCap := s.b.RPCGasCap()
+	if gasCap == 0 {
+		gasCap = math.MaxUint64 / 2
+	}
+	var (
+		gp     = new(core.GasPool).AddGas(gasCap)
+		traced int
+		result = &CallBundleResult{
+			StateBlockHash: stateBlock,
+			BlockNumber:    hexutil.Uint64(header.Number.Uint64()),
+			Results:        make([]BundleTxResult, 0, len(txs)),
+		}
+	)
+	for i, tx := range txs {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", callTimeout)
+		}
+		var (
+			msg    types.Message
+			txHash common.Hash
+			err    error
+		)
+		switch {
+		case tx.Tx != nil:
+			txHash = tx.Tx.Hash()
+			msg, err = tx.Tx.AsMessage(signer, header.BaseFee)
+		case tx.Call != nil:
+			msg, err = tx.Call.ToMessage(gp.Gas(), header.BaseFee)
+		default:
+			err = errors.New("missing transaction")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		// Calls may leave the fee fields empty like in eth_call, but signed
+		// transactions have to pay the base fee of the block as on chain.
+		vmConfig := vm.Config{NoBaseFee: tx.Call != nil}
+
+		// Trace with whatever is left of the struct log limit of the bundle
+		var (
+			tracer *vm.StructLogger
+			limit  int
+		)
+		if opts.Trace != nil {
+			logConfig := *opts.Trace
+			if limit = callBundleMaxStructLogs - traced; logConfig.Limit == 0 || logConfig.Limit > limit {
+				logConfig.Limit = limit
+			}
+			tracer = vm.NewStructLogger(&logConfig)
+			vmConfig.Debug, vmConfig.Tracer = true, tracer
+		}
+		statedb.Prepare(txHash, common.Hash{}, i)
+		logged := len(statedb.GetLogs(txHash))
+
+		evm, vmError, err := s.b.GetEVM(ctx, msg, statedb, header, &vmConfig)
+		if err != nil {
+			return nil, err
+		}
+		// Wait for the context to be done and cancel the evm. Even if the
+		// EVM has finished, cancelling may be done (repeatedly)
+		gopool.Submit(func() {
+			<-ctx.Done()
+			evm.Cancel()
+		})
+		res, err := core.ApplyMessage(evm, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		// If the timer caused an abort, return an appropriate error message
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", callTimeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		var dirties map[common.Address][]common.Hash
+		if opts.StateDiff {
+			dirties = statedb.TxDirties()
+		}
+		statedb.Finalise(deleteEmpty)
+
+		txResult := BundleTxResult{
+			From:        msg.From(),
+			To:          msg.To(),
+			GasUsed:     hexutil.Uint64(res.UsedGas),
+			GasPayer:    res.GasPayer,
+			ReturnValue: res.Return(),
+			Logs:        statedb.GetLogs(txHash)[logged:],
+		}
+		if tx.Tx != nil {
+			txResult.TxHash = &txHash
+		}
+		if len(res.Revert()) > 0 {
+			txResult.Error, txResult.Revert = newRevertError(res).Error(), res.Revert()
+		} else if res.Err != nil {
+			txResult.Error = res.Err.Error()
+		}
+		if txResult.Logs == nil {
+			txResult.Logs = []*types.Log{}
+		}
+		if diffs != nil {
+			txResult.StateDiff = diffs.diff(statedb, dirties)
+			if diffs.changes > callBundleMaxStateDiffs {
+				return nil, fmt.Errorf("state diff exceeds the limit of %d changes at transaction %d", callBundleMaxStateDiffs, i)
+			}
+		}
+		if tracer != nil {
+			logs := tracer.StructLogs()
+			if len(logs) >= limit {
+				return nil, fmt.Errorf("trace reaches the limit of %d struct logs at transaction %d", callBundleMaxStructLogs, i)
+			}
+			traced += len(logs)
+			txResult.StructLogs = FormatLogs(logs)
+		}
+		result.GasUsed += hexutil.Uint64(res.UsedGas)
+		result.Results = append(result.Results, txResult)
+	}
+	return result, nil
+}
+
+// bundleState tracks the accounts and storage slots as left by the transactions
+// of a bundle so far, so that the changes of every transaction can be diffed
+// without copying the whole state for each of them.
+type bundleState struct {
+	base     *state.StateDB // State before the first transaction
+	accounts map[common.Address]*bundleAccount
+	storage  map[common.Address]map[common.Hash]common.Hash
+	changes  int // Number of changed accounts and slots reported so far
+}
+
+// bundleAccount is the state of an account as left by a bundle transaction.
+type bundleAccount struct {
+	balance *big.Int
+	nonce   uint64
+	code    []byte
+}
+
+func newBundleState(base *state.StateDB) *bundleState {
+	return &bundleState{
+		base:     base,
+		accounts: make(map[common.Address]*bundleAccount),
+		storage:  make(map[common.Address]map[common.Hash]common.Hash),
+	}
+}
+
+// account returns the account as left by the last transaction touching it.
+func (s *bundleState) account(addr common.Address) *bundleAccount {
+	if account, ok := s.accounts[addr]; ok {
+		return account
+	}
+	return &bundleAccount{
+		balance: s.base.GetBalance(addr),
+		nonce:   s.base.GetNonce(addr),
+		code:    s.base.GetCode(addr),
+	}
+}
+
+// slot returns the storage slot as left by the last transaction writing it.
+func (s *bundleState) slot(addr common.Address, slot common.Hash) common.Hash {
+	if value, ok := s.storage[addr][slot]; ok {
+		return value
+	}
+	return s.base.GetState(addr, slot)
+}
+
+// diff compares the accounts and storage slots modified by a bundle transaction
+// before and after its execution, and remembers their new values for the next
+// transactions.
+func (s *bundleState) diff(post *state.StateDB, dirties map[common.Address][]common.Hash) map[common.Address]*AccountDiff {
+	diffs := make(map[common.Address]*AccountDiff)
+	for addr, slots := range dirties {
+		var (
+			diff = new(AccountDiff)
+			pre  = s.account(addr)
+		)
+		if to := post.GetBalance(addr); pre.balance.Cmp(to) != 0 {
+			diff.Balance = &BalanceDiff{From: (*hexutil.Big)(pre.balance), To: (*hexutil.Big)(to)}
+		}
+		if to := post.GetNonce(addr); pre.nonce != to {
+			diff.Nonce = &NonceDiff{From: hexutil.Uint64(pre.nonce), To: hexutil.Uint64(to)}
+		}
+		if to := post.GetCode(addr); !bytes.Equal(pre.code, to) {
+			diff.Code = &CodeDiff{From: pre.code, To: to}
+		}
+		s.accounts[addr] = &bundleAccount{balance: post.GetBalance(addr), nonce: post.GetNonce(addr), code: post.GetCode(addr)}
+
+		for _, slot := range slots {
+			from, to := s.slot(addr, slot), post.GetState(addr, slot)
+			if from != to {
+				if diff.Storage == nil {
+					diff.Storage = make(map[common.Hash]StorageDiff)
+				}
+				diff.Storage[slot] = StorageDiff{From: from, To: to}
+			}
+			if s.storage[addr] == nil {
+				s.storage[addr] = make(map[common.Hash]common.Hash)
+			}
+			s.storage[addr][slot] = to
+		}
+		if diff.Balance != nil || diff.Nonce != nil || diff.Code != nil || len(diff.Storage) > 0 {
+			diffs[addr] = diff
+			s.changes += 1 + len(diff.Storage)
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,275 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	bundleKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	bundleAddr    = crypto.PubkeyToAddress(bundleKey.PublicKey)
+	bundleCounter = common.HexToAddress("0xc0")
+	bundleNumber  = common.HexToAddress("0xc1")
+)
+
+// bundleBackend is the subset of a Backend needed to simulate bundles on top
+// of a local chain.
+type bundleBackend struct {
+	Backend
+	chain  *core.BlockChain
+	gasCap uint64
+}
+
+func (b *bundleBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
+func (b *bundleBackend) RPCGasCap() uint64                { return b.gasCap }
+
+func (b *bundleBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header := b.chain.CurrentHeader()
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *bundleBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	context := core.NewEVMBlockContext(header, b.chain, nil)
+	return vm.NewEVM(context, core.NewEVMTxContext(msg), state, b.chain.Config(), *vmConfig), func() error { return nil }, nil
+}
+
+// newBundleAPI creates an API on a London chain with a funded account, a contract
+// incrementing and returning slot 0 on every call and a contract returning the
+// block number.
+func newBundleAPI(t *testing.T, gasCap uint64) (*PublicBlockChainAPI, *core.BlockChain) {
+	config := *params.TestChainConfig
+	config.LondonBlock = common.Big0
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = &core.Genesis{
+			Config: &config,
+			Alloc: core.GenesisAlloc{
+				bundleAddr:    {Balance: big.NewInt(params.Ether)},
+				bundleCounter: {Code: hexutil.MustDecode("0x6000546001018060005560005260206000f3"), Balance: new(big.Int)},
+				bundleNumber:  {Code: hexutil.MustDecode("0x4360005260206000f3"), Balance: new(big.Int)},
+			},
+		}
+	)
+	genesis.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	return NewPublicBlockChainAPI(&bundleBackend{chain: chain, gasCap: gasCap}), chain
+}
+
+// signBundleTx signs a transaction calling the counter contract.
+func signBundleTx(t *testing.T, chain *core.BlockChain, nonce uint64, gasPrice *big.Int) BundleTransaction {
+	tx, err := types.SignTx(types.NewTransaction(nonce, bundleCounter, new(big.Int), 100000, gasPrice, nil), types.LatestSigner(chain.Config()), bundleKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return BundleTransaction{Tx: tx}
+}
+
+// bundleCall creates a call from the zero address, whose nonce the signed
+// transactions don't depend on.
+func bundleCall(to common.Address) BundleTransaction {
+	return BundleTransaction{Call: &CallArgs{To: &to}}
+}
+
+func TestCallBundleOrdered(t *testing.T) {
+	api, chain := newBundleAPI(t, 0)
+	defer chain.Stop()
+
+	txs := []BundleTransaction{
+		bundleCall(bundleCounter),
+		signBundleTx(t, chain, 0, chain.CurrentHeader().BaseFee),
+		bundleCall(bundleCounter),
+	}
+	result, err := api.CallBundle(context.Background(), txs, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil)
+	if err != nil {
+		t.Fatalf("failed to call bundle: %v", err)
+	}
+	if len(result.Results) != len(txs) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(result.Results), len(txs))
+	}
+	var total uint64
+	for i, res := range result.Results {
+		// every transaction sees the counter left by the previous ones
+		if have := new(big.Int).SetBytes(res.ReturnValue); have.Uint64() != uint64(i+1) {
+			t.Errorf("result %d: counter mismatch: have %v, want %d", i, have, i+1)
+		}
+		if res.Error != "" {
+			t.Errorf("result %d: unexpected error: %s", i, res.Error)
+		}
+		total += uint64(res.GasUsed)
+	}
+	if result.Results[0].TxHash != nil || result.Results[1].TxHash == nil || *result.Results[1].TxHash != txs[1].Tx.Hash() {
+		t.Errorf("transaction hashes mismatch")
+	}
+	if uint64(result.GasUsed) != total {
+		t.Errorf("bundle gas mismatch: have %d, want %d", result.GasUsed, total)
+	}
+	if result.StateBlockHash != chain.CurrentHeader().Hash() {
+		t.Errorf("state block mismatch: have %x, want %x", result.StateBlockHash, chain.CurrentHeader().Hash())
+	}
+}
+
+func TestCallBundleAborted(t *testing.T) {
+	api, chain := newBundleAPI(t, 0)
+	defer chain.Stop()
+
+	baseFee := chain.CurrentHeader().BaseFee
+	tests := []struct {
+		tx   BundleTransaction
+		want error
+	}{
+		// a signed transaction with a nonce gap can't be applied
+		{signBundleTx(t, chain, 1, baseFee), core.ErrNonceTooHigh},
+		// signed transactions are not exempt from the base fee, not even unpriced ones
+		{signBundleTx(t, chain, 0, new(big.Int).Sub(baseFee, common.Big1)), core.ErrFeeCapTooLow},
+		{signBundleTx(t, chain, 0, new(big.Int)), core.ErrFeeCapTooLow},
+	}
+	for i, tt := range tests {
+		txs := []BundleTransaction{bundleCall(bundleCounter), tt.tx, bundleCall(bundleCounter)}
+		result, err := api.CallBundle(context.Background(), txs, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+		if result != nil {
+			t.Errorf("test %d: aborted bundle returned results", i)
+		}
+	}
+}
+
+func TestCallBundleBlockOverrides(t *testing.T) {
+	api, chain := newBundleAPI(t, 0)
+	defer chain.Stop()
+
+	number := big.NewInt(1000)
+	opts := &CallBundleOptions{BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(number)}}
+	result, err := api.CallBundle(context.Background(), []BundleTransaction{bundleCall(bundleNumber)}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), opts)
+	if err != nil {
+		t.Fatalf("failed to call bundle: %v", err)
+	}
+	if uint64(result.BlockNumber) != number.Uint64() {
+		t.Errorf("block number mismatch: have %d, want %d", result.BlockNumber, number)
+	}
+	if have := new(big.Int).SetBytes(result.Results[0].ReturnValue); have.Cmp(number) != 0 {
+		t.Errorf("NUMBER mismatch: have %v, want %v", have, number)
+	}
+	if result.StateBlockHash != chain.CurrentHeader().Hash() {
+		t.Errorf("state block mismatch: have %x, want %x", result.StateBlockHash, chain.CurrentHeader().Hash())
+	}
+}
+
+func TestCallBundleStateDiff(t *testing.T) {
+	api, chain := newBundleAPI(t, 0)
+	defer chain.Stop()
+
+	txs := []BundleTransaction{
+		bundleCall(bundleCounter),
+		signBundleTx(t, chain, 0, chain.CurrentHeader().BaseFee),
+	}
+	result, err := api.CallBundle(context.Background(), txs, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &CallBundleOptions{StateDiff: true})
+	if err != nil {
+		t.Fatalf("failed to call bundle: %v", err)
+	}
+	for i, res := range result.Results {
+		counter := res.StateDiff[bundleCounter]
+		if counter == nil {
+			t.Fatalf("result %d: counter missing from state diff", i)
+		}
+		want := StorageDiff{From: common.BigToHash(big.NewInt(int64(i))), To: common.BigToHash(big.NewInt(int64(i + 1)))}
+		if have := counter.Storage[common.Hash{}]; len(counter.Storage) != 1 || have != want {
+			t.Errorf("result %d: storage diff mismatch: have %v, want %v", i, counter.Storage, want)
+		}
+		if counter.Balance != nil || counter.Nonce != nil || counter.Code != nil {
+			t.Errorf("result %d: unchanged counter fields reported: %+v", i, counter)
+		}
+	}
+	// the call pays no gas, the signed transaction bumps the nonce and pays
+	if diff := result.Results[0].StateDiff[common.Address{}]; diff == nil || diff.Balance != nil {
+		t.Errorf("call sender diff mismatch: %+v", diff)
+	}
+	sender := result.Results[1].StateDiff[bundleAddr]
+	if sender == nil || sender.Nonce == nil || sender.Balance == nil {
+		t.Fatalf("sender changes missing from state diff: %+v", sender)
+	}
+	if sender.Nonce.From != 0 || sender.Nonce.To != 1 {
+		t.Errorf("nonce diff mismatch: have %d -> %d, want 0 -> 1", sender.Nonce.From, sender.Nonce.To)
+	}
+	if sender.Balance.From.ToInt().Cmp(sender.Balance.To.ToInt()) <= 0 {
+		t.Errorf("sender not charged: have %v -> %v", sender.Balance.From, sender.Balance.To)
+	}
+}
+
+func TestCallBundleGasCap(t *testing.T) {
+	// the counter call takes about 43k gas, leaving too little for a second one
+	api, chain := newBundleAPI(t, 60000)
+	defer chain.Stop()
+
+	result, err := api.CallBundle(context.Background(), []BundleTransaction{bundleCall(bundleCounter)}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil)
+	if err != nil {
+		t.Fatalf("failed to call bundle within the gas cap: %v", err)
+	}
+	if used := uint64(result.GasUsed); used <= 60000-params.TxGas || used > 60000 {
+		t.Fatalf("unexpected gas usage %d for the gas cap test", used)
+	}
+	txs := []BundleTransaction{bundleCall(bundleCounter), bundleCall(bundleCounter)}
+	if _, err := api.CallBundle(context.Background(), txs, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil); !errors.Is(err, core.ErrIntrinsicGas) {
+		t.Errorf("error mismatch: have %v, want %v", err, core.ErrIntrinsicGas)
+	}
+}
+
+func TestCallBundleLimits(t *testing.T) {
+	api, chain := newBundleAPI(t, 0)
+	defer chain.Stop()
+
+	txs := make([]BundleTransaction, callBundleMaxTxs+1)
+	for i := range txs {
+		txs[i] = bundleCall(bundleCounter)
+	}
+	if _, err := api.CallBundle(context.Background(), txs, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil); err == nil {
+		t.Errorf("oversized bundle accepted")
+	}
+	// a bundle of the maximum size still diffs every transaction against the previous one
+	result, err := api.CallBundle(context.Background(), txs[1:], rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &CallBundleOptions{StateDiff: true})
+	if err != nil {
+		t.Fatalf("failed to call bundle: %v", err)
+	}
+	for i, res := range result.Results {
+		want := StorageDiff{From: common.BigToHash(big.NewInt(int64(i))), To: common.BigToHash(big.NewInt(int64(i + 1)))}
+		if have := res.StateDiff[bundleCounter].Storage[common.Hash{}]; have != want {
+			t.Fatalf("result %d: storage diff mismatch: have %v, want %v", i, have, want)
+		}
+	}
+}
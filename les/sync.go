@@ -181,8 +181,8 @@ func (h *clientHandler) synchronise(peer *serverPeer) {
 		// For the ethash consensus engine, the start header is the block header
 		// of the checkpoint.
 		//
-		// For the clique consensus engine, the start header is the block header
-		// of the latest epoch covered by checkpoint.
+		// For the clique and parlia consensus engines, the start header is the
+		// block header of the latest epoch covered by checkpoint.
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel()
 		if !checkpoint.Empty() && !h.backend.blockchain.SyncCheckpoint(ctx, checkpoint) {
@@ -58,6 +58,7 @@ type LightChain struct {
 	chainFeed     event.Feed
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
+	finalizedFeed event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -65,6 +66,8 @@ type LightChain struct {
 	bodyRLPCache *lru.Cache // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache // Cache for the most recent entire blocks
 
+	lastFinalizedHeader atomic.Value // Latest finalized header announced
+
 	chainmu sync.RWMutex // protects header inserts
 	quit    chan struct{}
 	wg      sync.WaitGroup
@@ -366,6 +369,7 @@ func (lc *LightChain) postChainEvents(events []interface{}) {
 		case core.ChainEvent:
 			if lc.CurrentHeader().Hash() == ev.Hash {
 				lc.chainHeadFeed.Send(core.ChainHeadEvent{Block: ev.Block})
+				lc.announceFinalized()
 			}
 			lc.chainFeed.Send(ev)
 		case core.ChainSideEvent:
@@ -429,6 +433,50 @@ func (lc *LightChain) CurrentHeader() *types.Header {
 	return lc.hc.CurrentHeader()
 }
 
+// CurrentFinalizedHeader retrieves the latest block finalized as of the head of
+// the canonical chain, either by the votes or by the seals of the validators on
+// top of it, nil if the consensus engine doesn't finalize blocks or none is
+// finalized yet.
+func (lc *LightChain) CurrentFinalizedHeader() *types.Header {
+	if engine, ok := lc.engine.(consensus.FastFinality); ok {
+		head := lc.CurrentHeader()
+		return laterHeader(engine.GetFinalizedHeader(lc, head), engine.GetSealFinalizedHeader(lc, head))
+	}
+	return nil
+}
+
+// CurrentJustifiedHeader retrieves the latest block justified as of the head of
+// the canonical chain, or the finalized one if it is more recent, nil if the
+// consensus engine doesn't justify blocks or none is justified yet.
+func (lc *LightChain) CurrentJustifiedHeader() *types.Header {
+	if engine, ok := lc.engine.(consensus.FastFinality); ok {
+		return laterHeader(engine.GetJustifiedHeader(lc, lc.CurrentHeader()), lc.CurrentFinalizedHeader())
+	}
+	return nil
+}
+
+// laterHeader returns the higher of two headers, either of which may be nil.
+func laterHeader(a, b *types.Header) *types.Header {
+	if a == nil || (b != nil && b.Number.Cmp(a.Number) > 0) {
+		return b
+	}
+	return a
+}
+
+// announceFinalized fires a ChainFinalizedEvent if the latest finalized block
+// changed since the previous announcement.
+func (lc *LightChain) announceFinalized() {
+	header := lc.CurrentFinalizedHeader()
+	if header == nil {
+		return
+	}
+	if last, _ := lc.lastFinalizedHeader.Load().(*types.Header); last != nil && last.Hash() == header.Hash() {
+		return
+	}
+	lc.lastFinalizedHeader.Store(header)
+	lc.finalizedFeed.Send(core.ChainFinalizedEvent{Header: header})
+}
+
 // GetTd retrieves a block's total difficulty in the canonical chain from the
 // database by hash and number, caching it if found.
 func (lc *LightChain) GetTd(hash common.Hash, number uint64) *big.Int {
@@ -512,7 +560,10 @@ func (lc *LightChain) Config() *params.ChainConfig { return lc.hc.Config() }
 // the checkpoint provided by the remote peer.
 //
 // Note if we are running the clique, fetches the last epoch snapshot header
-// which covered by checkpoint.
+// which covered by checkpoint. If we are running parlia, whose epoch length
+// may be changed by governance, the engine walks back to the last epoch header
+// and fetches the epoch header preceding that one too, as its validators are
+// still in charge during the first half of the epoch.
 func (lc *LightChain) SyncCheckpoint(ctx context.Context, checkpoint *params.TrustedCheckpoint) bool {
 	// Ensure the remote checkpoint head is ahead of us
 	head := lc.CurrentHeader().Number.Uint64()
@@ -524,6 +575,23 @@ func (lc *LightChain) SyncCheckpoint(ctx context.Context, checkpoint *params.Tru
 	if head >= latest {
 		return true
 	}
+	if lc.hc.Config().Parlia != nil {
+		posa, ok := lc.engine.(consensus.PoSA)
+		if !ok {
+			return false
+		}
+		header, err := posa.EpochCheckpoint(latest, func(number uint64) (*types.Header, error) {
+			return GetHeaderByNumber(ctx, lc.odr, number)
+		})
+		if err != nil {
+			log.Debug("Failed to retrieve epoch checkpoint", "number", latest, "err", err)
+			return false
+		}
+		latest = header.Number.Uint64() // epoch snapshot for parlia
+		if head >= latest {
+			return true
+		}
+	}
 	// Retrieve the latest useful header and update to it
 	if header, err := GetHeaderByNumber(ctx, lc.odr, latest); header != nil && err == nil {
 		lc.chainmu.Lock()
@@ -561,6 +629,11 @@ func (lc *LightChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) eve
 	return lc.scope.Track(lc.chainHeadFeed.Subscribe(ch))
 }
 
+// SubscribeChainFinalizedEvent registers a subscription of ChainFinalizedEvent.
+func (lc *LightChain) SubscribeChainFinalizedEvent(ch chan<- core.ChainFinalizedEvent) event.Subscription {
+	return lc.scope.Track(lc.finalizedFeed.Subscribe(ch))
+}
+
 // SubscribeChainSideEvent registers a subscription of ChainSideEvent.
 func (lc *LightChain) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
 	return lc.scope.Track(lc.chainSideFeed.Subscribe(ch))
@@ -52,6 +52,9 @@ type Config struct {
 	GasPrice      *big.Int       // Minimum gas price for mining a transaction
 	Recommit      time.Duration  // The time interval for miner to re-create mining work.
 	Noverify      bool           // Disable remote mining solution verification(only useful in ethash).
+
+	GasFreeLaneShare         uint64 // Percentage of the block gas reserved for gas-free senders (0 = no reservation)
+	GasFreeContractLaneShare uint64 // Percentage of the block gas reserved for calls to gas-free contracts (0 = no reservation)
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -181,6 +184,12 @@ func (miner *Miner) SetRecommitInterval(interval time.Duration) {
 	miner.worker.setRecommitInterval(interval)
 }
 
+// SetOrderingPolicy replaces the policy deciding the order in which the pending
+// transactions are packed into the blocks.
+func (miner *Miner) SetOrderingPolicy(policy OrderingPolicy) {
+	miner.worker.setOrderingPolicy(policy)
+}
+
 // Pending returns the currently pending block and associated state.
 func (miner *Miner) Pending() (*types.Block, *state.StateDB) {
 	if miner.worker.isRunning() {
@@ -0,0 +1,178 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/systemcontract"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Names of the lanes packed by the LanePolicy.
+const (
+	GasFreeLane         = "gasfree"         // Transactions of the senders on the gas-free list
+	GasFreeContractLane = "gasfreecontract" // Transactions calling gas-free contracts
+	LocalLane           = "local"           // Transactions of local accounts
+	RemoteLane          = "remote"          // All other transactions
+)
+
+// Lane is a group of pending transactions packed into a block together, ordered
+// by price and nonce.
+type Lane struct {
+	Name string                                // Name of the lane, used for metrics
+	Txs  map[common.Address]types.Transactions // Transactions of the lane, grouped by sender and sorted by nonce
+	Gas  uint64                                // Maximum gas the lane may use, zero if capped only by the block
+}
+
+// OrderingPolicy decides the order in which the pending transactions are packed
+// into a block by splitting them into lanes, which the worker commits one after
+// the other. Transactions may appear in several lanes, the ones already included
+// by a previous lane are skipped, so later lanes can pick up the leftovers of the
+// earlier ones.
+//
+// Gas reserved for the system transactions of the consensus engine is never made
+// available to the lanes.
+type OrderingPolicy interface {
+	// Lanes splits the pending transactions to be packed into the given header.
+	Lanes(header *types.Header, pending map[common.Address]types.Transactions) []*Lane
+}
+
+// chainConfigBackend is implemented by the backends giving access to the
+// parameters of the ChainConfig system contract.
+type chainConfigBackend interface {
+	ChainConfigReader() *systemcontract.ChainConfigReader
+}
+
+// LanePolicy is the default ordering policy. It reserves a share of the block gas
+// for the senders on the gas-free list and another for calls to the gas-free
+// contracts, which would otherwise be packed last due to their zero gas price
+// and starve under load. The reserved lanes are packed first, followed by the
+// transactions of the local and then the remote accounts. Gas-free transactions
+// exceeding their reservation compete for the rest of the block.
+//
+// The shares are read from the ChainConfig contract, falling back to the miner
+// configuration if the contract doesn't set them.
+type LanePolicy struct {
+	pool   *core.TxPool
+	reader *systemcontract.ChainConfigReader // Optional reader of the ChainConfig contract
+
+	gasFreeShare         uint64 // Default percentage of the block gas reserved for gas-free senders
+	gasFreeContractShare uint64 // Default percentage of the block gas reserved for gas-free contracts
+}
+
+// NewLanePolicy creates a lane ordering policy for the pending transactions of
+// the given pool. The ChainConfig contract reader is optional.
+func NewLanePolicy(config *Config, pool *core.TxPool, reader *systemcontract.ChainConfigReader) *LanePolicy {
+	return &LanePolicy{
+		pool:                 pool,
+		reader:               reader,
+		gasFreeShare:         config.GasFreeLaneShare,
+		gasFreeContractShare: config.GasFreeContractLaneShare,
+	}
+}
+
+// Lanes implements OrderingPolicy, splitting the pending transactions into the
+// gas-free, gas-free contract, local and remote lanes.
+func (p *LanePolicy) Lanes(header *types.Header, pending map[common.Address]types.Transactions) []*Lane {
+	gasFreeShare, contractShare := p.shares(header)
+	senders, contracts := p.pool.GasFreeAccounts()
+
+	var (
+		gasFree  = &Lane{Name: GasFreeLane, Txs: make(map[common.Address]types.Transactions), Gas: header.GasLimit * gasFreeShare / 100}
+		contract = &Lane{Name: GasFreeContractLane, Txs: make(map[common.Address]types.Transactions), Gas: header.GasLimit * contractShare / 100}
+		local    = &Lane{Name: LocalLane, Txs: make(map[common.Address]types.Transactions)}
+		remote   = &Lane{Name: RemoteLane, Txs: make(map[common.Address]types.Transactions)}
+	)
+	for from, txs := range pending {
+		remote.Txs[from] = txs
+		if senders[from] {
+			gasFree.Txs[from] = txs
+			continue
+		}
+		// Calls to gas-free contracts can only be packed up to the first
+		// transaction of the sender doing something else
+		var calls int
+		for calls < len(txs) && txs[calls].To() != nil && contracts[*txs[calls].To()] {
+			calls++
+		}
+		if calls > 0 {
+			contract.Txs[from] = txs[:calls]
+		}
+	}
+	for _, account := range p.pool.Locals() {
+		if txs := remote.Txs[account]; len(txs) > 0 {
+			delete(remote.Txs, account)
+			local.Txs[account] = txs
+		}
+	}
+	// Lanes without a reservation would not be capped, leave them to the others
+	var lanes []*Lane
+	if gasFree.Gas > 0 {
+		lanes = append(lanes, gasFree)
+	}
+	if contract.Gas > 0 {
+		lanes = append(lanes, contract)
+	}
+	return append(lanes, local, remote)
+}
+
+// shares returns the percentages of the block gas reserved for the gas-free
+// senders and contracts when packing the given header.
+func (p *LanePolicy) shares(header *types.Header) (uint64, uint64) {
+	gasFreeShare, contractShare := p.gasFreeShare, p.gasFreeContractShare
+	if p.reader != nil {
+		cfg, err := p.reader.ParamsByHash(header.ParentHash)
+		if err != nil {
+			log.Debug("Failed to read lane shares from chain config", "number", header.Number, "err", err)
+		} else {
+			if cfg.GasFreeLaneShare != 0 {
+				gasFreeShare = uint64(cfg.GasFreeLaneShare)
+			}
+			if cfg.GasFreeContractLaneShare != 0 {
+				contractShare = uint64(cfg.GasFreeContractLaneShare)
+			}
+		}
+	}
+	// The reservations can't exceed the block, the gas-free senders come first
+	if gasFreeShare > 100 {
+		gasFreeShare = 100
+	}
+	if gasFreeShare+contractShare > 100 {
+		contractShare = 100 - gasFreeShare
+	}
+	return gasFreeShare, contractShare
+}
+
+// updateLaneMeters records the gas and the transactions a lane packed into a
+// block, along with the percentage of its reservation it used.
+func updateLaneMeters(lane *Lane, gas uint64, txs int) {
+	if !metrics.Enabled {
+		return
+	}
+	prefix := "miner/lane/" + lane.Name + "/"
+	metrics.GetOrRegisterMeter(prefix+"gas", nil).Mark(int64(gas))
+	metrics.GetOrRegisterMeter(prefix+"txs", nil).Mark(int64(txs))
+	if lane.Gas > 0 {
+		sampler := func() metrics.Sample {
+			return metrics.NewExpDecaySample(1028, 0.015)
+		}
+		metrics.GetOrRegisterHistogramLazy(prefix+"utilisation", nil, sampler).Update(int64(gas * 100 / lane.Gas))
+	}
+}
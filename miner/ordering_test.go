@@ -0,0 +1,196 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	laneGasFreeKey, _  = crypto.GenerateKey()
+	laneCallerKey, _   = crypto.GenerateKey()
+	laneContract       = common.HexToAddress("0xfeee")
+	laneOrdinaryKeys   = make([]*ecdsa.PrivateKey, 3)
+	laneGasFreeAddress = crypto.PubkeyToAddress(laneGasFreeKey.PublicKey)
+)
+
+func init() {
+	for i := range laneOrdinaryKeys {
+		laneOrdinaryKeys[i], _ = crypto.GenerateKey()
+	}
+}
+
+// laneTester is a worker packing blocks on top of a chain without EIP-1559, so
+// that gas-free transactions are valid, with a single gas-free sender and
+// contract.
+type laneTester struct {
+	worker *worker
+	signer types.Signer
+}
+
+func newLaneTester(t *testing.T, config *Config) *laneTester {
+	chainConfig := *params.TestChainConfig
+	chainConfig.LondonBlock = nil
+
+	gspec := core.Genesis{Config: &chainConfig, Alloc: make(core.GenesisAlloc)}
+	for _, key := range laneOrdinaryKeys {
+		gspec.Alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: testBankFunds}
+	}
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+
+	engine := ethash.NewFaker()
+	chain, _ := core.NewBlockChain(db, nil, &chainConfig, engine, vm.Config{}, nil, nil)
+	t.Cleanup(chain.Stop)
+
+	poolConfig := testTxPoolConfig
+	poolConfig.GasFreeContracts = map[common.Address]bool{laneContract: true}
+	gasFreeAddresses := func(common.Hash) (map[common.Address]uint, error) {
+		return map[common.Address]uint{laneGasFreeAddress: 1}, nil
+	}
+	gasPrice := func(common.Hash) (*big.Int, error) { return new(big.Int), nil }
+	pool := core.NewEnhanceTxPool(poolConfig, &chainConfig, chain, gasFreeAddresses, gasPrice)
+	t.Cleanup(pool.Stop)
+
+	w := newWorker(config, &chainConfig, engine, &testWorkerBackend{chain: chain, txPool: pool}, new(event.TypeMux), nil, false)
+	t.Cleanup(w.close)
+
+	return &laneTester{worker: w, signer: types.LatestSigner(&chainConfig)}
+}
+
+// txs signs count transfers of the given key at the given gas price.
+func (lt *laneTester) txs(key *ecdsa.PrivateKey, to common.Address, price int64, count int) types.Transactions {
+	var txs types.Transactions
+	for nonce := 0; nonce < count; nonce++ {
+		txs = append(txs, types.MustSignNewTx(key, lt.signer, &types.LegacyTx{
+			Nonce:    uint64(nonce),
+			To:       &to,
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(price),
+		}))
+	}
+	return txs
+}
+
+// pack fills a block with room for the given number of transfers from the given
+// pending transactions, returning the number of transfers included per lane.
+func (lt *laneTester) pack(t *testing.T, slots uint64, pending map[common.Address]types.Transactions) (gasFree, contract, ordinary int, first common.Address) {
+	w := lt.worker
+	parent := w.chain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   params.SystemTxsGas + slots*params.TxGas,
+		Time:       parent.Time() + 1,
+		Difficulty: big.NewInt(1),
+	}
+	if err := w.makeCurrent(parent, header); err != nil {
+		t.Fatalf("failed to create mining context: %v", err)
+	}
+	if w.commitLanes(w.ordering.Lanes(header, pending), common.Address{}, nil) {
+		t.Fatalf("packing interrupted")
+	}
+	for i, tx := range w.current.txs {
+		from, _ := types.Sender(lt.signer, tx)
+		if i == 0 {
+			first = from
+		}
+		switch {
+		case from == laneGasFreeAddress:
+			gasFree++
+		case *tx.To() == laneContract:
+			contract++
+		default:
+			ordinary++
+		}
+	}
+	return gasFree, contract, ordinary, first
+}
+
+// Tests that the gas-free transactions get their reserved share of the block
+// even if it's flooded by paying transactions, and that they may use more of it
+// if there's room left.
+func TestLaneFairness(t *testing.T) {
+	const slots = 100
+
+	tests := []struct {
+		gasFreeShare, contractShare uint64
+		ordinaryTxs                 int
+		gasFree, contract, ordinary int // Minimum gas-free and exact ordinary transfers packed
+	}{
+		// Without reservations the gas-free transactions starve
+		{0, 0, 40, 0, 0, 100},
+		// With reservations they get their share, rounded down to full transfers
+		{10, 10, 40, 12, 12, 76},
+		{30, 0, 40, 37, 0, 63},
+		// Unused reservations are left to the others, exceeding them is allowed
+		// as long as the block has room for it
+		{10, 10, 0, 50, 50, 0},
+		{10, 10, 10, 12, 12, 30},
+	}
+	for i, tt := range tests {
+		lt := newLaneTester(t, &Config{GasFreeLaneShare: tt.gasFreeShare, GasFreeContractLaneShare: tt.contractShare})
+
+		pending := map[common.Address]types.Transactions{
+			laneGasFreeAddress: lt.txs(laneGasFreeKey, common.Address{0x01}, 0, 50),
+			crypto.PubkeyToAddress(laneCallerKey.PublicKey): lt.txs(laneCallerKey, laneContract, 0, 50),
+		}
+		for j, key := range laneOrdinaryKeys {
+			pending[crypto.PubkeyToAddress(key.PublicKey)] = lt.txs(key, common.Address{0x02}, int64(j+1), tt.ordinaryTxs)
+		}
+		gasFree, contract, ordinary, first := lt.pack(t, slots, pending)
+		if gasFree < tt.gasFree || contract < tt.contract || ordinary != tt.ordinary {
+			t.Errorf("test %d: packed transactions mismatch: have %d/%d/%d, want %d/%d/%d", i, gasFree, contract, ordinary, tt.gasFree, tt.contract, tt.ordinary)
+		}
+		if total := gasFree + contract + ordinary; total != slots {
+			t.Errorf("test %d: block not filled: have %d transfers, want %d", i, total, slots)
+		}
+		if tt.gasFree > 0 && first != laneGasFreeAddress {
+			t.Errorf("test %d: gas-free lane not packed first", i)
+		}
+	}
+}
+
+// Tests that the reserved shares never exceed the block.
+func TestLaneShares(t *testing.T) {
+	tests := []struct {
+		gasFreeShare, contractShare uint64
+		gasFree, contract           uint64
+	}{
+		{10, 20, 10, 20},
+		{60, 60, 60, 40},
+		{120, 10, 100, 0},
+	}
+	for i, tt := range tests {
+		policy := NewLanePolicy(&Config{GasFreeLaneShare: tt.gasFreeShare, GasFreeContractLaneShare: tt.contractShare}, nil, nil)
+		if gasFree, contract := policy.shares(&types.Header{Number: common.Big1}); gasFree != tt.gasFree || contract != tt.contract {
+			t.Errorf("test %d: shares mismatch: have %d/%d, want %d/%d", i, gasFree, contract, tt.gasFree, tt.contract)
+		}
+	}
+}
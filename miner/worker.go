@@ -26,9 +26,11 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/consensus/parlia"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/systemcontract"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
@@ -159,9 +161,10 @@ type worker struct {
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
+	mu       sync.RWMutex // The lock used to protect the coinbase, extra and ordering fields
 	coinbase common.Address
 	extra    []byte
+	ordering OrderingPolicy // Policy deciding the order the pending transactions are packed in
 
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
@@ -215,6 +218,13 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		resubmitIntervalCh: make(chan time.Duration),
 		resubmitAdjustCh:   make(chan *intervalAdjust, resubmitAdjustChanSize),
 	}
+	// Reserve block space for the gas-free transactions by default
+	var reader *systemcontract.ChainConfigReader
+	if backend, ok := eth.(chainConfigBackend); ok {
+		reader = backend.ChainConfigReader()
+	}
+	worker.ordering = NewLanePolicy(config, eth.TxPool(), reader)
+
 	// Subscribe NewTxsEvent for tx pool
 	worker.txsSub = eth.TxPool().SubscribeNewTxsEvent(worker.txsCh)
 	// Subscribe events for blockchain
@@ -254,6 +264,14 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+// setOrderingPolicy sets the policy deciding the order the pending transactions
+// are packed in.
+func (w *worker) setOrderingPolicy(policy OrderingPolicy) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ordering = policy
+}
+
 // setRecommitInterval updates the interval for miner sealing work recommitting.
 func (w *worker) setRecommitInterval(interval time.Duration) {
 	w.resubmitIntervalCh <- interval
@@ -506,9 +524,9 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
 				tcount := w.current.tcount
-				w.commitTransactions(txset, coinbase, nil)
+				w.commitTransactions(txset, coinbase, 0, nil)
 				// Only update the snapshot if any new transactons were added
 				// to the pending block
 				if tcount != w.current.tcount {
@@ -748,7 +766,10 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+// commitTransactions applies the given transactions to the current block until
+// it's full or no transactions are left, using at most gasLimit gas if non-zero.
+// It returns true if the work was interrupted by a new head.
+func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, gasLimit uint64, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -774,6 +795,7 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		processorCapacity = txs.CurrentSize()
 	}
 	bloomProcessors := core.NewAsyncReceiptBloomGenerator(processorCapacity)
+	gasUsed := w.current.header.GasUsed
 
 LOOP:
 	for {
@@ -815,6 +837,16 @@ LOOP:
 		if tx == nil {
 			break
 		}
+		// Skip the accounts whose next transaction doesn't fit the gas left to use
+		if gasLimit != 0 {
+			if used := w.current.header.GasUsed - gasUsed; tx.Gas() > gasLimit-used {
+				if gasLimit-used < params.TxGas {
+					break
+				}
+				txs.Pop()
+				continue
+			}
+		}
 		// Error may be ignored here. The error has already been checked
 		// during transaction acceptance is the transaction pool.
 		//
@@ -890,6 +922,35 @@ LOOP:
 	return false
 }
 
+// commitLanes packs the lanes of pending transactions into the current block one
+// after the other, skipping the transactions already included by the previous
+// lanes. It returns true if the work was interrupted by a new head.
+func (w *worker) commitLanes(lanes []*Lane, coinbase common.Address, interrupt *int32) bool {
+	for _, lane := range lanes {
+		if len(lane.Txs) == 0 {
+			continue
+		}
+		// Drop the transactions already included, keeping the nonce order
+		txs := make(map[common.Address]types.Transactions)
+		for from, list := range lane.Txs {
+			nonce := w.current.state.GetNonce(from)
+			for len(list) > 0 && list[0].Nonce() < nonce {
+				list = list[1:]
+			}
+			if len(list) > 0 {
+				txs[from] = list
+			}
+		}
+		gasUsed, tcount := w.current.header.GasUsed, w.current.tcount
+		set := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
+		if w.commitTransactions(set, coinbase, lane.Gas, interrupt) {
+			return true
+		}
+		updateLaneMeters(lane, w.current.header.GasUsed-gasUsed, w.current.tcount-tcount)
+	}
+	return false
+}
+
 // commitNewWork generates several new sealing tasks based on the parent block.
 func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64) {
 	w.mu.RLock()
@@ -909,6 +970,10 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		Extra:      w.extra,
 		Time:       uint64(timestamp),
 	}
+	// Set baseFee if we are on an EIP-1559 chain
+	if w.chainConfig.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(w.chainConfig, parent.Header())
+	}
 	// Only set the coinbase if our consensus engine is running (avoid spurious block rewards)
 	if w.isRunning() {
 		if w.coinbase == (common.Address{}) {
@@ -943,25 +1008,8 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	// Short circuit if there is no available pending transactions
 	if len(pending) != 0 {
 		start := time.Now()
-		// Split the pending transactions into locals and remotes
-		localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-		for _, account := range w.eth.TxPool().Locals() {
-			if txs := remoteTxs[account]; len(txs) > 0 {
-				delete(remoteTxs, account)
-				localTxs[account] = txs
-			}
-		}
-		if len(localTxs) > 0 {
-			txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
-			if w.commitTransactions(txs, w.coinbase, interrupt) {
-				return
-			}
-		}
-		if len(remoteTxs) > 0 {
-			txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
-			if w.commitTransactions(txs, w.coinbase, interrupt) {
-				return
-			}
+		if w.commitLanes(w.ordering.Lanes(header, pending), w.coinbase, interrupt) {
+			return
 		}
 		commitTxsTimer.UpdateSince(start)
 		log.Info("Gas pool", "height", header.Number.String(), "pool", w.current.gasPool.String())
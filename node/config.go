@@ -104,6 +104,11 @@ type Config struct {
 	// RangeLimit enable 5000 blocks limit when handle range query
 	RangeLimit bool `toml:",omitempty"`
 
+	// DBEngine is the key-value storage engine backing the databases, either
+	// leveldb or pebble. An existing database is always reopened with the
+	// engine it was created with.
+	DBEngine string `toml:",omitempty"`
+
 	// USB enables hardware wallet monitoring and connectivity.
 	USB bool `toml:",omitempty"`
 
@@ -20,6 +20,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"golang.org/x/crypto/sha3"
 
@@ -45,6 +46,7 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		big.NewInt(0),
+		nil,
 		big.NewInt(0),
 		nil, nil, nil, nil,
 		big.NewInt(0),
@@ -56,6 +58,7 @@ var (
 		nil,
 		nil,
 		nil,
+		nil,
 	}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
@@ -76,6 +79,7 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		big.NewInt(0),
+		nil,
 		big.NewInt(0),
 		nil, nil, nil, nil,
 		big.NewInt(0),
@@ -85,6 +89,7 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		big.NewInt(0),
+		nil,
 		&CliqueConfig{Period: 0, Epoch: 30000},
 		nil,
 	}
@@ -102,6 +107,7 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		big.NewInt(0),
+		nil,
 		big.NewInt(0),
 		nil, nil, nil, nil,
 		big.NewInt(0),
@@ -111,6 +117,7 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		nil,
+		nil,
 		nil, nil,
 	}
 )
@@ -185,6 +192,7 @@ type ChainConfig struct {
 	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already on istanbul)
 	MuirGlacierBlock    *big.Int `json:"muirGlacierBlock,omitempty"`    // Eip-2384 (bomb delay) switch block (nil = no fork, 0 = already activated)
 	BerlinBlock         *big.Int `json:"berlinBlock,omitempty"`         // Berlin switch block (nil = no fork, 0 = already on berlin)
+	LondonBlock         *big.Int `json:"londonBlock,omitempty"`         // London switch block (nil = no fork, 0 = already on london)
 
 	RuntimeUpgradeBlock *big.Int `json:"runtimeUpgradeBlock,omitempty"`
 	DeployerProxyBlock  *big.Int `json:"deployerProxyBlock,omitempty"`
@@ -200,6 +208,7 @@ type ChainConfig struct {
 	BlockRewardsBlock *big.Int `json:"blockRewardsBlock,omitempty" toml:",omitempty"`
 	Contract48kBlock  *big.Int `json:"contract48kBlock,omitempty" toml:",omitempty"` // contract48kBlock switch block (nil = no fork, 0 = already activated)
 	Fncy2Block        *big.Int `json:"fncy2Block,omitempty" toml:",omitempty"`       // fncy2Block switch block (nil = no fork, 0 = already activated)
+	GasSponsorBlock   *big.Int `json:"gasSponsorBlock,omitempty" toml:",omitempty"`  // gasSponsorBlock switch block (nil = no fork, 0 = already activated)
 	// Various consensus engines
 	Clique *CliqueConfig `json:"clique,omitempty" toml:",omitempty"`
 	Parlia *ParliaConfig `json:"parlia,omitempty" toml:",omitempty"`
@@ -218,10 +227,30 @@ func (c *CliqueConfig) String() string {
 
 // ParliaConfig is the consensus engine configs for proof-of-staked-authority based sealing.
 type ParliaConfig struct {
-	Period        uint64   `json:"period"`        // Number of seconds between blocks to enforce
-	Epoch         uint64   `json:"epoch"`         // Epoch length to update validatorSet
-	BlockRewards  *big.Int `json:"blockRewards"`  // Block rewards to be paid for each produced block
-	StopMintBlock *big.Int `json:"stopMintBlock"` // Do not continue minting after configuring the block
+	Period            uint64   `json:"period"`                      // Number of seconds between blocks to enforce
+	Epoch             uint64   `json:"epoch"`                       // Epoch length to update validatorSet
+	BlockRewards      *big.Int `json:"blockRewards"`                // Block rewards to be paid for each produced block
+	StopMintBlock     *big.Int `json:"stopMintBlock"`               // Do not continue minting after configuring the block
+	BaseFeePolicy     string   `json:"baseFeePolicy,omitempty"`     // What happens to the EIP-1559 base fee (empty = burn)
+	ParamsBlock       *big.Int `json:"paramsBlock,omitempty"`       // Block from which the period, epoch and validator limit are governed by the ChainConfig contract (nil = never)
+	FastFinalityBlock *big.Int `json:"fastFinalityBlock,omitempty"` // Block from which validator votes are aggregated into the headers to finalize blocks (nil = never)
+
+	EvidenceBlock    *big.Int        `json:"evidenceBlock,omitempty"`    // Block from which double sign evidence is submitted via system transactions (nil = never)
+	EvidenceContract *common.Address `json:"evidenceContract,omitempty"` // System contract double sign evidence is submitted to (nil = slash contract)
+	EvidenceMethod   string          `json:"evidenceMethod,omitempty"`   // Method taking the two rlp encoded conflicting headers, e.g. "submitDoubleSignEvidence(bytes,bytes)"
+}
+
+// Base fee policies of Parlia chains, see ParliaConfig.BaseFeePolicy.
+const (
+	BaseFeePolicyBurn       = "burn"       // The base fee is destroyed like on Ethereum
+	BaseFeePolicyDistribute = "distribute" // The base fee is collected with the tips and distributed to the validators
+)
+
+// IsBaseFeeDistributed reports whether the EIP-1559 base fee is paid to the
+// system address, and thus routed into the validator rewards, instead of
+// being burnt.
+func (c *ChainConfig) IsBaseFeeDistributed() bool {
+	return c.Parlia != nil && c.Parlia.BaseFeePolicy == BaseFeePolicyDistribute
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -240,67 +269,60 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v, Muir Glacier: %v, Ramanujan: %v, Niels: %v, MirrorSync: %v, Bruno: %v, Berlin: %v, YOLO v3: %v, Engine: %v}",
-		c.ChainID,
-		c.HomesteadBlock,
-		c.EIP150Block,
-		c.EIP155Block,
-		c.EIP158Block,
-		c.ByzantiumBlock,
-		c.ConstantinopleBlock,
-		c.PetersburgBlock,
-		c.IstanbulBlock,
-		c.MuirGlacierBlock,
-		c.RamanujanBlock,
-		c.NielsBlock,
-		c.MirrorSyncBlock,
-		c.BrunoBlock,
-		c.BerlinBlock,
-		c.YoloV3Block,
-		engine,
-	)
+	var b strings.Builder
+	fmt.Fprintf(&b, "{ChainID: %v", c.ChainID)
+	for _, f := range forks {
+		fmt.Fprintf(&b, " %s: %v", f.name, f.block(c))
+	}
+	fmt.Fprintf(&b, " Engine: %v}", engine)
+	return b.String()
 }
 
 // IsHomestead returns whether num is either equal to the homestead block or greater.
 func (c *ChainConfig) IsHomestead(num *big.Int) bool {
-	return isForked(c.HomesteadBlock, num)
+	return homesteadFork.isActive(c, num)
 }
 
 // IsEIP150 returns whether num is either equal to the EIP150 fork block or greater.
 func (c *ChainConfig) IsEIP150(num *big.Int) bool {
-	return isForked(c.EIP150Block, num)
+	return eip150Fork.isActive(c, num)
 }
 
 // IsEIP155 returns whether num is either equal to the EIP155 fork block or greater.
 func (c *ChainConfig) IsEIP155(num *big.Int) bool {
-	return isForked(c.EIP155Block, num)
+	return eip155Fork.isActive(c, num)
 }
 
 // IsEIP158 returns whether num is either equal to the EIP158 fork block or greater.
 func (c *ChainConfig) IsEIP158(num *big.Int) bool {
-	return isForked(c.EIP158Block, num)
+	return eip158Fork.isActive(c, num)
 }
 
 func (c *ChainConfig) IsContract48kBlock(num *big.Int) bool {
-	return isForked(c.Contract48kBlock, num)
+	return contract48kFork.isActive(c, num)
 }
 func (c *ChainConfig) IsFncy2(num *big.Int) bool {
-	return isForked(c.Fncy2Block, num)
+	return fncy2Fork.isActive(c, num)
+}
+
+// IsGasSponsor returns whether num is either equal to the gas sponsorship fork block or greater.
+func (c *ChainConfig) IsGasSponsor(num *big.Int) bool {
+	return gasSponsorFork.isActive(c, num)
 }
 
 // IsByzantium returns whether num is either equal to the Byzantium fork block or greater.
 func (c *ChainConfig) IsByzantium(num *big.Int) bool {
-	return isForked(c.ByzantiumBlock, num)
+	return byzantiumFork.isActive(c, num)
 }
 
 // IsConstantinople returns whether num is either equal to the Constantinople fork block or greater.
 func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
-	return isForked(c.ConstantinopleBlock, num)
+	return constantinopleFork.isActive(c, num)
 }
 
 // IsRamanujan returns whether num is either equal to the IsRamanujan fork block or greater.
 func (c *ChainConfig) IsRamanujan(num *big.Int) bool {
-	return isForked(c.RamanujanBlock, num)
+	return ramanujanFork.isActive(c, num)
 }
 
 // IsOnRamanujan returns whether num is equal to the Ramanujan fork block
@@ -310,7 +332,7 @@ func (c *ChainConfig) IsOnRamanujan(num *big.Int) bool {
 
 // IsNiels returns whether num is either equal to the Niels fork block or greater.
 func (c *ChainConfig) IsNiels(num *big.Int) bool {
-	return isForked(c.NielsBlock, num)
+	return nielsFork.isActive(c, num)
 }
 
 // IsOnNiels returns whether num is equal to the IsNiels fork block
@@ -320,7 +342,7 @@ func (c *ChainConfig) IsOnNiels(num *big.Int) bool {
 
 // IsMirrorSync returns whether num is either equal to the MirrorSync fork block or greater.
 func (c *ChainConfig) IsMirrorSync(num *big.Int) bool {
-	return isForked(c.MirrorSyncBlock, num)
+	return mirrorSyncFork.isActive(c, num)
 }
 
 // IsOnMirrorSync returns whether num is equal to the MirrorSync fork block
@@ -330,7 +352,7 @@ func (c *ChainConfig) IsOnMirrorSync(num *big.Int) bool {
 
 // IsBruno returns whether num is either equal to the Burn fork block or greater.
 func (c *ChainConfig) IsBruno(num *big.Int) bool {
-	return isForked(c.BrunoBlock, num)
+	return brunoFork.isActive(c, num)
 }
 
 // IsOnBruno returns whether num is equal to the Burn fork block
@@ -340,46 +362,71 @@ func (c *ChainConfig) IsOnBruno(num *big.Int) bool {
 
 // IsMuirGlacier returns whether num is either equal to the Muir Glacier (EIP-2384) fork block or greater.
 func (c *ChainConfig) IsMuirGlacier(num *big.Int) bool {
-	return isForked(c.MuirGlacierBlock, num)
+	return muirGlacierFork.isActive(c, num)
 }
 
 // IsPetersburg returns whether num is either
 // - equal to or greater than the PetersburgBlock fork block,
 // - OR is nil, and Constantinople is active
 func (c *ChainConfig) IsPetersburg(num *big.Int) bool {
-	return isForked(c.PetersburgBlock, num) || c.PetersburgBlock == nil && isForked(c.ConstantinopleBlock, num)
+	return petersburgFork.isActive(c, num)
 }
 
 // IsIstanbul returns whether num is either equal to the Istanbul fork block or greater.
 func (c *ChainConfig) IsIstanbul(num *big.Int) bool {
-	return isForked(c.IstanbulBlock, num)
+	return istanbulFork.isActive(c, num)
 }
 
 // IsBerlin returns whether num is either equal to the Berlin fork block or greater.
 func (c *ChainConfig) IsBerlin(num *big.Int) bool {
-	return isForked(c.BerlinBlock, num) || isForked(c.YoloV3Block, num)
+	return berlinFork.isActive(c, num)
+}
+
+// IsLondon returns whether num is either equal to the London fork block or greater.
+func (c *ChainConfig) IsLondon(num *big.Int) bool {
+	return londonFork.isActive(c, num)
 }
 
 // IsCatalyst returns whether num is either equal to the Merge fork block or greater.
 func (c *ChainConfig) IsCatalyst(num *big.Int) bool {
-	return isForked(c.CatalystBlock, num)
+	return catalystFork.isActive(c, num)
 }
 
 // IsEWASM returns whether num represents a block number after the EWASM fork
 func (c *ChainConfig) IsEWASM(num *big.Int) bool {
-	return isForked(c.EWASMBlock, num)
+	return ewasmFork.isActive(c, num)
 }
 
 func (c *ChainConfig) HasRuntimeUpgrade(num *big.Int) bool {
-	return isForked(c.RuntimeUpgradeBlock, num)
+	return runtimeUpgradeFork.isActive(c, num)
 }
 
 func (c *ChainConfig) HasDeployerProxy(num *big.Int) bool {
-	return isForked(c.DeployerProxyBlock, num)
+	return deployerProxyFork.isActive(c, num)
 }
 
 func (c *ChainConfig) IsBlockRewardsBlock(num *big.Int) bool {
-	return isForked(c.BlockRewardsBlock, num)
+	return blockRewardsFork.isActive(c, num)
+}
+
+// IsParliaParams returns whether num is either equal to the Parlia ParamsBlock or
+// greater, i.e. whether the epoch headers carry the governance controlled
+// parameters.
+func (c *ChainConfig) IsParliaParams(num *big.Int) bool {
+	return parliaParamsFork.isActive(c, num)
+}
+
+// IsFastFinality returns whether num is either equal to the Parlia
+// FastFinalityBlock or greater, i.e. whether headers may carry the votes
+// attesting their parent.
+func (c *ChainConfig) IsFastFinality(num *big.Int) bool {
+	return fastFinalityFork.isActive(c, num)
+}
+
+// IsDoubleSignEvidence returns whether num is either equal to the Parlia
+// EvidenceBlock or greater, i.e. whether blocks may submit double sign evidence.
+func (c *ChainConfig) IsDoubleSignEvidence(num *big.Int) bool {
+	return evidenceFork.isActive(c, num)
 }
 
 // CheckCompatible checks whether scheduled fork transitions have been imported
@@ -401,92 +448,61 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 }
 
 // CheckConfigForkOrder checks that we don't "skip" any forks, geth isn't pluggable enough
-// to guarantee that forks can be implemented in a different order than on official networks
+// to guarantee that forks can be implemented in a different order than on official networks.
+// It also rejects unknown Parlia base fee policies.
 func (c *ChainConfig) CheckConfigForkOrder() error {
-	type fork struct {
-		name     string
-		block    *big.Int
-		optional bool // if true, the fork may be nil and next fork is still allowed
+	if c.Parlia != nil {
+		switch c.Parlia.BaseFeePolicy {
+		case "", BaseFeePolicyBurn, BaseFeePolicyDistribute:
+		default:
+			return fmt.Errorf("unsupported Parlia base fee policy %q, want %q or %q", c.Parlia.BaseFeePolicy, BaseFeePolicyBurn, BaseFeePolicyDistribute)
+		}
 	}
-	var lastFork fork
-	for _, cur := range []fork{
-		{name: "mirrorSyncBlock", block: c.MirrorSyncBlock},
-		{name: "brunoBlock", block: c.BrunoBlock},
-		{name: "berlinBlock", block: c.BerlinBlock},
-	} {
-		if lastFork.name != "" {
+	lastForks := make(map[forkGroup]*forkDefinition)
+	for _, cur := range forks {
+		if cur.group == unorderedForks {
+			continue
+		}
+		if last := lastForks[cur.group]; last != nil {
+			lastBlock, curBlock := last.block(c), cur.block(c)
 			// Next one must be higher number
-			if lastFork.block == nil && cur.block != nil {
+			if lastBlock == nil && curBlock != nil {
 				return fmt.Errorf("unsupported fork ordering: %v not enabled, but %v enabled at %v",
-					lastFork.name, cur.name, cur.block)
+					last.name, cur.name, curBlock)
 			}
-			if lastFork.block != nil && cur.block != nil {
-				if lastFork.block.Cmp(cur.block) > 0 {
+			if lastBlock != nil && curBlock != nil {
+				if lastBlock.Cmp(curBlock) > 0 {
 					return fmt.Errorf("unsupported fork ordering: %v enabled at %v, but %v enabled at %v",
-						lastFork.name, lastFork.block, cur.name, cur.block)
+						last.name, lastBlock, cur.name, curBlock)
 				}
 			}
 		}
 		// If it was optional and not set, then ignore it
-		if !cur.optional || cur.block != nil {
-			lastFork = cur
+		if !cur.optional || cur.block(c) != nil {
+			lastForks[cur.group] = cur
 		}
 	}
 	return nil
 }
 
 func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
-	if isForkIncompatible(c.HomesteadBlock, newcfg.HomesteadBlock, head) {
-		return newCompatError("Homestead fork block", c.HomesteadBlock, newcfg.HomesteadBlock)
-	}
-	if isForkIncompatible(c.EIP150Block, newcfg.EIP150Block, head) {
-		return newCompatError("EIP150 fork block", c.EIP150Block, newcfg.EIP150Block)
-	}
-	if isForkIncompatible(c.EIP155Block, newcfg.EIP155Block, head) {
-		return newCompatError("EIP155 fork block", c.EIP155Block, newcfg.EIP155Block)
-	}
-	if isForkIncompatible(c.EIP158Block, newcfg.EIP158Block, head) {
-		return newCompatError("EIP158 fork block", c.EIP158Block, newcfg.EIP158Block)
-	}
-	if c.IsEIP158(head) && !configNumEqual(c.ChainID, newcfg.ChainID) {
-		return newCompatError("EIP158 chain ID", c.EIP158Block, newcfg.EIP158Block)
-	}
-	if isForkIncompatible(c.ByzantiumBlock, newcfg.ByzantiumBlock, head) {
-		return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
-	}
-	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
-		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
-	}
-	if isForkIncompatible(c.PetersburgBlock, newcfg.PetersburgBlock, head) {
-		// the only case where we allow Petersburg to be set in the past is if it is equal to Constantinople
-		// mainly to satisfy fork ordering requirements which state that Petersburg fork be set if Constantinople fork is set
-		if isForkIncompatible(c.ConstantinopleBlock, newcfg.PetersburgBlock, head) {
-			return newCompatError("Petersburg fork block", c.PetersburgBlock, newcfg.PetersburgBlock)
+	for _, f := range forks {
+		stored, updated := f.block(c), f.block(newcfg)
+		if isForkIncompatible(stored, updated, head) {
+			// the only case where we allow Petersburg to be set in the past is if it is equal to Constantinople
+			// mainly to satisfy fork ordering requirements which state that Petersburg fork be set if Constantinople fork is set
+			if f != petersburgFork || isForkIncompatible(c.ConstantinopleBlock, updated, head) {
+				return newCompatError(f.name+" fork block", stored, updated)
+			}
+		}
+		if f == eip158Fork && c.IsEIP158(head) && !configNumEqual(c.ChainID, newcfg.ChainID) {
+			return newCompatError("EIP158 chain ID", c.EIP158Block, newcfg.EIP158Block)
+		}
+		// The base fee policy has no fork block of its own, it can't change once
+		// London charged any base fee
+		if f == londonFork && c.IsLondon(head) && c.IsBaseFeeDistributed() != newcfg.IsBaseFeeDistributed() {
+			return newCompatError("Parlia base fee policy", c.LondonBlock, newcfg.LondonBlock)
 		}
-	}
-	if isForkIncompatible(c.IstanbulBlock, newcfg.IstanbulBlock, head) {
-		return newCompatError("Istanbul fork block", c.IstanbulBlock, newcfg.IstanbulBlock)
-	}
-	if isForkIncompatible(c.MuirGlacierBlock, newcfg.MuirGlacierBlock, head) {
-		return newCompatError("Muir Glacier fork block", c.MuirGlacierBlock, newcfg.MuirGlacierBlock)
-	}
-	if isForkIncompatible(c.BerlinBlock, newcfg.BerlinBlock, head) {
-		return newCompatError("Berlin fork block", c.BerlinBlock, newcfg.BerlinBlock)
-	}
-	if isForkIncompatible(c.YoloV3Block, newcfg.YoloV3Block, head) {
-		return newCompatError("YOLOv3 fork block", c.YoloV3Block, newcfg.YoloV3Block)
-	}
-	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
-		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
-	}
-	if isForkIncompatible(c.RamanujanBlock, newcfg.RamanujanBlock, head) {
-		return newCompatError("ramanujan fork block", c.RamanujanBlock, newcfg.RamanujanBlock)
-	}
-	if isForkIncompatible(c.MirrorSyncBlock, newcfg.MirrorSyncBlock, head) {
-		return newCompatError("mirrorSync fork block", c.MirrorSyncBlock, newcfg.MirrorSyncBlock)
-	}
-	if isForkIncompatible(c.BrunoBlock, newcfg.BrunoBlock, head) {
-		return newCompatError("bruno fork block", c.BrunoBlock, newcfg.BrunoBlock)
 	}
 	return nil
 }
@@ -555,9 +571,9 @@ type Rules struct {
 	ChainID                                                       *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158, IsContract48kBlock bool
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul       bool
-	IsBerlin, IsCatalyst                                          bool
+	IsBerlin, IsLondon, IsCatalyst                                bool
 	HasRuntimeUpgrade, HasDeployerProxy                           bool
-	HasBlockRewards                                               bool
+	HasBlockRewards, HasGasSponsor                                bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -566,21 +582,11 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainID == nil {
 		chainID = new(big.Int)
 	}
-	return Rules{
-		ChainID:            new(big.Int).Set(chainID),
-		IsHomestead:        c.IsHomestead(num),
-		IsEIP150:           c.IsEIP150(num),
-		IsEIP155:           c.IsEIP155(num),
-		IsEIP158:           c.IsEIP158(num),
-		IsContract48kBlock: c.IsContract48kBlock(num),
-		IsByzantium:        c.IsByzantium(num),
-		IsConstantinople:   c.IsConstantinople(num),
-		IsPetersburg:       c.IsPetersburg(num),
-		IsIstanbul:         c.IsIstanbul(num),
-		IsBerlin:           c.IsBerlin(num),
-		IsCatalyst:         c.IsCatalyst(num),
-		HasRuntimeUpgrade:  c.HasRuntimeUpgrade(num),
-		HasDeployerProxy:   c.HasDeployerProxy(num),
-		HasBlockRewards:    c.IsBlockRewardsBlock(num),
+	rules := Rules{ChainID: new(big.Int).Set(chainID)}
+	for _, f := range forks {
+		if f.rule != nil {
+			*f.rule(&rules) = f.isActive(c, num)
+		}
 	}
+	return rules
 }
@@ -19,6 +19,7 @@ package params
 import (
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -76,6 +77,68 @@ func TestCheckCompatible(t *testing.T) {
 			head:    40,
 			wantErr: nil,
 		},
+		{
+			stored: &ChainConfig{Fncy2Block: big.NewInt(10)},
+			new:    &ChainConfig{Fncy2Block: big.NewInt(20)},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Fncy2 fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{Parlia: &ParliaConfig{StopMintBlock: big.NewInt(10)}},
+			new:    &ChainConfig{Parlia: &ParliaConfig{}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "StopMint fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{Parlia: &ParliaConfig{FastFinalityBlock: big.NewInt(10)}},
+			new:    &ChainConfig{Parlia: &ParliaConfig{FastFinalityBlock: big.NewInt(20)}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "FastFinality fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{LondonBlock: big.NewInt(10), Parlia: &ParliaConfig{}},
+			new:     &ChainConfig{LondonBlock: big.NewInt(10), Parlia: &ParliaConfig{BaseFeePolicy: BaseFeePolicyBurn}},
+			head:    15,
+			wantErr: nil,
+		},
+		{
+			stored:  &ChainConfig{LondonBlock: big.NewInt(10), Parlia: &ParliaConfig{}},
+			new:     &ChainConfig{LondonBlock: big.NewInt(10), Parlia: &ParliaConfig{BaseFeePolicy: BaseFeePolicyDistribute}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{LondonBlock: big.NewInt(10), Parlia: &ParliaConfig{}},
+			new:    &ChainConfig{LondonBlock: big.NewInt(10), Parlia: &ParliaConfig{BaseFeePolicy: BaseFeePolicyDistribute}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Parlia base fee policy",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{RuntimeUpgradeBlock: big.NewInt(10), DeployerProxyBlock: big.NewInt(10)},
+			new:     &ChainConfig{RuntimeUpgradeBlock: big.NewInt(10), DeployerProxyBlock: big.NewInt(20)},
+			head:    9,
+			wantErr: nil,
+		},
 		{
 			stored: &ChainConfig{ConstantinopleBlock: big.NewInt(30)},
 			new:    &ChainConfig{ConstantinopleBlock: big.NewInt(30), PetersburgBlock: big.NewInt(31)},
@@ -96,3 +159,85 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+// TestForkTableComplete checks that every fork block of the chain config is
+// declared in the fork table, and that the table announces the same forks in
+// the forkid as the reflection based gathering it replaced.
+func TestForkTableComplete(t *testing.T) {
+	config := &ChainConfig{}
+	kind := reflect.TypeOf(ChainConfig{})
+	value := reflect.ValueOf(config).Elem()
+
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		if !strings.HasSuffix(field.Name, "Block") || field.Type != reflect.TypeOf(new(big.Int)) {
+			continue
+		}
+		block := big.NewInt(int64(1000 + i))
+		value.Field(i).Set(reflect.ValueOf(block))
+
+		var found *forkDefinition
+		for _, f := range forks {
+			if f.block(config) == block {
+				found = f
+			}
+		}
+		if found == nil {
+			t.Errorf("fork block %s is not declared in the fork table", field.Name)
+			continue
+		}
+		if !found.forkID {
+			t.Errorf("fork %s is not part of the forkid", found.name)
+		}
+	}
+	// The forks of the Parlia engine config are not scheduled on the config above
+	if have, want := len(config.ForkIDBlocks()), len(forks)-4; have != want {
+		t.Errorf("forkid block count mismatch: have %d, want %d", have, want)
+	}
+}
+
+func TestCheckConfigForkOrder(t *testing.T) {
+	tests := []struct {
+		config  *ChainConfig
+		wantErr bool
+	}{
+		{&ChainConfig{}, false},
+		{&ChainConfig{MirrorSyncBlock: big.NewInt(1), BrunoBlock: big.NewInt(2), BerlinBlock: big.NewInt(3)}, false},
+		{&ChainConfig{MirrorSyncBlock: big.NewInt(2), BrunoBlock: big.NewInt(1)}, true},
+		{&ChainConfig{BrunoBlock: big.NewInt(1)}, true},
+		// unordered forks can be scheduled independently
+		{&ChainConfig{Fncy2Block: big.NewInt(1), Contract48kBlock: big.NewInt(5), RuntimeUpgradeBlock: big.NewInt(3)}, false},
+		// Parlia engine forks are optional, but ordered if scheduled
+		{&ChainConfig{Parlia: &ParliaConfig{FastFinalityBlock: big.NewInt(1)}}, false},
+		{&ChainConfig{Parlia: &ParliaConfig{ParamsBlock: big.NewInt(1), EvidenceBlock: big.NewInt(2), FastFinalityBlock: big.NewInt(3)}}, false},
+		{&ChainConfig{Parlia: &ParliaConfig{ParamsBlock: big.NewInt(3), FastFinalityBlock: big.NewInt(2)}}, true},
+		// only the known base fee policies are accepted
+		{&ChainConfig{Parlia: &ParliaConfig{BaseFeePolicy: BaseFeePolicyBurn}}, false},
+		{&ChainConfig{Parlia: &ParliaConfig{BaseFeePolicy: BaseFeePolicyDistribute}}, false},
+		{&ChainConfig{Parlia: &ParliaConfig{BaseFeePolicy: "distributed"}}, true},
+	}
+	for i, tt := range tests {
+		if err := tt.config.CheckConfigForkOrder(); (err != nil) != tt.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want error %v", i, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	config := &ChainConfig{
+		ChainID:             big.NewInt(1),
+		ConstantinopleBlock: big.NewInt(10),
+		RuntimeUpgradeBlock: big.NewInt(20),
+		YoloV3Block:         big.NewInt(30),
+	}
+	rules := config.Rules(big.NewInt(20))
+	if !rules.IsConstantinople || !rules.IsPetersburg || !rules.HasRuntimeUpgrade {
+		t.Errorf("missing active rules: %+v", rules)
+	}
+	if rules.IsBerlin || rules.HasDeployerProxy || rules.IsHomestead {
+		t.Errorf("unexpected active rules: %+v", rules)
+	}
+	if rules := config.Rules(big.NewInt(30)); !rules.IsBerlin {
+		t.Errorf("berlin not implied by yolov3: %+v", rules)
+	}
+}
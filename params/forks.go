@@ -0,0 +1,322 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"math/big"
+)
+
+// forkGroup is a set of forks which must be scheduled in the order they are
+// declared in the fork table.
+type forkGroup int
+
+const (
+	unorderedForks forkGroup = iota // Forks which may be scheduled independently
+	bscForks                        // BSC upgrades depending on each other
+	parliaForks                     // Parlia engine upgrades, each of them optional
+)
+
+// forkDefinition declares a block based protocol upgrade. The fork table below
+// is the single source of truth for fork activation, the Rules flags, config
+// compatibility and ordering checks, the config's String representation and
+// the forks announced in the eth protocol forkid.
+type forkDefinition struct {
+	name     string                                  // Human readable name, used in errors and listings
+	block    func(c *ChainConfig) *big.Int           // Accessor of the activation block (nil = no fork)
+	active   func(c *ChainConfig, num *big.Int) bool // Custom activation rule, nil if the fork is active from its block on
+	rule     func(r *Rules) *bool                    // Rules flag mirroring the fork, nil if the fork has none
+	group    forkGroup                               // Ordering group of the fork
+	optional bool                                    // If true, the fork may be nil while later forks of the group are enabled
+	forkID   bool                                    // Whether the fork is announced in the eth protocol forkid
+}
+
+// isActive returns whether the fork is active at the given block on chain c.
+func (f *forkDefinition) isActive(c *ChainConfig, num *big.Int) bool {
+	if f.active != nil {
+		return f.active(c, num)
+	}
+	return isForked(f.block(c), num)
+}
+
+var (
+	homesteadFork = &forkDefinition{
+		name:   "Homestead",
+		block:  func(c *ChainConfig) *big.Int { return c.HomesteadBlock },
+		rule:   func(r *Rules) *bool { return &r.IsHomestead },
+		forkID: true,
+	}
+	eip150Fork = &forkDefinition{
+		name:   "EIP150",
+		block:  func(c *ChainConfig) *big.Int { return c.EIP150Block },
+		rule:   func(r *Rules) *bool { return &r.IsEIP150 },
+		forkID: true,
+	}
+	eip155Fork = &forkDefinition{
+		name:   "EIP155",
+		block:  func(c *ChainConfig) *big.Int { return c.EIP155Block },
+		rule:   func(r *Rules) *bool { return &r.IsEIP155 },
+		forkID: true,
+	}
+	eip158Fork = &forkDefinition{
+		name:   "EIP158",
+		block:  func(c *ChainConfig) *big.Int { return c.EIP158Block },
+		rule:   func(r *Rules) *bool { return &r.IsEIP158 },
+		forkID: true,
+	}
+	byzantiumFork = &forkDefinition{
+		name:   "Byzantium",
+		block:  func(c *ChainConfig) *big.Int { return c.ByzantiumBlock },
+		rule:   func(r *Rules) *bool { return &r.IsByzantium },
+		forkID: true,
+	}
+	constantinopleFork = &forkDefinition{
+		name:   "Constantinople",
+		block:  func(c *ChainConfig) *big.Int { return c.ConstantinopleBlock },
+		rule:   func(r *Rules) *bool { return &r.IsConstantinople },
+		forkID: true,
+	}
+	petersburgFork = &forkDefinition{
+		name:  "Petersburg",
+		block: func(c *ChainConfig) *big.Int { return c.PetersburgBlock },
+		// Petersburg is implicitly active together with Constantinople if not set
+		active: func(c *ChainConfig, num *big.Int) bool {
+			return isForked(c.PetersburgBlock, num) || c.PetersburgBlock == nil && isForked(c.ConstantinopleBlock, num)
+		},
+		rule:   func(r *Rules) *bool { return &r.IsPetersburg },
+		forkID: true,
+	}
+	istanbulFork = &forkDefinition{
+		name:   "Istanbul",
+		block:  func(c *ChainConfig) *big.Int { return c.IstanbulBlock },
+		rule:   func(r *Rules) *bool { return &r.IsIstanbul },
+		forkID: true,
+	}
+	muirGlacierFork = &forkDefinition{
+		name:   "Muir Glacier",
+		block:  func(c *ChainConfig) *big.Int { return c.MuirGlacierBlock },
+		forkID: true,
+	}
+	ramanujanFork = &forkDefinition{
+		name:   "Ramanujan",
+		block:  func(c *ChainConfig) *big.Int { return c.RamanujanBlock },
+		forkID: true,
+	}
+	nielsFork = &forkDefinition{
+		name:   "Niels",
+		block:  func(c *ChainConfig) *big.Int { return c.NielsBlock },
+		forkID: true,
+	}
+	mirrorSyncFork = &forkDefinition{
+		name:   "MirrorSync",
+		block:  func(c *ChainConfig) *big.Int { return c.MirrorSyncBlock },
+		group:  bscForks,
+		forkID: true,
+	}
+	brunoFork = &forkDefinition{
+		name:   "Bruno",
+		block:  func(c *ChainConfig) *big.Int { return c.BrunoBlock },
+		group:  bscForks,
+		forkID: true,
+	}
+	berlinFork = &forkDefinition{
+		name:  "Berlin",
+		block: func(c *ChainConfig) *big.Int { return c.BerlinBlock },
+		// The YOLOv3 testnet enables the Berlin ruleset ahead of time
+		active: func(c *ChainConfig, num *big.Int) bool {
+			return isForked(c.BerlinBlock, num) || isForked(c.YoloV3Block, num)
+		},
+		rule:   func(r *Rules) *bool { return &r.IsBerlin },
+		group:  bscForks,
+		forkID: true,
+	}
+	londonFork = &forkDefinition{
+		name:   "London",
+		block:  func(c *ChainConfig) *big.Int { return c.LondonBlock },
+		rule:   func(r *Rules) *bool { return &r.IsLondon },
+		group:  bscForks,
+		forkID: true,
+	}
+	yoloV3Fork = &forkDefinition{
+		name:   "YOLOv3",
+		block:  func(c *ChainConfig) *big.Int { return c.YoloV3Block },
+		forkID: true,
+	}
+	ewasmFork = &forkDefinition{
+		name:   "EWASM",
+		block:  func(c *ChainConfig) *big.Int { return c.EWASMBlock },
+		forkID: true,
+	}
+	catalystFork = &forkDefinition{
+		name:   "Catalyst",
+		block:  func(c *ChainConfig) *big.Int { return c.CatalystBlock },
+		rule:   func(r *Rules) *bool { return &r.IsCatalyst },
+		forkID: true,
+	}
+	runtimeUpgradeFork = &forkDefinition{
+		name:   "RuntimeUpgrade",
+		block:  func(c *ChainConfig) *big.Int { return c.RuntimeUpgradeBlock },
+		rule:   func(r *Rules) *bool { return &r.HasRuntimeUpgrade },
+		forkID: true,
+	}
+	deployerProxyFork = &forkDefinition{
+		name:   "DeployerProxy",
+		block:  func(c *ChainConfig) *big.Int { return c.DeployerProxyBlock },
+		rule:   func(r *Rules) *bool { return &r.HasDeployerProxy },
+		forkID: true,
+	}
+	blockRewardsFork = &forkDefinition{
+		name:   "BlockRewards",
+		block:  func(c *ChainConfig) *big.Int { return c.BlockRewardsBlock },
+		rule:   func(r *Rules) *bool { return &r.HasBlockRewards },
+		forkID: true,
+	}
+	contract48kFork = &forkDefinition{
+		name:   "Contract48k",
+		block:  func(c *ChainConfig) *big.Int { return c.Contract48kBlock },
+		rule:   func(r *Rules) *bool { return &r.IsContract48kBlock },
+		forkID: true,
+	}
+	fncy2Fork = &forkDefinition{
+		name:   "Fncy2",
+		block:  func(c *ChainConfig) *big.Int { return c.Fncy2Block },
+		forkID: true,
+	}
+	gasSponsorFork = &forkDefinition{
+		name:   "GasSponsor",
+		block:  func(c *ChainConfig) *big.Int { return c.GasSponsorBlock },
+		rule:   func(r *Rules) *bool { return &r.HasGasSponsor },
+		forkID: true,
+	}
+	// StopMint lives in the engine config and was never part of the forkid,
+	// announcing it now would split existing networks.
+	stopMintFork = &forkDefinition{
+		name: "StopMint",
+		block: func(c *ChainConfig) *big.Int {
+			if c.Parlia == nil {
+				return nil
+			}
+			return c.Parlia.StopMintBlock
+		},
+	}
+	parliaParamsFork = &forkDefinition{
+		name: "ParliaParams",
+		block: func(c *ChainConfig) *big.Int {
+			if c.Parlia == nil {
+				return nil
+			}
+			return c.Parlia.ParamsBlock
+		},
+		group:    parliaForks,
+		optional: true,
+		forkID:   true,
+	}
+	evidenceFork = &forkDefinition{
+		name: "DoubleSignEvidence",
+		block: func(c *ChainConfig) *big.Int {
+			if c.Parlia == nil {
+				return nil
+			}
+			return c.Parlia.EvidenceBlock
+		},
+		group:    parliaForks,
+		optional: true,
+		forkID:   true,
+	}
+	fastFinalityFork = &forkDefinition{
+		name: "FastFinality",
+		block: func(c *ChainConfig) *big.Int {
+			if c.Parlia == nil {
+				return nil
+			}
+			return c.Parlia.FastFinalityBlock
+		},
+		group:    parliaForks,
+		optional: true,
+		forkID:   true,
+	}
+)
+
+// forks is the table of all known forks. Compatibility checks are done in the
+// order of the table, forks of the same group must be scheduled in this order.
+var forks = []*forkDefinition{
+	homesteadFork,
+	eip150Fork,
+	eip155Fork,
+	eip158Fork,
+	byzantiumFork,
+	constantinopleFork,
+	petersburgFork,
+	istanbulFork,
+	muirGlacierFork,
+	ramanujanFork,
+	nielsFork,
+	mirrorSyncFork,
+	brunoFork,
+	berlinFork,
+	londonFork,
+	yoloV3Fork,
+	ewasmFork,
+	catalystFork,
+	runtimeUpgradeFork,
+	deployerProxyFork,
+	blockRewardsFork,
+	contract48kFork,
+	fncy2Fork,
+	gasSponsorFork,
+	stopMintFork,
+	parliaParamsFork,
+	evidenceFork,
+	fastFinalityFork,
+}
+
+// ForkStatus describes the scheduling of a single fork on a chain.
+type ForkStatus struct {
+	Name   string   // Human readable name of the fork
+	Block  *big.Int // Activation block, nil if the fork is not scheduled
+	Active bool     // Whether the fork is active at the queried block
+	ForkID bool     // Whether the fork is announced in the eth protocol forkid
+}
+
+// ForkSchedule returns the scheduling of every known fork and whether it is
+// active at the given block.
+func (c *ChainConfig) ForkSchedule(num *big.Int) []ForkStatus {
+	schedule := make([]ForkStatus, 0, len(forks))
+	for _, f := range forks {
+		schedule = append(schedule, ForkStatus{
+			Name:   f.name,
+			Block:  f.block(c),
+			Active: f.isActive(c, num),
+			ForkID: f.forkID,
+		})
+	}
+	return schedule
+}
+
+// ForkIDBlocks returns the activation blocks of all scheduled forks which are
+// announced in the eth protocol forkid, in table order and with duplicates.
+func (c *ChainConfig) ForkIDBlocks() []uint64 {
+	var blocks []uint64
+	for _, f := range forks {
+		if !f.forkID {
+			continue
+		}
+		if block := f.block(c); block != nil {
+			blocks = append(blocks, block.Uint64())
+		}
+	}
+	return blocks
+}
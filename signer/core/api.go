@@ -40,7 +40,7 @@ const (
 	// numberOfAccountsToDerive For hardware wallets, the number of accounts to derive
 	numberOfAccountsToDerive = 10
 	// ExternalAPIVersion -- see extapi_changelog.md
-	ExternalAPIVersion = "6.1.0"
+	ExternalAPIVersion = "6.3.0"
 	// InternalAPIVersion -- see intapi_changelog.md
 	InternalAPIVersion = "7.0.1"
 )
@@ -115,6 +115,7 @@ type SignerAPI struct {
 	validator   Validator
 	rejectMode  bool
 	credentials storage.Storage
+	slashing    *slashingProtection
 }
 
 // Metadata about a request
@@ -282,12 +283,19 @@ func NewSignerAPI(am *accounts.Manager, chainID int64, noUSB bool, ui UIClientAP
 	if advancedMode {
 		log.Info("Clef is in advanced mode: will warn instead of reject")
 	}
-	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, !advancedMode, credentials}
+	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, !advancedMode, credentials, newSlashingProtection(storage.NewEphemeralStorage())}
 	if !noUSB {
 		signer.startUSBListener()
 	}
 	return signer
 }
+
+// SetSlashingProtection sets the storage remembering the Parlia headers signed,
+// so that the signer never signs two different headers at the same height. By
+// default the signed headers are only remembered in memory.
+func (api *SignerAPI) SetSlashingProtection(db storage.Storage) {
+	api.slashing = newSlashingProtection(db)
+}
 func (api *SignerAPI) openTrezor(url accounts.URL) {
 	resp, err := api.UI.OnInputRequired(UserInputRequest{
 		Prompt: "Pin required to open Trezor wallet\n" +
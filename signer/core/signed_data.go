@@ -63,6 +63,14 @@ var (
 		accounts.MimetypeParlia,
 		0x03,
 	}
+	ApplicationParliaDiffLayer = SigFormat{
+		accounts.MimetypeParliaDiffLayer,
+		0x04,
+	}
+	ApplicationParliaVote = SigFormat{
+		accounts.MimetypeParliaVote,
+		0x05,
+	}
 	TextPlain = SigFormat{
 		accounts.MimetypeTextPlain,
 		0x45,
@@ -172,7 +180,21 @@ func (api *SignerAPI) SignData(ctx context.Context, contentType string, addr com
 	if err != nil {
 		return nil, err
 	}
-	signature, err := api.sign(req, transformV)
+	var signature hexutil.Bytes
+	if req.ContentType == ApplicationParlia.Mime {
+		// Parlia headers are only signed if they don't conflict with one signed
+		// before, otherwise the validator would get slashed
+		header, _, decErr := parlia.DecodeParliaRLP(req.Rawdata)
+		if decErr != nil {
+			return nil, decErr
+		}
+		err = api.slashing.sign(addr.Address(), header.Number.Uint64(), common.BytesToHash(req.Hash), func() (err error) {
+			signature, err = api.sign(req, transformV)
+			return err
+		})
+	} else {
+		signature, err = api.sign(req, transformV)
+	}
 	if err != nil {
 		api.UI.ShowError(err.Error())
 		return nil, err
@@ -272,16 +294,16 @@ func (api *SignerAPI) determineSignatureFormat(ctx context.Context, contentType
 		if err != nil {
 			return nil, useEthereumV, err
 		}
-		header := &types.Header{}
-		if err := rlp.DecodeBytes(parliaData, header); err != nil {
+		// The incoming parlia header is the sealed part only, prefixed by the chain id
+		header, chainID, err := parlia.DecodeParliaRLP(parliaData)
+		if err != nil {
 			return nil, useEthereumV, err
 		}
-		// The incoming parlia header is already truncated, sent to us with a extradata already shortened
-		if len(header.Extra) < 65 {
-			// Need to add it back, to get a suitable length for hashing
-			newExtra := make([]byte, len(header.Extra)+65)
-			copy(newExtra, header.Extra)
-			header.Extra = newExtra
+		if chainID.Cmp(api.chainID) != 0 {
+			return nil, useEthereumV, fmt.Errorf("parlia header for chain id %d does not match the configuration of the signer", chainID)
+		}
+		if header.Coinbase != addr.Address() {
+			return nil, useEthereumV, fmt.Errorf("parlia header sealed by %s, not by %s", header.Coinbase.Hex(), addr.Address().Hex())
 		}
 		// Get back the rlp data, encoded by us
 		sighash, parliaRlp, err := parliaHeaderHashAndRlp(header, api.chainID)
@@ -292,12 +314,93 @@ func (api *SignerAPI) determineSignatureFormat(ctx context.Context, contentType
 			{
 				Name:  "Parlia header",
 				Typ:   "parlia",
-				Value: fmt.Sprintf("parlia header %d [0x%x]", header.Number, header.Hash()),
+				Value: fmt.Sprintf("parlia header %d [0x%x]", header.Number, sighash),
+			},
+			{
+				Name:  "Block number",
+				Typ:   "uint64",
+				Value: header.Number.String(),
+			},
+			{
+				Name:  "Parent hash",
+				Typ:   "bytes32",
+				Value: header.ParentHash.Hex(),
+			},
+			{
+				Name:  "Difficulty",
+				Typ:   "uint256",
+				Value: header.Difficulty.String(),
 			},
 		}
 		// Parlia uses V on the form 0 or 1
 		useEthereumV = false
 		req = &SignDataRequest{ContentType: mediaType, Rawdata: parliaRlp, Messages: messages, Hash: sighash}
+	case ApplicationParliaDiffLayer.Mime:
+		stringData, ok := data.(string)
+		if !ok {
+			return nil, useEthereumV, fmt.Errorf("input for %v must be an hex-encoded string", ApplicationParliaDiffLayer.Mime)
+		}
+		diffData, err := hexutil.Decode(stringData)
+		if err != nil {
+			return nil, useEthereumV, err
+		}
+		// Only well formed diff layers are signed, which can't be mistaken for a
+		// header, as that would bypass the slashing protection
+		diffLayer := new(types.DiffLayer)
+		if err := rlp.DecodeBytes(diffData, diffLayer); err != nil {
+			return nil, useEthereumV, err
+		}
+		sighash := crypto.Keccak256(diffData)
+		messages := []*NameValueType{
+			{
+				Name:  "Parlia diff layer",
+				Typ:   "parlia",
+				Value: fmt.Sprintf("parlia diff layer %d [0x%x]", diffLayer.Number, sighash),
+			},
+			{
+				Name:  "Block hash",
+				Typ:   "bytes32",
+				Value: diffLayer.BlockHash.Hex(),
+			},
+		}
+		// Diff layer signatures are verified like Parlia seals, V on the form 0 or 1
+		useEthereumV = false
+		req = &SignDataRequest{ContentType: mediaType, Rawdata: diffData, Messages: messages, Hash: sighash}
+	case ApplicationParliaVote.Mime:
+		stringData, ok := data.(string)
+		if !ok {
+			return nil, useEthereumV, fmt.Errorf("input for %v must be an hex-encoded string", ApplicationParliaVote.Mime)
+		}
+		voteData, err := hexutil.Decode(stringData)
+		if err != nil {
+			return nil, useEthereumV, err
+		}
+		// Only well formed votes are signed, which can't be mistaken for a header
+		vote := new(types.VoteData)
+		if err := rlp.DecodeBytes(voteData, vote); err != nil {
+			return nil, useEthereumV, err
+		}
+		sighash := crypto.Keccak256(voteData)
+		messages := []*NameValueType{
+			{
+				Name:  "Parlia vote",
+				Typ:   "parlia",
+				Value: fmt.Sprintf("parlia vote for block %d [0x%x]", vote.TargetNumber, sighash),
+			},
+			{
+				Name:  "Target hash",
+				Typ:   "bytes32",
+				Value: vote.TargetHash.Hex(),
+			},
+			{
+				Name:  "Justified block",
+				Typ:   "parlia",
+				Value: fmt.Sprintf("%d [%s]", vote.SourceNumber, vote.SourceHash.Hex()),
+			},
+		}
+		// Votes are verified like Parlia seals, V on the form 0 or 1
+		useEthereumV = false
+		req = &SignDataRequest{ContentType: mediaType, Rawdata: voteData, Messages: messages, Hash: sighash}
 	default: // also case TextPlain.Mime:
 		// Calculates an Ethereum ECDSA signature for:
 		// hash = keccak256("\x19${byteVersion}Ethereum Signed Message:\n${message length}${message}")
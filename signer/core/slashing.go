@@ -0,0 +1,138 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// slashingProtectionWindow is the number of most recent heights for which the
+// signed headers of an account are remembered. Requests to sign below the window
+// are refused, as it can't be told any more whether they are conflicting.
+const slashingProtectionWindow = 256
+
+var (
+	// ErrDoubleSign is returned if a header is requested to be signed at a height
+	// where the account already signed a different header.
+	ErrDoubleSign = errors.New("different header already signed at this height")
+
+	// ErrHeaderTooOld is returned if a header is requested to be signed below the
+	// heights remembered by the slashing protection.
+	ErrHeaderTooOld = errors.New("header below slashing protection window")
+)
+
+// signedHeaders is the slashing protection record of an account.
+type signedHeaders struct {
+	Low     uint64                 `json:"low"`     // Lowest height that may be signed
+	Headers map[uint64]common.Hash `json:"headers"` // Seal hashes of the headers signed, by height
+}
+
+// slashingProtection remembers the Parlia headers signed by every account, so
+// that no two different headers are ever signed at the same height, which would
+// get the validator slashed for double signing.
+type slashingProtection struct {
+	db   storage.Storage
+	lock sync.Mutex // Serializes the signing of headers, from check until record
+}
+
+// newSlashingProtection creates a slashing protection storing its records in
+// the given storage.
+func newSlashingProtection(db storage.Storage) *slashingProtection {
+	return &slashingProtection{db: db}
+}
+
+// load retrieves the slashing protection record of an account.
+func (p *slashingProtection) load(addr common.Address) (*signedHeaders, error) {
+	record := &signedHeaders{Headers: make(map[uint64]common.Hash)}
+	blob, err := p.db.Get(addr.Hex())
+	if errors.Is(err, storage.ErrNotFound) {
+		return record, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(blob), record); err != nil {
+		return nil, err
+	}
+	if record.Headers == nil {
+		record.Headers = make(map[uint64]common.Hash)
+	}
+	return record, nil
+}
+
+// store persists the slashing protection record of an account. The storage
+// doesn't report write failures, so the record is read back to verify that it
+// got persisted.
+func (p *slashingProtection) store(addr common.Address, record *signedHeaders) error {
+	blob, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	p.db.Put(addr.Hex(), string(blob))
+	stored, err := p.db.Get(addr.Hex())
+	if err != nil {
+		return fmt.Errorf("failed to persist slashing protection record: %w", err)
+	}
+	if stored != string(blob) {
+		return errors.New("failed to persist slashing protection record")
+	}
+	return nil
+}
+
+// sign invokes the signing function if the account didn't sign a different
+// header at the same height yet. Signing the same header again is allowed. The
+// header is recorded as signed before the signing function is invoked, so that
+// a signature is never handed out without the record surviving a crash; if the
+// record can't be persisted nothing is signed. A header recorded for a failed
+// signing attempt still blocks different headers at its height.
+func (p *slashingProtection) sign(addr common.Address, number uint64, sealHash common.Hash, signFn func() error) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	record, err := p.load(addr)
+	if err != nil {
+		return err
+	}
+	if number < record.Low {
+		return fmt.Errorf("%w: #%d < #%d", ErrHeaderTooOld, number, record.Low)
+	}
+	signed, ok := record.Headers[number]
+	if ok && signed != sealHash {
+		return fmt.Errorf("%w: #%d signed %x", ErrDoubleSign, number, signed)
+	}
+	if !ok {
+		record.Headers[number] = sealHash
+		if number >= slashingProtectionWindow && number-slashingProtectionWindow+1 > record.Low {
+			record.Low = number - slashingProtectionWindow + 1
+		}
+		for height := range record.Headers {
+			if height < record.Low {
+				delete(record.Headers, height)
+			}
+		}
+		if err := p.store(addr, record); err != nil {
+			return err
+		}
+	}
+	return signFn()
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+func TestSlashingProtectionPersistsBeforeSigning(t *testing.T) {
+	var (
+		addr = common.HexToAddress("0x01")
+		db   = storage.NewEphemeralStorage()
+		p    = newSlashingProtection(db)
+	)
+	// the record must already be persisted when the signature is produced
+	err := p.sign(addr, 10, common.Hash{0x01}, func() error {
+		record, err := newSlashingProtection(db).load(addr)
+		if err != nil {
+			return err
+		}
+		if record.Headers[10] != (common.Hash{0x01}) {
+			return errors.New("header not recorded before signing")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	// a failed signing attempt still blocks different headers at its height
+	failure := errors.New("signer failure")
+	if err := p.sign(addr, 11, common.Hash{0x02}, func() error { return failure }); err != failure {
+		t.Fatalf("signing error mismatch: have %v, want %v", err, failure)
+	}
+	if err := p.sign(addr, 11, common.Hash{0x03}, func() error { return nil }); !errors.Is(err, ErrDoubleSign) {
+		t.Fatalf("double sign error mismatch: have %v, want %v", err, ErrDoubleSign)
+	}
+	if err := p.sign(addr, 11, common.Hash{0x02}, func() error { return nil }); err != nil {
+		t.Fatalf("failed to retry signing: %v", err)
+	}
+}
+
+func TestSlashingProtectionStorageFailure(t *testing.T) {
+	p := newSlashingProtection(&storage.NoStorage{})
+
+	signed := false
+	err := p.sign(common.HexToAddress("0x01"), 10, common.Hash{0x01}, func() error {
+		signed = true
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("signing succeeded without persisting the record")
+	}
+	if signed {
+		t.Errorf("header signed without persisting the record")
+	}
+}